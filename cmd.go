@@ -6,56 +6,171 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
-	"regexp"
+	"runtime"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
 	"github.com/bwplotka/bingo/pkg/runner"
 	"github.com/bwplotka/bingo/pkg/version"
 )
 
-func NewBingoGetCommand(logger *log.Logger) *cobra.Command {
+func NewBingoGetCommand(logger logging.Logger) *cobra.Command {
 	var (
-		goCmd    string
-		rename   string
-		name     string
-		insecure bool
-		link     bool
-		timeOut  uint
+		goCmd      string
+		rename     string
+		name       string
+		insecure   bool
+		link       bool
+		linkDir    string
+		makefile   string
+		asLibrary  string
+		outputDir  string
+		timeOut    uint
+		parallel   int
+		dryRun     bool
+		printCmd   bool
+		keepGoing  bool
+		workspace  bool
+		noBuild    bool
+		modOnly    bool
+		interact   bool
+		pinGo      bool
+		vendor     bool
+		reproduce  bool
+		keepTemp   bool
+		force      bool
+		forceClean bool
+		strict     bool
+
+		ignoreConstraints bool
+		offline           bool
+		commitViaProxy    bool
+
+		update          bool
+		allowPrerelease bool
+		sideBySide      bool
+		comment         string
+		toolchain       string
+		postInstall     string
+		replaces        []string
 	)
 
 	cmd := &cobra.Command{
-		Use: "get [flags] [<package or binary>[@version1 or none,version2,version3...]]",
+		Use: "get [flags] [<package or binary>[@version1 or none,version2,version3...]...]",
 		Example: "bingo get github.com/fatih/faillint\n" +
 			"bingo get github.com/fatih/faillint@latest\n" +
 			"bingo get github.com/fatih/faillint@v1.5.0\n" +
 			"bingo get github.com/fatih/faillint@v1.1.0,v1.5.0\n" +
-			"bingo get github.com/fatih/faillint@none // this will be deleted ",
+			"bingo get github.com/fatih/faillint@none // this will be deleted \n" +
+			"bingo get --keep-going github.com/fatih/faillint@v1.5.0 goimports@v0.1.0 // this will get both tools, even if one of them fails\n" +
+			"bingo get --update goimports@^v0.1 // this will bump goimports to the latest v0.x release, but at least v0.1.0\n" +
+			"bingo get --interactive github.com/fatih/faillint // this will ask which released version of faillint to pin\n" +
+			"bingo get --print-cmd faillint // this will print go build command bingo runs to build faillint, without running it\n" +
+			"bingo get --side-by-side github.com/x/migrate@v1.4.0 github.com/x/migrate/v2@v2.1.0 // this will pin both as migrate-v1 and migrate-v2\n" +
+			"bingo get 'proto*' // this will reinstall all pinned tools with name starting with proto\n" +
+			"bingo get --toolchain=go1.22.0 golangci-lint // this will build golangci-lint with Go 1.22.0\n" +
+			"bingo get --post-install='strip {{.Bin}}' golangci-lint // this will strip golangci-lint binary after every build\n" +
+			"bingo get --replace=github.com/x/tool=../tool github.com/x/tool/cmd/foo // this will build foo from local checkout",
 		Short: "add development tools to the current project (e.g: bingo get github.com/fatih/faillint@latest)",
 		Long: "go get like, simple CLI that allows automated versioning of Go package level \n" +
-			"binaries(e.g required as dev tools by your project!) built on top of Go Modules, allowing reproducible dev environments.",
+			"binaries(e.g required as dev tools by your project!) built on top of Go Modules, allowing reproducible dev environments.\n" +
+			"Without arguments, all pinned tools are installed, except ones with name matching patterns in <moddir>/.bingoignore.",
+		ValidArgsFunction: completeToolNames(0),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(goCmd) == 0 {
 				return errors.New("'go' flag cannot be empty")
 			}
-			if len(args) > 1 {
-				return errors.New("too many arguments except none or binary/package")
-			}
 			if len(rename) > 0 && len(name) > 0 {
 				return errors.New("Both -n and -r were specified. You can either rename or create new one.")
 			}
-			if len(name) > 0 && !regexp.MustCompile(`[a-zA-Z0-9.-_]+`).MatchString(name) {
-				return errors.New("-n name contains not allowed characters")
+			if len(name) > 0 {
+				if err := bingo.ValidateBinaryName(name); err != nil {
+					return errors.Wrap(err, "-n")
+				}
+			}
+			if len(rename) > 0 {
+				if err := bingo.ValidateBinaryName(rename); err != nil {
+					return errors.Wrap(err, "-r")
+				}
+			}
+			if cmd.Flags().Changed("link-dir") && !link {
+				return errors.New("--link-dir can be only used with -l")
+			}
+			if noBuild && link {
+				return errors.New("--no-build cannot be used with -l")
+			}
+			if parallel < 1 {
+				return errors.New("-p has to be at least 1")
+			}
+			if update && len(args) == 0 {
+				return errors.New("--update requires package or binary to update")
+			}
+			if update && len(rename) > 0 {
+				return errors.New("--update cannot be used with -r")
+			}
+			if interact && (update || len(rename) > 0) {
+				return errors.New("--interactive cannot be used with --update or -r")
+			}
+			if sideBySide && len(args) == 0 {
+				return errors.New("--side-by-side requires package to get")
+			}
+			if sideBySide && (update || interact || len(rename) > 0 || len(name) > 0) {
+				return errors.New("--side-by-side cannot be used with --update, --interactive, -n or -r")
+			}
+			if printCmd && noBuild {
+				return errors.New("--print-cmd cannot be used with --no-build")
+			}
+			if (force || forceClean) && noBuild {
+				return errors.New("--force and --force-clean cannot be used with --no-build")
+			}
+			if allowPrerelease && !update {
+				return errors.New("--allow-prerelease can be only used with --update")
+			}
+			if len(comment) > 0 && len(args) == 0 {
+				return errors.New("--comment requires package or binary to comment")
+			}
+			if strings.ContainsAny(comment, "\r\n") {
+				return errors.New("--comment has to be a single line")
+			}
+			if len(toolchain) > 0 && len(args) == 0 {
+				return errors.New("--toolchain requires package or binary to build")
+			}
+			if len(toolchain) > 0 && toolchain != "none" {
+				if _, err := version.Parse(toolchain); err != nil || !strings.HasPrefix(toolchain, "go") {
+					return errors.Errorf("--toolchain has to be a Go toolchain name (e.g. go1.22.0) or none, got %v", toolchain)
+				}
+			}
+			if len(postInstall) > 0 && len(args) == 0 {
+				return errors.New("--post-install requires package or binary to build")
+			}
+			if len(postInstall) > 0 && postInstall != "none" {
+				if err := bingo.ValidatePostInstall(postInstall); err != nil {
+					return errors.Wrap(err, "--post-install")
+				}
+			}
+			if cmd.Flags().Changed("pin-go") && len(args) == 0 {
+				return errors.New("--pin-go requires package or binary to pin go directive of")
 			}
-			if len(rename) > 0 && !regexp.MustCompile(`[a-zA-Z0-9.-_]+`).MatchString(rename) {
-				return errors.New("-r name contains not allowed characters")
+			if cmd.Flags().Changed("vendor-tools") && len(args) == 0 {
+				return errors.New("--vendor-tools requires package or binary to vendor")
+			}
+			if len(replaces) > 0 && len(args) == 0 {
+				return errors.New("--replace requires package or binary to build")
+			}
+			if len(replaces) > 0 && (update || len(rename) > 0) {
+				return errors.New("--replace cannot be used with --update or -r")
 			}
 			return nil
 		},
@@ -63,20 +178,38 @@ func NewBingoGetCommand(logger *log.Logger) *cobra.Command {
 			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 			defer cancel()
 
+			if printCmd {
+				// Like dry run, nothing is written or built; build commands are printed instead of planned changes.
+				dryRun = true
+			}
 			modDirAbs, err := filepath.Abs(moddir)
 			if err != nil {
 				return errors.Wrap(err, "abs")
 			}
+			var getErr error
 			defer func() {
+				if keepTemp && getErr != nil {
+					// Kept for debugging of the failed install, see --keep-temp.
+					return
+				}
 				if err == nil {
 					// Leave tmp files on error for debug purposes.
 					if cerr := cleanGoGetTmpFiles(modDirAbs); cerr != nil {
-						logger.Println("cannot clean tmp files", err)
+						logger.Warnf("cannot clean tmp files: %v\n", cerr)
 					}
 				}
 			}()
 
-			r, err := runner.NewRunner(ctx, logger, insecure, goCmd)
+			localReplaces := make([]localReplace, 0, len(replaces))
+			for _, rep := range replaces {
+				l, err := parseLocalReplace(rep, modDirAbs)
+				if err != nil {
+					return errors.Wrap(err, "--replace")
+				}
+				localReplaces = append(localReplaces, l)
+			}
+
+			r, err := runner.NewRunner(ctx, nil, insecure, goCmd, runner.WithLogger(logger), runner.WithOutput(os.Stderr, os.Stderr), runner.WithWorkspaceMode(workspace), runner.WithReproducible(reproduce), runner.WithOffline(offline))
 			if err != nil {
 				return err
 			}
@@ -85,59 +218,327 @@ func NewBingoGetCommand(logger *log.Logger) *cobra.Command {
 			}
 
 			cfg := getConfig{
-				runner:    r,
-				modDir:    modDirAbs,
-				relModDir: moddir,
-				name:      name,
-				rename:    rename,
-				link:      link,
-				timeOut:   timeOut,
-				verbose:   verbose,
+				runner:          r,
+				modDir:          modDirAbs,
+				relModDir:       moddir,
+				name:            name,
+				rename:          rename,
+				link:            link,
+				linkDir:         linkDir,
+				parallel:        parallel,
+				dryRun:          dryRun,
+				printCmd:        printCmd,
+				noBuild:         noBuild,
+				update:          update,
+				allowPrerelease: allowPrerelease,
+				comment:         comment,
+				toolchain:       toolchain,
+				postInstall:     postInstall,
+				replaces:        localReplaces,
+				sideBySide:      sideBySide,
+				keepTemp:        keepTemp,
+				rebuild:         bingo.RebuildIfChanged,
+				timeOut:         timeOut,
+
+				ignoreConstraints: ignoreConstraints,
+				commitViaProxy:    commitViaProxy,
+				modOnlyIfChanged:  modOnly,
 			}
-			var target string
-			if len(args) > 0 {
-				target = args[0]
+			if cmd.Flags().Changed("pin-go") {
+				cfg.pinGo = &pinGo
 			}
-
-			if err := get(ctx, logger, cfg, target); err != nil {
-				return errors.Wrap(err, "get")
+			if cmd.Flags().Changed("vendor-tools") {
+				cfg.vendor = &vendor
+			}
+			switch {
+			case forceClean:
+				cfg.rebuild = bingo.RebuildClean
+			case force:
+				cfg.rebuild = bingo.RebuildForce
+			}
+			if interact {
+				if !isTerminal(os.Stdin) {
+					logger.Warnf("stdin is not a terminal, ignoring --interactive; tools without version are pinned to the latest one\n")
+				} else {
+					cfg.pickVersion = promptVersion(os.Stdin, os.Stderr)
+					// Keep prompts and installation logs of tools in order.
+					cfg.parallel = 1
+				}
+			}
+			if cmd.Flags().Changed("output-dir") && !dryRun {
+				if err := bingo.EnsureModDir(logger, moddir); err != nil {
+					return errors.Wrap(err, "ensure mod dir")
+				}
+				if err := bingo.SetOutputDir(moddir, outputDir); err != nil {
+					return errors.Wrap(err, "--output-dir")
+				}
+			}
+			switch len(args) {
+			case 0:
+				getErr = get(ctx, logger, cfg, "")
+			case 1:
+				getErr = get(ctx, logger, cfg, args[0])
+			default:
+				getErr = getMany(ctx, logger, cfg, args, keepGoing)
+			}
+			if getErr != nil {
+				getErr = errors.Wrap(withGoErrorHint(getErr), "get")
+				if !keepGoing {
+					return getErr
+				}
+				// Tools got successfully changed module files, so helpers still have to be regenerated.
+				// Not a usage error, don't print help.
+				cmd.SilenceUsage = true
+			}
+			if dryRun {
+				return getErr
 			}
 
+			if makefile != "" {
+				if err := bingo.SetMakefileTargetsPath(moddir, makefile); err != nil {
+					return errors.Wrap(err, "--gen-makefile")
+				}
+			}
+			if asLibrary != "" {
+				if err := bingo.SetLibraryStubPath(moddir, asLibrary); err != nil {
+					return errors.Wrap(err, "--as-library")
+				}
+			}
 			pkgs, err := bingo.ListPinnedMainPackages(logger, modDirAbs, true)
 			if err != nil {
 				return errors.Wrap(err, "list pinned")
 			}
 			if len(pkgs) == 0 {
-				return bingo.RemoveHelpers(modDirAbs)
+				if err := bingo.RemoveHelpers(modDirAbs); err != nil {
+					return err
+				}
+				return getErr
 			}
-			return bingo.GenHelpers(moddir, version.Version, pkgs)
+			if err := bingo.GenHelpers(moddir, version.Version, reproduce, pkgs); err != nil {
+				return err
+			}
+			if err := checkBinaryNameConflicts(logger, modDirAbs, strict); err != nil && getErr == nil {
+				return err
+			}
+			return getErr
 		},
 	}
 	flags := cmd.Flags()
 	flags.StringVarP(&name, "name", "n", "", "The -n flag instructs to get binary and name it with given name instead of default,\n"+
 		"so the last element of package directory. Allowed characters [A-z0-9._-]. If -n is used and no package/binary is specified,\n"+
-		"bingo get will return error. If -n is used with existing binary name, copy of this binary will be done. Cannot be used with -r")
+		"bingo get will return error. If -n is used with existing binary name, copy of this binary will be done. The name is recorded\n"+
+		"in the module file as name=<name> attribute. Cannot be used with -r")
 	flags.StringVarP(&rename, "rename", "r", "", "The -r flag instructs to get existing binary and rename it with given name. Allowed characters [A-z0-9._-]. \n"+
 		"If -r is used and no package/binary is specified or non existing binary name is used, bingo will return error. Cannot be used with -n.")
 	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
 	flags.BoolVar(&insecure, "insecure", insecure, `Use -insecure flag when using 'go get'`)
+	flags.BoolVar(&workspace, "workspace", false, "If enabled, go commands are run with GOWORK from the environment instead of GOWORK=off, so go.work (if any) is used.\n"+
+		"Note that go rejects -modfile bingo uses in workspace mode, so it only makes sense with GOWORK set explicitly (e.g. to off).")
 	flags.BoolVarP(&link, "link", "l", link, "If enabled, bingo will also create soft link called <tool> that links to the current <tool>-<version> binary.\n"+
 		"Use Variables.mk and variables.env if you want to be sure that what you are invoking is what is pinned.")
+	flags.StringVar(&linkDir, "link-dir", "bin", "Additional directory (relative to the current directory) where -l creates <tool> link to the current <tool>-<version> binary,\n"+
+		"so e.g ./bin/<tool> always points to the pinned version. Links are re-pointed atomically. Set to empty to create links in GOBIN only.")
+	flags.StringVar(&makefile, "gen-makefile", "", "Path (e.g tools.mk) of Makefile to generate with variable, rule and phony target for every tool, so 'make <tool>'\n"+
+		"installs the pinned version using bingo get. The path is recorded in the module directory, so the file is regenerated on every bingo get.")
+	flags.StringVar(&asLibrary, "as-library", "", "Path of Go file to generate with \"tools\" build tag and blank import of every tool, so tool modules are tracked by go.mod\n"+
+		"of the main module and tools can be run with go run, e.g. in go:generate directives. The file has go:generate directive per tool syncing\n"+
+		"go.mod with the version pinned by bingo. The path is recorded in the module directory, so the file is regenerated on every bingo get.\n"+
+		"If specified without value, tools/tools.go is used.")
+	flags.Lookup("as-library").NoOptDefVal = "tools/tools.go"
+	flags.StringVar(&outputDir, "output-dir", "", "Directory (relative to the current directory, e.g. third_party/bin) where binaries are built instead of GOBIN.\n"+
+		"The directory is recorded in the module directory, so all following bingo commands and generated helpers use it. Set to empty to use GOBIN again.")
 	flags.UintVarP(&timeOut, "timeout", "t", 5, "The maximum time (in minutes) to wait for each go command before killing it.\n"+
 		"Set this flag to 0 to indefinitely wait on them.")
+	flags.IntVarP(&parallel, "parallel", "p", runtime.GOMAXPROCS(0), "The maximum number of tools installed concurrently when all or more than one tools are requested.\n"+
+		"Failures are reported for each tool at the end.")
+	flags.BoolVar(&keepGoing, "keep-going", false, "If enabled, bingo gets all given tools (or all pinned tools), even if some of them are invalid or fail,\n"+
+		"and regenerates helpers for the ones got successfully. Failures are reported for each tool at the end. By default, no tool is got if any\n"+
+		"given one is invalid, and tools not started yet are skipped after the first failure.")
+	flags.BoolVar(&reproduce, "reproducible", true, "If enabled, binaries are built with -trimpath and (Go 1.18+) -buildvcs=false, so they are byte-identical across machines.\n"+
+		"Flags given in the tool's build flags take precedence, e.g. -trimpath=false opts the tool out.")
+	flags.BoolVar(&keepTemp, "keep-temp", false, "If enabled, temporary module and sum files of the tool that failed to install are kept in the module directory\n"+
+		"and their path and the go build command to reproduce the build manually are printed. They are removed by the next bingo get.")
+	flags.BoolVar(&strict, "strict", false, "If enabled, bingo fails (instead of warning) if more than one pinned tool is built as the same binary name, so one\n"+
+		"overwrites the other in GOBIN.")
+	flags.BoolVar(&ignoreConstraints, "ignore-constraints", false, "If enabled, bingo pins versions denied in "+bingo.ConstraintsFile+" file in the module directory with a warning instead of\n"+
+		"refusing to pin them.")
+	flags.BoolVar(&commitViaProxy, "commit-via-proxy", true, "If enabled, commits (e.g. tool@abc1234) are resolved to pseudo-versions through the module proxy from GOPROXY\n"+
+		"(or "+defaultModuleProxy+" if it has none), since proxy and VCS can disagree on the commit time, and a warning is printed if\n"+
+		"resolving directly from VCS gives different pseudo-version. Modules matching GONOPROXY or GOPRIVATE are still resolved directly.\n"+
+		"If disabled, commits are resolved as GOPROXY says.")
+	flags.BoolVar(&offline, "offline", false, "If enabled, go never accesses network (GOPROXY=off GOFLAGS=-mod=mod), so tools are resolved and built only from modules\n"+
+		"already in the module cache (e.g. pre-populated GOMODCACHE in air-gapped environment). Missing modules fail the installation.")
+	flags.BoolVar(&force, "force", false, "If enabled, binaries are always rebuilt, even if they exist, match recorded checksums and were built with the same build\n"+
+		"attributes (e.g. after upgrading Go). Checksums of rebuilt binaries are recorded again.")
+	flags.BoolVar(&forceClean, "force-clean", false, "If enabled, binaries are always rebuilt like with --force, but without using the build cache (go build -a), and tool modules are\n"+
+		"removed from the module cache first, so they are downloaded and verified again, e.g. if the module cache got corrupted.")
+	flags.BoolVar(&dryRun, "dry-run", false, "If enabled, bingo resolves versions, but only prints planned changes to mod files and binaries without writing or building anything.")
+	flags.BoolVar(&printCmd, "print-cmd", false, "If enabled, bingo resolves versions like --dry-run, but prints go build commands (with environment variables, shell-escaped) it would run\n"+
+		"to build the tools instead of planned changes, so the build can be reproduced manually outside of bingo. Nothing is written or built.")
+	flags.BoolVar(&noBuild, "no-build", false, "If enabled, bingo resolves versions and updates mod and sum files, but does not build binaries, e.g. to build them later on\n"+
+		"other machine with bingo get. Binaries not built are reported as not installed by bingo list.")
+	flags.BoolVar(&modOnly, "mod-only-if-changed", true, "If enabled, mod and sum files whose content did not change (e.g. getting already pinned tool) are not rewritten, so their\n"+
+		"modification time is kept and no-op get does not cause VCS changes or trigger rebuilds. Disable to always rewrite them.")
+	flags.BoolVar(&interact, "interactive", false, "If enabled and stdin is a terminal, bingo lists released versions of every tool requested without version and not pinned yet,\n"+
+		"and asks which one to pin instead of pinning the latest one. Ignored if stdin is not a terminal (e.g. in CI). Cannot be used with --update or -r.")
+	flags.BoolVar(&sideBySide, "side-by-side", false, "If enabled, bingo pins each given version of the package as a separate tool named <tool>-<major version> (e.g. <tool>-v2.mod),\n"+
+		"so different major versions are installed and exposed in helpers (e.g. as TOOL_V1 and TOOL_V2) side by side. Versions have to differ in major version.\n"+
+		"Cannot be used with --update, --interactive, -n or -r.")
+	flags.BoolVar(&update, "update", false, "If enabled, bingo updates given tool to the latest released version of its module. Version after @ is treated as constraint\n"+
+		"(e.g ^v0.1, ~v1.2 or 'v1.2 - v1.5'), so the latest version matching it is chosen.")
+	flags.BoolVar(&allowPrerelease, "allow-prerelease", false, "If enabled, --update considers also pre-release versions (e.g v1.2.0-rc.1).")
+	flags.StringVar(&comment, "comment", "", "Human readable description of the tool (e.g what it is used for), recorded in the module file as\n"+
+		"'// bingo:comment <comment>' line and shown by bingo list. If empty, existing comment is kept.")
+	flags.StringVar(&toolchain, "toolchain", "", "Go toolchain (e.g go1.22.0) the tool is built with, recorded as toolchain directive in the module file.\n"+
+		"bingo sets GOTOOLCHAIN when building this tool, so Go downloads the toolchain if needed (requires Go 1.21+). Use 'none' to remove it.\n"+
+		"If empty, existing toolchain is kept.")
+	flags.BoolVar(&pinGo, "pin-go", false, "If enabled, go directive of the tool's module file is kept as it is when the tool is updated, instead of being raised\n"+
+		"by go get to what the new version requires (Go 1.21+), so e.g. CI with older Go keeps working. Update fails if the pinned one is not enough.\n"+
+		"Recorded in the module file as '// "+bingo.GoVersionPinnedDirective+"' line. Use --pin-go=false to remove it. If not set, existing setting is kept.")
+	flags.BoolVar(&vendor, "vendor-tools", false, "EXPERIMENTAL: If enabled, sources of modules the tool needs are copied into "+bingo.VendorDir+" directory of the module directory\n"+
+		"(one <module>@<version> directory each, meant to be committed) and the tool is built from them without network access. This makes\n"+
+		"the repository bigger (often by megabytes per tool). Recorded in the module file as '// "+bingo.VendorDirective+"' line. Use --vendor-tools=false to\n"+
+		"remove it. If not set, existing setting is kept.")
+	flags.StringVar(&postInstall, "post-install", "", "Command run on every binary of the tool after it's built (e.g. 'strip {{.Bin}}'), recorded in the module file as\n"+
+		"'// bingo:post-install <command>' line. Arguments are split like in shell and {{.Bin}} (absolute binary path), {{.Name}} and {{.Version}}\n"+
+		"are replaced in each of them. The command runs in the module directory with the build environment. If it fails, install fails\n"+
+		"and the binary is not recorded as verified. Use 'none' to remove it. If empty, existing command is kept.")
+	flags.StringArrayVar(&replaces, "replace", nil, "The --replace=<module path>=<dir> flag instructs to build given module from local directory (relative to the current\n"+
+		"directory) instead of released version. It's recorded as replace directive in the module file (even with bingo:no_directive_fetch)\n"+
+		"and the tool is pinned to "+bingo.LocalReplaceVersion+" version. Binaries built from local replace are always rebuilt.\n"+
+		"Can be specified multiple times, e.g. for dependencies of the tool. Getting the tool in released version drops the replace (unless bingo:no_directive_fetch is set).")
+	return cmd
+}
+
+func NewBingoCleanCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd     string
+		pruneMod  bool
+		yes       bool
+		dryRun    bool
+		reproduce bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "clean [flags]",
+		Short: "Removes binaries from GOBIN and files from the module directory that belong to tools no longer pinned in this project.",
+		Long: "Clean removes versioned binaries installed by bingo (and links to them) from GOBIN if tool has no module file anymore, and\n" +
+			"sum and meta files without module file. With --prune-mod, module files without valid require directive are removed too.\n" +
+			"Files to remove are always listed. Either --yes or --dry-run has to be specified.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("clean does not take arguments")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			if yes == dryRun {
+				return errors.New("clean requires exactly one of --yes or --dry-run")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDirAbs, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+			r, err := runner.NewRunner(ctx, nil, false, goCmd, runner.WithLogger(logger))
+			if err != nil {
+				return err
+			}
+			if verbose {
+				r.Verbose()
+			}
+			gobin, err := bingo.BinDir(r.With(ctx, "", "", nil), modDirAbs)
+			if err != nil {
+				return errors.Wrap(err, "deduct GOBIN")
+			}
+
+			orphans, err := bingo.Orphans(modDirAbs, gobin, pruneMod)
+			if err != nil {
+				return errors.Wrap(err, "find orphaned files")
+			}
+			if len(orphans) == 0 {
+				_, _ = fmt.Fprintln(os.Stdout, "nothing to clean")
+				return nil
+			}
+
+			action := "removed"
+			if dryRun {
+				action = "would remove"
+			}
+			removedMod := false
+			for _, o := range orphans {
+				if !dryRun {
+					if err := os.Remove(o); err != nil && !os.IsNotExist(err) {
+						return errors.Wrapf(err, "remove %v", o)
+					}
+					removedMod = removedMod || strings.HasSuffix(o, ".mod")
+				}
+				_, _ = fmt.Fprintf(os.Stdout, "%v %v\n", action, o)
+			}
+			if !removedMod {
+				return nil
+			}
+
+			return bingo.RegenHelpers(logger, moddir, version.Version, reproduce)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.BoolVar(&pruneMod, "prune-mod", false, "If enabled, module files without valid require directive of the tool (e.g. empty or malformed) are removed too,\n"+
+		"together with their binaries.")
+	flags.BoolVar(&yes, "yes", false, "Confirm removal of listed files.")
+	flags.BoolVar(&dryRun, "dry-run", false, "If enabled, bingo only lists files that would be removed.")
+	flags.BoolVar(&reproduce, "reproducible", true, "If enabled, regenerated Variables.mk builds tools with -trimpath and (Go 1.18+) -buildvcs=false, as bingo get\n"+
+		"--reproducible does.")
 	return cmd
 }
 
-func NewBingoListCommand(logger *log.Logger) *cobra.Command {
+func NewBingoListCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd         string
+		output        string
+		check         bool
+		installedOnly bool
+		missingOnly   bool
+		showReplaces  bool
+		deps          bool
+		strict        bool
+		tree          bool
+	)
+
 	cmd := &cobra.Command{
-		Use:     "list <flags> [<package or binary>]",
-		Version: version.Version,
-		Short:   "List enumerates all or one binary that are/is currently pinned in this project. ",
-		Long:    "List enumerates all or one binary that are/is currently pinned in this project. It will print exact path, Version and immutable output.",
+		Use:               "list <flags> [<package or binary or name pattern>]",
+		Version:           version.Version,
+		Short:             "List enumerates all or one binary that are/is currently pinned in this project. ",
+		Long:              "List enumerates all or one binary that are/is currently pinned in this project. It will print exact path, Version and immutable output.",
+		ValidArgsFunction: completeToolNames(1),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 1 {
 				return errors.New("too many arguments except none or binary/package")
 			}
+			if output != "table" && output != "json" {
+				return errors.Errorf("unsupported output format %q; expected table or json", output)
+			}
+			if installedOnly && missingOnly {
+				return errors.New("--installed-only and --missing-only cannot be used together")
+			}
+			if check && (installedOnly || missingOnly) {
+				return errors.New("--check cannot be used with --installed-only or --missing-only")
+			}
+			if showReplaces && (check || output != "table") {
+				return errors.New("--show-replaces can be used only with table output and without --check")
+			}
+			if deps && (check || output != "json") {
+				return errors.New("--deps can be used only with json output and without --check")
+			}
+			if tree && (check || showReplaces) {
+				return errors.New("--tree cannot be used with --check or --show-replaces")
+			}
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -149,25 +550,967 @@ func NewBingoListCommand(logger *log.Logger) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if err := checkBinaryNameConflicts(logger, modDir, strict); err != nil {
+				// Not a usage error, don't print help.
+				cmd.SilenceUsage = true
+				return err
+			}
 			var target string
 			if len(args) > 0 {
 				target = args[0]
 			}
 			bingo.SortRenderables(pkgs)
-			return pkgs.PrintTab(target, os.Stdout)
+			if bingo.IsNamePattern(target) {
+				if pkgs, err = pkgs.FilterByName(target); err != nil {
+					return err
+				}
+				target = ""
+			}
+			filterInstalled := installedOnly || missingOnly
+			if output == "table" && !check && !filterInstalled && !tree {
+				if showReplaces {
+					return pkgs.PrintReplacesTab(modDir, target, os.Stdout)
+				}
+				return pkgs.PrintTab(target, os.Stdout)
+			}
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			r, err := runner.NewRunner(ctx, nil, false, goCmd, runner.WithLogger(logger))
+			if err != nil {
+				return err
+			}
+			gobin, err := bingo.BinDir(r.With(ctx, "", "", nil), modDir)
+			if err != nil {
+				return errors.Wrap(err, "deduct GOBIN")
+			}
+			if check {
+				return checkInstalled(modDir, gobin, target, pkgs)
+			}
+			if filterInstalled {
+				if target != "" {
+					// Filter by name first, so filtered out tool is not reported as not pinned.
+					if pkgs, err = pkgs.FilterByName(target); err != nil {
+						return err
+					}
+					target = ""
+				}
+				if pkgs, err = pkgs.FilterByInstalled(gobin, installedOnly); err != nil {
+					return err
+				}
+			}
+			if output == "table" && !tree {
+				if showReplaces {
+					return pkgs.PrintReplacesTab(modDir, target, os.Stdout)
+				}
+				return pkgs.PrintTab(target, os.Stdout)
+			}
+			entries, err := pkgs.ListEntries(target, gobin)
+			if err != nil {
+				return err
+			}
+			if deps {
+				if err := bingo.SetDependencies(ctx, r, entries); err != nil {
+					return err
+				}
+			}
+			if !tree {
+				return bingo.PrintListEntriesJSON(entries, os.Stdout)
+			}
+			if output == "table" {
+				return bingo.PrintListModulesTab(bingo.GroupListEntries(entries), os.Stdout)
+			}
+			return bingo.PrintListModulesJSON(bingo.GroupListEntries(entries), os.Stdout)
 		},
 	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.BoolVar(&check, "check", false, "If enabled, instead of listing, bingo checks if binaries installed in GOBIN match pinned versions and build attributes\n"+
+		"and reports mismatches with non-zero exit code. Useful as a CI gate.")
+	flags.BoolVar(&installedOnly, "installed-only", false, "If enabled, only tool versions with binary installed in GOBIN are listed.")
+	flags.BoolVar(&missingOnly, "missing-only", false, "If enabled, only pinned tool versions with binary missing in GOBIN are listed (e.g. to see what bingo get would build).")
+	flags.BoolVar(&showReplaces, "show-replaces", false, "If enabled, instead of tools, bingo lists replace directives in module files of the listed tools, e.g. fetched from\n"+
+		"the tool's own go.mod (unless bingo:no_directive_fetch is set). Useful to debug why tool is built with particular dependency version.")
+	flags.StringVarP(&output, "output", "o", "table", "Output format. One of: table, json. JSON output is an array of objects with stable schema (see bingo.ListEntry).")
+	flags.BoolVar(&strict, "strict", false, "If enabled, bingo fails (instead of warning) if more than one pinned tool is built as the same binary name.")
+	flags.BoolVar(&deps, "deps", false, "If enabled, JSON output includes modules each installed binary was built with (read with go version -m), e.g. for\n"+
+		"vulnerability scanning. Requires -o json. It might be slow, since every binary is read.")
+	flags.BoolVar(&tree, "tree", false, "If enabled, tools are grouped by module they are built from, with the shared module version shown once. With -o json,\n"+
+		"output is an array of module objects with their tools as entries (see bingo.ListModule).")
 	return cmd
 }
 
-func NewBingoVersionCommand() *cobra.Command {
+// checkBinaryNameConflicts warns about or, if strict, fails on tools pinned in modDir built as the same binary name.
+func checkBinaryNameConflicts(logger logging.Logger, modDir string, strict bool) error {
+	conflicts, err := bingo.BinaryNameConflicts(modDir)
+	if err != nil {
+		return errors.Wrap(err, "check binary name conflicts")
+	}
+	for _, c := range conflicts {
+		msg := fmt.Sprintf("tools %v are all built as %v binary, so they overwrite each other in GOBIN; rename all but one of them, "+
+			"e.g. with bingo rename %v <new name>", strings.Join(c.Tools, ", "), c.BinaryName, c.Tools[len(c.Tools)-1])
+		if strict {
+			logger.Errorf("%s\n", msg)
+			continue
+		}
+		logger.Warnf("%s\n", msg)
+	}
+	if strict && len(conflicts) > 0 {
+		return errors.Errorf("found %d binary name conflicts", len(conflicts))
+	}
+	return nil
+}
+
+func checkInstalled(modDir, gobin, target string, pkgs bingo.PackageRenderables) error {
+	var mismatches []string
+	found := false
+	for _, p := range pkgs {
+		if target != "" && p.Name != target {
+			continue
+		}
+		found = true
+		for _, v := range p.Versions {
+			m, err := bingo.CheckInstalled(filepath.Join(modDir, v.ModFile), gobin)
+			if err != nil {
+				return errors.Wrapf(err, "check %v", v.ModFile)
+			}
+			for _, mm := range m {
+				mismatches = append(mismatches, v.ModFile+": "+mm)
+			}
+		}
+	}
+	if target != "" && !found {
+		return errors.Errorf("Pinned tool %s not found", target)
+	}
+	for _, m := range mismatches {
+		_, _ = fmt.Fprintln(os.Stdout, m)
+	}
+	if len(mismatches) > 0 {
+		return errors.Errorf("found %d mismatches between pinned and installed tools; run bingo get to fix them", len(mismatches))
+	}
+	return nil
+}
+
+func NewBingoImportCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd     string
+		insecure  bool
+		timeOut   uint
+		dryRun    bool
+		fromMod   string
+		reproduce bool
+	)
+
 	cmd := &cobra.Command{
-		Use:   "version",
-		Short: "Prints bingo Version.",
-		Long:  `Prints bingo Version.`,
-		Run: func(cmd *cobra.Command, args []string) {
-			_, _ = fmt.Fprintln(os.Stdout, version.Version)
+		Use:   "import [flags]",
+		Short: "Pins tools already installed in GOBIN (e.g. with go install) that are not pinned in this project yet.",
+		Long: "Import scans GOBIN, reads package and module version embedded in each binary (see go version -m) and pins it as if\n" +
+			"bingo get <package>@<version> was run, named after the binary. Binaries that are not Go module binaries, were built from\n" +
+			"local checkout or are already pinned are reported and skipped. Build flags and environment variables are not imported.\n\n" +
+			"With --from-go-mod, import pins tools from tool directives (Go 1.24+) of the project go.mod instead, with versions of\n" +
+			"modules providing them as required in go.mod and go.sum. Tools that can't be resolved to a version are reported and skipped.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("import does not take arguments")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDirAbs, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+
+			r, err := runner.NewRunner(ctx, nil, insecure, goCmd, runner.WithLogger(logger), runner.WithOutput(os.Stderr, os.Stderr), runner.WithReproducible(reproduce))
+			if err != nil {
+				return err
+			}
+			if verbose {
+				r.Verbose()
+			}
+			cfg := getConfig{
+				runner:    r,
+				modDir:    modDirAbs,
+				relModDir: moddir,
+				dryRun:    dryRun,
+				timeOut:   timeOut,
+			}
+
+			var importErr error
+			if fromMod != "" {
+				importErr = withGoErrorHint(importGoModTools(ctx, logger, cfg, fromMod))
+			} else {
+				gobin, err := bingo.GoBin(r.With(ctx, "", "", nil))
+				if err != nil {
+					return errors.Wrap(err, "deduct GOBIN")
+				}
+				importErr = withGoErrorHint(importTools(ctx, logger, cfg, gobin))
+			}
+			if dryRun {
+				return importErr
+			}
+
+			// Regenerate helpers even if some imports failed, so the successful ones are usable.
+			pkgs, err := bingo.ListPinnedMainPackages(logger, modDirAbs, true)
+			if err != nil {
+				return errors.Wrap(err, "list pinned")
+			}
+			if len(pkgs) > 0 {
+				if err := bingo.GenHelpers(moddir, version.Version, reproduce, pkgs); err != nil {
+					return errors.Wrap(err, "generate helpers")
+				}
+			}
+			if importErr != nil {
+				return errors.Wrap(importErr, "import")
+			}
+			return nil
 		},
 	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.BoolVar(&insecure, "insecure", insecure, `Use -insecure flag when using 'go get'`)
+	flags.UintVarP(&timeOut, "timeout", "t", 5, "The maximum time (in minutes) to wait for each go command before killing it.\n"+
+		"Set this flag to 0 to indefinitely wait on them.")
+	flags.BoolVar(&dryRun, "dry-run", false, "If enabled, bingo only prints which tools would be imported and planned changes, without writing or building anything.")
+	flags.StringVar(&fromMod, "from-go-mod", "", "Path to the go.mod to import tools from tool directives of, instead of GOBIN. If specified without value, go.mod\n"+
+		"in the current directory is used.")
+	flags.BoolVar(&reproduce, "reproducible", true, "If enabled, binaries are built with -trimpath and (Go 1.18+) -buildvcs=false, so they are byte-identical across machines.\n"+
+		"Flags given in the tool's build flags take precedence, e.g. -trimpath=false opts the tool out.")
+	flags.Lookup("from-go-mod").NoOptDefVal = "go.mod"
 	return cmd
 }
+
+func NewBingoMigrateCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd     string
+		fromDir   string
+		dryRun    bool
+		reproduce bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "migrate [flags]",
+		Example: "bingo migrate --from=tools // this will convert module files in tools directory to per tool module files in .bingo",
+		Short:   "Converts legacy layouts (all tools in one module file or different module directory) into per tool module files.",
+		Long: "Migrate splits module files with more than one tool pinned (as old bingo versions or manual setups did) into module files\n" +
+			"named after each tool, with the same versions, build attributes and replace directives. With --from, all module files of the\n" +
+			"given directory are moved into the module directory. Original files are backed up in <moddir>/backup and removed, so running\n" +
+			"migrate again is a no-op. Nothing is installed; run bingo get afterwards to install migrated tools.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("migrate does not take arguments")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDirAbs, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+			var fromDirAbs string
+			if fromDir != "" {
+				if fromDirAbs, err = filepath.Abs(fromDir); err != nil {
+					return errors.Wrap(err, "abs")
+				}
+				if fromDirAbs == modDirAbs {
+					return errors.New("--from has to be different than module directory")
+				}
+			}
+
+			legacyFiles, err := bingo.LegacyModFiles(modDirAbs, fromDirAbs)
+			if err != nil {
+				return errors.Wrap(err, "find legacy module files")
+			}
+			if len(legacyFiles) == 0 {
+				_, _ = fmt.Fprintln(os.Stdout, "Nothing to migrate")
+				return nil
+			}
+			if !dryRun {
+				if err := bingo.EnsureModDir(logger, moddir); err != nil {
+					return errors.Wrap(err, "ensure mod dir")
+				}
+			}
+
+			r, err := runner.NewRunner(ctx, nil, false, goCmd, runner.WithLogger(logger), runner.WithOutput(os.Stderr, os.Stderr))
+			if err != nil {
+				return err
+			}
+			if verbose {
+				r.Verbose()
+			}
+
+			created, verb := "created", "migrated"
+			if dryRun {
+				created, verb = "would create", "would migrate"
+			}
+			for _, f := range legacyFiles {
+				m, err := bingo.MigrateModFile(ctx, r, logger, modDirAbs, f, dryRun)
+				if err != nil {
+					return errors.Wrapf(err, "migrate %v", f)
+				}
+				name := f
+				if wd, err := os.Getwd(); err == nil {
+					if rel, err := filepath.Rel(wd, f); err == nil {
+						name = rel
+					}
+				}
+				msg := fmt.Sprintf("%s %s: %s %s", verb, name, created, strings.Join(m.Created, ", "))
+				if len(m.Existing) > 0 {
+					msg += fmt.Sprintf("; kept existing %s", strings.Join(m.Existing, ", "))
+				}
+				if m.Backup != "" {
+					msg += fmt.Sprintf("; backed up in %s", filepath.Join(moddir, bingo.MigrationBackupDir))
+				}
+				_, _ = fmt.Fprintln(os.Stdout, msg)
+			}
+			if dryRun {
+				return nil
+			}
+
+			pkgs, err := bingo.ListPinnedMainPackages(logger, modDirAbs, true)
+			if err != nil {
+				return errors.Wrap(err, "list pinned")
+			}
+			if len(pkgs) > 0 {
+				if err := bingo.GenHelpers(moddir, version.Version, reproduce, pkgs); err != nil {
+					return errors.Wrap(err, "generate helpers")
+				}
+			}
+			logger.Infof("Migration done; run bingo get to install migrated tools\n")
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.StringVar(&fromDir, "from", "", "Legacy directory with module files of tools (e.g. tools or _tools) to move into the module directory.")
+	flags.BoolVar(&dryRun, "dry-run", false, "If enabled, bingo only prints planned changes, without writing anything.")
+	flags.BoolVar(&reproduce, "reproducible", true, "If enabled, generated Variables.mk builds tools with -trimpath and (Go 1.18+) -buildvcs=false, as bingo get\n"+
+		"--reproducible does.")
+	return cmd
+}
+
+func NewBingoRenameCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd     string
+		insecure  bool
+		link      bool
+		linkDir   string
+		timeOut   uint
+		reproduce bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "rename [flags] <binary> <new name>",
+		Example: "bingo rename golangci-lint lint // this will pin and install golangci-lint as lint and remove golangci-lint binaries",
+		Short:   "Renames pinned tool, reinstalls it under the new name and removes old binaries.",
+		Long: "Rename moves module files of the tool (e.g. .bingo/<binary>.mod) to the new name and drops name attribute, so the binary\n" +
+			"follows the new name. The tool is reinstalled, helper variables are regenerated and old binaries (with links pointing to them)\n" +
+			"are removed. It fails if tool with the new name is already pinned.",
+		ValidArgsFunction: completeToolNames(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("rename requires binary name and new name")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			if err := bingo.ValidateBinaryName(args[1]); err != nil {
+				return errors.Wrap(err, "new name")
+			}
+			if cmd.Flags().Changed("link-dir") && !link {
+				return errors.New("--link-dir can be only used with -l")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDirAbs, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+
+			r, err := runner.NewRunner(ctx, nil, insecure, goCmd, runner.WithLogger(logger), runner.WithOutput(os.Stderr, os.Stderr), runner.WithReproducible(reproduce))
+			if err != nil {
+				return err
+			}
+			if verbose {
+				r.Verbose()
+			}
+			gobin, err := bingo.BinDir(r.With(ctx, "", "", nil), modDirAbs)
+			if err != nil {
+				return errors.Wrap(err, "deduct GOBIN")
+			}
+
+			cfg := getConfig{
+				runner:    r,
+				modDir:    modDirAbs,
+				relModDir: moddir,
+				link:      link,
+				linkDir:   linkDir,
+				timeOut:   timeOut,
+			}
+			renameErr := withGoErrorHint(renameTool(ctx, logger, cfg, gobin, strings.ToLower(args[0]), args[1]))
+
+			// Regenerate helpers even if reinstall failed, since module files might be renamed already.
+			pkgs, err := bingo.ListPinnedMainPackages(logger, modDirAbs, false)
+			if err != nil {
+				return errors.Wrap(err, "list pinned")
+			}
+			if len(pkgs) > 0 {
+				if err := bingo.GenHelpers(moddir, version.Version, reproduce, pkgs); err != nil {
+					return errors.Wrap(err, "generate helpers")
+				}
+			}
+			if renameErr != nil {
+				return errors.Wrap(renameErr, "rename")
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.BoolVar(&insecure, "insecure", insecure, `Use -insecure flag when using 'go get'`)
+	flags.BoolVarP(&link, "link", "l", link, "If enabled, bingo will also create soft link called <new name> that links to the reinstalled binary.")
+	flags.StringVar(&linkDir, "link-dir", "bin", "Additional directory (relative to the current directory) where -l creates <new name> link. Old links pointing\n"+
+		"to removed binaries are removed from GOBIN and this directory. Set to empty to create links in GOBIN only.")
+	flags.BoolVar(&reproduce, "reproducible", true, "If enabled, binaries are built with -trimpath and (Go 1.18+) -buildvcs=false, so they are byte-identical across machines.\n"+
+		"Flags given in the tool's build flags take precedence, e.g. -trimpath=false opts the tool out.")
+	flags.UintVarP(&timeOut, "timeout", "t", 5, "The maximum time (in minutes) to wait for each go command before killing it.\n"+
+		"Set this flag to 0 to indefinitely wait on them.")
+	return cmd
+}
+
+func NewBingoDiffCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd    string
+		name     string
+		insecure bool
+		timeOut  uint
+		exitCode bool
+
+		update          bool
+		allowPrerelease bool
+		toolchain       string
+	)
+
+	cmd := &cobra.Command{
+		Use: "diff [flags] <package or binary>[@version1 or none,version2,version3...]",
+		Example: "bingo diff faillint@v1.5.0\n" +
+			"bingo diff --update golangci-lint // this will show changes bingo get --update golangci-lint would introduce\n" +
+			"bingo diff --exit-code goimports@latest // this will fail if goimports is not pinned to the latest version",
+		Short: "Shows changes to module files and binary versions a bingo get would introduce, without writing anything.",
+		Long: "Diff resolves the target as bingo get does and prints unified diff of each tool module file that would change\n" +
+			"(require, replace, go and toolchain directives), followed by summary of binary version changes. Nothing is written or built.",
+		ValidArgsFunction: completeToolNames(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			if len(args) != 1 {
+				return errors.New("diff requires exactly one package or binary")
+			}
+			if len(name) > 0 {
+				if err := bingo.ValidateBinaryName(name); err != nil {
+					return errors.Wrap(err, "-n")
+				}
+			}
+			if allowPrerelease && !update {
+				return errors.New("--allow-prerelease can be only used with --update")
+			}
+			if len(toolchain) > 0 && toolchain != "none" {
+				if _, err := version.Parse(toolchain); err != nil || !strings.HasPrefix(toolchain, "go") {
+					return errors.Errorf("--toolchain has to be a Go toolchain name (e.g. go1.22.0) or none, got %v", toolchain)
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDirAbs, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+			defer func() {
+				if err == nil {
+					if cerr := cleanGoGetTmpFiles(modDirAbs); cerr != nil {
+						logger.Warnf("cannot clean tmp files: %v\n", cerr)
+					}
+				}
+			}()
+
+			r, err := runner.NewRunner(ctx, nil, insecure, goCmd, runner.WithLogger(logger), runner.WithOutput(os.Stderr, os.Stderr))
+			if err != nil {
+				return err
+			}
+			if verbose {
+				r.Verbose()
+			}
+
+			diffs := &getDiffs{}
+			cfg := getConfig{
+				runner:          r,
+				modDir:          modDirAbs,
+				relModDir:       moddir,
+				name:            name,
+				parallel:        1,
+				dryRun:          true,
+				diffs:           diffs,
+				update:          update,
+				allowPrerelease: allowPrerelease,
+				toolchain:       toolchain,
+				timeOut:         timeOut,
+			}
+			if err := get(ctx, logger, cfg, args[0]); err != nil {
+				return errors.Wrap(withGoErrorHint(err), "diff")
+			}
+			if err := diffs.print(os.Stdout); err != nil {
+				return err
+			}
+			if exitCode && diffs.changed() {
+				// Not a usage error, don't print help.
+				cmd.SilenceUsage = true
+				return errors.New("bingo get would introduce changes")
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&name, "name", "n", "", "Show changes for getting binary under given name instead of default one, as bingo get -n does.")
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.BoolVar(&insecure, "insecure", insecure, `Use -insecure flag when using 'go get'`)
+	flags.UintVarP(&timeOut, "timeout", "t", 5, "The maximum time (in minutes) to wait for each go command before killing it.\n"+
+		"Set this flag to 0 to indefinitely wait on them.")
+	flags.BoolVar(&exitCode, "exit-code", false, "If enabled, bingo diff exits with non-zero code if bingo get would introduce any changes (e.g. for CI checks).")
+	flags.BoolVar(&update, "update", false, "Show changes of updating given tool to the latest released version matching the version constraint, as bingo get --update does.")
+	flags.BoolVar(&allowPrerelease, "allow-prerelease", false, "If enabled, --update considers also pre-release versions (e.g v1.2.0-rc.1).")
+	flags.StringVar(&toolchain, "toolchain", "", "Show changes of setting Go toolchain (e.g go1.22.0) of the tool, as bingo get --toolchain does. Use 'none' to remove it.")
+	return cmd
+}
+
+func NewBingoOutdatedCommand(logger logging.Logger) *cobra.Command {
+	var goCmd string
+
+	cmd := &cobra.Command{
+		Use:   "outdated [flags]",
+		Short: "Reports pinned tools with newer versions available.",
+		Long: "Outdated queries the latest released version of the module of each pinned tool and prints tools with newer version available,\n" +
+			"without modifying anything. Major versions released under different module path (e.g. with /v2 suffix) are reported separately,\n" +
+			"since bingo get --update can't upgrade to them; use bingo get with the new path instead.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("outdated does not take arguments")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDirAbs, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+			pkgs, err := bingo.ListPinnedMainPackages(logger, modDirAbs, false)
+			if err != nil {
+				return err
+			}
+			if len(pkgs) == 0 {
+				_, _ = fmt.Fprintln(os.Stdout, "no tools are pinned")
+				return nil
+			}
+			bingo.SortRenderables(pkgs)
+
+			r, err := runner.NewRunner(ctx, nil, false, goCmd, runner.WithLogger(logger))
+			if err != nil {
+				return err
+			}
+			if verbose {
+				r.Verbose()
+			}
+			mv := newModuleVersions(func(envs envars.EnvSlice) runner.Runnable {
+				// Module directory has fake root module, so go list -m works there.
+				return r.With(ctx, "", modDirAbs, envs)
+			})
+			entries, outdatedErr := outdatedTools(mv, pkgs)
+			if len(entries) == 0 && outdatedErr == nil {
+				_, _ = fmt.Fprintln(os.Stdout, "all tools are up to date")
+				return nil
+			}
+			if len(entries) > 0 {
+				printOutdated(entries, os.Stdout)
+			}
+			if outdatedErr != nil {
+				return errors.Wrap(withGoErrorHint(outdatedErr), "check some of the tools")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&goCmd, "go", "go", "Path to the go command.")
+	return cmd
+}
+
+func NewBingoVerifyCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd  string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify [flags] [<binary or name pattern>]",
+		Short: "Verifies that all or given pinned tools are installed in pinned versions, without network access.",
+		Long: "Verify checks that binary of every pinned tool exists in GOBIN, is built from the pinned package and version (as embedded\n" +
+			"in the binary, see go version -m) and matches checksum recorded on install. Binaries are reported as ok, missing, stale or\n" +
+			"modified and verify exits with non-zero code if any is not ok. Nothing is fetched or built, so it's fast enough to run on every build.",
+		ValidArgsFunction: completeToolNames(1),
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("too many arguments except none or binary")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			if output != "table" && output != "json" {
+				return errors.Errorf("unsupported output format %q; expected table or json", output)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDir, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+			pkgs, err := bingo.ListPinnedMainPackages(logger, modDir, false)
+			if err != nil {
+				return err
+			}
+			bingo.SortRenderables(pkgs)
+			if len(args) > 0 {
+				if pkgs, err = pkgs.FilterByName(args[0]); err != nil {
+					return err
+				}
+			}
+
+			r, err := runner.NewRunner(ctx, nil, false, goCmd, runner.WithLogger(logger))
+			if err != nil {
+				return err
+			}
+			gobin, err := bingo.BinDir(r.With(ctx, "", "", nil), modDir)
+			if err != nil {
+				return errors.Wrap(err, "deduct GOBIN")
+			}
+
+			var bins []bingo.InstalledBinary
+			for _, p := range pkgs {
+				for _, v := range p.Versions {
+					b, err := bingo.VerifyInstalled(ctx, r, filepath.Join(modDir, v.ModFile), gobin)
+					if err != nil {
+						return errors.Wrapf(err, "verify %v", v.ModFile)
+					}
+					bins = append(bins, b...)
+				}
+			}
+
+			if output == "json" {
+				err = bingo.PrintVerifyJSON(bins, os.Stdout)
+			} else {
+				err = bingo.PrintVerifyTab(bins, os.Stdout)
+			}
+			if err != nil {
+				return err
+			}
+			notOK := 0
+			for _, b := range bins {
+				if b.State != bingo.InstallStateOK {
+					notOK++
+				}
+			}
+			if notOK > 0 {
+				return errors.Errorf("%d of %d pinned binaries are not installed as pinned; run bingo get to install them", notOK, len(bins))
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.StringVarP(&output, "output", "o", "table", "Output format. One of: table, json. JSON output is an array of objects with stable schema (see bingo.InstalledBinary).")
+	return cmd
+}
+
+func NewBingoCheckCommand(logger logging.Logger) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check [flags]",
+		Short: "Checks that module files of pinned tools are well-formed, without installing anything.",
+		Long: "Check parses every module file in the module directory and reports the ones that are not valid bingo module files, e.g.\n" +
+			"with syntax errors, module line not written by bingo, missing go directive or direct require, or malformed build attributes\n" +
+			"in the require comment. Check exits with non-zero code if any problem is found. Nothing is fetched or built (unlike verify,\n" +
+			"it does not look at binaries), so it's fast enough for pre-commit hooks.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("check does not take arguments")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := os.Stat(moddir); err != nil {
+				if os.IsNotExist(err) {
+					return errors.Errorf("module directory %v does not exist; pin tools with bingo get first", moddir)
+				}
+				return err
+			}
+			checked, problems, err := bingo.CheckModFiles(moddir)
+			if err != nil {
+				return err
+			}
+			for _, p := range problems {
+				_, _ = fmt.Fprintln(os.Stdout, p.Error())
+			}
+			if len(problems) > 0 {
+				cmd.SilenceUsage = true
+				return errors.Errorf("%d of %d module files are malformed", len(problems), checked)
+			}
+			logger.Infof("All %d module files in %v are well-formed\n", checked, moddir)
+			return nil
+		},
+	}
+	return cmd
+}
+
+func NewBingoEnvCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd  string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "env [flags]",
+		Short: "Prints paths and Go details bingo resolves for this project.",
+		Long: "Env prints GOBIN, bingo module directory, paths of the generated helpers, binary paths of all pinned tools and the go command\n" +
+			"bingo uses with its version. Default output is KEY=value lines, so scripts can use them with eval $(bingo env).",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("env does not take arguments")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			if output != "env" && output != "json" {
+				return errors.Errorf("unsupported output format %q; expected env or json", output)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			modDir, err := filepath.Abs(moddir)
+			if err != nil {
+				return errors.Wrap(err, "abs")
+			}
+			pkgs, err := bingo.ListPinnedMainPackages(logger, modDir, false)
+			if err != nil {
+				return err
+			}
+			bingo.SortRenderables(pkgs)
+
+			r, err := runner.NewRunner(ctx, nil, false, goCmd, runner.WithLogger(logger))
+			if err != nil {
+				return err
+			}
+			gobin, err := bingo.BinDir(r.With(ctx, "", "", nil), modDir)
+			if err != nil {
+				return errors.Wrap(err, "deduct GOBIN")
+			}
+			env, err := bingo.NewEnv(modDir, gobin, pkgs)
+			if err != nil {
+				return err
+			}
+			if env.Go, err = exec.LookPath(goCmd); err != nil {
+				return errors.Wrapf(err, "look up %v", goCmd)
+			}
+			if env.Go, err = filepath.Abs(env.Go); err != nil {
+				return errors.Wrap(err, "abs")
+			}
+			env.GoVersion = r.GoVersion().String()
+			if env.GoRoot, err = r.GoEnv(ctx, "GOROOT"); err != nil {
+				return errors.Wrap(err, "go env GOROOT")
+			}
+
+			if output == "json" {
+				return env.PrintJSON(os.Stdout)
+			}
+			return env.PrintShell(os.Stdout)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.StringVarP(&output, "output", "o", "env", "Output format. One of: env, json. JSON output is an object with stable schema (see bingo.Env).")
+	return cmd
+}
+
+func NewBingoGenVarsCommand(logger logging.Logger) *cobra.Command {
+	var (
+		makefile  string
+		reproduce bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "gen-vars [flags]",
+		Short: "Regenerates helpers (e.g. variables.env and Variables.mk) from module files of pinned tools without installing them.",
+		Long: "Gen-vars regenerates variables.env, Variables.mk and other helpers (including Makefile from --gen-makefile and Go file from\n" +
+			"--as-library of bingo get, if enabled) from module files in the module directory, based on current paths. Nothing is resolved\n" +
+			"or built, so it's handy when helpers were deleted or the output directory changed. Run bingo get to install the tools.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("gen-vars does not take arguments")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := os.Stat(moddir); err != nil {
+				if os.IsNotExist(err) {
+					return errors.Errorf("module directory %v does not exist; pin tools with bingo get first", moddir)
+				}
+				return err
+			}
+			if makefile != "" {
+				if err := bingo.SetMakefileTargetsPath(moddir, makefile); err != nil {
+					return errors.Wrap(err, "--gen-makefile")
+				}
+			}
+			return bingo.RegenHelpers(logger, moddir, version.Version, reproduce)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&makefile, "gen-makefile", "", "Path (e.g tools.mk) of Makefile to generate with variable, rule and phony target for every tool, like with\n"+
+		"bingo get --gen-makefile. The path is recorded in the module directory, so the file is regenerated on every bingo get.")
+	flags.BoolVar(&reproduce, "reproducible", true, "If enabled, Variables.mk builds tools with -trimpath and (Go 1.18+) -buildvcs=false, as bingo get\n"+
+		"--reproducible does.")
+	return cmd
+}
+
+func NewBingoDoctorCommand(logger logging.Logger) *cobra.Command {
+	var (
+		goCmd   string
+		timeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor [flags]",
+		Short: "Diagnoses common environment issues that break installing tools.",
+		Long: "Doctor checks that the go command works and its version is supported, GOBIN is set and in PATH, git is available, module\n" +
+			"proxy from GOPROXY is reachable and no environment variables known to break installs (e.g. GOFLAGS) are set. Each check\n" +
+			"is reported as pass, warn or fail with a hint how to fix it. Doctor exits with non-zero code if any check fails.",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return errors.New("doctor does not take arguments")
+			}
+			if len(goCmd) == 0 {
+				return errors.New("'go' flag cannot be empty")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+			defer cancel()
+
+			checks := runDoctor(ctx, logger, goCmd, moddir, &http.Client{Timeout: timeout})
+			printDoctor(checks, os.Stdout)
+
+			failed := 0
+			for _, c := range checks {
+				if c.status == doctorFail {
+					failed++
+				}
+			}
+			if failed > 0 {
+				cmd.SilenceUsage = true
+				return errors.Errorf("%d of %d checks failed", failed, len(checks))
+			}
+			return nil
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVar(&goCmd, "go", "go", "Path to the go command.")
+	flags.DurationVar(&timeout, "timeout", 5*time.Second, "Timeout of a single module proxy reachability probe.")
+	return cmd
+}
+
+func NewBingoVersionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Prints bingo Version.",
+		Long:  `Prints bingo Version.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			_, _ = fmt.Fprintln(os.Stdout, version.Version)
+		},
+	}
+	return cmd
+}
+
+func NewBingoCompletionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish",
+		Short: "Generates shell completion script for bingo commands, flags and pinned tool names.",
+		Long: "Completion prints completion script for the given shell to stdout. Tool names are completed from module files in\n" +
+			"the module directory (see --moddir) at the time of completion, so newly pinned tools are completed without regenerating the script.\n" +
+			"For example, to load completion in the current bash session, run: source <(bingo completion bash)",
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Args:      cobra.ExactValidArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := cmd.Root()
+			switch args[0] {
+			case "bash":
+				return root.GenBashCompletionV2(os.Stdout, true)
+			case "zsh":
+				return root.GenZshCompletion(os.Stdout)
+			default:
+				return root.GenFishCompletion(os.Stdout, true)
+			}
+		},
+	}
+	return cmd
+}
+
+// completeToolNames returns completion function completing first maxArgs arguments (all if maxArgs is 0) with names of
+// tools pinned in the module directory.
+func completeToolNames(maxArgs int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if maxArgs > 0 && len(args) >= maxArgs {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		names, err := bingo.ToolNames(moddir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var matching []string
+		for _, n := range names {
+			if strings.HasPrefix(n, toComplete) {
+				matching = append(matching, n)
+			}
+		}
+		return matching, cobra.ShellCompDirectiveNoFileComp
+	}
+}
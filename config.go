@@ -0,0 +1,169 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// configFile is a file in the module directory with defaults of bingo flags.
+	configFile = "config.yaml"
+	// projectConfigFile is a file in the current directory with defaults of bingo flags, used if the module directory has no
+	// configFile.
+	projectConfigFile = "bingo.yaml"
+)
+
+// configKeys are flags that can have defaults set in the config file. Keys are named after flags.
+var configKeys = []string{"parallel", "reproducible", "gen-makefile", "offline"}
+
+// configValue is a value of a single key from the config file.
+type configValue struct {
+	key   string
+	value string
+	line  int
+}
+
+// parseConfig parses config file content. Only a subset of YAML is supported: flat mapping of keys to scalar values (plain,
+// single or double quoted), with # comments.
+func parseConfig(b []byte) ([]configValue, error) {
+	var (
+		ret  []configValue
+		seen = map[string]struct{}{}
+	)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimRight(s.Text(), " \t\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' || line[0] == '-' {
+			return nil, errors.Newf("line %d: only flat mapping of keys to values is supported, got %q", n, line)
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, errors.Newf("line %d: expected key: value, got %q", n, line)
+		}
+		key := strings.TrimSpace(line[:i])
+		value, err := parseConfigScalar(strings.TrimSpace(line[i+1:]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", n)
+		}
+		if _, ok := seen[key]; ok {
+			return nil, errors.Newf("line %d: duplicate key %v", n, key)
+		}
+		seen[key] = struct{}{}
+		ret = append(ret, configValue{key: key, value: value, line: n})
+	}
+	return ret, s.Err()
+}
+
+func parseConfigScalar(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, `"`):
+		end := 1
+		for ; end < len(v) && v[end] != '"'; end++ {
+			if v[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(v) {
+			return "", errors.Newf("unterminated quote in %q", v)
+		}
+		if rest := strings.TrimSpace(v[end+1:]); rest != "" && rest[0] != '#' {
+			return "", errors.Newf("unexpected %q after quoted value", rest)
+		}
+		return strconv.Unquote(v[:end+1])
+	case strings.HasPrefix(v, "'"):
+		var b strings.Builder
+		for i := 1; i < len(v); i++ {
+			if v[i] != '\'' {
+				b.WriteByte(v[i])
+				continue
+			}
+			if i+1 < len(v) && v[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			if rest := strings.TrimSpace(v[i+1:]); rest != "" && rest[0] != '#' {
+				return "", errors.Newf("unexpected %q after quoted value", rest)
+			}
+			return b.String(), nil
+		}
+		return "", errors.Newf("unterminated quote in %q", v)
+	case strings.HasPrefix(v, "[") || strings.HasPrefix(v, "{"):
+		return "", errors.Newf("only scalar values are supported, got %q", v)
+	}
+	if i := strings.Index(v, " #"); i >= 0 {
+		v = strings.TrimSpace(v[:i])
+	}
+	if strings.HasPrefix(v, "#") {
+		return "", nil
+	}
+	return v, nil
+}
+
+// loadConfig reads config file from the module directory or, if there is none, from the current directory. It returns
+// nil values and empty path if neither exists.
+func loadConfig(logger logging.Logger, modDir string) (values []configValue, path string, _ error) {
+	for _, p := range []string{filepath.Join(modDir, configFile), projectConfigFile} {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, "", errors.Wrapf(err, "read %v", p)
+		}
+		if path != "" {
+			logger.Warnf("%v is ignored, since %v exists\n", p, path)
+			continue
+		}
+		values, err = parseConfig(b)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "parse %v", p)
+		}
+		path = p
+	}
+	return values, path, nil
+}
+
+// applyConfig sets flags of the command that were not set explicitly to values from the config file, so flags override
+// the config. Keys of flags the command does not have are ignored. Unknown keys are reported as warning only, so config
+// written for newer bingo still works.
+func applyConfig(logger logging.Logger, cmd *cobra.Command, path string, values []configValue) error {
+	for _, v := range values {
+		if !isConfigKey(v.key) {
+			logger.Warnf("%v:%d: unknown key %q ignored; supported keys: %v\n", path, v.line, v.key, strings.Join(configKeys, ", "))
+			continue
+		}
+		f := cmd.Flags().Lookup(v.key)
+		if f == nil || f.Changed {
+			continue
+		}
+		if err := f.Value.Set(v.value); err != nil {
+			return errors.Wrapf(err, "%v:%d: invalid value %q of %v", path, v.line, v.value, v.key)
+		}
+		logger.Debugf("%v: %v=%v\n", path, v.key, v.value)
+	}
+	return nil
+}
+
+func isConfigKey(key string) bool {
+	for _, k := range configKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/testutil"
+	"github.com/spf13/cobra"
+)
+
+func TestParseConfig(t *testing.T) {
+	for _, tcase := range []struct {
+		content     string
+		expected    []configValue
+		expectedErr string
+	}{
+		{content: ""},
+		{content: "---\n# Defaults for CI.\n\n"},
+		{
+			content: "parallel: 4\nreproducible: false # Needed for debug builds.\ngen-makefile: \"tools.mk\"\noffline: 'true'\n",
+			expected: []configValue{
+				{key: "parallel", value: "4", line: 1},
+				{key: "reproducible", value: "false", line: 2},
+				{key: "gen-makefile", value: "tools.mk", line: 3},
+				{key: "offline", value: "true", line: 4},
+			},
+		},
+		{content: "gen-makefile: 'it''s.mk' # Quoted.\n", expected: []configValue{{key: "gen-makefile", value: "it's.mk", line: 1}}},
+		{content: "gen-makefile:\n", expected: []configValue{{key: "gen-makefile", line: 1}}},
+		{content: "gen:\n  makefile: tools.mk\n", expectedErr: `line 2: only flat mapping of keys to values is supported, got "  makefile: tools.mk"`},
+		{content: "- parallel\n", expectedErr: `line 1: only flat mapping of keys to values is supported, got "- parallel"`},
+		{content: "parallel 4\n", expectedErr: `line 1: expected key: value, got "parallel 4"`},
+		{content: "parallel: [4]\n", expectedErr: `line 1: only scalar values are supported, got "[4]"`},
+		{content: "gen-makefile: \"tools.mk\n", expectedErr: `line 1: unterminated quote in "\"tools.mk"`},
+		{content: "parallel: 4\nparallel: 2\n", expectedErr: "line 2: duplicate key parallel"},
+	} {
+		t.Run(tcase.content, func(t *testing.T) {
+			values, err := parseConfig([]byte(tcase.content))
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, values)
+		})
+	}
+}
+
+func TestLoadAndApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	testutil.Ok(t, err)
+	testutil.Ok(t, os.Chdir(dir))
+	t.Cleanup(func() { testutil.Ok(t, os.Chdir(wd)) })
+
+	logs := &strings.Builder{}
+	logger := logging.NewStd(log.New(logs, "", 0), logging.LevelInfo)
+	modDir := filepath.Join(dir, ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	values, path, err := loadConfig(logger, modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "", path)
+	testutil.Equals(t, 0, len(values))
+
+	testutil.Ok(t, os.WriteFile(projectConfigFile, []byte("parallel: 2\n"), os.ModePerm))
+	values, path, err = loadConfig(logger, modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, projectConfigFile, path)
+	testutil.Equals(t, []configValue{{key: "parallel", value: "2", line: 1}}, values)
+
+	// Config in the module directory takes precedence.
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, configFile), []byte("parallel: 4\noffline: true\nyolo: 1\n"), os.ModePerm))
+	values, path, err = loadConfig(logger, modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, filepath.Join(modDir, configFile), path)
+	testutil.Equals(t, "WARNING: bingo.yaml is ignored, since "+path+" exists\n", logs.String())
+	logs.Reset()
+
+	newCmd := func(args ...string) (*cobra.Command, *int, *bool) {
+		cmd := &cobra.Command{}
+		parallel := cmd.Flags().IntP("parallel", "p", 1, "")
+		offline := cmd.Flags().Bool("offline", false, "")
+		testutil.Ok(t, cmd.ParseFlags(args))
+		return cmd, parallel, offline
+	}
+
+	cmd, parallel, offline := newCmd()
+	testutil.Ok(t, applyConfig(logger, cmd, path, values))
+	testutil.Equals(t, 4, *parallel)
+	testutil.Equals(t, true, *offline)
+	testutil.Equals(t, "WARNING: "+path+`:3: unknown key "yolo" ignored; supported keys: parallel, reproducible, gen-makefile, offline`+"\n", logs.String())
+
+	// Flags override the config.
+	cmd, parallel, offline = newCmd("-p", "8", "--offline=false")
+	testutil.Ok(t, applyConfig(logger, cmd, path, values))
+	testutil.Equals(t, 8, *parallel)
+	testutil.Equals(t, false, *offline)
+
+	cmd, _, _ = newCmd()
+	err = applyConfig(logger, cmd, path, []configValue{{key: "parallel", value: "many", line: 1}})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, path+`:1: invalid value "many" of parallel: strconv.ParseInt: parsing "many": invalid syntax`, err.Error())
+}
+
+func TestApplyConfig_Reproducible(t *testing.T) {
+	// All commands building tools or generating Variables.mk honour reproducible from the config.
+	for _, cmd := range []*cobra.Command{
+		NewBingoGetCommand(logging.Discard),
+		NewBingoImportCommand(logging.Discard),
+		NewBingoRenameCommand(logging.Discard),
+		NewBingoCleanCommand(logging.Discard),
+		NewBingoMigrateCommand(logging.Discard),
+		NewBingoGenVarsCommand(logging.Discard),
+	} {
+		testutil.Ok(t, applyConfig(logging.Discard, cmd, configFile, []configValue{{key: "reproducible", value: "false", line: 1}}))
+		f := cmd.Flags().Lookup("reproducible")
+		testutil.Assert(t, f != nil, "%v: no reproducible flag", cmd.Name())
+		testutil.Equals(t, "false", f.Value.String(), cmd.Name())
+	}
+}
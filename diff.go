@@ -0,0 +1,155 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/efficientgo/core/errcapture"
+)
+
+// modFileDiff is a change of a single tool module file bingo get would introduce.
+type modFileDiff struct {
+	// relModFile is a path of the module file relative to the current directory.
+	relModFile string
+	// old and new are content of the module file before and after get. Empty if the module file does not exist.
+	old, new string
+	// oldVersion and newVersion are versions of the direct module. Empty if the module file does not exist.
+	oldVersion, newVersion string
+}
+
+// getDiffs collects changes bingo get would introduce. When set in get config (together with dry run), get records
+// changes instead of printing the plan.
+type getDiffs struct {
+	mu    sync.Mutex
+	diffs []modFileDiff
+}
+
+// add records change of the module file from the content of outModFile on disk to the given module file.
+func (d *getDiffs) add(relModDir, outModFile string, modFile *bingo.ModFile) error {
+	old, oldVersion, err := readModFileForDiff(outModFile)
+	if err != nil {
+		return err
+	}
+	new, err := os.ReadFile(modFile.Filepath())
+	if err != nil {
+		return err
+	}
+	var newVersion string
+	if p := modFile.DirectPackage(); p != nil {
+		newVersion = p.Module.Version
+	}
+	d.append(modFileDiff{
+		relModFile: filepath.Join(relModDir, filepath.Base(outModFile)),
+		old:        old, oldVersion: oldVersion,
+		new: string(new), newVersion: newVersion,
+	})
+	return nil
+}
+
+// addRemoved records removal of the given module file.
+func (d *getDiffs) addRemoved(relModDir, modFile string) error {
+	old, oldVersion, err := readModFileForDiff(modFile)
+	if err != nil {
+		return err
+	}
+	d.append(modFileDiff{relModFile: filepath.Join(relModDir, filepath.Base(modFile)), old: old, oldVersion: oldVersion})
+	return nil
+}
+
+func (d *getDiffs) append(diff modFileDiff) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if diff.old != diff.new {
+		d.diffs = append(d.diffs, diff)
+	}
+}
+
+// readModFileForDiff returns content and direct module version of the module file. Both are empty if the file does not
+// exist.
+func readModFileForDiff(file string) (content string, version string, err error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	mf, err := bingo.OpenModFile(file)
+	if err != nil {
+		// Malformed file is recreated by get, so show it as it is.
+		return string(b), "", nil
+	}
+	defer errcapture.Do(&err, mf.Close, "close")
+
+	if p := mf.DirectPackage(); p != nil {
+		version = p.Module.Version
+	}
+	return string(b), version, nil
+}
+
+// changed returns true if get would change any module file.
+func (d *getDiffs) changed() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.diffs) > 0
+}
+
+// print writes unified diff of each changed module file followed by summary of binary version changes.
+func (d *getDiffs) print(w io.Writer) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.diffs) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+
+	summary := make([]string, 0, len(d.diffs))
+	for _, diff := range d.diffs {
+		oldName, newName := diff.relModFile, diff.relModFile
+		if diff.old == "" {
+			oldName = "/dev/null"
+		}
+		if diff.new == "" {
+			newName = "/dev/null"
+		}
+		if _, err := io.WriteString(w, bingo.UnifiedDiff(oldName, newName, diff.old, diff.new)); err != nil {
+			return err
+		}
+
+		name := strings.TrimSuffix(filepath.Base(diff.relModFile), ".mod")
+		if diff.oldVersion == diff.newVersion {
+			summary = append(summary, fmt.Sprintf("%s: %s (module file changes only)", name, diff.newVersion))
+			continue
+		}
+		summary = append(summary, fmt.Sprintf("%s: %s -> %s", name, versionOrNone(diff.oldVersion), versionOrNone(diff.newVersion)))
+	}
+
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	for _, s := range summary {
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func versionOrNone(v string) string {
+	if v == "" {
+		return "none"
+	}
+	return v
+}
@@ -0,0 +1,77 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestGetDiffs(t *testing.T) {
+	tmpDir := t.TempDir()
+	modFile := func(version string) string {
+		return "module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT\n\ngo 1.14\n\nrequire github.com/fatih/faillint " + version + "\n"
+	}
+	write := func(file, content string) string {
+		p := filepath.Join(tmpDir, file)
+		testutil.Ok(t, os.WriteFile(p, []byte(content), os.ModePerm))
+		return p
+	}
+	open := func(file string) *bingo.ModFile {
+		mf, err := bingo.OpenModFile(file)
+		testutil.Ok(t, err)
+		t.Cleanup(func() { _ = mf.Close() })
+		return mf
+	}
+
+	d := &getDiffs{}
+	b := bytes.Buffer{}
+	testutil.Ok(t, d.print(&b))
+	testutil.Equals(t, "no changes\n", b.String())
+
+	// Unchanged module files are not recorded.
+	testutil.Ok(t, d.add(".bingo", write("faillint.mod", modFile("v1.4.0")), open(write("faillint.tmp.mod", modFile("v1.4.0")))))
+	testutil.Assert(t, !d.changed())
+
+	testutil.Ok(t, d.add(".bingo", filepath.Join(tmpDir, "faillint.mod"), open(write("faillint.tmp2.mod", modFile("v1.5.0")))))
+	testutil.Ok(t, d.add(".bingo", filepath.Join(tmpDir, "faillint.1.mod"), open(write("faillint.1.tmp.mod", modFile("v1.6.0")))))
+	testutil.Ok(t, d.addRemoved(".bingo", write("other.mod", modFile("v1.0.0"))))
+	testutil.Assert(t, d.changed())
+
+	b.Reset()
+	testutil.Ok(t, d.print(&b))
+	testutil.Equals(t, "--- .bingo/faillint.mod\n"+
+		"+++ .bingo/faillint.mod\n"+
+		"@@ -2,4 +2,4 @@\n"+
+		" \n"+
+		" go 1.14\n"+
+		" \n"+
+		"-require github.com/fatih/faillint v1.4.0\n"+
+		"+require github.com/fatih/faillint v1.5.0\n"+`--- /dev/null
++++ .bingo/faillint.1.mod
+@@ -0,0 +1,5 @@
++module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
++
++go 1.14
++
++require github.com/fatih/faillint v1.6.0
+--- .bingo/other.mod
++++ /dev/null
+@@ -1,5 +0,0 @@
+-module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+-
+-go 1.14
+-
+-require github.com/fatih/faillint v1.0.0
+
+faillint: v1.4.0 -> v1.5.0
+faillint.1: none -> v1.6.0
+other: v1.0.0 -> none
+`, b.String())
+}
@@ -0,0 +1,297 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errors"
+)
+
+// doctorStatus is a result of a single bingo doctor check.
+type doctorStatus string
+
+const (
+	doctorPass doctorStatus = "pass"
+	doctorWarn doctorStatus = "warn"
+	doctorFail doctorStatus = "fail"
+)
+
+// doctorCheck is a single bingo doctor check result with remediation hint, if it did not pass.
+type doctorCheck struct {
+	name    string
+	status  doctorStatus
+	details string
+	hint    string
+}
+
+// runDoctor checks environment bingo runs in for issues that commonly break installs. Checks that need working go
+// command are skipped if it does not work.
+func runDoctor(ctx context.Context, logger logging.Logger, goCmd, modDir string, client *http.Client) []doctorCheck {
+	r, check := checkGoCommand(ctx, logger, goCmd)
+	checks := []doctorCheck{check}
+	if r == nil {
+		return checks
+	}
+
+	goEnv := map[string]string{}
+	for _, k := range []string{"GOBIN", "GOPROXY", "GOFLAGS", "GOSUMDB", "GOINSECURE"} {
+		v, err := r.GoEnv(ctx, k)
+		if err != nil {
+			return append(checks, doctorCheck{
+				name:    "go env",
+				status:  doctorFail,
+				details: fmt.Sprintf("go env %v: %v", k, err),
+				hint:    "fix the Go installation or environment, so go env works",
+			})
+		}
+		goEnv[k] = v
+	}
+
+	checks = append(checks, checkGOBIN(r.With(ctx, "", "", nil), modDir, goEnv["GOBIN"])...)
+	proxies, direct, off := parseGOPROXY(goEnv["GOPROXY"])
+	checks = append(checks, checkGit(len(proxies) == 0 && direct))
+	if off {
+		checks = append(checks, doctorCheck{
+			name:    "GOPROXY",
+			status:  doctorWarn,
+			details: fmt.Sprintf("GOPROXY=%v disables downloading modules", goEnv["GOPROXY"]),
+			hint:    "only tools already in the module cache can be installed; unset GOPROXY to use the default module proxy",
+		})
+	} else {
+		checks = append(checks, checkProxies(ctx, client, goEnv["GOPROXY"], proxies, direct))
+	}
+	return append(checks, checkEnvs(goEnv)...)
+}
+
+// checkGoCommand checks that go command works and its version is supported. Returned runner is nil if go does not work.
+func checkGoCommand(ctx context.Context, logger logging.Logger, goCmd string) (*runner.Runner, doctorCheck) {
+	c := doctorCheck{name: "go"}
+	r, err := runner.NewRunner(ctx, nil, false, goCmd, runner.WithLogger(logger))
+	if r == nil {
+		c.status = doctorFail
+		c.details = err.Error()
+		c.hint = "install Go 1.14 or newer (see https://go.dev/dl/) and add it to PATH, or pass path to the go command with --go"
+		return nil, c
+	}
+	path, lerr := exec.LookPath(goCmd)
+	if lerr != nil {
+		path = goCmd
+	}
+	if err != nil {
+		c.status = doctorFail
+		c.details = fmt.Sprintf("%v (%v)", err, path)
+		c.hint = "upgrade Go to 1.14 or newer (see https://go.dev/dl/), or pass path to newer go command with --go"
+		return r, c
+	}
+	c.status = doctorPass
+	c.details = fmt.Sprintf("go%v (%v)", r.GoVersion(), path)
+	return r, c
+}
+
+// checkGOBIN checks that the directory binaries are installed to is set explicitly and it's in PATH.
+func checkGOBIN(runnable runner.Runnable, modDir, gobinEnv string) []doctorCheck {
+	gobin, err := bingo.GoBin(runnable)
+	if err == nil {
+		gobin, err = filepath.Abs(gobin)
+	}
+	var binDir string
+	if err == nil {
+		binDir, err = bingo.BinDir(runnable, modDir)
+	}
+	if err != nil {
+		return []doctorCheck{{
+			name:    "GOBIN",
+			status:  doctorFail,
+			details: err.Error(),
+			hint:    "set GOBIN (e.g. go env -w GOBIN=$HOME/go/bin) or GOPATH, so bingo knows where to install binaries",
+		}}
+	}
+
+	c := doctorCheck{name: "GOBIN", status: doctorPass, details: binDir}
+	switch {
+	case filepath.Clean(binDir) != filepath.Clean(gobin):
+		c.details = fmt.Sprintf("%v (output directory of the project)", binDir)
+	case gobinEnv == "":
+		c.status = doctorWarn
+		c.details = fmt.Sprintf("GOBIN is not set; binaries are installed to %v (GOPATH/bin)", binDir)
+		c.hint = "set GOBIN explicitly (e.g. go env -w GOBIN=" + binDir + "), so it's clear where binaries are installed"
+	}
+	checks := []doctorCheck{c}
+
+	if !inPath(binDir) {
+		return append(checks, doctorCheck{
+			name:    "PATH",
+			status:  doctorWarn,
+			details: fmt.Sprintf("%v is not in PATH", binDir),
+			hint: fmt.Sprintf("add it to PATH (e.g. export PATH=\"%v%c$PATH\") to run tools by name, or use their versioned paths "+
+				"from variables.env or Variables.mk", binDir, filepath.ListSeparator),
+		})
+	}
+	return append(checks, doctorCheck{name: "PATH", status: doctorPass, details: fmt.Sprintf("%v is in PATH", binDir)})
+}
+
+// inPath returns true if the directory is one of PATH entries.
+func inPath(dir string) bool {
+	for _, p := range filepath.SplitList(os.Getenv("PATH")) {
+		if p != "" && filepath.Clean(p) == filepath.Clean(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGit checks that git is available. Without module proxy it's required to fetch any module.
+func checkGit(required bool) doctorCheck {
+	path, err := exec.LookPath("git")
+	if err == nil {
+		return doctorCheck{name: "git", status: doctorPass, details: path}
+	}
+	c := doctorCheck{
+		name:    "git",
+		status:  doctorWarn,
+		details: "git not found in PATH",
+		hint:    "install git; go needs it to fetch modules directly from VCS, e.g. private modules (GOPRIVATE) or commits not in the module proxy",
+	}
+	if required {
+		c.status = doctorFail
+		c.details += "; GOPROXY=direct fetches all modules from VCS"
+	}
+	return c
+}
+
+// parseGOPROXY returns module proxy URLs from GOPROXY list, whether it falls back to direct VCS access and whether
+// it's off.
+func parseGOPROXY(goproxy string) (proxies []string, direct bool, off bool) {
+	for _, p := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		switch p = strings.TrimSpace(p); p {
+		case "":
+		case "direct":
+			direct = true
+		case "off":
+			off = len(proxies) == 0 && !direct
+		default:
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies, direct, off
+}
+
+// checkProxies checks that at least one of module proxies is reachable. Any HTTP response other than server error
+// counts, since proxies don't have to serve their root path.
+func checkProxies(ctx context.Context, client *http.Client, goproxy string, proxies []string, direct bool) doctorCheck {
+	if len(proxies) == 0 {
+		return doctorCheck{name: "GOPROXY", status: doctorPass, details: fmt.Sprintf("GOPROXY=%v fetches modules directly from VCS", goproxy)}
+	}
+
+	var errs []string
+	for _, p := range proxies {
+		err := probeProxy(ctx, client, p)
+		if err == nil {
+			return doctorCheck{name: "GOPROXY", status: doctorPass, details: fmt.Sprintf("%v is reachable", p)}
+		}
+		errs = append(errs, fmt.Sprintf("%v: %v", p, err))
+	}
+	c := doctorCheck{
+		name:    "GOPROXY",
+		status:  doctorFail,
+		details: "no module proxy is reachable: " + strings.Join(errs, "; "),
+		hint:    "check network connection and HTTPS_PROXY, or set GOPROXY to reachable module proxy",
+	}
+	if direct {
+		c.status = doctorWarn
+		c.details += "; go falls back to fetching modules directly from VCS"
+	}
+	return c
+}
+
+func probeProxy(ctx context.Context, client *http.Client, proxy string) error {
+	if strings.HasPrefix(proxy, "file://") {
+		_, err := os.Stat(strings.TrimPrefix(proxy, "file://"))
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, proxy, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return errors.Newf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
+
+// checkEnvs returns checks of environment variables that commonly break installs. Go environment is taken from go env,
+// so it includes variables set with go env -w.
+func checkEnvs(goEnv map[string]string) []doctorCheck {
+	var checks []doctorCheck
+	if goflags := goEnv["GOFLAGS"]; goflags != "" {
+		c := doctorCheck{
+			name:    "GOFLAGS",
+			status:  doctorWarn,
+			details: fmt.Sprintf("GOFLAGS=%q applies to every go command bingo runs, including builds of all tools", goflags),
+			hint:    "unset GOFLAGS unless it's intended for all tools; flags for single tool can be pinned in its module file (e.g. -tags=extended)",
+		}
+		for _, f := range strings.Fields(goflags) {
+			if f == "-mod=vendor" {
+				c.status = doctorFail
+				c.details = fmt.Sprintf("GOFLAGS=%q makes go build tools from vendor directory, which bingo modules don't have", goflags)
+			}
+		}
+		checks = append(checks, c)
+	}
+	for _, k := range []string{"GONOSUMCHECK", "GONOVERIFY"} {
+		if v := os.Getenv(k); v != "" {
+			checks = append(checks, doctorCheck{
+				name:    k,
+				status:  doctorWarn,
+				details: fmt.Sprintf("%v=%v is not supported by go anymore and has no effect", k, v),
+				hint:    "use GONOSUMDB or GOPRIVATE to skip checksum database for given modules",
+			})
+		}
+	}
+	if goEnv["GOSUMDB"] == "off" {
+		checks = append(checks, doctorCheck{
+			name:    "GOSUMDB",
+			status:  doctorWarn,
+			details: "GOSUMDB=off disables verification of all modules against the checksum database",
+			hint:    "unset GOSUMDB and use GONOSUMDB or GOPRIVATE to skip verification of private modules only",
+		})
+	}
+	if v := goEnv["GOINSECURE"]; v != "" {
+		checks = append(checks, doctorCheck{
+			name:    "GOINSECURE",
+			status:  doctorWarn,
+			details: fmt.Sprintf("GOINSECURE=%v allows fetching modules over insecure connections", v),
+			hint:    "unset GOINSECURE unless the modules can't be fetched over HTTPS",
+		})
+	}
+	if len(checks) == 0 {
+		checks = append(checks, doctorCheck{name: "env", status: doctorPass, details: "no environment variables known to break installs are set"})
+	}
+	return checks
+}
+
+func printDoctor(checks []doctorCheck, w io.Writer) {
+	for _, c := range checks {
+		_, _ = fmt.Fprintf(w, "[%v] %v: %v\n", c.status, c.name, c.details)
+		if c.hint != "" {
+			_, _ = fmt.Fprintf(w, "       hint: %v\n", c.hint)
+		}
+	}
+}
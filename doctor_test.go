@@ -0,0 +1,112 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwplotka/bingo/internal/fakecmd"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestParseGOPROXY(t *testing.T) {
+	for _, tcase := range []struct {
+		goproxy         string
+		expectedProxies []string
+		expectedDirect  bool
+		expectedOff     bool
+	}{
+		{goproxy: ""},
+		{goproxy: "https://proxy.golang.org,direct", expectedProxies: []string{"https://proxy.golang.org"}, expectedDirect: true},
+		{goproxy: "https://a.example.com|https://b.example.com", expectedProxies: []string{"https://a.example.com", "https://b.example.com"}},
+		{goproxy: "direct", expectedDirect: true},
+		{goproxy: "off", expectedOff: true},
+		{goproxy: "https://proxy.golang.org,off", expectedProxies: []string{"https://proxy.golang.org"}},
+	} {
+		t.Run(tcase.goproxy, func(t *testing.T) {
+			proxies, direct, off := parseGOPROXY(tcase.goproxy)
+			testutil.Equals(t, tcase.expectedProxies, proxies)
+			testutil.Equals(t, tcase.expectedDirect, direct)
+			testutil.Equals(t, tcase.expectedOff, off)
+		})
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that prints environment variables as go env does.
+	goCmd := fakecmd.Go(t, dir, `  env) eval echo "\$$2" ;;`)
+	gitDir := filepath.Join(dir, "git")
+	testutil.Ok(t, os.MkdirAll(gitDir, os.ModePerm))
+	fakecmd.Write(t, gitDir, "git", "")
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer proxy.Close()
+	brokenProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer brokenProxy.Close()
+
+	gopath := filepath.Join(dir, "gopath")
+	gobin := filepath.Join(dir, "bin")
+	for _, k := range []string{"GONOSUMCHECK", "GONOVERIFY", "GOSUMDB", "GOINSECURE"} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("GOPATH", gopath)
+
+	logger := logging.Discard
+	modDir := filepath.Join(dir, ".bingo")
+	t.Run("healthy", func(t *testing.T) {
+		t.Setenv("PATH", strings.Join([]string{gobin, gitDir}, string(filepath.ListSeparator)))
+		t.Setenv("GOBIN", gobin)
+		t.Setenv("GOPROXY", brokenProxy.URL+","+proxy.URL+",direct")
+		t.Setenv("GOFLAGS", "")
+
+		testutil.Equals(t, []doctorCheck{
+			{name: "go", status: doctorPass, details: "go1.21.0 (" + goCmd + ")"},
+			{name: "GOBIN", status: doctorPass, details: gobin},
+			{name: "PATH", status: doctorPass, details: gobin + " is in PATH"},
+			{name: "git", status: doctorPass, details: filepath.Join(gitDir, "git")},
+			{name: "GOPROXY", status: doctorPass, details: proxy.URL + " is reachable"},
+			{name: "env", status: doctorPass, details: "no environment variables known to break installs are set"},
+		}, runDoctor(context.Background(), logger, goCmd, modDir, http.DefaultClient))
+	})
+	t.Run("broken", func(t *testing.T) {
+		t.Setenv("PATH", "")
+		t.Setenv("GOBIN", "")
+		t.Setenv("GOPROXY", "direct")
+		t.Setenv("GOFLAGS", "-mod=vendor")
+		t.Setenv("GONOSUMCHECK", "1")
+
+		checks := runDoctor(context.Background(), logger, goCmd, modDir, http.DefaultClient)
+		var statuses []string
+		for _, c := range checks {
+			statuses = append(statuses, c.name+" "+string(c.status))
+		}
+		testutil.Equals(t, []string{
+			"go pass",
+			"GOBIN warn",
+			"PATH warn",
+			"git fail",
+			"GOPROXY pass",
+			"GOFLAGS fail",
+			"GONOSUMCHECK warn",
+		}, statuses)
+		testutil.Equals(t, "GOBIN is not set; binaries are installed to "+filepath.Join(gopath, "bin")+" (GOPATH/bin)", checks[1].details)
+	})
+	t.Run("no go", func(t *testing.T) {
+		checks := runDoctor(context.Background(), logger, filepath.Join(dir, "not-existing-go"), modDir, http.DefaultClient)
+		testutil.Equals(t, 1, len(checks))
+		testutil.Equals(t, doctorFail, checks[0].status)
+	})
+}
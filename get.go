@@ -7,30 +7,29 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
 	"github.com/Masterminds/semver"
 	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
 	"github.com/bwplotka/bingo/pkg/mod"
 	"github.com/bwplotka/bingo/pkg/runner"
 	"github.com/bwplotka/bingo/pkg/version"
 	"github.com/efficientgo/core/errcapture"
 	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/merrors"
 	"golang.org/x/mod/module"
 )
 
-var (
-	goModVersionRegexp = regexp.MustCompile("^v[0-9]*$")
-)
-
 func parseTarget(rawTarget string) (name string, pkgPath string, versions []string, err error) {
 	if rawTarget == "" {
 		return "", "", nil, errors.New("target is empty, this should be filtered earlier")
@@ -58,17 +57,32 @@ func parseTarget(rawTarget string) (name string, pkgPath string, versions []stri
 		}
 	}
 
-	name = nameOrPackage
+	name = strings.ToLower(nameOrPackage)
 	if strings.Contains(nameOrPackage, "/") {
 		// Binary referenced by path, get default name from package path.
-		pkgPath = nameOrPackage
-		name = path.Base(pkgPath)
-		if pkgSplit := strings.Split(pkgPath, "/"); len(pkgSplit) > 3 && goModVersionRegexp.MatchString(name) {
-			// It's common pattern to name urls with versions in go modules. Exclude that.
-			name = pkgSplit[len(pkgSplit)-2]
+		pkgPath, err = normalizePackagePath(nameOrPackage)
+		if err != nil {
+			return "", "", nil, err
 		}
+		name = bingo.DefaultBinaryName(pkgPath)
+	}
+	return name, pkgPath, versions, nil
+}
+
+// normalizePackagePath returns the package path as typed by the user with trailing slashes removed and the host (first
+// path element) lowercased, since hosts are case-insensitive, e.g. github.com/Foo/Bar for Github.com/Foo/Bar/. Other
+// elements are case-sensitive, so they are kept as they are. The result is validated with module.CheckPath.
+func normalizePackagePath(pkgPath string) (string, error) {
+	p := strings.TrimRight(pkgPath, "/")
+	if i := strings.Index(p, "/"); i >= 0 {
+		p = strings.ToLower(p[:i]) + p[i:]
+	} else {
+		p = strings.ToLower(p)
 	}
-	return strings.ToLower(name), pkgPath, versions, nil
+	if err := module.CheckPath(p); err != nil {
+		return "", errors.Wrapf(err, "invalid package path %q", pkgPath)
+	}
+	return p, nil
 }
 
 type installPackageConfig struct {
@@ -76,8 +90,44 @@ type installPackageConfig struct {
 	modDir    string
 	relModDir string
 	link      bool
-
-	verbose bool
+	// linkDir is an additional directory for tool links if link is true.
+	linkDir string
+	// dryRun makes get print planned changes instead of writing mod files and building binaries.
+	dryRun bool
+	// diffs, if set, collects changes to mod files in dry run instead of printing them.
+	diffs *getDiffs
+	// printCmd makes dry run print go build commands of the tool instead of planned changes.
+	printCmd bool
+	// noBuild makes get update mod files without building binaries.
+	noBuild bool
+	// modOnlyIfChanged makes get keep mod and sum files untouched (including modification time) if their content did not change.
+	modOnlyIfChanged bool
+	// update makes get resolve the latest version of the module matching the target version treated as constraint.
+	update          bool
+	allowPrerelease bool
+	// comment is recorded in the module file as bingo:comment directive, if not empty.
+	comment string
+	// toolchain is recorded in the module file as toolchain directive, if not empty. "none" removes it.
+	toolchain string
+	// postInstall is recorded in the module file as bingo:post-install directive, if not empty. "none" removes it.
+	postInstall string
+	// pinGo, if set, adds (true) or removes (false) bingo:pin_go directive of the module file. Nil keeps the existing one.
+	pinGo *bool
+	// vendor, if set, adds (true) or removes (false) bingo:vendor directive of the module file. Nil keeps the existing one.
+	vendor *bool
+	// replaces are local directories to build modules from instead of their released versions.
+	replaces []localReplace
+	// pickVersion, if set, picks version of the tool requested without version instead of the latest one (see --interactive).
+	pickVersion versionPicker
+	// keepTemp makes failed install keep temporary module files and report them, so the build can be debugged manually.
+	keepTemp bool
+	// rebuild tells if binaries matching recorded checksums are rebuilt anyway (see --force and --force-clean).
+	rebuild bingo.Rebuild
+	// ignoreConstraints makes get only warn about versions denied in bingo.ConstraintsFile instead of refusing them.
+	ignoreConstraints bool
+	// commitViaProxy makes get resolve commits to pseudo-versions through the module proxy, even if GOPROXY is direct,
+	// and warn if direct VCS resolution differs (see --commit-via-proxy).
+	commitViaProxy bool
 }
 
 type getConfig struct {
@@ -87,9 +137,34 @@ type getConfig struct {
 	name      string
 	rename    string
 	link      bool
+	linkDir   string
+	// parallel is a maximum number of tools installed concurrently when all tools are requested.
+	parallel int
+	dryRun   bool
+	diffs    *getDiffs
+	printCmd bool
+	noBuild  bool
+	// modOnlyIfChanged makes get keep mod and sum files untouched if their content did not change (see --mod-only-if-changed).
+	modOnlyIfChanged bool
+
+	update          bool
+	allowPrerelease bool
+	comment         string
+	toolchain       string
+	postInstall     string
+	pinGo           *bool
+	vendor          *bool
+	replaces        []localReplace
+	pickVersion     versionPicker
+	// sideBySide makes get pin each version of the target as a separate tool named after its major version.
+	sideBySide bool
+	keepTemp   bool
+	rebuild    bingo.Rebuild
+
+	ignoreConstraints bool
+	commitViaProxy    bool
 
 	timeOut uint
-	verbose bool
 }
 
 func (c getConfig) forPackage() installPackageConfig {
@@ -97,12 +172,103 @@ func (c getConfig) forPackage() installPackageConfig {
 		modDir:    c.modDir,
 		relModDir: c.relModDir,
 		runner:    c.runner,
-		verbose:   c.verbose,
 		link:      c.link,
+		linkDir:   c.linkDir,
+		dryRun:    c.dryRun,
+		diffs:     c.diffs,
+		printCmd:  c.printCmd,
+		noBuild:   c.noBuild,
+
+		modOnlyIfChanged: c.modOnlyIfChanged,
+
+		update:          c.update,
+		allowPrerelease: c.allowPrerelease,
+		comment:         c.comment,
+		toolchain:       c.toolchain,
+		postInstall:     c.postInstall,
+		pinGo:           c.pinGo,
+		vendor:          c.vendor,
+		replaces:        c.replaces,
+		pickVersion:     c.pickVersion,
+		keepTemp:        c.keepTemp,
+		rebuild:         c.rebuild,
+
+		ignoreConstraints: c.ignoreConstraints,
+		commitViaProxy:    c.commitViaProxy,
+	}
+}
+
+// setToolchain records toolchain directive in the module file or removes it if toolchain is empty. Go refuses to build
+// with toolchain older than go directive (typically written by `go mod init` of the local Go), so go directive is
+// lowered to the toolchain version if needed.
+func setToolchain(modFile *bingo.ModFile, toolchain string) error {
+	if toolchain == "" {
+		return modFile.SetToolchain("")
+	}
+	want, err := version.Parse(toolchain)
+	if err != nil {
+		return err
+	}
+	if goVersion, err := version.Parse(modFile.GoVersion()); err == nil && want.LessThan(goVersion) {
+		if modFile.IsGoVersionPinned() {
+			return errors.Newf("go directive %v is pinned with %v, but toolchain is older; use toolchain %v or newer", modFile.GoVersion(), bingo.GoVersionPinnedDirective, modFile.GoVersion())
+		}
+		// Language version only, since go drops toolchain directive equal to go directive.
+		if err := modFile.SetGoVersion(fmt.Sprintf("%v.%v", want.Major(), want.Minor())); err != nil {
+			return errors.Wrap(err, "set go version")
+		}
+	}
+	return modFile.SetToolchain(toolchain)
+}
+
+// localReplace represents local directory module is built from, as given in --replace=<module path>=<dir>.
+type localReplace struct {
+	modulePath string
+	// dir is relative to the module directory or absolute.
+	dir string
+}
+
+// parseLocalReplace parses <module path>=<dir> where dir is relative to the current working directory and makes dir
+// relative to the modDir, so the module file stays portable.
+func parseLocalReplace(raw string, modDir string) (localReplace, error) {
+	s := strings.SplitN(raw, "=", 2)
+	if len(s) != 2 || s[0] == "" || s[1] == "" {
+		return localReplace{}, errors.Newf("expected <module path>=<local dir>, got %q", raw)
+	}
+	modulePath, dir := s[0], s[1]
+	if err := module.CheckImportPath(modulePath); err != nil {
+		return localReplace{}, errors.Wrapf(err, "module path %q", modulePath)
+	}
+	if filepath.IsAbs(dir) {
+		return localReplace{modulePath: modulePath, dir: dir}, nil
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return localReplace{}, errors.Wrap(err, "abs")
 	}
+	relDir, err := filepath.Rel(modDir, absDir)
+	if err != nil {
+		return localReplace{}, errors.Wrapf(err, "%v relative to %v", absDir, modDir)
+	}
+	return localReplace{modulePath: modulePath, dir: relDir}, nil
+}
+
+// matchLocalReplace returns local replace of the module the target package belongs to, if any.
+func matchLocalReplace(replaces []localReplace, target bingo.Package) (ret localReplace, ok bool) {
+	for _, r := range replaces {
+		if target.Path() != r.modulePath && !strings.HasPrefix(target.Path(), r.modulePath+"/") {
+			continue
+		}
+		// Prefer the most specific (nested) module.
+		if len(r.modulePath) > len(ret.modulePath) {
+			ret, ok = r, true
+		}
+	}
+	return ret, ok
 }
 
-func getAll(ctx context.Context, logger *log.Logger, c getConfig) (err error) {
+func getAll(ctx context.Context, logger logging.Logger, c getConfig) (err error) {
 	if c.name != "" {
 		return errors.New("name cannot by specified if no target was given")
 	}
@@ -114,14 +280,167 @@ func getAll(ctx context.Context, logger *log.Logger, c getConfig) (err error) {
 	if err != nil {
 		return err
 	}
+	ignorePatterns, err := bingo.IgnorePatterns(c.relModDir)
+	if err != nil {
+		return errors.Wrap(err, "read ignored tools")
+	}
+	pkgs, ignored := pkgs.FilterIgnored(ignorePatterns)
+
+	type getJob struct {
+		i       int
+		name    string
+		modFile string
+		target  bingo.Package
+	}
+	var jobs []getJob
 	for _, p := range pkgs {
+		if p.Additional {
+			// Built together with the direct package of the same module file.
+			continue
+		}
 		for i, targetPkg := range p.ToPackages() {
-			if err := getPackage(ctx, logger, c.forPackage(), i, p.Name, targetPkg); err != nil {
-				return errors.Wrapf(err, "%d: getting %s", i, targetPkg.String())
+			jobs = append(jobs, getJob{i: i, name: p.Name, modFile: filepath.Join(c.modDir, p.Versions[i].ModFile), target: targetPkg})
+		}
+	}
+
+	parallel := c.parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	// Each job works on its own tmp mod files, so they can be safely run concurrently.
+	errs := make([]error, len(jobs))
+	sem := make(chan struct{}, parallel)
+	wg := sync.WaitGroup{}
+	for j, job := range jobs {
+		wg.Add(1)
+		go func(j int, job getJob) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if c.incremental() && isInstallUpToDate(ctx, logger, c, job.name, job.modFile) {
+				logger.Infof("%s: %s unchanged since the last install; skipping (use --force to reinstall)\n", job.name, filepath.Base(job.modFile))
+				return
+			}
+			if err := getPackage(ctx, logger, c.forPackage(), job.i, job.name, job.target); err != nil {
+				errs[j] = errors.Wrapf(err, "%d: getting %s", job.i, job.target.String())
 			}
+		}(j, job)
+	}
+	wg.Wait()
+
+	merr := merrors.New()
+	for _, err := range errs {
+		merr.Add(err)
+	}
+	if merr.Err() == nil {
+		if len(ignored) > 0 {
+			names := make([]string, 0, len(ignored))
+			for _, p := range ignored {
+				names = append(names, p.Name)
+			}
+			logger.Infof("Skipped tools ignored in %s: %s\n", filepath.Join(c.relModDir, bingo.IgnoreFile), strings.Join(names, ", "))
+		}
+		return nil
+	}
+
+	// Summary in the same order as tools are listed.
+	logger.Infof("Failed to get some of the tools:\n")
+	for j, job := range jobs {
+		status := "ok"
+		if errs[j] != nil {
+			status = "FAILED"
 		}
+		logger.Infof("  %s (%s): %s\n", job.name, job.target.String(), status)
 	}
-	return nil
+	for _, p := range ignored {
+		logger.Infof("  %s: ignored in %s\n", p.Name, filepath.Join(c.relModDir, bingo.IgnoreFile))
+	}
+	return merr.Err()
+}
+
+// incremental returns true if getting all tools can skip tools whose module files did not change since their last install
+// (see bingo.IsInstallUpToDate), so only changed and new tools are resolved and built. Any option that changes module
+// files or forces the rebuild disables it.
+func (c getConfig) incremental() bool {
+	return c.rebuild == bingo.RebuildIfChanged && !c.dryRun && !c.noBuild && !c.update && c.comment == "" && c.toolchain == "" &&
+		c.postInstall == "" && c.pinGo == nil && c.vendor == nil && len(c.replaces) == 0
+}
+
+// isInstallUpToDate returns true if the tool pinned in the given module file is installed as pinned. Errors are only
+// logged, since the tool is then simply installed again.
+func isInstallUpToDate(ctx context.Context, logger logging.Logger, c getConfig, name, modFilePath string) bool {
+	mf, err := bingo.OpenModFile(modFilePath)
+	if err != nil {
+		logger.Debugf("%s: cannot check if installed binaries are up to date: %v\n", name, err)
+		return false
+	}
+	defer func() { _ = mf.Close() }()
+
+	upToDate, err := bingo.IsInstallUpToDate(ctx, logger, c.runner, c.modDir, "", name, c.link, c.linkDir, mf)
+	if err != nil {
+		logger.Debugf("%s: cannot check if installed binaries are up to date: %v\n", name, err)
+		return false
+	}
+	return upToDate
+}
+
+// getMatching performs get for each pinned tool with name matching the given pattern (see path.Match), as if the tool was
+// referenced by name.
+func getMatching(ctx context.Context, logger logging.Logger, c getConfig, pattern string) error {
+	if c.name != "" {
+		return errors.New("name cannot by specified for tool name pattern")
+	}
+	if c.rename != "" {
+		return errors.New("rename cannot by specified for tool name pattern")
+	}
+	if strings.Contains(pattern, "@") {
+		return errors.Newf("tool name pattern cannot take version arguments (string after @), got %v; reference tools by name to change versions", pattern)
+	}
+
+	pkgs, err := bingo.ListPinnedMainPackages(logger, c.relModDir, false)
+	if err != nil {
+		return err
+	}
+	matched, err := pkgs.FilterByName(strings.ToLower(pattern))
+	if err != nil {
+		return err
+	}
+
+	merr := merrors.New()
+	for _, p := range matched {
+		if p.Additional {
+			continue
+		}
+		if err := get(ctx, logger, c, p.Name); err != nil {
+			merr.Add(errors.Wrapf(err, "getting %s", p.Name))
+		}
+	}
+	return merr.Err()
+}
+
+// withGoErrorHint adds actionable hint to the error if it's caused by recognized go command failure (see runner.GoError).
+func withGoErrorHint(err error) error {
+	switch {
+	case errors.Is(err, runner.ErrNetwork):
+		return errors.Wrap(err, "network error while fetching modules; check connectivity and GOPROXY settings, then retry")
+	case errors.Is(err, runner.ErrNotInModCache):
+		return errors.Wrap(err, "module is not in the module cache and downloading is disabled; populate GOMODCACHE first (e.g. with bingo get "+
+			"or go mod download on machine with network access) or retry without --offline")
+	case errors.Is(err, runner.ErrAuth):
+		return errors.Wrapf(err, "private module server requires credentials; put them in .netrc (or file NETRC points to) or git credential helper "+
+			"and set %v (or GOPRIVATE) to the module path pattern, so go fetches it directly", bingo.PrivateModulesEnv)
+	case errors.Is(err, runner.ErrForbidden):
+		return errors.Wrapf(err, "access denied by proxy/server (credentials or policy); check that your credentials are allowed to fetch the module "+
+			"and that GOPROXY serves it, or set %v (or GOPRIVATE) to the module path pattern, so go fetches it directly", bingo.PrivateModulesEnv)
+	case errors.Is(err, runner.ErrModuleNotFound):
+		return errors.Wrap(err, "module, package or version not found; check the path and version (for private modules set GOPRIVATE)")
+	case errors.Is(err, runner.ErrBuildFailed):
+		return errors.Wrap(err, "tool failed to compile; it might not support Go version or build attributes it's built with")
+	}
+	return err
 }
 
 func existingModFiles(modDir string, targetName string) (existingModFiles []string, _ error) {
@@ -137,34 +456,254 @@ func existingModFiles(modDir string, targetName string) (existingModFiles []stri
 }
 
 // get performs bingo get: it's like go get, but package aware, without go source files and on dedicated mod file.
-// rawTarget is name or target package path, optionally with module version or array versions.
-func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string) (err error) {
-	var cancel context.CancelFunc = func() {}
-
-	if c.timeOut > 0 {
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(c.timeOut)*time.Minute)
+// rawTarget is name or target package path, optionally with module version or array versions, or tool name pattern.
+func get(ctx context.Context, logger logging.Logger, c getConfig, rawTarget string) (err error) {
+	if bingo.IsNamePattern(rawTarget) {
+		// Each matching tool is got separately with its own timeout.
+		return getMatching(ctx, logger, c, rawTarget)
 	}
 
+	ctx, cancel := withGetTimeout(ctx, c)
 	defer cancel()
 
 	// Cleanup all bingo modules' tmp files for fresh start.
 	if err := cleanGoGetTmpFiles(c.modDir); err != nil {
 		return err
 	}
-	if err := ensureModDirExists(logger, c.relModDir); err != nil {
-		return errors.Wrap(err, "ensure mod dir")
+	c, cleanup, err := prepareModDir(logger, c)
+	if err != nil {
+		return err
 	}
+	defer cleanup()
 
 	if rawTarget == "" {
 		// Empty target means to get all. It recursively invokes get for each existing binary.
 		return getAll(ctx, logger, c)
 	}
+	return getTarget(ctx, logger, c, rawTarget)
+}
+
+func withGetTimeout(ctx context.Context, c getConfig) (context.Context, context.CancelFunc) {
+	if c.timeOut > 0 {
+		return context.WithTimeout(ctx, time.Duration(c.timeOut)*time.Minute)
+	}
+	return ctx, func() {}
+}
+
+// prepareModDir ensures the module directory exists. In dry run, missing module directory is not created, and the returned
+// config resolves in scratch directory instead, removed by the returned cleanup.
+func prepareModDir(logger logging.Logger, c getConfig) (_ getConfig, cleanup func(), _ error) {
+	cleanup = func() {}
+	if !c.dryRun {
+		if err := bingo.EnsureModDir(logger, c.relModDir); err != nil {
+			return c, cleanup, errors.Wrap(err, "ensure mod dir")
+		}
+		return c, cleanup, nil
+	}
+	if _, err := os.Stat(c.modDir); !os.IsNotExist(err) {
+		return c, cleanup, nil
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "%s would be created\n", c.relModDir)
+
+	// Resolve in scratch directory instead.
+	tmpDir, err := os.MkdirTemp("", "bingo-dry-run")
+	if err != nil {
+		return c, cleanup, err
+	}
+	cleanup = func() { _ = os.RemoveAll(tmpDir) }
+	if err := bingo.EnsureModDir(logger, tmpDir); err != nil {
+		cleanup()
+		return c, func() {}, errors.Wrap(err, "ensure scratch mod dir")
+	}
+	c.modDir = tmpDir
+	return c, cleanup, nil
+}
+
+// getMany performs get for each of the given targets (package or tool name, optionally with versions), getting up to
+// c.parallel of them concurrently, and logs result of each. Without keepGoing, nothing is done if any target is invalid,
+// and targets not started yet are skipped after the first failure.
+func getMany(ctx context.Context, logger logging.Logger, c getConfig, rawTargets []string, keepGoing bool) error {
+	if c.name != "" {
+		return errors.New("name cannot by specified for more than one target")
+	}
+	if c.rename != "" {
+		return errors.New("rename cannot by specified for more than one target")
+	}
+	if c.comment != "" {
+		return errors.New("comment cannot by specified for more than one target")
+	}
+
+	type getJob struct {
+		rawTarget string
+		err       error
+		skipped   bool
+	}
+	jobs := make([]getJob, len(rawTargets))
+	// Each tool works on its own tmp mod files, so the same tool can't be got twice concurrently.
+	requested := map[string]string{}
+	invalid := merrors.New()
+	for j, rawTarget := range rawTargets {
+		jobs[j].rawTarget = rawTarget
+		if bingo.IsNamePattern(rawTarget) {
+			jobs[j].err = errors.Newf("tool name pattern %v cannot be combined with other targets", rawTarget)
+		} else if names, err := targetNames(c, rawTarget); err != nil {
+			jobs[j].err = err
+		} else {
+			for _, name := range names {
+				if other, ok := requested[name]; ok {
+					jobs[j].err = errors.Newf("%v and %v reference the same tool %v", other, rawTarget, name)
+					break
+				}
+			}
+			if jobs[j].err == nil {
+				for _, name := range names {
+					requested[name] = rawTarget
+				}
+			}
+		}
+		invalid.Add(jobs[j].err)
+	}
+	if invalid.Err() != nil && !keepGoing {
+		return invalid.Err()
+	}
+
+	// Cleanup all bingo modules' tmp files for fresh start.
+	if err := cleanGoGetTmpFiles(c.modDir); err != nil {
+		return err
+	}
+	c, cleanup, err := prepareModDir(logger, c)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	parallel := c.parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		failedMtx sync.Mutex
+		failed    bool
+	)
+	sem := make(chan struct{}, parallel)
+	wg := sync.WaitGroup{}
+	for j := range jobs {
+		if jobs[j].err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(job *getJob) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			failedMtx.Lock()
+			job.skipped = failed && !keepGoing
+			failedMtx.Unlock()
+			if job.skipped {
+				return
+			}
+
+			// Each target is got separately with its own timeout.
+			ctx, cancel := withGetTimeout(ctx, c)
+			defer cancel()
+			if err := getTarget(ctx, logger, c, job.rawTarget); err != nil {
+				job.err = errors.Wrapf(err, "getting %s", job.rawTarget)
+				failedMtx.Lock()
+				failed = true
+				failedMtx.Unlock()
+			}
+		}(&jobs[j])
+	}
+	wg.Wait()
+
+	// Summary in the same order as targets were given.
+	merr := merrors.New()
+	logger.Infof("Results:\n")
+	for _, job := range jobs {
+		status := "ok"
+		switch {
+		case job.skipped:
+			status = "skipped"
+		case job.err != nil:
+			status = "FAILED"
+		}
+		logger.Infof("  %s: %s\n", job.rawTarget, status)
+		merr.Add(job.err)
+	}
+	return merr.Err()
+}
+
+// targetNames returns names of tools the raw target gets.
+func targetNames(c getConfig, rawTarget string) ([]string, error) {
+	name, pkgPath, versions, err := parseTarget(rawTarget)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %v", rawTarget)
+	}
+	if !c.sideBySide {
+		return []string{name}, nil
+	}
+	return sideBySideNames(name, pkgPath, versions)
+}
+
+// sideBySideNames returns names of side by side variants of the tool for the given versions, in the same order.
+func sideBySideNames(name, pkgPath string, versions []string) ([]string, error) {
+	if pkgPath == "" {
+		return nil, errors.Newf("--side-by-side requires package path, got tool name %v; pinned side by side variants are got by their names, e.g. %v-v2", name, name)
+	}
+	names := make([]string, 0, len(versions))
+	for i, v := range versions {
+		if v == "none" {
+			return nil, errors.Newf("--side-by-side cannot be used with @none; remove side by side variant by its name, e.g. %v-v2@none", name)
+		}
+		n, err := bingo.SideBySideName(name, pkgPath, v)
+		if err != nil {
+			return nil, err
+		}
+		for j, other := range names {
+			if other == n {
+				return nil, errors.Newf("versions %v and %v would be both pinned as %v; side by side versions have to differ in major version. "+
+					"Pin them without --side-by-side instead", versions[j], versions[i], n)
+			}
+		}
+		names = append(names, n)
+	}
+	return names, nil
+}
+
+// getSideBySide gets each version of the package as a separate tool named after its major version (see
+// bingo.SideBySideName), so e.g. v1 and v2 of the tool are installed and exposed in helpers side by side.
+func getSideBySide(ctx context.Context, logger logging.Logger, c getConfig, name, pkgPath string, versions []string) error {
+	names, err := sideBySideNames(name, pkgPath, versions)
+	if err != nil {
+		return err
+	}
+	c.sideBySide = false
+	for i, v := range versions {
+		c.name = names[i]
+		rawTarget := pkgPath
+		if v != "" {
+			rawTarget += "@" + v
+		}
+		if err := getTarget(ctx, logger, c, rawTarget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// getTarget performs get of a single target package or tool name, optionally with versions.
+func getTarget(ctx context.Context, logger logging.Logger, c getConfig, rawTarget string) (err error) {
 	// NOTE: pkgPath can be empty. This means that tool was referenced by name.
 	name, pkgPath, versions, err := parseTarget(rawTarget)
 	if err != nil {
 		return errors.Wrapf(err, "parse %v", rawTarget)
 	}
+	if c.sideBySide {
+		return getSideBySide(ctx, logger, c, name, pkgPath, versions)
+	}
 
 	if c.rename != "" {
 		// Treat rename specially.
@@ -205,7 +744,10 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 			if mf.DirectPackage() == nil {
 				return errors.Wrapf(err, "failed to rename tool %v to %v name; found empty mod file %v; Use full path to install tool again", name, c.rename, e)
 			}
-			targets = append(targets, *mf.DirectPackage())
+			t := *mf.DirectPackage()
+			// Binary follows the new tool name.
+			t.Name = ""
+			targets = append(targets, t)
 		}
 
 		for i, t := range targets {
@@ -215,7 +757,7 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 		}
 
 		// Remove old mod files.
-		return removeAllGlob(filepath.Join(c.modDir, name+".*"))
+		return removeAllGlobOrPlan(c.dryRun, filepath.Join(c.modDir, name+".*"))
 	}
 
 	targetName := name
@@ -231,6 +773,19 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 		return errors.Wrapf(err, "existing mod files for %v", targetName)
 	}
 
+	if c.update {
+		if len(versions) > 1 || versions[0] == "none" {
+			return errors.Newf("--update takes at most one version constraint, got %v", versions)
+		}
+		if len(existing) > 1 {
+			return errors.Newf("tool %v has multiple versions pinned %v; --update works only with tools pinned to a single version", targetName, existing)
+		}
+	}
+
+	if len(c.replaces) > 0 && (len(versions) > 1 || (versions[0] != "" && versions[0] != "latest")) {
+		return errors.Newf("version cannot be specified with --replace, tool is built from local directory, got %v", versions)
+	}
+
 	switch versions[0] {
 	case "none":
 		if pkgPath != "" {
@@ -241,7 +796,15 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 		}
 		// None means we no longer want to version this package.
 		// NOTE: We don't remove binaries.
-		return removeAllGlob(filepath.Join(c.modDir, name+".*"))
+		if c.diffs != nil {
+			for _, e := range existing {
+				if err := c.diffs.addRemoved(c.relModDir, e); err != nil {
+					return errors.Wrap(err, "diff")
+				}
+			}
+			return nil
+		}
+		return removeAllGlobOrPlan(c.dryRun, filepath.Join(c.modDir, name+".*"))
 	case "":
 		if len(existing) > 1 {
 			// Edge case. If no version is specified requested, allow to pull all array versions at once.
@@ -252,7 +815,7 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 	targets := make([]bingo.Package, 0, len(versions))
 	pathWasSpecified := pkgPath != ""
 	for i, v := range versions {
-		target := bingo.Package{Module: module.Version{Version: v}, RelPath: pkgPath} // "Unknown" module mode.
+		target := bingo.Package{Module: module.Version{Version: v}, RelPath: pkgPath, Name: c.name} // "Unknown" module mode.
 		if len(existing) > i {
 			e := existing[i]
 
@@ -264,6 +827,11 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 
 			if mf.DirectPackage() != nil {
 				if target.Path() != "" && target.Path() != mf.DirectPackage().Path() {
+					if pathWasSpecified && strings.EqualFold(target.Path(), mf.DirectPackage().Path()) {
+						return errors.Newf("package path %q differs only in case from %q pinned in %v; module paths are case-sensitive, "+
+							"so it would be a different module. Use %v to get the pinned tool or `-n` flag to pin the other one under different name",
+							target.Path(), mf.DirectPackage().Path(), e, mf.DirectPackage().Path())
+					}
 					if pathWasSpecified {
 						return errors.Newf("found mod file %v that has different package path %q than given %q"+
 							"Uninstall existing tool using `%v@none` or use `-n` flag to choose different name", e, mf.DirectPackage().Path(), target.Path(), targetName)
@@ -274,10 +842,27 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 
 				target.Module.Path = mf.DirectPackage().Module.Path
 				if target.Module.Version == "" {
-					// If no version is requested, use the existing version.
-					target.Module.Version = mf.DirectPackage().Module.Version
+					switch {
+					case !c.update:
+						// If no version is requested, use the existing version.
+						target.Module.Version = mf.DirectPackage().Module.Version
+						target.Branch = mf.DirectPackage().Branch
+						target.Tag = mf.DirectPackage().Tag
+						target.Latest = mf.DirectPackage().Latest
+					case mf.DirectPackage().Latest:
+						// Update of the tool pinned at latest version resolves the latest module query again.
+						target.Module.Version = "latest"
+					case mf.DirectPackage().Branch != "":
+						// Update of the tool pinned from branch resolves the current tip of the branch.
+						target.Module.Version = mf.DirectPackage().Branch
+					case mf.DirectPackage().Tag != "":
+						// Update of the tool pinned from non-semver tag resolves the tag again, in case it was moved.
+						target.Module.Version = mf.DirectPackage().Tag
+					}
 				}
 				target.RelPath = mf.DirectPackage().RelPath
+				// Needed for module fetch settings (e.g. GOPROXY) during resolution.
+				target.BuildEnvs = mf.DirectPackage().BuildEnvs
 
 				// Save for future versions without potentially existing files.
 				pkgPath = target.Path()
@@ -306,7 +891,14 @@ func get(ctx context.Context, logger *log.Logger, c getConfig, rawTarget string)
 	for _, f := range existingTargetModArrFiles {
 		i, perr := strconv.ParseInt(strings.Split(filepath.Base(f), ".")[1], 10, 64)
 		if perr != nil || int(i) >= len(versions) {
-			if rerr := os.RemoveAll(f); rerr != nil {
+			if c.diffs != nil {
+				if derr := c.diffs.addRemoved(c.relModDir, f); derr != nil {
+					err = derr
+					return
+				}
+				continue
+			}
+			if rerr := removeAllGlobOrPlan(c.dryRun, f); rerr != nil {
 				err = rerr
 				return
 			}
@@ -327,24 +919,55 @@ func validateNewName(versions []string, old, new string) error {
 
 func cleanGoGetTmpFiles(modDir string) error {
 	// Remove all tmp files
-	if err := removeAllGlob(filepath.Join(modDir, "*.*.tmp.*")); err != nil {
+	if err := removeTmpFiles(filepath.Join(modDir, "*.*.tmp.*")); err != nil {
 		return err
 	}
-	return removeAllGlob(filepath.Join(modDir, "*.tmp.*"))
+	return removeTmpFiles(filepath.Join(modDir, "*.tmp.*"))
 }
 
-func validateTargetName(targetName string) error {
-	if targetName == "cmd" {
-		return errors.Newf("package would be installed with ambiguous name %s. This is a common, but slightly annoying package layout"+
-			"It's advised to choose unique name with -n flag", targetName)
+// removeTmpFiles removes all tmp files matching glob, except files of tmp module files locked by other processes, since
+// those are used by concurrent bingo get. Files are removed under the lock of their module file.
+func removeTmpFiles(glob string) error {
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		return err
+	}
+	var (
+		owners       []string
+		filesByOwner = map[string][]string{}
+	)
+	for _, f := range files {
+		if mod.IsLockFile(f) {
+			continue
+		}
+		owner := strings.TrimSuffix(f, filepath.Ext(f)) + ".mod"
+		if _, ok := filesByOwner[owner]; !ok {
+			owners = append(owners, owner)
+		}
+		filesByOwner[owner] = append(filesByOwner[owner], f)
 	}
-	if targetName == strings.TrimSuffix(bingo.FakeRootModFileName, ".mod") {
-		return errors.Newf("requested binary with name %q`. This is impossible, choose different name using -n flag", strings.TrimSuffix(bingo.FakeRootModFileName, ".mod"))
+	for _, owner := range owners {
+		l, err := mod.Lock(owner, 0)
+		if err != nil {
+			if errors.Is(err, mod.ErrLocked) {
+				continue
+			}
+			return err
+		}
+		for _, f := range filesByOwner[owner] {
+			if err := os.RemoveAll(f); err != nil {
+				errcapture.Do(&err, l.Unlock, "unlock")
+				return err
+			}
+		}
+		if err := l.Unlock(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func resolvePackage(logger *log.Logger, verbose bool, tmpModFile string, runnable runner.Runnable, target *bingo.Package) (err error) {
+func resolvePackage(logger logging.Logger, tmpModFile string, runnable runner.Runnable, target *bingo.Package) (err error) {
 	// Do initial go get -d and remember output.
 	// NOTE: We have to use get -d to resolve version and tell us what is the module and what package.
 	// If go get will not succeed, or will not update go mod, we will try manual lookup.
@@ -395,12 +1018,247 @@ func resolvePackage(logger *log.Logger, verbose bool, tmpModFile string, runnabl
 
 	// We fallback only if go-get failed which happens when it does not know what version to choose.
 	// In this case
-	if err := resolveInGoModCache(logger, verbose, cacheModPath, target); err != nil {
+	if err := resolveInGoModCache(logger, cacheModPath, target); err != nil {
+		var goErr *runner.GoError
+		if errors.As(gerr, &goErr) && goErr.Kind != nil {
+			// Keep recognized go get failure in the chain, so it's reported with a hint (see withGoErrorHint).
+			return errors.Wrapf(gerr, "fallback to local go mod cache resolution failed (%v) after go get failure", err)
+		}
 		return errors.Wrapf(err, "fallback to local go mod cache resolution failed after go get failure: %v", gerr)
 	}
 	return nil
 }
 
+// resolveUpdateVersion sets target version to the latest version of its module, matching the target version treated as constraint.
+func resolveUpdateVersion(logger logging.Logger, runnable runner.Runnable, target *bingo.Package, allowPrerelease bool) error {
+	constraint := target.Module.Version
+
+	modPath, versions, err := listModuleVersions(runnable, *target)
+	if err != nil {
+		return err
+	}
+	v, err := selectUpdateVersion(versions, constraint, allowPrerelease)
+	if err != nil {
+		return errors.Wrapf(err, "module %v", modPath)
+	}
+	logger.Debugf("latest version of %v matching %q is %v\n", modPath, constraint, v)
+	setModuleVersion(target, modPath, v)
+	return nil
+}
+
+// resolvePickedVersion sets target version to the one picked from released versions of its module.
+func resolvePickedVersion(logger logging.Logger, runnable runner.Runnable, target *bingo.Package, pick versionPicker) error {
+	modPath, versions, err := listModuleVersions(runnable, *target)
+	if err != nil {
+		return err
+	}
+	v, err := pick(modPath, versions)
+	if err != nil {
+		return errors.Wrapf(err, "module %v", modPath)
+	}
+	logger.Debugf("picked version %v of %v\n", v, modPath)
+	setModuleVersion(target, modPath, v)
+	return nil
+}
+
+// candidateModulePaths returns the target module path or, if it's not known, prefixes of the target package path from the
+// longest, as module paths the package might be in.
+func candidateModulePaths(target bingo.Package) []string {
+	if target.Module.Path != "" {
+		return []string{target.Module.Path}
+	}
+	var ret []string
+	for p := target.Path(); p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// listModuleVersions returns released versions of the target module. If module path is not known, the longest prefix of
+// the package path that is a module with released versions is used.
+func listModuleVersions(runnable runner.Runnable, target bingo.Package) (modPath string, versions []string, _ error) {
+	merr := merrors.New()
+	for _, modPath := range candidateModulePaths(target) {
+		versions, err := runnable.ModVersions(modPath)
+		if err != nil {
+			merr.Add(err)
+			continue
+		}
+		if len(versions) == 0 {
+			merr.Add(errors.Newf("module %v has no released versions", modPath))
+			continue
+		}
+		return modPath, versions, nil
+	}
+	return "", nil, errors.Wrapf(merr.Err(), "list versions of %v", target.Path())
+}
+
+// setModuleVersion sets target module version and, if module path was not known, module path and package path within it.
+func setModuleVersion(target *bingo.Package, modPath, version string) {
+	if target.Module.Path == "" {
+		target.RelPath = strings.TrimPrefix(strings.TrimPrefix(target.RelPath, modPath), "/")
+		target.Module.Path = modPath
+	}
+	target.Module.Version = version
+}
+
+var commitSHARegexp = regexp.MustCompile("^[0-9a-f]{7,40}$")
+
+// isCommitSHA returns true if version looks like (possibly short) git commit SHA, e.g. "abc1234".
+func isCommitSHA(version string) bool {
+	return commitSHARegexp.MatchString(version)
+}
+
+// resolveCommitVersion sets target version given as commit SHA to canonical pseudo-version (v0.0.0-<date>-<12 chars of SHA>).
+// If module path is not known, the longest prefix of the package path that is a module containing the commit is used.
+// If direct is not nil, the commit is resolved with it too (expected to fetch from VCS directly) and a warning is logged
+// if it gives different pseudo-version (e.g. proxy and VCS disagree on commit time). Version resolved with runnable is used anyway.
+func resolveCommitVersion(logger logging.Logger, runnable runner.Runnable, direct runner.Runnable, target *bingo.Package) error {
+	sha := target.Module.Version
+
+	merr := merrors.New()
+	for _, modPath := range candidateModulePaths(*target) {
+		v, err := runnable.ModQuery(modPath, sha)
+		if err != nil {
+			if isAmbiguousRevisionErr(err) {
+				return errors.Newf("commit %v is ambiguous in %v; use more characters of the SHA or the full one", sha, modPath)
+			}
+			merr.Add(err)
+			continue
+		}
+		logger.Debugf("commit %v of %v resolved to %v\n", sha, modPath, v)
+		if direct != nil {
+			if dv, err := direct.ModQuery(modPath, sha); err != nil {
+				logger.Debugf("cannot resolve commit %v of %v directly from VCS to cross-check: %v\n", sha, modPath, err)
+			} else if dv != v {
+				logger.Warnf("commit %v of %v resolves to %v via module proxy, but to %v directly from VCS; "+
+					"pinning the proxy one, so all machines resolve the same version\n", sha, modPath, v, dv)
+			}
+		}
+		if target.Module.Path == "" {
+			target.RelPath = strings.TrimPrefix(strings.TrimPrefix(target.RelPath, modPath), "/")
+			target.Module.Path = modPath
+		}
+		target.Module.Version = v
+		return nil
+	}
+	return errors.Wrapf(merr.Err(), "commit %v of %v not found; make sure it's pushed and reachable from a branch or tag of the module repository", sha, target.Path())
+}
+
+// defaultModuleProxy is used to resolve commits if GOPROXY has no proxy, e.g. GOPROXY=direct.
+const defaultModuleProxy = "https://proxy.golang.org"
+
+// moduleProxies returns GOPROXY value with proxies only (without "direct" and "off"), so go resolves versions through
+// them, or defaultModuleProxy if there are none. GOPROXY=off is kept, since network access is disabled then. Modules
+// matching GONOPROXY (or GOPRIVATE) are still fetched directly by go.
+func moduleProxies(goproxy string) string {
+	proxies, _, off := parseGOPROXY(goproxy)
+	switch {
+	case len(proxies) > 0:
+		return strings.Join(proxies, ",")
+	case off:
+		return "off"
+	}
+	return defaultModuleProxy
+}
+
+// isBranchQuery returns true if version is neither semantic version, commit SHA nor module query (e.g. "latest" or
+// "<v1.2"), so it's a branch or non-semver tag (e.g. "release-2024-01") to resolve the commit of.
+func isBranchQuery(version string) bool {
+	if version == "" || module.CanonicalVersion(version) != "" || isCommitSHA(version) || strings.ContainsAny(version[:1], "<>=") {
+		return false
+	}
+	switch version {
+	case "latest", "upgrade", "patch", "none":
+		return false
+	}
+	return bingo.ValidateBranchName(version) == nil
+}
+
+// resolveRefVersion sets target version given as branch or non-semver tag to pseudo-version of the commit it points to
+// and records the branch or tag, so the next update re-resolves it. Whether it's a tag is told by the reference go
+// resolved it from; if go or proxy does not report it, a branch is assumed. If module path is not known, the longest
+// prefix of the package path that is a module containing the reference is used.
+func resolveRefVersion(logger logging.Logger, runnable runner.Runnable, target *bingo.Package) error {
+	ref := target.Module.Version
+
+	merr := merrors.New()
+	for _, modPath := range candidateModulePaths(*target) {
+		v, fullRef, err := runnable.ModQueryRef(modPath, ref)
+		if err != nil {
+			if errors.Is(err, runner.ErrNetwork) {
+				// Don't report network failure as missing branch or tag.
+				return errors.Wrapf(err, "resolve %v of %v", ref, target.Path())
+			}
+			merr.Add(err)
+			continue
+		}
+		if target.Module.Path == "" {
+			target.RelPath = strings.TrimPrefix(strings.TrimPrefix(target.RelPath, modPath), "/")
+			target.Module.Path = modPath
+		}
+		target.Module.Version = v
+		target.Branch, target.Tag = "", ""
+		if fullRef == "refs/tags/"+ref {
+			logger.Debugf("tag %v of %v resolved to %v\n", ref, modPath, v)
+			target.Tag = ref
+			return nil
+		}
+		logger.Debugf("branch %v of %v resolved to %v\n", ref, modPath, v)
+		target.Branch = ref
+		return nil
+	}
+	return errors.Wrapf(merr.Err(), "branch or tag %v of %v not found", ref, target.Path())
+}
+
+func isAmbiguousRevisionErr(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "ambiguous")
+}
+
+// selectUpdateVersion returns the highest of given versions that matches constraint (empty or "latest" matches all).
+// Pre-release versions are skipped unless allowPrerelease is true.
+func selectUpdateVersion(versions []string, constraint string, allowPrerelease bool) (string, error) {
+	var c *semver.Constraints
+	if constraint != "" && constraint != "latest" {
+		var err error
+		c, err = semver.NewConstraint(constraint)
+		if err != nil {
+			return "", errors.Wrapf(err, "parse version constraint %q", constraint)
+		}
+	}
+
+	var (
+		best    *semver.Version
+		bestRaw string
+	)
+	for _, v := range versions {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			continue
+		}
+		if c != nil {
+			// Constraints never match pre-releases, so check their release counterpart instead.
+			release, err := sv.SetPrerelease("")
+			if err != nil {
+				return "", err
+			}
+			if !c.Check(&release) {
+				continue
+			}
+		}
+		if sv.Prerelease() != "" && !allowPrerelease {
+			continue
+		}
+		if best == nil || sv.GreaterThan(best) {
+			best, bestRaw = sv, v
+		}
+	}
+	if best == nil {
+		return "", errors.Newf("no version matching %q found in %v", constraint, versions)
+	}
+	return bestRaw, nil
+}
+
 func gomodcache(runnable runner.Runnable) (string, error) {
 	cachepath, err := runnable.GoEnv("GOMODCACHE")
 	if err != nil {
@@ -464,7 +1322,7 @@ func encodePath(path string) string {
 }
 
 // resolveInGoModCache will try to find a referenced module in the Go modules cache.
-func resolveInGoModCache(logger *log.Logger, verbose bool, cacheModPath string, target *bingo.Package) error {
+func resolveInGoModCache(logger logging.Logger, cacheModPath string, target *bingo.Package) error {
 	modMetaCache := filepath.Join(cacheModPath, "cache/download")
 	modulePath := target.Path()
 	// Case sensitivity problem is fixed by replacing upper case with '/!<lower case letter>` signature.
@@ -482,15 +1340,11 @@ func resolveInGoModCache(logger *log.Logger, verbose bool, cacheModPath string,
 			if !os.IsNotExist(err) {
 				return err
 			}
-			if verbose {
-				logger.Println("resolveInGoModCache:", modMetaDir, "directory does not exists")
-			}
+			logger.Debugf("resolveInGoModCache: %v directory does not exists\n", modMetaDir)
 			continue
 
 		}
-		if verbose {
-			logger.Println("resolveInGoModCache: Found", modMetaDir, "directory")
-		}
+		logger.Debugf("resolveInGoModCache: Found %v directory\n", modMetaDir)
 
 		// There are 2 major cases:
 		// 1. We have @latest or version is not pinned: find latest module having this package.
@@ -514,10 +1368,7 @@ func resolveInGoModCache(logger *log.Logger, verbose bool, cacheModPath string,
 					return err
 				}
 
-				if verbose {
-					logger.Println("resolveInGoModCache:", filepath.Join(modMetaDir, target.Module.Version+".info"),
-						"file not exists. Looking for +incompatible info file")
-				}
+				logger.Debugf("resolveInGoModCache: %v file not exists. Looking for +incompatible info file\n", filepath.Join(modMetaDir, target.Module.Version+".info"))
 
 				// Try +incompatible.
 				if _, err := os.Stat(filepath.Join(modMetaDir, target.Module.Version+"+incompatible.info")); err != nil {
@@ -525,10 +1376,7 @@ func resolveInGoModCache(logger *log.Logger, verbose bool, cacheModPath string,
 						return err
 					}
 
-					if verbose {
-						logger.Println("resolveInGoModCache:", filepath.Join(modMetaDir, target.Module.Version+"+incompatible.info"),
-							"file not exists. Looking for different module")
-					}
+					logger.Debugf("resolveInGoModCache: %v file not exists. Looking for different module\n", filepath.Join(modMetaDir, target.Module.Version+"+incompatible.info"))
 					continue
 				}
 				target.Module.Version += "+incompatible"
@@ -556,17 +1404,33 @@ func resolveInGoModCache(logger *log.Logger, verbose bool, cacheModPath string,
 			}
 		}
 
-		if verbose {
-			ver := target.Module.Version
-			if len(ver) > 12 {
-				ver = ver[:12]
-			}
-			logger.Println("resolveInGoModCache: .info file for sha", ver, "does not exists. Looking for different module")
+		ver := target.Module.Version
+		if len(ver) > 12 {
+			ver = ver[:12]
 		}
+		logger.Debugf("resolveInGoModCache: .info file for sha %v does not exists. Looking for different module\n", ver)
 	}
 	return errors.Newf("no module was cached matching given package %v", target.Path())
 }
 
+// checkVersionConstraints returns error if resolved module version is denied by bingo.ConstraintsFile in the mod
+// directory. With ignoreConstraints it only logs a warning.
+func checkVersionConstraints(logger logging.Logger, c installPackageConfig, m module.Version) error {
+	cs, err := bingo.VersionConstraints(c.modDir)
+	if err != nil {
+		return err
+	}
+	denied, ok := bingo.DeniedBy(cs, m)
+	if !ok {
+		return nil
+	}
+	if c.ignoreConstraints {
+		logger.Warnf("%v is denied by %v; pinning anyway as requested by --ignore-constraints\n", m, denied)
+		return nil
+	}
+	return errors.Newf("%v is denied by %v; pin different version or use --ignore-constraints to pin it anyway", m, denied)
+}
+
 // getPackage takes package array index, tool name and package path (also module path and version which are optional) and
 // generates new module with the given package's module as the only dependency (direct require statement).
 // For generation purposes we take the existing <name>.mod file (if exists, if paths matches). This allows:
@@ -577,10 +1441,8 @@ func resolveInGoModCache(logger *log.Logger, verbose bool, cacheModPath string,
 // As resolution of module vs package for Go Module is convoluted and all code is under internal dir, we have to rely on `go` binary
 // capabilities and output.
 // TODO(bwplotka): Consider copying code for it? Of course it's would be easier if such tool would exist in Go project itself (:
-func getPackage(ctx context.Context, logger *log.Logger, c installPackageConfig, i int, name string, target bingo.Package) (err error) {
-	if c.verbose {
-		logger.Println("getting target", target.String(), "(module", target.Module.Path, ")")
-	}
+func getPackage(ctx context.Context, logger logging.Logger, c installPackageConfig, i int, name string, target bingo.Package) (err error) {
+	logger.Debugf("getting target %v (module %v)\n", target.String(), target.Module.Path)
 
 	// The out module file we generate/maintain keep in modDir.
 	outModFile := filepath.Join(c.modDir, name+".mod")
@@ -595,9 +1457,20 @@ func getPackage(ctx context.Context, logger *log.Logger, c installPackageConfig,
 
 	outSumFile := strings.TrimSuffix(outModFile, ".mod") + ".sum"
 
+	if r, ok := matchLocalReplace(c.replaces, target); ok {
+		// Local code has no released version, so there is nothing to resolve.
+		target.RelPath = strings.TrimPrefix(strings.TrimPrefix(target.Path(), r.modulePath), "/")
+		target.Module = module.Version{Path: r.modulePath, Version: bingo.LocalReplaceVersion}
+	}
+
+	if target.Module.Version == "latest" {
+		// Go always treats "latest" as module query, never as a tag or branch named so, so record it was requested.
+		target.Latest = true
+	}
+
 	// If we don't have all information, resolve version.
 	var fetchedDirectives nonRequireDirectives
-	if target.Module.Version == "" || !strings.HasPrefix(target.Module.Version, "v") || target.Module.Path == "" {
+	if c.update || target.Module.Version == "" || !strings.HasPrefix(target.Module.Version, "v") || isBranchQuery(target.Module.Version) || target.Module.Path == "" {
 		// Set up totally empty mod file to get clear version to install.
 		tmpEmptyModFile, err := bingo.CreateFromExistingOrNew(ctx, c.runner, logger, "", tmpEmptyModFilePath)
 		if err != nil {
@@ -606,8 +1479,52 @@ func getPackage(ctx context.Context, logger *log.Logger, c installPackageConfig,
 
 		defer errcapture.Do(&err, tmpEmptyModFile.Close, "close")
 
-		runnable := c.runner.With(ctx, tmpEmptyModFile.Filepath(), c.modDir, nil)
-		if err := resolvePackage(logger, c.verbose, tmpEmptyModFile.Filepath(), runnable, &target); err != nil {
+		fetchEnvs := target.ModuleFetchEnvs()
+		// Empty mod file has no directives, so take it from the existing one.
+		if noSumDB, err := bingo.IsSumDBDisabledInFile(outModFile); err != nil {
+			return errors.Wrapf(err, "read %v", outModFile)
+		} else if noSumDB && target.Module.Path != "" {
+			fetchEnvs = bingo.SumDBDisabledEnvs(target.Module.Path, fetchEnvs)
+		}
+		if fetchEnvs, err = bingo.PrivateModuleEnvs(target.Path(), os.Getenv(bingo.PrivateModulesEnv), fetchEnvs); err != nil {
+			return err
+		}
+		runnable := c.runner.With(ctx, tmpEmptyModFile.Filepath(), c.modDir, fetchEnvs)
+		// Version requested as latest is resolved by go (see resolvePackage), which falls back to the pseudo-version of the
+		// default branch if the module has no releases.
+		if c.update && !isBranchQuery(target.Module.Version) && !target.Latest {
+			if err := resolveUpdateVersion(logger, runnable, &target, c.allowPrerelease); err != nil {
+				return errors.Wrap(err, "resolve update")
+			}
+		}
+		if c.pickVersion != nil && target.Module.Version == "" {
+			if err := resolvePickedVersion(logger, runnable, &target, c.pickVersion); err != nil {
+				return errors.Wrap(err, "pick version")
+			}
+		}
+		if isBranchQuery(target.Module.Version) {
+			if err := resolveRefVersion(logger, runnable, &target); err != nil {
+				return errors.Wrap(err, "resolve branch or tag")
+			}
+		}
+		if isCommitSHA(target.Module.Version) {
+			commitRunnable, direct := runnable, runner.Runnable(nil)
+			if c.commitViaProxy {
+				// Proxy and VCS can disagree on commit time, so always resolve pseudo-versions through the proxy to get the same one everywhere.
+				goproxy, err := runnable.GoEnv("GOPROXY")
+				if err != nil {
+					return errors.Wrap(err, "go env GOPROXY")
+				}
+				if proxies := moduleProxies(goproxy); proxies != "off" {
+					commitRunnable = c.runner.With(ctx, tmpEmptyModFile.Filepath(), c.modDir, envars.MergeEnvSlices(fetchEnvs, "GOPROXY="+proxies))
+					direct = c.runner.With(ctx, tmpEmptyModFile.Filepath(), c.modDir, envars.MergeEnvSlices(fetchEnvs, "GOPROXY=direct"))
+				}
+			}
+			if err := resolveCommitVersion(logger, commitRunnable, direct, &target); err != nil {
+				return errors.Wrap(err, "resolve commit")
+			}
+		}
+		if err := resolvePackage(logger, tmpEmptyModFile.Filepath(), runnable, &target); err != nil {
 			return err
 		}
 
@@ -619,9 +1536,21 @@ func getPackage(ctx context.Context, logger *log.Logger, c installPackageConfig,
 		}
 	}
 
+	if target.Module.Version != bingo.LocalReplaceVersion {
+		if err := checkVersionConstraints(logger, c, target.Module); err != nil {
+			return err
+		}
+	}
+
 	// Now we should have target with all required info, prepare tmp file.
-	// TODO(bwplotka): Is this still needed?
-	if err := cleanGoGetTmpFiles(c.modDir); err != nil {
+	// Remove only our own tmp files, since other tools might be installed concurrently.
+	removeTmpFiles := func() error {
+		if err := removeTmpFiles(strings.TrimSuffix(tmpEmptyModFilePath, ".mod") + ".*"); err != nil {
+			return err
+		}
+		return removeTmpFiles(strings.TrimSuffix(tmpModFilePath, ".mod") + ".*")
+	}
+	if err := removeTmpFiles(); err != nil {
 		return err
 	}
 
@@ -631,41 +1560,163 @@ func getPackage(ctx context.Context, logger *log.Logger, c installPackageConfig,
 	}
 	defer errcapture.Do(&err, tmpModFile.Close, "close")
 
-	if !tmpModFile.IsDirectivesAutoFetchDisabled() && !fetchedDirectives.isEmpty() {
-		if err := tmpModFile.SetReplaceDirectives(fetchedDirectives.replace...); err != nil {
-			return err
+	if err := tmpModFile.Validate(); err != nil {
+		return errors.Wrapf(err, "malformed %v; fix it manually", outModFile)
+	}
+
+	if !tmpModFile.IsDirectivesAutoFetchDisabled() {
+		replaces, changed := tmpModFile.ReplaceDirectives(), false
+		if !fetchedDirectives.isEmpty() {
+			// Local replaces are kept, they take precedence over fetched ones.
+			replaces, changed = withLocalReplaces(fetchedDirectives.replace, tmpModFile.LocalReplaces()), true
 		}
-		if err := tmpModFile.SetExcludeDirectives(fetchedDirectives.exclude...); err != nil {
-			return err
+		if target.Module.Version != bingo.LocalReplaceVersion && tmpModFile.IsLocallyReplaced(target.Module.Path) {
+			logger.Infof("%v: dropping local replace of %v, since version %v was requested\n", filepath.Base(outModFile), target.Module.Path, target.Module.Version)
+			replaces, changed = bingo.WithoutReplace(replaces, target.Module.Path), true
 		}
-		if err := tmpModFile.SetRetractDirectives(fetchedDirectives.retract...); err != nil {
+		if changed {
+			if err := tmpModFile.SetReplaceDirectives(replaces...); err != nil {
+				return err
+			}
+		}
+		// Shared template takes precedence over fetched and existing replaces.
+		tmpl, err := bingo.ReadReplaceTemplate(c.modDir)
+		if err != nil {
 			return err
 		}
+		if err := tmpModFile.SetReplaceTemplate(tmpl); err != nil {
+			return errors.Wrapf(err, "merge %v", bingo.ReplaceTemplateFileName)
+		}
+		if !fetchedDirectives.isEmpty() {
+			if err := tmpModFile.SetExcludeDirectives(fetchedDirectives.exclude...); err != nil {
+				return err
+			}
+			if err := tmpModFile.SetRetractDirectives(fetchedDirectives.retract...); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Explicitly requested local replaces are set even if directives auto fetch is disabled.
+	for _, r := range c.replaces {
+		if err := tmpModFile.SetLocalReplace(r.modulePath, r.dir); err != nil {
+			return errors.Wrapf(err, "set local replace of %v", r.modulePath)
+		}
 	}
 
 	// Currently user can't specify build flags and envvars from CLI, take if from optionally, manually updated mod file.
 	if old := tmpModFile.DirectPackage(); old != nil {
 		target.BuildEnvs = old.BuildEnvs
 		target.BuildFlags = old.BuildFlags
+		target.EnvFile = old.EnvFile
+		target.WorkDir = old.WorkDir
+		target.PostInstall = old.PostInstall
+		target.VerifyCmd = old.VerifyCmd
+		target.VerifyOutput = old.VerifyOutput
+		if target.Name == "" {
+			target.Name = old.Name
+		}
+	}
+	if c.postInstall != "" {
+		target.PostInstall = c.postInstall
+		if c.postInstall == "none" {
+			target.PostInstall = ""
+		}
 	}
 	if err := tmpModFile.SetDirectRequire(target); err != nil {
 		return err
 	}
+	if c.comment != "" {
+		if err := tmpModFile.SetComment(c.comment); err != nil {
+			return err
+		}
+	}
+	if c.pinGo != nil {
+		if err := tmpModFile.SetGoVersionPinned(*c.pinGo); err != nil {
+			return err
+		}
+	}
+	if c.vendor != nil {
+		if err := tmpModFile.SetVendored(*c.vendor); err != nil {
+			return err
+		}
+	}
+	if c.toolchain != "" {
+		toolchain := c.toolchain
+		if toolchain == "none" {
+			toolchain = ""
+		}
+		if err := setToolchain(tmpModFile, toolchain); err != nil {
+			return errors.Wrapf(err, "set toolchain %v", c.toolchain)
+		}
+	}
+
+	if c.dryRun {
+		if c.diffs != nil {
+			if err := c.diffs.add(c.relModDir, outModFile, tmpModFile); err != nil {
+				return errors.Wrap(err, "diff")
+			}
+			return removeTmpFiles()
+		}
+		if c.printCmd {
+			if err := printBuildCommands(ctx, logger, c, name, outModFile, tmpModFile); err != nil {
+				return err
+			}
+			return removeTmpFiles()
+		}
+		if err := printGetPlan(ctx, c, name, outModFile, tmpModFile); err != nil {
+			return err
+		}
+		return removeTmpFiles()
+	}
 
-	if err := install(ctx, logger, c.runner, c.modDir, name, c.link, tmpModFile); err != nil {
-		return errors.Wrap(err, "install")
+	if c.noBuild {
+		if err := bingo.UpdateModFile(ctx, logger, c.runner, c.modDir, name, tmpModFile); err != nil {
+			return keptTempErr(ctx, logger, c, name, tmpModFile, errors.Wrap(err, "update mod file"))
+		}
+	} else if err := bingo.Install(ctx, logger, c.runner, c.modDir, "", name, c.link, c.linkDir, c.rebuild, tmpModFile); err != nil {
+		return keptTempErr(ctx, logger, c, name, tmpModFile, errors.Wrap(err, "install"))
 	}
 
 	// We were working on tmp file, do atomic rename.
-	if err := os.Rename(tmpModFile.Filepath(), outModFile); err != nil {
+	replace := func(src, dst string) error { return os.Rename(src, dst) }
+	if c.modOnlyIfChanged {
+		replace = func(src, dst string) error {
+			_, err := bingo.ReplaceIfChanged(src, dst)
+			return err
+		}
+	}
+	if err := replace(tmpModFile.Filepath(), outModFile); err != nil {
 		return errors.Wrap(err, "rename mod file")
 	}
-	if err := os.Rename(bingo.SumFilePath(tmpModFile.Filepath()), outSumFile); err != nil {
+	if err := replace(bingo.SumFilePath(tmpModFile.Filepath()), outSumFile); err != nil {
 		return errors.Wrap(err, "rename sum file")
 	}
+	if c.noBuild {
+		// Meta file describes installed binaries, which did not change.
+		logger.Infof("%v: pinned %v without building; run bingo get %v to build it\n", filepath.Base(outModFile), target.String(), name)
+		return nil
+	}
+	if err := os.Rename(bingo.MetaFilePath(tmpModFile.Filepath()), bingo.MetaFilePath(outModFile)); err != nil {
+		return errors.Wrap(err, "rename meta file")
+	}
 	return nil
 }
 
+// keptTempErr returns install error with path of the temporary module file, if it's kept for debugging (see --keep-temp).
+// It also logs go build command to reproduce the build with it manually.
+func keptTempErr(ctx context.Context, logger logging.Logger, c installPackageConfig, name string, tmpModFile *bingo.ModFile, err error) error {
+	if !c.keepTemp {
+		return err
+	}
+	if cmds, cerr := bingo.BuildCommands(ctx, logger, c.runner, c.modDir, "", name, "", tmpModFile); cerr == nil {
+		for _, cmd := range cmds {
+			logger.Infof("%v: temporary files are kept; reproduce the build with:\n\t%v\n", name, bingo.ShellCommand(cmd))
+		}
+	}
+	return errors.Wrapf(err, "temporary module file kept at %v", tmpModFile.Filepath())
+}
+
 func localGoModFileAfterGet(gopath string, target bingo.Package) string {
 	modulePath := target.Module.String()
 
@@ -693,11 +1744,21 @@ func (d nonRequireDirectives) isEmpty() bool {
 	return len(d.replace) == 0 && len(d.exclude) == 0 && len(d.retract) == 0
 }
 
+// withLocalReplaces returns replace directives with given local replaces added. Local replaces take precedence over
+// replaces of the same module.
+func withLocalReplaces(replaces []mod.ReplaceDirective, locals []mod.ReplaceDirective) []mod.ReplaceDirective {
+	ret := replaces
+	for _, l := range locals {
+		ret = append(bingo.WithoutReplace(ret, l.Old.Path), l)
+	}
+	return ret
+}
+
 // autoFetchDirectives is returning all non-require directives, that allows bingo to use exactly the same exclude, replace and retract statement
 // as the target module we want to install.
 // It's a very common case where modules mitigate faulty modules or conflicts with replace directives.
 // Since we always download single tool dependency module per tool module, we can copy its non-require statements if exists to fix this common case.
-func autoFetchDirectives(runnable runner.Runnable, logger *log.Logger, target bingo.Package) (d nonRequireDirectives, _ error) {
+func autoFetchDirectives(runnable runner.Runnable, logger logging.Logger, target bingo.Package) (d nonRequireDirectives, _ error) {
 	gopath, err := runnable.GoEnv("GOPATH")
 	if err != nil {
 		return d, errors.Wrap(err, "go env")
@@ -720,169 +1781,129 @@ func autoFetchDirectives(runnable runner.Runnable, logger *log.Logger, target bi
 		return d, errors.Wrapf(err, "parse target mod file %v", targetModFile)
 	}
 
-	if semver.MustParse(targetModParsed.GoVersion()).GreaterThan(runnable.GoVersion()) {
-		logger.Printf("WARNING: Go module you are trying to install requires higher Go version (%v) than you are using (%v). Use newer Go version to install it if you encounter build errors (e.g when generics were used).\n", targetModParsed.GoVersion(), runnable.GoVersion().String())
+	// Missing or unparsable go directive is left for go build to complain about.
+	if targetGoVersion, err := version.Parse(targetModParsed.GoVersion()); err == nil && !version.AtLeast(runnable.GoVersion(), targetGoVersion) {
+		logger.Warnf("Go module you are trying to install requires higher Go version (%v) than you are using (%v). Use newer Go version to install it if you encounter build errors (e.g when generics were used).\n", targetModParsed.GoVersion(), runnable.GoVersion().String())
 	}
 
 	d.replace = targetModParsed.ReplaceDirectives()
 	d.exclude = targetModParsed.ExcludeDirectives()
 	d.retract = targetModParsed.RetractDirectives()
 
-	if len(d.retract) > 0 && runnable.GoVersion().LessThan(version.Go116) {
+	if len(d.retract) > 0 && !version.AtLeast(runnable.GoVersion(), version.Go116) {
 		return d, errors.Newf("target Go module is using new 'retract' directive. Use Go1.16+ to build it")
 	}
 	return d, nil
 }
 
-// gobin mimics the way go install finds where to install go tool.
-func gobin(runnable runner.Runnable) (string, error) {
-	binPath, err := runnable.GoEnv("GOBIN")
+// printGetPlan prints changes that get would make to the mod file and binaries.
+func printGetPlan(ctx context.Context, c installPackageConfig, name string, outModFile string, modFile *bingo.ModFile) error {
+	diff, err := modFile.DiffAgainst(outModFile)
 	if err != nil {
-		return "", errors.Wrap(err, "go env GOBIN")
-	}
-	if binPath != "" {
-		return binPath, nil
+		return errors.Wrap(err, "diff")
 	}
 
-	gpath, err := runnable.GoEnv("GOPATH")
-	if err != nil {
-		return "", errors.Wrap(err, "go env GOPATH")
+	action := "edited"
+	if _, err := os.Stat(outModFile); os.IsNotExist(err) {
+		action = "created"
+	} else if len(diff) == 0 {
+		action = "unchanged"
 	}
-	return filepath.Join(gpath, "bin"), nil
-}
-
-func install(ctx context.Context, logger *log.Logger, r *runner.Runner, modDir string, name string, link bool, modFile *bingo.ModFile) (err error) {
-	pkg := modFile.DirectPackage()
-	if err := validateTargetName(name); err != nil {
-		return errors.Wrap(err, pkg.String())
+	_, _ = fmt.Fprintf(os.Stdout, "%s would be %s\n", filepath.Join(c.relModDir, filepath.Base(outModFile)), action)
+	for _, l := range diff {
+		_, _ = fmt.Fprintln(os.Stdout, "\t"+l)
 	}
 
-	modCtx := r.With(ctx, modFile.Filepath(), modDir, nil)
-
-	// Check if path is pointing to non-buildable package.
-	var listArgs []string
-	listArgs = append(listArgs, modFile.DirectPackage().BuildFlags...)
-	listArgs = append(listArgs, "-mod=mod", "-f={{.Name}}", pkg.Path())
-	if listOutput, err := modCtx.List(listArgs...); err != nil {
-		return errors.Wrap(err, "list")
-	} else if !strings.HasSuffix(listOutput, "main") {
-		return errors.Newf("package %s is non-main (go list output %q), nothing to get and build", pkg.Path(), listOutput)
+	for _, r := range modFile.RequireDirectives() {
+		if r.Indirect {
+			continue
+		}
+		line := "require " + r.Module.Path + " " + r.Module.Version
+		if r.ExtraSuffixComment != "" {
+			line += " // " + r.ExtraSuffixComment
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "resulting %s\n", line)
+		break
 	}
 
-	// Use go get -d to recreate .sum file
-	// TODO(bwplotka): Do it only if not present or if we update mod to new version?
-	if out, err := modCtx.GetD(modFile.DirectPackage().String()); err != nil {
-		return errors.Wrap(err, out)
+	pkgs := modFile.DirectPackages()
+	names, err := bingo.BinaryNames(name, pkgs)
+	if err != nil {
+		return err
 	}
-
-	gobin, err := gobin(modCtx)
+	gobin, err := bingo.BinDir(c.runner.With(ctx, modFile.Filepath(), c.modDir, nil), c.modDir)
 	if err != nil {
 		return errors.Wrap(err, "deduct GOBIN")
 	}
-
-	// go install does not define -modfile flag, so we mimic go install with go build -o instead.
-	binPath := filepath.Join(gobin, fmt.Sprintf("%s-%s", name, pkg.Module.Version))
-
-	// New context with new environment files.
-	modCtx = r.With(ctx, modFile.Filepath(), modDir, pkg.BuildEnvs)
-	if err := modCtx.Build(pkg.Path(), binPath, pkg.BuildFlags...); err != nil {
-		if strings.Contains(err.Error(), "module declares its path as: ") &&
-			strings.Contains(err.Error(), fmt.Sprintf("but was required as: %v", modFile.DirectPackage().Path())) {
-
-			// TODO(bwplotka): Add native mode for forks.
-			logger.Println("The", modFile.DirectPackage().Path(), "module is a potential fork, since go.mod has mismatching module."+
-				" Building forks is not supported yet. See https://github.com/bwplotka/bingo/issues/110.")
+	for i, pkg := range pkgs {
+		pkg.Name = names[i]
+		binPath := bingo.BinaryPath(pkg, gobin)
+		if modFile.IsLocallyReplaced(pkg.Module.Path) {
+			_, _ = fmt.Fprintf(os.Stdout, "%s would be built from local replace\n", binPath)
+			continue
 		}
-		return errors.Wrap(err, "build versioned")
-	}
-
-	if !link {
-		return nil
+		if c.rebuild != bingo.RebuildIfChanged {
+			_, _ = fmt.Fprintf(os.Stdout, "%s would be rebuilt (forced)\n", binPath)
+			continue
+		}
+		key, err := bingo.BinChecksumKeyFor(c.runner.With(ctx, modFile.Filepath(), c.modDir, pkg.BuildEnvs), names[i], pkg)
+		if err != nil {
+			return err
+		}
+		upToDate, err := bingo.IsUpToDate(c.modDir, key, binPath)
+		if err != nil {
+			return err
+		}
+		if upToDate {
+			_, _ = fmt.Fprintf(os.Stdout, "%s is up to date; no rebuild needed\n", binPath)
+			continue
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "%s would be built\n", binPath)
 	}
+	return nil
+}
 
-	if err := os.RemoveAll(filepath.Join(gobin, name)); err != nil {
-		return errors.Wrap(err, "rm")
+// printBuildCommands prints go build commands bingo would run to build binaries of the tool, once the module file is
+// written to outModFile.
+func printBuildCommands(ctx context.Context, logger logging.Logger, c installPackageConfig, name string, outModFile string, modFile *bingo.ModFile) error {
+	cmds, err := bingo.BuildCommands(ctx, logger, c.runner, c.modDir, "", name, outModFile, modFile)
+	if err != nil {
+		return err
 	}
-	if err := os.Symlink(binPath, filepath.Join(gobin, name)); err != nil {
-		return errors.Wrap(err, "symlink")
+	for _, cmd := range cmds {
+		_, _ = fmt.Fprintln(os.Stdout, bingo.ShellCommand(cmd))
 	}
 	return nil
 }
 
-const modREADMEFmt = `# Project Development Dependencies.
-
-This is directory which stores Go modules with pinned buildable package that is used within this repository, managed by https://github.com/bwplotka/bingo.
-
-* Run ` + "`" + "bingo get" + "`" + ` to install all tools having each own module file in this directory.
-* Run ` + "`" + "bingo get <tool>" + "`" + ` to install <tool> that have own module file in this directory.
-* For Makefile: Make sure to put ` + "`" + "include %s/Variables.mk" + "`" + ` in your Makefile, then use $(<upper case tool name>) variable where <tool> is the %s/<tool>.mod.
-* For shell: Run ` + "`" + "source %s/variables.env" + "`" + ` to source all environment variable for each tool.
-* See https://github.com/bwplotka/bingo or -h on how to add, remove or change binaries dependencies.
-
-## Requirements
-
-* Go 1.14+
-`
-
-const gitignore = `
-# Ignore everything
-*
-
-# But not these files:
-!.gitignore
-!*.mod
-!*.sum
-!README.md
-!Variables.mk
-!variables.env
-
-*tmp.mod
-*tmp.sum
-`
-
-func ensureModDirExists(logger *log.Logger, relModDir string) error {
-	_, err := os.Stat(relModDir)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return errors.Wrapf(err, "stat bingo module dir %s", relModDir)
-		}
-
-		logger.Printf("Bingo not used before here, creating directory for pinned modules for you at %s\n", relModDir)
-		if err := os.MkdirAll(relModDir, os.ModePerm); err != nil {
-			return errors.Wrapf(err, "create moddir %s", relModDir)
-		}
+// removeAllGlobOrPlan removes all files matching glob or, in dry run, only prints what would be removed.
+func removeAllGlobOrPlan(dryRun bool, glob string) error {
+	if !dryRun {
+		return removeAllGlob(glob)
 	}
-
-	// Hack against:
-	// "A file named go.mod must still be present in order to determine the module root directory, but it is not accessed."
-	// Ref: https://golang.org/doc/go1.14#go-flags
-	// TODO(bwplotka): Remove it: https://github.com/bwplotka/bingo/issues/20
-	if err := os.WriteFile(
-		filepath.Join(relModDir, bingo.FakeRootModFileName),
-		[]byte("module _ // Fake go.mod auto-created by 'bingo' for go -moddir compatibility with non-Go projects. Commit this file, together with other .mod files."),
-		0666,
-	); err != nil {
+	files, err := filepath.Glob(glob)
+	if err != nil {
 		return err
 	}
-
-	// README.
-	if err := os.WriteFile(
-		filepath.Join(relModDir, "README.md"),
-		[]byte(fmt.Sprintf(modREADMEFmt, relModDir, relModDir, relModDir)),
-		0666,
-	); err != nil {
-		return err
+	for _, f := range files {
+		if mod.IsLockFile(f) {
+			continue
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "%s would be removed\n", f)
 	}
-	// gitignore.
-	return os.WriteFile(filepath.Join(relModDir, ".gitignore"), []byte(gitignore), 0666)
+	return nil
 }
 
+// removeAllGlob removes all files matching glob, except lock files (see mod.Lock), since other processes might use them.
 func removeAllGlob(glob string) error {
 	files, err := filepath.Glob(glob)
 	if err != nil {
 		return err
 	}
 	for _, f := range files {
+		if mod.IsLockFile(f) {
+			continue
+		}
 		if err := os.RemoveAll(f); err != nil {
 			return err
 		}
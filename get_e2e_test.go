@@ -433,7 +433,7 @@ func TestCompatibility(t *testing.T) {
 					})
 					// TODO(bwplotka): Test variables.env as well.
 					t.Run("Makefile", func(t *testing.T) {
-						if !goVersion.LessThan(version.Go116) {
+						if version.AtLeast(goVersion, version.Go116) {
 							// These projects are configured with modules but the generated Makefiles do not contain the
 							// `-mod=mod` argument, and that makes those Makefiles incompatible with Go modules in 1.16.
 							// Let's run bingo get to simulate
@@ -530,7 +530,7 @@ func TestGet_ModuleCases(t *testing.T) {
 		expectBingoListRows(t, []row(nil), g.ExecOutput(t, p.root, bingoPath, "list"))
 		testutil.Equals(t, []string{}, g.existingBinaries(t))
 
-		if goVersion.LessThan(semver.MustParse("v1.18")) {
+		if !version.AtLeast(goVersion, version.Go118) {
 			err := g.ExpectErr(p.root, bingoPath, "get", "github.com/bwplotka/bingo-testmodule/buildable2@d48721795572f7b824f60a5b0623e524b263ed0c")
 			testutil.NotOk(t, err)
 		} else {
@@ -647,8 +647,8 @@ func TestExpectBingoListRows(t *testing.T) {
 		{name: "mdox", binName: "mdox-v0.2.1", pkgVersion: "github.com/bwplotka/mdox@v0.2.1"},
 		{name: "misspell", binName: "misspell-v0.3.4", pkgVersion: "github.com/client9/misspell/cmd/misspell@v0.3.4"},
 		{name: "proxy", binName: "proxy-v0.10.0", pkgVersion: "github.com/gomods/athens/cmd/proxy@v0.10.0"},
-	}, `Name		Binary Name					Package @ Version								Build EnvVars	Build Flags
-----		-----------					-----------------								-------------	-----------
+	}, `Name		Binary Name					Package @ Version								Build EnvVars	Build Flags	Comment
+----		-----------					-----------------								-------------	-----------	-------
 copyright	copyright-v0.0.0-20210112004814-138d5e5695fe	github.com/efficientgo/tools/copyright@v0.0.0-20210112004814-138d5e5695fe			
 embedmd		embedmd-v1.0.0					github.com/campoy/embedmd@v1.0.0						CGO_ENABLED=1	-tags=lol
 faillint	faillint-v1.5.0					github.com/fatih/faillint@v1.5.0								
@@ -4,10 +4,20 @@
 package main
 
 import (
+	"archive/zip"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/runner"
 	"github.com/efficientgo/core/errors"
 	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
 )
 
 func TestParseTarget(t *testing.T) {
@@ -68,6 +78,23 @@ func TestParseTarget(t *testing.T) {
 			target:      "tool@version1123,version13,none",
 			expectedErr: errors.New("none is not allowed when there are more than one specified Version, got: [version1123 version13 none]"),
 		},
+		{
+			target:       "Github.com/Foo/Bar/@v1.0.0",
+			expectedName: "bar", expectedPkgPath: "github.com/Foo/Bar", expectedVersions: []string{"v1.0.0"},
+		},
+		{
+			target:       "SIGS.K8S.IO/kustomize/kustomize/v3//",
+			expectedName: "kustomize", expectedPkgPath: "sigs.k8s.io/kustomize/kustomize/v3",
+			expectedVersions: []string{""},
+		},
+		{
+			target:      "tool/cmd/tool",
+			expectedErr: errors.New(`invalid package path "tool/cmd/tool": malformed module path "tool/cmd/tool": missing dot in first path element`),
+		},
+		{
+			target:      "github.com/x/../tool",
+			expectedErr: errors.New(`invalid package path "github.com/x/../tool": malformed module path "github.com/x/../tool": invalid path element ".."`),
+		},
 		{
 			target:       "github.com/bwplotka/bingo/v2@v0.2.5-rc.1214,bb92924b84d060515f8eb35f428a8fd816c1d938,version1241",
 			expectedName: "bingo", expectedPkgPath: "github.com/bwplotka/bingo/v2", expectedVersions: []string{"v0.2.5-rc.1214", "bb92924b84d060515f8eb35f428a8fd816c1d938", "version1241"},
@@ -89,3 +116,532 @@ func TestParseTarget(t *testing.T) {
 	}
 
 }
+
+func TestSelectUpdateVersion(t *testing.T) {
+	versions := []string{"v0.1.0", "v0.1.5", "v0.2.0-rc.1", "v0.1.12", "v1.0.0", "v1.1.0-beta.1", "v2.0.0+incompatible"}
+	for _, tcase := range []struct {
+		constraint      string
+		allowPrerelease bool
+
+		expected    string
+		expectedErr string
+	}{
+		{expected: "v2.0.0+incompatible"},
+		{constraint: "latest", expected: "v2.0.0+incompatible"},
+		{constraint: "^v0.1", expected: "v0.1.12"},
+		{constraint: "^v1", expected: "v1.0.0"},
+		{constraint: "^v1", allowPrerelease: true, expected: "v1.1.0-beta.1"},
+		{constraint: ">=v0.1.5", expected: "v2.0.0+incompatible"},
+		{constraint: "v0.1 - v0.9", expected: "v0.1.12"},
+		{constraint: "v0.1 - v0.9", allowPrerelease: true, expected: "v0.2.0-rc.1"},
+		{constraint: "~v0.1.3", expected: "v0.1.12"},
+		{constraint: "^v3", expectedErr: `no version matching "^v3" found in [v0.1.0 v0.1.5 v0.2.0-rc.1 v0.1.12 v1.0.0 v1.1.0-beta.1 v2.0.0+incompatible]`},
+		{constraint: "yolo", expectedErr: `parse version constraint "yolo": improper constraint: yolo`},
+	} {
+		t.Run(tcase.constraint, func(t *testing.T) {
+			v, err := selectUpdateVersion(versions, tcase.constraint, tcase.allowPrerelease)
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, v)
+		})
+	}
+}
+
+func TestResolvePickedVersion(t *testing.T) {
+	logger := logging.Discard
+	r := modVersionsRunnable{versions: map[string][]string{"github.com/x/tool": {"v0.1.0", "v0.2.0"}}, queries: map[string]int{}}
+
+	var picked []string
+	pick := func(modPath string, versions []string) (string, error) {
+		picked = append(picked, modPath)
+		testutil.Equals(t, []string{"v0.1.0", "v0.2.0"}, versions)
+		return "v0.1.0", nil
+	}
+
+	target := bingo.Package{RelPath: "github.com/x/tool/cmd/foo"}
+	testutil.Ok(t, resolvePickedVersion(logger, r, &target, pick))
+	testutil.Equals(t, bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "v0.1.0"}, RelPath: "cmd/foo"}, target)
+	testutil.Equals(t, []string{"github.com/x/tool"}, picked)
+
+	target = bingo.Package{RelPath: "github.com/y/tool"}
+	testutil.NotOk(t, resolvePickedVersion(logger, r, &target, pick))
+	testutil.Equals(t, 1, len(picked))
+}
+
+func TestParseLocalReplace(t *testing.T) {
+	wd, err := os.Getwd()
+	testutil.Ok(t, err)
+	modDir := filepath.Join(wd, ".bingo")
+
+	for _, tcase := range []struct {
+		replace string
+
+		expected    localReplace
+		expectedErr string
+	}{
+		{replace: "github.com/x/tool=../tool", expected: localReplace{modulePath: "github.com/x/tool", dir: "../../tool"}},
+		{replace: "github.com/x/tool=tool", expected: localReplace{modulePath: "github.com/x/tool", dir: "../tool"}},
+		{replace: "github.com/x/tool=/src/tool", expected: localReplace{modulePath: "github.com/x/tool", dir: "/src/tool"}},
+		{replace: "github.com/x/tool", expectedErr: `expected <module path>=<local dir>, got "github.com/x/tool"`},
+		{replace: "github.com/x/tool=", expectedErr: `expected <module path>=<local dir>, got "github.com/x/tool="`},
+		{replace: "=../tool", expectedErr: `expected <module path>=<local dir>, got "=../tool"`},
+	} {
+		t.Run(tcase.replace, func(t *testing.T) {
+			r, err := parseLocalReplace(tcase.replace, modDir)
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, r)
+		})
+	}
+}
+
+func TestMatchLocalReplace(t *testing.T) {
+	replaces := []localReplace{
+		{modulePath: "github.com/x/tool", dir: "../tool"},
+		{modulePath: "github.com/x/tool/v2", dir: "../tool2"},
+		{modulePath: "github.com/x/dep", dir: "../dep"},
+	}
+
+	r, ok := matchLocalReplace(replaces, bingo.Package{RelPath: "github.com/x/tool/cmd/foo"})
+	testutil.Equals(t, true, ok)
+	testutil.Equals(t, replaces[0], r)
+
+	r, ok = matchLocalReplace(replaces, bingo.Package{Module: module.Version{Path: "github.com/x/tool/v2"}, RelPath: "cmd/foo"})
+	testutil.Equals(t, true, ok)
+	testutil.Equals(t, replaces[1], r)
+
+	_, ok = matchLocalReplace(replaces, bingo.Package{RelPath: "github.com/x/toolbox/cmd/foo"})
+	testutil.Equals(t, false, ok)
+}
+
+func TestSetToolchain(t *testing.T) {
+	modFile := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte("module _\n\ngo 1.23.1\n\nrequire github.com/fatih/faillint v1.5.0\n"), os.ModePerm))
+
+	mf, err := bingo.OpenModFile(modFile)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { _ = mf.Close() })
+
+	// Go directive newer than toolchain is lowered.
+	testutil.Ok(t, setToolchain(mf, "go1.22.0"))
+	testutil.Equals(t, "1.22", mf.GoVersion())
+	testutil.Equals(t, "go1.22.0", mf.Toolchain())
+
+	testutil.Ok(t, setToolchain(mf, "go1.24.2"))
+	testutil.Equals(t, "1.22", mf.GoVersion())
+	testutil.Equals(t, "go1.24.2", mf.Toolchain())
+
+	testutil.Ok(t, setToolchain(mf, ""))
+	testutil.Equals(t, "", mf.Toolchain())
+}
+
+func TestIsCommitSHA(t *testing.T) {
+	for v, expected := range map[string]bool{
+		"abc1234": true,
+		"e64124511800702a4d8d79e04cf6f1af32e7bef2": true,
+		"abc123":   false,
+		"ABC1234":  false,
+		"v1.5.0":   false,
+		"latest":   false,
+		"master":   false,
+		"":         false,
+		"abc1234x": false,
+	} {
+		testutil.Equals(t, expected, isCommitSHA(v), v)
+	}
+}
+
+// modQueryRunnable resolves module queries from the map keyed by <module path>@<query>. Queries resolve from refs (e.g.
+// "refs/tags/release-1") if set for the same key.
+type modQueryRunnable struct {
+	runner.Runnable
+
+	resolved map[string]string
+	refs     map[string]string
+	errs     map[string]error
+}
+
+func (r modQueryRunnable) ModQuery(modulePath, query string) (string, error) {
+	if err, ok := r.errs[modulePath+"@"+query]; ok {
+		return "", err
+	}
+	if v, ok := r.resolved[modulePath+"@"+query]; ok {
+		return v, nil
+	}
+	return "", errors.Newf("%v@%v: not found", modulePath, query)
+}
+
+func (r modQueryRunnable) ModQueryRef(modulePath, query string) (string, string, error) {
+	v, err := r.ModQuery(modulePath, query)
+	if err != nil {
+		return "", "", err
+	}
+	return v, r.refs[modulePath+"@"+query], nil
+}
+
+func TestResolveCommitVersion(t *testing.T) {
+	logger := logging.Discard
+	r := modQueryRunnable{
+		resolved: map[string]string{"github.com/x/tool@abc1234": "v0.0.0-20200519204825-abc123456789"},
+		errs:     map[string]error{"github.com/x/tool@abc12": errors.New("github.com/x/tool@abc12: short object ID abc12 is ambiguous")},
+	}
+
+	t.Run("module path unknown", func(t *testing.T) {
+		target := bingo.Package{RelPath: "github.com/x/tool/cmd/foo", Module: module.Version{Version: "abc1234"}}
+		testutil.Ok(t, resolveCommitVersion(logger, r, nil, &target))
+		testutil.Equals(t, bingo.Package{
+			Module:  module.Version{Path: "github.com/x/tool", Version: "v0.0.0-20200519204825-abc123456789"},
+			RelPath: "cmd/foo",
+		}, target)
+	})
+	t.Run("module path known", func(t *testing.T) {
+		target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "abc1234"}, RelPath: "cmd/foo"}
+		testutil.Ok(t, resolveCommitVersion(logger, r, nil, &target))
+		testutil.Equals(t, "v0.0.0-20200519204825-abc123456789", target.Module.Version)
+	})
+	t.Run("ambiguous", func(t *testing.T) {
+		target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "abc12"}}
+		err := resolveCommitVersion(logger, r, nil, &target)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, "commit abc12 is ambiguous in github.com/x/tool; use more characters of the SHA or the full one", err.Error())
+	})
+	t.Run("not found", func(t *testing.T) {
+		target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "def5678"}}
+		err := resolveCommitVersion(logger, r, nil, &target)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, "commit def5678 of github.com/x/tool not found; make sure it's pushed and reachable from a branch or tag of the module repository: "+
+			"github.com/x/tool@def5678: not found", err.Error())
+	})
+}
+
+func TestResolveCommitVersion_ViaProxy(t *testing.T) {
+	logs := &strings.Builder{}
+	logger := logging.NewStd(log.New(logs, "", 0), logging.LevelInfo)
+	proxy := modQueryRunnable{resolved: map[string]string{"github.com/x/tool@abc1234": "v0.0.0-20200519204825-abc123456789"}}
+	direct := modQueryRunnable{resolved: map[string]string{"github.com/x/tool@abc1234": "v0.0.0-20200519204826-abc123456789"}}
+
+	target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "abc1234"}}
+	testutil.Ok(t, resolveCommitVersion(logger, proxy, direct, &target))
+	testutil.Equals(t, "v0.0.0-20200519204825-abc123456789", target.Module.Version)
+	testutil.Equals(t, "WARNING: commit abc1234 of github.com/x/tool resolves to v0.0.0-20200519204825-abc123456789 via module proxy, "+
+		"but to v0.0.0-20200519204826-abc123456789 directly from VCS; pinning the proxy one, so all machines resolve the same version\n", logs.String())
+
+	// Same or failed direct resolution is fine.
+	logs.Reset()
+	target = bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "abc1234"}}
+	testutil.Ok(t, resolveCommitVersion(logger, proxy, proxy, &target))
+	testutil.Equals(t, "v0.0.0-20200519204825-abc123456789", target.Module.Version)
+	target = bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "abc1234"}}
+	testutil.Ok(t, resolveCommitVersion(logger, proxy, modQueryRunnable{}, &target))
+	testutil.Equals(t, "v0.0.0-20200519204825-abc123456789", target.Module.Version)
+	testutil.Equals(t, "", logs.String())
+}
+
+func TestModuleProxies(t *testing.T) {
+	for goproxy, expected := range map[string]string{
+		"":                                "https://proxy.golang.org",
+		"direct":                          "https://proxy.golang.org",
+		"off":                             "off",
+		"https://proxy.golang.org,direct": "https://proxy.golang.org",
+		"https://a.example.com|https://b.example.com,direct": "https://a.example.com,https://b.example.com",
+	} {
+		testutil.Equals(t, expected, moduleProxies(goproxy), goproxy)
+	}
+}
+
+func TestIsBranchQuery(t *testing.T) {
+	for v, expected := range map[string]bool{
+		"main":           true,
+		"master":         true,
+		"release/v1.2":   true,
+		"v2-dev":         true,
+		"v1.5.0":         false,
+		"v1.2":           false,
+		"abc1234":        false,
+		"latest":         false,
+		"upgrade":        false,
+		"none":           false,
+		"<v1.2.0":        false,
+		"":               false,
+		"-main":          false,
+		"feature..x":     false,
+		"feature branch": false,
+	} {
+		testutil.Equals(t, expected, isBranchQuery(v), v)
+	}
+}
+
+func TestResolveRefVersion(t *testing.T) {
+	logger := logging.Discard
+	r := modQueryRunnable{
+		resolved: map[string]string{
+			"github.com/x/tool@main":            "v0.0.0-20200519204825-abc123456789",
+			"github.com/x/tool@release-2024-01": "v0.0.0-20240105120000-def123456789",
+		},
+		refs: map[string]string{
+			"github.com/x/tool@main":            "refs/heads/main",
+			"github.com/x/tool@release-2024-01": "refs/tags/release-2024-01",
+		},
+		errs: map[string]error{
+			"github.com/x/tool@flaky": errors.Wrap(runner.ErrNetwork, "dial tcp: i/o timeout"),
+		},
+	}
+
+	t.Run("module path unknown", func(t *testing.T) {
+		target := bingo.Package{RelPath: "github.com/x/tool/cmd/foo", Module: module.Version{Version: "main"}}
+		testutil.Ok(t, resolveRefVersion(logger, r, &target))
+		testutil.Equals(t, bingo.Package{
+			Module:  module.Version{Path: "github.com/x/tool", Version: "v0.0.0-20200519204825-abc123456789"},
+			RelPath: "cmd/foo",
+			Branch:  "main",
+		}, target)
+	})
+	t.Run("tag", func(t *testing.T) {
+		target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "release-2024-01"}, RelPath: "cmd/foo", Branch: "main"}
+		testutil.Ok(t, resolveRefVersion(logger, r, &target))
+		testutil.Equals(t, bingo.Package{
+			Module:  module.Version{Path: "github.com/x/tool", Version: "v0.0.0-20240105120000-def123456789"},
+			RelPath: "cmd/foo",
+			Tag:     "release-2024-01",
+		}, target)
+	})
+	t.Run("unknown reference", func(t *testing.T) {
+		r := modQueryRunnable{resolved: map[string]string{"github.com/x/tool@release-2024-01": "v0.0.0-20240105120000-def123456789"}}
+		target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "release-2024-01"}}
+		testutil.Ok(t, resolveRefVersion(logger, r, &target))
+		testutil.Equals(t, "release-2024-01", target.Branch)
+		testutil.Equals(t, "", target.Tag)
+	})
+	t.Run("not found", func(t *testing.T) {
+		target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "dev"}}
+		err := resolveRefVersion(logger, r, &target)
+		testutil.NotOk(t, err)
+		testutil.Equals(t, "branch or tag dev of github.com/x/tool not found: github.com/x/tool@dev: not found", err.Error())
+	})
+	t.Run("network failure", func(t *testing.T) {
+		target := bingo.Package{Module: module.Version{Path: "github.com/x/tool", Version: "flaky"}}
+		err := resolveRefVersion(logger, r, &target)
+		testutil.NotOk(t, err)
+		testutil.Assert(t, errors.Is(err, runner.ErrNetwork))
+		testutil.Equals(t, "resolve flaky of github.com/x/tool: dial tcp: i/o timeout: network error", err.Error())
+	})
+}
+
+func TestGetMany_InvalidTargets(t *testing.T) {
+	logger := logging.Discard
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	c := getConfig{modDir: modDir, relModDir: modDir, parallel: 2}
+
+	err := getMany(context.Background(), logger, c, []string{"github.com/x/tool/cmd/foo", "proto*", "foo@v1.0.0", "bar@v1.0.0,v1.0.0"}, false)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "3 errors: tool name pattern proto* cannot be combined with other targets; "+
+		"github.com/x/tool/cmd/foo and foo@v1.0.0 reference the same tool foo; "+
+		"parse bar@v1.0.0,v1.0.0: version duplicates are not allowed, got: [v1.0.0 v1.0.0]", err.Error())
+	// Nothing is done if any target is invalid.
+	_, err = os.Stat(modDir)
+	testutil.Assert(t, os.IsNotExist(err), "expected no module directory, got %v", err)
+
+	c.name = "foo"
+	testutil.NotOk(t, getMany(context.Background(), logger, c, []string{"github.com/x/tool/cmd/foo", "bar"}, true))
+}
+
+func TestSideBySideNames(t *testing.T) {
+	names, err := sideBySideNames("migrate", "github.com/x/migrate", []string{"v1.4.0", "v2.0.0+incompatible", "v0.9.0"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"migrate-v1", "migrate-v2", "migrate-v0"}, names)
+
+	_, err = sideBySideNames("migrate", "", []string{"v1.4.0"})
+	testutil.NotOk(t, err)
+	_, err = sideBySideNames("migrate", "github.com/x/migrate", []string{"none"})
+	testutil.NotOk(t, err)
+	_, err = sideBySideNames("migrate", "github.com/x/migrate", []string{"v1.4.0", "v1.5.0"})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "versions v1.4.0 and v1.5.0 would be both pinned as migrate-v1; side by side versions have to differ in major version. "+
+		"Pin them without --side-by-side instead", err.Error())
+
+	// Side by side variants of the same tool are different tools.
+	logger := logging.Discard
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	c := getConfig{modDir: modDir, relModDir: modDir, parallel: 2, sideBySide: true}
+	err = getMany(context.Background(), logger, c, []string{"github.com/x/migrate@v1.4.0", "github.com/x/migrate/v2@v2.0.0", "github.com/y/migrate@v1.0.0"}, false)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, "github.com/x/migrate@v1.4.0 and github.com/y/migrate@v1.0.0 reference the same tool migrate-v1", err.Error())
+}
+
+func TestKeptTempErr(t *testing.T) {
+	modDir := t.TempDir()
+	modFile := filepath.Join(modDir, "tool.tmp.mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte("module _\n\ngo 1.17\n\nrequire github.com/fatih/faillint v1.5.0 // CGO_ENABLED=1\n"), os.ModePerm))
+	mf, err := bingo.OpenModFile(modFile)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { _ = mf.Close() })
+
+	logs := &strings.Builder{}
+	logger := logging.NewStd(log.New(logs, "", 0), logging.LevelInfo)
+	r, err := runner.NewRunner(context.Background(), nil, false, "go")
+	testutil.Ok(t, err)
+	installErr := errors.New("build versioned: exit 1")
+
+	c := installPackageConfig{runner: r, modDir: modDir}
+	testutil.Equals(t, installErr, keptTempErr(context.Background(), logger, c, "tool", mf, installErr))
+	testutil.Equals(t, "", logs.String())
+
+	c.keepTemp = true
+	err = keptTempErr(context.Background(), logger, c, "tool", mf, installErr)
+	testutil.Equals(t, "temporary module file kept at "+modFile+": build versioned: exit 1", err.Error())
+	testutil.Assert(t, errors.Is(err, installErr), "expected install error wrapped")
+	testutil.Assert(t, strings.Contains(logs.String(), "CGO_ENABLED=1 GO111MODULE=on GOWORK=off go build -modfile="+modFile+" "), "expected build command logged, got %q", logs.String())
+}
+
+func TestGet_CaseMismatch(t *testing.T) {
+	logger := logging.Discard
+	r, err := runner.NewRunner(context.Background(), nil, false, "go")
+	testutil.Ok(t, err)
+
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "bar.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.17
+
+require github.com/foo/bar v1.0.0
+`), os.ModePerm))
+
+	c := getConfig{runner: r, modDir: modDir, relModDir: modDir, parallel: 1}
+	err = get(context.Background(), logger, c, "github.com/Foo/Bar@v1.0.1")
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), `package path "github.com/Foo/Bar" differs only in case from "github.com/foo/bar" pinned in `), "got %v", err)
+}
+
+func TestGet_Offline(t *testing.T) {
+	// Empty module cache, so the tool cannot be installed without network.
+	t.Setenv("GOMODCACHE", t.TempDir())
+	logger := logging.Discard
+	r, err := runner.NewRunner(context.Background(), nil, false, "go", runner.WithOffline(true))
+	testutil.Ok(t, err)
+
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	c := getConfig{runner: r, modDir: modDir, relModDir: modDir, parallel: 1}
+	err = withGoErrorHint(get(context.Background(), logger, c, "github.com/fatih/faillint@v1.5.0"))
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, runner.ErrNotInModCache), "expected not in module cache error, got %v", err)
+	testutil.Assert(t, strings.HasPrefix(err.Error(), "module is not in the module cache and downloading is disabled; "), "expected friendly message, got %v", err)
+}
+
+func TestWithGoErrorHint(t *testing.T) {
+	for _, tcase := range []struct {
+		kind           error
+		expectedPrefix string
+	}{
+		{kind: runner.ErrAuth, expectedPrefix: "private module server requires credentials; "},
+		{kind: runner.ErrForbidden, expectedPrefix: "access denied by proxy/server (credentials or policy); "},
+	} {
+		err := withGoErrorHint(errors.Wrap(tcase.kind, "get"))
+		testutil.Assert(t, errors.Is(err, tcase.kind), "expected %v, got %v", tcase.kind, err)
+		testutil.Assert(t, strings.HasPrefix(err.Error(), tcase.expectedPrefix), "expected friendly message, got %v", err)
+	}
+}
+
+// writeProxyVersion adds version of single package module to the file module proxy in dir. Pseudo-versions are not
+// listed as released, but returned as the latest version.
+func writeProxyVersion(t *testing.T, dir, modPath, version string) {
+	t.Helper()
+
+	vDir := filepath.Join(dir, modPath, "@v")
+	testutil.Ok(t, os.MkdirAll(vDir, os.ModePerm))
+	goMod := "module " + modPath + "\n\ngo 1.17\n"
+	testutil.Ok(t, os.WriteFile(filepath.Join(vDir, version+".mod"), []byte(goMod), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(vDir, version+".info"), []byte(`{"Version":"`+version+`","Time":"2023-01-01T00:00:00Z"}`), os.ModePerm))
+
+	f, err := os.Create(filepath.Join(vDir, version+".zip"))
+	testutil.Ok(t, err)
+	w := zip.NewWriter(f)
+	for name, content := range map[string]string{"go.mod": goMod, "main.go": "package main\n\nfunc main() {}\n"} {
+		fw, err := w.Create(modPath + "@" + version + "/" + name)
+		testutil.Ok(t, err)
+		_, err = fw.Write([]byte(content))
+		testutil.Ok(t, err)
+	}
+	testutil.Ok(t, w.Close())
+	testutil.Ok(t, f.Close())
+
+	l, err := os.OpenFile(filepath.Join(vDir, "list"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, os.ModePerm)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, l.Close()) }()
+	if module.IsPseudoVersion(version) {
+		testutil.Ok(t, os.WriteFile(filepath.Join(dir, modPath, "@latest"), []byte(`{"Version":"`+version+`","Time":"2023-01-01T00:00:00Z"}`), os.ModePerm))
+		return
+	}
+	_, err = l.WriteString(version + "\n")
+	testutil.Ok(t, err)
+}
+
+func TestGet_Latest(t *testing.T) {
+	proxy := t.TempDir()
+	writeProxyVersion(t, proxy, "example.com/tool", "v1.0.0")
+	t.Setenv("GOPROXY", "file://"+filepath.ToSlash(proxy))
+	t.Setenv("GOSUMDB", "off")
+	t.Setenv("GOMODCACHE", t.TempDir())
+	t.Setenv("GOFLAGS", "-modcacherw")
+
+	logger := logging.Discard
+	r, err := runner.NewRunner(context.Background(), nil, false, "go")
+	testutil.Ok(t, err)
+
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	c := getConfig{runner: r, modDir: modDir, relModDir: modDir, parallel: 1, noBuild: true}
+	pinned := func() bingo.Package {
+		t.Helper()
+
+		mf, err := bingo.OpenModFile(filepath.Join(modDir, "tool.mod"))
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, mf.Close()) }()
+		return *mf.DirectPackage()
+	}
+
+	// Concrete version is pinned, but it's recorded it was requested as latest.
+	testutil.Ok(t, get(context.Background(), logger, c, "example.com/tool@latest"))
+	p := pinned()
+	testutil.Equals(t, "v1.0.0", p.Module.Version)
+	testutil.Equals(t, true, p.Latest)
+
+	// Get without version keeps both.
+	writeProxyVersion(t, proxy, "example.com/tool", "v1.1.0")
+	testutil.Ok(t, get(context.Background(), logger, c, "tool"))
+	p = pinned()
+	testutil.Equals(t, "v1.0.0", p.Module.Version)
+	testutil.Equals(t, true, p.Latest)
+
+	// Update resolves latest again.
+	c.update = true
+	testutil.Ok(t, get(context.Background(), logger, c, "tool"))
+	p = pinned()
+	testutil.Equals(t, "v1.1.0", p.Module.Version)
+	testutil.Equals(t, true, p.Latest)
+
+	// Explicit version drops the marker.
+	c.update = false
+	testutil.Ok(t, get(context.Background(), logger, c, "tool@v1.0.0"))
+	p = pinned()
+	testutil.Equals(t, "v1.0.0", p.Module.Version)
+	testutil.Equals(t, false, p.Latest)
+
+	// Module without releases is updated to the pseudo-version of the latest commit.
+	writeProxyVersion(t, proxy, "example.com/untagged", "v0.0.0-20230101000000-abcdefabcdef")
+	testutil.Ok(t, get(context.Background(), logger, c, "example.com/untagged@latest"))
+	writeProxyVersion(t, proxy, "example.com/untagged", "v0.0.0-20230201000000-123456123456")
+	c.update = true
+	testutil.Ok(t, get(context.Background(), logger, c, "untagged"))
+	mf, err := bingo.OpenModFile(filepath.Join(modDir, "untagged.mod"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "v0.0.0-20230201000000-123456123456", mf.DirectPackage().Module.Version)
+	testutil.Ok(t, mf.Close())
+}
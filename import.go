@@ -0,0 +1,280 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/merrors"
+	"golang.org/x/mod/modfile"
+)
+
+// importCandidate is a binary found in GOBIN that can be pinned.
+type importCandidate struct {
+	name    string
+	pkgPath string
+	version string
+}
+
+// target returns <package>@<version> as accepted by bingo get.
+func (c importCandidate) target() string {
+	return c.pkgPath + "@" + c.version
+}
+
+// importCandidateFor returns candidate for binary with given file name and build info or reason why it can't be imported.
+func importCandidateFor(fileName string, info runner.BuildInfo) (importCandidate, string) {
+	name := strings.TrimSuffix(fileName, ".exe")
+	if err := bingo.ValidateBinaryName(name); err != nil {
+		return importCandidate{}, err.Error()
+	}
+	v := info.Main.Version
+	switch {
+	case info.MainReplace != nil:
+		return importCandidate{}, fmt.Sprintf("module %v was replaced with %v during build", info.Main.Path, info.MainReplace.Path)
+	case v == "" || v == "(devel)" || strings.HasSuffix(v, "+dirty"):
+		return importCandidate{}, fmt.Sprintf("built from local checkout of %v, no released version to pin", info.Main.Path)
+	case strings.Contains(name, "-"+v):
+		return importCandidate{}, "versioned binary, most likely installed by bingo already"
+	}
+	return importCandidate{name: name, pkgPath: info.Path, version: v}, ""
+}
+
+// importTools pins binaries installed in gobin (e.g. with go install) that are not pinned yet, as if `bingo get <package>@<version>`
+// was run for each of them. Package and version are read from build information embedded in the binary. Binaries that can't be
+// imported are reported and skipped.
+func importTools(ctx context.Context, logger logging.Logger, c getConfig, gobin string) (err error) {
+	files, err := os.ReadDir(gobin)
+	if err != nil {
+		return errors.Wrapf(err, "read GOBIN %v", gobin)
+	}
+
+	var candidates []importCandidate
+	for _, f := range files {
+		// Symlinks are skipped, since those are created by bingo get -l.
+		if !f.Type().IsRegular() {
+			continue
+		}
+
+		info, err := c.runner.BuildInfo(ctx, filepath.Join(gobin, f.Name()))
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stdout, "skipped %v: not a Go module binary\n", f.Name())
+			logger.Debugf("%v\n", err)
+			continue
+		}
+		candidate, reason := importCandidateFor(f.Name(), info)
+		if reason != "" {
+			_, _ = fmt.Fprintf(os.Stdout, "skipped %v: %v\n", f.Name(), reason)
+			continue
+		}
+
+		pinned, err := reportIfPinned(c, f.Name(), candidate.name)
+		if err != nil {
+			return err
+		}
+		if !pinned {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return pinImportCandidates(ctx, logger, c, candidates)
+}
+
+// reportIfPinned reports and returns true if tool with given name is pinned already.
+func reportIfPinned(c getConfig, source string, name string) (bool, error) {
+	existing, err := existingModFiles(c.modDir, name)
+	if err != nil {
+		return false, errors.Wrapf(err, "existing mod files for %v", name)
+	}
+	if len(existing) == 0 {
+		return false, nil
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "skipped %v: already pinned in %v\n", source, filepath.Join(c.relModDir, filepath.Base(existing[0])))
+	return true, nil
+}
+
+// pinImportCandidates runs bingo get for each candidate and reports the result.
+func pinImportCandidates(ctx context.Context, logger logging.Logger, c getConfig, candidates []importCandidate) error {
+	merr := merrors.New()
+	for _, candidate := range candidates {
+		cfg := c
+		if candidate.name != bingo.DefaultBinaryName(candidate.pkgPath) {
+			cfg.name = candidate.name
+		}
+		if err := get(ctx, logger, cfg, candidate.target()); err != nil {
+			merr.Add(errors.Wrapf(err, "import %v as %v", candidate.name, candidate.target()))
+			continue
+		}
+		action := "imported"
+		if c.dryRun {
+			action = "would import"
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "%v %v as %v\n", action, candidate.name, candidate.target())
+	}
+	return merr.Err()
+}
+
+// goModTools represents tool directives of the project go.mod together with information needed to resolve their versions.
+type goModTools struct {
+	modulePath string
+	tools      []string
+	requires   map[string]string
+	replaced   map[string]struct{}
+	sums       map[string]struct{}
+}
+
+// parseGoModTools parses tool directives (introduced in Go 1.24) from go.mod content and versions of required modules
+// from go.mod and go.sum content. The go.mod is parsed in lax mode, so directives unknown to our modfile version are kept
+// in the syntax tree only.
+func parseGoModTools(modFile string, modData, sumData []byte) (*goModTools, error) {
+	m, err := modfile.ParseLax(modFile, modData, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %v", modFile)
+	}
+	if m.Module == nil {
+		return nil, errors.Newf("%v has no module directive", modFile)
+	}
+
+	t := &goModTools{
+		modulePath: m.Module.Mod.Path,
+		requires:   map[string]string{},
+		replaced:   map[string]struct{}{},
+		sums:       map[string]struct{}{},
+	}
+	for _, r := range m.Require {
+		t.requires[r.Mod.Path] = r.Mod.Version
+	}
+
+	// Replace directives are ignored by lax parsing as well, so those are read from the syntax tree too.
+	addLine := func(verb string, tokens []string) error {
+		if len(tokens) == 0 {
+			return nil
+		}
+		p, err := unquoteModToken(tokens[0])
+		if err != nil {
+			return errors.Wrapf(err, "%v directive in %v", verb, modFile)
+		}
+		switch verb {
+		case "tool":
+			t.tools = append(t.tools, p)
+		case "replace":
+			t.replaced[p] = struct{}{}
+		}
+		return nil
+	}
+	for _, stmt := range m.Syntax.Stmt {
+		switch x := stmt.(type) {
+		case *modfile.Line:
+			if err := addLine(x.Token[0], x.Token[1:]); err != nil {
+				return nil, err
+			}
+		case *modfile.LineBlock:
+			if len(x.Token) != 1 {
+				continue
+			}
+			for _, l := range x.Line {
+				if err := addLine(x.Token[0], l.Token); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, line := range strings.Split(string(sumData), "\n") {
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			continue
+		}
+		t.sums[f[0]+"@"+strings.TrimSuffix(f[1], "/go.mod")] = struct{}{}
+	}
+	return t, nil
+}
+
+func unquoteModToken(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) && !strings.HasPrefix(s, "`") {
+		return s, nil
+	}
+	return strconv.Unquote(s)
+}
+
+// candidateFor returns candidate for tool package path or reason why it can't be resolved to a version.
+func (t *goModTools) candidateFor(pkgPath string) (importCandidate, string) {
+	name := bingo.DefaultBinaryName(pkgPath)
+	if err := bingo.ValidateBinaryName(name); err != nil {
+		return importCandidate{}, err.Error()
+	}
+	if isInModule(pkgPath, t.modulePath) {
+		return importCandidate{}, fmt.Sprintf("part of the main module %v, no released version to pin", t.modulePath)
+	}
+
+	modPath := ""
+	for p := range t.requires {
+		if isInModule(pkgPath, p) && len(p) > len(modPath) {
+			modPath = p
+		}
+	}
+	if modPath == "" {
+		return importCandidate{}, "no require directive for module providing it; run go mod tidy"
+	}
+	v := t.requires[modPath]
+	if _, ok := t.replaced[modPath]; ok {
+		return importCandidate{}, fmt.Sprintf("module %v is replaced in go.mod", modPath)
+	}
+	if _, ok := t.sums[modPath+"@"+v]; !ok {
+		return importCandidate{}, fmt.Sprintf("missing go.sum entry for %v@%v; run go mod tidy", modPath, v)
+	}
+	return importCandidate{name: name, pkgPath: pkgPath, version: v}, ""
+}
+
+func isInModule(pkgPath, modPath string) bool {
+	return pkgPath == modPath || strings.HasPrefix(pkgPath, modPath+"/")
+}
+
+// importGoModTools pins tools from tool directives of the given go.mod, as if `bingo get <package>@<version>` was run for
+// each of them, with version of the module providing the tool required in go.mod and present in go.sum. Tools that can't
+// be resolved to a version are reported and skipped.
+func importGoModTools(ctx context.Context, logger logging.Logger, c getConfig, goModFile string) error {
+	modData, err := os.ReadFile(goModFile)
+	if err != nil {
+		return errors.Wrapf(err, "read %v", goModFile)
+	}
+	sumFile := strings.TrimSuffix(goModFile, ".mod") + ".sum"
+	sumData, err := os.ReadFile(sumFile)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "read %v", sumFile)
+	}
+	t, err := parseGoModTools(goModFile, modData, sumData)
+	if err != nil {
+		return err
+	}
+	if len(t.tools) == 0 {
+		_, _ = fmt.Fprintf(os.Stdout, "no tool directives found in %v\n", goModFile)
+		return nil
+	}
+
+	var candidates []importCandidate
+	for _, tool := range t.tools {
+		candidate, reason := t.candidateFor(tool)
+		if reason != "" {
+			_, _ = fmt.Fprintf(os.Stdout, "skipped %v: %v\n", tool, reason)
+			continue
+		}
+		pinned, err := reportIfPinned(c, tool, candidate.name)
+		if err != nil {
+			return err
+		}
+		if !pinned {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return pinImportCandidates(ctx, logger, c, candidates)
+}
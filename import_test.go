@@ -0,0 +1,146 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestImportCandidateFor(t *testing.T) {
+	for _, tcase := range []struct {
+		fileName string
+		info     runner.BuildInfo
+
+		expected       importCandidate
+		expectedReason string
+	}{
+		{
+			fileName: "faillint",
+			info:     runner.BuildInfo{Path: "github.com/fatih/faillint", Main: module.Version{Path: "github.com/fatih/faillint", Version: "v1.5.0"}},
+			expected: importCandidate{name: "faillint", pkgPath: "github.com/fatih/faillint", version: "v1.5.0"},
+		},
+		{
+			fileName: "lint.exe",
+			info:     runner.BuildInfo{Path: "github.com/fatih/faillint", Main: module.Version{Path: "github.com/fatih/faillint", Version: "v1.5.0"}},
+			expected: importCandidate{name: "lint", pkgPath: "github.com/fatih/faillint", version: "v1.5.0"},
+		},
+		{
+			fileName:       "faillint-v1.5.0",
+			info:           runner.BuildInfo{Path: "github.com/fatih/faillint", Main: module.Version{Path: "github.com/fatih/faillint", Version: "v1.5.0"}},
+			expectedReason: "versioned binary, most likely installed by bingo already",
+		},
+		{
+			fileName:       "bingo",
+			info:           runner.BuildInfo{Path: "github.com/bwplotka/bingo", Main: module.Version{Path: "github.com/bwplotka/bingo", Version: "(devel)"}},
+			expectedReason: "built from local checkout of github.com/bwplotka/bingo, no released version to pin",
+		},
+		{
+			fileName:       "bingo",
+			info:           runner.BuildInfo{Path: "github.com/bwplotka/bingo", Main: module.Version{Path: "github.com/bwplotka/bingo", Version: "v0.9.1-0.20231010101010-abcdefabcdef+dirty"}},
+			expectedReason: "built from local checkout of github.com/bwplotka/bingo, no released version to pin",
+		},
+		{
+			fileName:       "foo",
+			info:           runner.BuildInfo{Path: "github.com/x/tool/cmd/foo", Main: module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}, MainReplace: &module.Version{Path: "../tool"}},
+			expectedReason: "module github.com/x/tool was replaced with ../tool during build",
+		},
+	} {
+		t.Run(tcase.fileName, func(t *testing.T) {
+			c, reason := importCandidateFor(tcase.fileName, tcase.info)
+			testutil.Equals(t, tcase.expectedReason, reason)
+			testutil.Equals(t, tcase.expected, c)
+		})
+	}
+}
+
+func TestGoModToolsCandidateFor(t *testing.T) {
+	modData := []byte(`module github.com/my/project
+
+go 1.24
+
+require (
+	github.com/fatih/faillint v1.5.0
+	golang.org/x/tools v0.1.0
+	golang.org/x/tools/gopls v0.7.0
+	github.com/x/replaced v1.0.0
+	github.com/x/nosum v1.0.0
+)
+
+replace github.com/x/replaced => ../replaced
+
+tool github.com/fatih/faillint
+
+tool (
+	golang.org/x/tools/cmd/goimports
+	golang.org/x/tools/gopls
+	"github.com/x/replaced/cmd/foo"
+	github.com/x/nosum
+	github.com/x/unknown/cmd/bar
+	github.com/my/project/cmd/gen
+)
+`)
+	sumData := []byte(`github.com/fatih/faillint v1.5.0 h1:abc=
+github.com/fatih/faillint v1.5.0/go.mod h1:abc=
+golang.org/x/tools v0.1.0 h1:abc=
+golang.org/x/tools/gopls v0.7.0/go.mod h1:abc=
+github.com/x/replaced v1.0.0 h1:abc=
+`)
+	tools, err := parseGoModTools("go.mod", modData, sumData)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{
+		"github.com/fatih/faillint",
+		"golang.org/x/tools/cmd/goimports",
+		"golang.org/x/tools/gopls",
+		"github.com/x/replaced/cmd/foo",
+		"github.com/x/nosum",
+		"github.com/x/unknown/cmd/bar",
+		"github.com/my/project/cmd/gen",
+	}, tools.tools)
+
+	for _, tcase := range []struct {
+		pkgPath string
+
+		expected       importCandidate
+		expectedReason string
+	}{
+		{
+			pkgPath:  "github.com/fatih/faillint",
+			expected: importCandidate{name: "faillint", pkgPath: "github.com/fatih/faillint", version: "v1.5.0"},
+		},
+		{
+			pkgPath:  "golang.org/x/tools/cmd/goimports",
+			expected: importCandidate{name: "goimports", pkgPath: "golang.org/x/tools/cmd/goimports", version: "v0.1.0"},
+		},
+		{
+			pkgPath:  "golang.org/x/tools/gopls",
+			expected: importCandidate{name: "gopls", pkgPath: "golang.org/x/tools/gopls", version: "v0.7.0"},
+		},
+		{
+			pkgPath:        "github.com/x/replaced/cmd/foo",
+			expectedReason: "module github.com/x/replaced is replaced in go.mod",
+		},
+		{
+			pkgPath:        "github.com/x/nosum",
+			expectedReason: "missing go.sum entry for github.com/x/nosum@v1.0.0; run go mod tidy",
+		},
+		{
+			pkgPath:        "github.com/x/unknown/cmd/bar",
+			expectedReason: "no require directive for module providing it; run go mod tidy",
+		},
+		{
+			pkgPath:        "github.com/my/project/cmd/gen",
+			expectedReason: "part of the main module github.com/my/project, no released version to pin",
+		},
+	} {
+		t.Run(tcase.pkgPath, func(t *testing.T) {
+			c, reason := tools.candidateFor(tcase.pkgPath)
+			testutil.Equals(t, tcase.expectedReason, reason)
+			testutil.Equals(t, tcase.expected, c)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/efficientgo/core/errors"
+)
+
+// maxPromptedVersions is a maximum number of the newest versions listed in the prompt. Older ones can be still typed in.
+const maxPromptedVersions = 10
+
+// versionPicker picks the version of the module to pin from its released versions, ordered as go list -m -versions
+// returns them (oldest first).
+type versionPicker func(modPath string, versions []string) (string, error)
+
+// isTerminal returns true if f is a terminal (character device), e.g. stdin of interactive shell and not a pipe or file in CI.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// promptVersion returns versionPicker that lists the newest versions in out and reads the choice from in, either number
+// from the list or any released version. Empty choice picks the latest release, like go get without version.
+// Prompts are serialized, so it's safe to use by tools got concurrently.
+func promptVersion(in io.Reader, out io.Writer) versionPicker {
+	var (
+		mtx sync.Mutex
+		r   = bufio.NewReader(in)
+	)
+	return func(modPath string, versions []string) (string, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		listed := make([]string, 0, maxPromptedVersions)
+		for i := len(versions) - 1; i >= 0 && len(listed) < maxPromptedVersions; i-- {
+			listed = append(listed, versions[i])
+		}
+		def := 0
+		if latest, err := selectUpdateVersion(versions, "", false); err == nil {
+			for i, v := range listed {
+				if v == latest {
+					def = i
+				}
+			}
+		}
+
+		fmt.Fprintf(out, "Versions of %v (newest first):\n", modPath)
+		for i, v := range listed {
+			suffix := ""
+			if i == def {
+				suffix = " (latest)"
+			}
+			fmt.Fprintf(out, "  %d) %v%v\n", i+1, v, suffix)
+		}
+		if len(versions) > len(listed) {
+			fmt.Fprintf(out, "  ... and %d older; type the version to pick one of them\n", len(versions)-len(listed))
+		}
+		for {
+			fmt.Fprintf(out, "Pick version [1-%d, default %d]: ", len(listed), def+1)
+			line, err := r.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				return "", errors.Wrap(err, "read choice")
+			}
+			choice := strings.TrimSpace(line)
+			if choice == "" {
+				return listed[def], nil
+			}
+			if n, err := strconv.Atoi(choice); err == nil && n >= 1 && n <= len(listed) {
+				return listed[n-1], nil
+			}
+			for _, v := range versions {
+				if v == choice {
+					return v, nil
+				}
+			}
+			fmt.Fprintf(out, "Invalid choice %q.\n", choice)
+		}
+	}
+}
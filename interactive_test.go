@@ -0,0 +1,58 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestPromptVersion(t *testing.T) {
+	versions := []string{"v0.1.0", "v0.2.0", "v0.3.0", "v0.4.0", "v0.5.0", "v0.6.0", "v0.7.0", "v0.8.0", "v0.9.0", "v1.0.0", "v1.1.0", "v1.2.0-rc.1"}
+	for _, tcase := range []struct {
+		name  string
+		input string
+
+		expected    string
+		expectedErr bool
+	}{
+		{name: "default is latest release", input: "\n", expected: "v1.1.0"},
+		{name: "number", input: "3\n", expected: "v1.0.0"},
+		{name: "pre-release by number", input: "1\n", expected: "v1.2.0-rc.1"},
+		{name: "older version not listed", input: "v0.1.0\n", expected: "v0.1.0"},
+		{name: "invalid choice asks again", input: "11\nv9.9.9\n2", expected: "v1.1.0"},
+		{name: "no choice", input: "yolo\n", expectedErr: true},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			var out bytes.Buffer
+			v, err := promptVersion(strings.NewReader(tcase.input), &out)("github.com/x/tool", versions)
+			if tcase.expectedErr {
+				testutil.NotOk(t, err)
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, v)
+		})
+	}
+
+	var out bytes.Buffer
+	_, err := promptVersion(strings.NewReader("\n"), &out)("github.com/x/tool", versions)
+	testutil.Ok(t, err)
+	testutil.Equals(t, `Versions of github.com/x/tool (newest first):
+  1) v1.2.0-rc.1
+  2) v1.1.0 (latest)
+  3) v1.0.0
+  4) v0.9.0
+  5) v0.8.0
+  6) v0.7.0
+  7) v0.6.0
+  8) v0.5.0
+  9) v0.4.0
+  10) v0.3.0
+  ... and 2 older; type the version to pick one of them
+Pick version [1-10, default 2]: `, out.String())
+}
@@ -0,0 +1,40 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+// Package fakecmd writes fake commands (e.g. go) as shell scripts for tests, so they don't need network, module cache
+// or the real commands installed.
+package fakecmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+// Write writes executable shell script with the given name and body into dir and returns its path.
+func Write(t testing.TB, dir, name, script string) string {
+	t.Helper()
+
+	cmd := filepath.Join(dir, name)
+	testutil.Ok(t, os.WriteFile(cmd, []byte("#!/bin/sh\n"+script), 0700))
+	return cmd
+}
+
+// Go writes fake go command into dir and returns its path. By default, it reports go1.21.0 on linux/amd64, lists every
+// package as main and builds binaries with "bin" content. Given case arms of the go subcommand (e.g. `build) ... ;;`)
+// take precedence over the defaults. Every call is recorded in $GO_CALLS_FILE, if set.
+func Go(t testing.TB, dir, arms string) string {
+	t.Helper()
+
+	return Write(t, dir, "go", `[ -z "$GO_CALLS_FILE" ] || echo "$@" >> "$GO_CALLS_FILE"
+case "$1" in
+`+arms+`
+  version) echo "go version go1.21.0 linux/amd64" ;;
+  list) echo main ;;
+  env) echo linux; echo amd64 ;;
+  build) for a in "$@"; do case "$a" in -o=*) echo bin > "${a#-o=}" ;; esac; done ;;
+esac
+`)
+}
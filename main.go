@@ -10,14 +10,31 @@ import (
 	"os"
 
 	"github.com/bwplotka/bingo/builtin"
+	"github.com/bwplotka/bingo/pkg/logging"
 
 	"github.com/spf13/cobra"
 )
 
 var verbose bool
 var moddir string
+var logLevel = logLevelFlag{level: logging.LevelInfo}
 
-func NewBingoCommand(logger *log.Logger) *cobra.Command {
+// logLevelFlag is a value of --log-level flag.
+type logLevelFlag struct {
+	level logging.Level
+}
+
+func (f *logLevelFlag) String() string { return f.level.String() }
+
+func (f *logLevelFlag) Set(s string) (err error) {
+	f.level, err = logging.ParseLevel(s)
+	return err
+}
+
+func (f *logLevelFlag) Type() string { return "level" }
+
+func NewBingoCommand(stdLogger *log.Logger) *cobra.Command {
+	logger := logging.NewStd(stdLogger, logging.LevelInfo)
 	cmd := &cobra.Command{
 		Use: "bingo",
 		Long: `bingo: 'go get' like, simple CLI that allows automated versioning of 
@@ -30,17 +47,62 @@ For detailed examples and documentation see: https://github.com/bwplotka/bingo
 `,
 	}
 	flags := cmd.PersistentFlags()
-	flags.BoolVarP(&verbose, "verbose", "v", false, "Print more")
+	flags.BoolVarP(&verbose, "verbose", "v", false, "Print more. Implies --log-level=debug.")
+	flags.Var(&logLevel, "log-level", "Minimum level of logged messages. One of: debug, info, warn, error. Use warn to hide progress\n"+
+		"messages, e.g. in CI logs. Debug messages include go commands bingo runs and their output.")
 	flags.StringVarP(&moddir, "moddir", "m", ".bingo", "Directory where separate modules for each binary will be maintained. \n"+
 		"Feel free to commit this directory to your VCS to bond binary versions to your project code. \n"+
 		"If the directory does not exist bingo logs and assumes a fresh project.")
 	cmd.AddCommand(NewBingoGetCommand(logger))
+	cmd.AddCommand(NewBingoDiffCommand(logger))
 	cmd.AddCommand(NewBingoListCommand(logger))
+	cmd.AddCommand(NewBingoImportCommand(logger))
+	cmd.AddCommand(NewBingoMigrateCommand(logger))
+	cmd.AddCommand(NewBingoCleanCommand(logger))
+	cmd.AddCommand(NewBingoRenameCommand(logger))
+	cmd.AddCommand(NewBingoOutdatedCommand(logger))
+	cmd.AddCommand(NewBingoVerifyCommand(logger))
+	cmd.AddCommand(NewBingoCheckCommand(logger))
+	cmd.AddCommand(NewBingoEnvCommand(logger))
+	cmd.AddCommand(NewBingoGenVarsCommand(logger))
+	cmd.AddCommand(NewBingoDoctorCommand(logger))
 	cmd.AddCommand(NewBingoVersionCommand())
+	cmd.AddCommand(NewBingoCompletionCommand())
+	// Replaced by our completion command, which supports only shells with dynamic completion of tool names.
+	cmd.CompletionOptions.DisableDefaultCmd = true
 	cmd.SetUsageTemplate(builtin.CommandHelpTemplate)
+	for _, c := range cmd.Commands() {
+		withConfigDefaults(logger, c)
+	}
+	cobra.OnInitialize(func() {
+		// Flags are parsed at this point.
+		if verbose {
+			logger.SetLevel(logging.LevelDebug)
+			return
+		}
+		logger.SetLevel(logLevel.level)
+	})
 	return cmd
 }
 
+// withConfigDefaults makes the command apply defaults from the config file (see loadConfig) to its flags before running.
+func withConfigDefaults(logger logging.Logger, cmd *cobra.Command) {
+	preRun := cmd.PersistentPreRunE
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		values, path, err := loadConfig(logger, moddir)
+		if err != nil {
+			return err
+		}
+		if err := applyConfig(logger, cmd, path, values); err != nil {
+			return err
+		}
+		if preRun == nil {
+			return nil
+		}
+		return preRun(cmd, args)
+	}
+}
+
 func main() {
 	logger := log.New(os.Stderr, "", 0)
 	rootCmd := NewBingoCommand(logger)
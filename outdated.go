@@ -0,0 +1,167 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/merrors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// maxMajorLookups limits how many following major versions (e.g. /v2, /v3...) are looked up for a single module.
+const maxMajorLookups = 10
+
+// outdatedEntry is a pinned tool version with newer version available.
+type outdatedEntry struct {
+	name    string
+	modPath string
+	current string
+	// latest is the latest released version of the current module path. It might be a major bump for +incompatible versions.
+	latest string
+	// newMajor is <module path>@<version> of the latest major version released under different module path (e.g. with /v2
+	// suffix), if any. bingo get --update can't upgrade to such version.
+	newMajor string
+}
+
+// isMajorBump returns true if latest version has different major version than the current one.
+func (e outdatedEntry) isMajorBump() bool {
+	return semver.Major(e.latest) != semver.Major(e.current)
+}
+
+// moduleVersions lists released versions of modules, querying each module with the same fetch settings only once.
+type moduleVersions struct {
+	runnable func(envs envars.EnvSlice) runner.Runnable
+
+	cache map[string]cachedModuleVersions
+}
+
+type cachedModuleVersions struct {
+	versions []string
+	err      error
+}
+
+func newModuleVersions(runnable func(envs envars.EnvSlice) runner.Runnable) *moduleVersions {
+	return &moduleVersions{runnable: runnable, cache: map[string]cachedModuleVersions{}}
+}
+
+func (m *moduleVersions) versions(modPath string, envs envars.EnvSlice) ([]string, error) {
+	key := strings.Join(append([]string{modPath}, envs...), " ")
+	if c, ok := m.cache[key]; ok {
+		return c.versions, c.err
+	}
+	versions, err := m.runnable(envs).ModVersions(modPath)
+	m.cache[key] = cachedModuleVersions{versions: versions, err: err}
+	return versions, err
+}
+
+// majorPath returns module path of the given major version (2 or higher) of the module, e.g. github.com/x/tool/v3 for
+// github.com/x/tool or github.com/x/tool/v2 and 3. It returns empty string if path is not a valid module path.
+func majorPath(modPath string, major int) string {
+	prefix, _, ok := module.SplitPathVersion(modPath)
+	if !ok {
+		return ""
+	}
+	if strings.HasPrefix(modPath, "gopkg.in/") {
+		return prefix + ".v" + strconv.Itoa(major)
+	}
+	return prefix + "/v" + strconv.Itoa(major)
+}
+
+// majorOf returns major version number of the given version, e.g. 3 for v3.1.1+incompatible.
+func majorOf(version string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(semver.Major(version), "v"))
+	return n
+}
+
+// outdatedTools returns pinned tool versions for which newer released version is available. Pre-releases and tools built
+// from local replace are skipped. Tools that failed to be checked are reported in the returned error.
+func outdatedTools(mv *moduleVersions, pkgs bingo.PackageRenderables) ([]outdatedEntry, error) {
+	var entries []outdatedEntry
+	merr := merrors.New()
+	for _, p := range pkgs {
+		if p.Additional {
+			// Shares module and version with the direct package of the tool.
+			continue
+		}
+		envs, err := bingo.PrivateModuleEnvs(p.ModPath, os.Getenv(bingo.PrivateModulesEnv), bingo.Package{BuildEnvs: p.BuildEnvVars}.ModuleFetchEnvs())
+		if err != nil {
+			merr.Add(errors.Wrap(err, p.Name))
+			continue
+		}
+		for _, v := range p.Versions {
+			if v.Version == bingo.LocalReplaceVersion {
+				continue
+			}
+
+			versions, err := mv.versions(p.ModPath, envs)
+			if err != nil {
+				merr.Add(errors.Wrapf(err, "%v: list versions of %v", p.Name, p.ModPath))
+				continue
+			}
+			e := outdatedEntry{name: p.Name, modPath: p.ModPath, current: v.Version, latest: v.Version}
+			if latest, err := selectUpdateVersion(versions, "", false); err == nil && semver.Compare(latest, e.latest) > 0 {
+				e.latest = latest
+			}
+
+			// Following majors with /vN suffix, starting after the latest major available under the current path.
+			next := majorOf(e.latest) + 1
+			if next < 2 {
+				next = 2
+			}
+			for i := 0; i < maxMajorLookups; i++ {
+				nextPath := majorPath(p.ModPath, next+i)
+				if nextPath == "" || nextPath == p.ModPath {
+					break
+				}
+				versions, err := mv.versions(nextPath, envs)
+				if err != nil {
+					if errors.Is(err, runner.ErrNetwork) {
+						merr.Add(errors.Wrapf(err, "%v: list versions of %v", p.Name, nextPath))
+					}
+					// Most likely no such major version.
+					break
+				}
+				latest, err := selectUpdateVersion(versions, "", false)
+				if err != nil {
+					break
+				}
+				e.newMajor = nextPath + "@" + latest
+			}
+
+			if e.latest != e.current || e.newMajor != "" {
+				entries = append(entries, e)
+			}
+		}
+	}
+	return entries, merr.Err()
+}
+
+func printOutdated(entries []outdatedEntry, w io.Writer) {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 1, 8, 1, '\t', tabwriter.AlignRight)
+	defer func() { _ = tw.Flush() }()
+
+	_, _ = fmt.Fprint(tw, "Name\tModule\tCurrent\tLatest\tMajor Bump\n----\t------\t-------\t------\t----------\n")
+	for _, e := range entries {
+		major := ""
+		switch {
+		case e.newMajor != "":
+			major = e.newMajor + " (new module path, update manually with bingo get)"
+		case e.isMajorBump():
+			major = "yes"
+		}
+		_, _ = fmt.Fprintln(tw, strings.Join([]string{e.name, e.modPath, e.current, e.latest, major}, "\t"))
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/testutil"
+)
+
+// modVersionsRunnable lists module versions from the map keyed by module path and counts queries.
+type modVersionsRunnable struct {
+	runner.Runnable
+
+	versions map[string][]string
+	errs     map[string]error
+	queries  map[string]int
+}
+
+func (r modVersionsRunnable) ModVersions(modulePath string) ([]string, error) {
+	r.queries[modulePath]++
+	if err, ok := r.errs[modulePath]; ok {
+		return nil, err
+	}
+	if v, ok := r.versions[modulePath]; ok {
+		return v, nil
+	}
+	return nil, errors.Newf("%v: 404 Not Found", modulePath)
+}
+
+func TestMajorPath(t *testing.T) {
+	testutil.Equals(t, "github.com/x/tool/v2", majorPath("github.com/x/tool", 2))
+	testutil.Equals(t, "github.com/x/tool/v4", majorPath("github.com/x/tool/v3", 4))
+	testutil.Equals(t, "gopkg.in/yaml.v3", majorPath("gopkg.in/yaml.v2", 3))
+	testutil.Equals(t, "", majorPath("gopkg.in/yaml", 3))
+}
+
+func TestOutdatedTools(t *testing.T) {
+	r := modVersionsRunnable{
+		versions: map[string][]string{
+			"github.com/fatih/faillint":            {"v1.4.0", "v1.5.0", "v1.6.0-rc.1"},
+			"github.com/x/uptodate":                {"v0.1.0"},
+			"github.com/x/major":                   {"v1.0.0", "v1.2.0"},
+			"github.com/x/major/v2":                {"v2.0.0", "v2.1.0"},
+			"github.com/x/major/v3":                {"v3.0.0-rc.1"},
+			"github.com/x/incompatible":            {"v1.0.0", "v3.1.1+incompatible"},
+			"github.com/x/pseudo":                  {},
+			"github.com/x/private":                 {"v1.1.0"},
+			"github.com/x/private/v2":              {"v2.0.0"},
+			"github.com/golangci/golangci-lint/v2": {"v2.0.0"},
+		},
+		errs: map[string]error{
+			"github.com/x/broken": errors.New("boom"),
+		},
+		queries: map[string]int{},
+	}
+	var usedEnvs []envars.EnvSlice
+	mv := newModuleVersions(func(envs envars.EnvSlice) runner.Runnable {
+		usedEnvs = append(usedEnvs, envs)
+		return r
+	})
+
+	pkgs := bingo.PackageRenderables{
+		{Name: "faillint", ModPath: "github.com/fatih/faillint", Versions: []bingo.PackageVersionRenderable{{Version: "v1.4.0"}, {Version: "v1.5.0"}}},
+		{Name: "uptodate", ModPath: "github.com/x/uptodate", Versions: []bingo.PackageVersionRenderable{{Version: "v0.1.0"}}},
+		{Name: "major", ModPath: "github.com/x/major", Versions: []bingo.PackageVersionRenderable{{Version: "v1.0.0"}}},
+		{Name: "incompatible", ModPath: "github.com/x/incompatible", Versions: []bingo.PackageVersionRenderable{{Version: "v1.0.0"}}},
+		{Name: "pseudo", ModPath: "github.com/x/pseudo", Versions: []bingo.PackageVersionRenderable{{Version: "v0.0.0-20200519204825-abc123456789"}}},
+		{Name: "local", ModPath: "github.com/x/local", Versions: []bingo.PackageVersionRenderable{{Version: bingo.LocalReplaceVersion}}},
+		{Name: "broken", ModPath: "github.com/x/broken", Versions: []bingo.PackageVersionRenderable{{Version: "v1.0.0"}}},
+		{
+			Name: "private", ModPath: "github.com/x/private", Versions: []bingo.PackageVersionRenderable{{Version: "v1.1.0"}},
+			BuildEnvVars: []string{"GOPROXY=https://internal", "CGO_ENABLED=0"},
+		},
+	}
+
+	entries, err := outdatedTools(mv, pkgs)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, []outdatedEntry{
+		{name: "faillint", modPath: "github.com/fatih/faillint", current: "v1.4.0", latest: "v1.5.0"},
+		{name: "major", modPath: "github.com/x/major", current: "v1.0.0", latest: "v1.2.0", newMajor: "github.com/x/major/v2@v2.1.0"},
+		{name: "incompatible", modPath: "github.com/x/incompatible", current: "v1.0.0", latest: "v3.1.1+incompatible"},
+		{name: "private", modPath: "github.com/x/private", current: "v1.1.0", latest: "v1.1.0", newMajor: "github.com/x/private/v2@v2.0.0"},
+	}, entries)
+
+	// Each module is queried once, even if pinned in multiple versions.
+	testutil.Equals(t, 1, r.queries["github.com/fatih/faillint"])
+	testutil.Equals(t, 1, r.queries["github.com/fatih/faillint/v2"])
+	// Fetch settings of the tool are used.
+	testutil.Equals(t, envars.EnvSlice{"GOPROXY=https://internal"}, usedEnvs[len(usedEnvs)-1])
+
+	b := bytes.Buffer{}
+	printOutdated(entries[1:3], &b)
+	testutil.Equals(t, "Name\t\tModule\t\t\t\tCurrent\tLatest\t\t\tMajor Bump\n"+
+		"----\t\t------\t\t\t\t-------\t------\t\t\t----------\n"+
+		"major\t\tgithub.com/x/major\t\tv1.0.0\tv1.2.0\t\t\tgithub.com/x/major/v2@v2.1.0 (new module path, update manually with bingo get)\n"+
+		"incompatible\tgithub.com/x/incompatible\tv1.0.0\tv3.1.1+incompatible\tyes\n", b.String())
+}
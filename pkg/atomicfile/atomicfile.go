@@ -0,0 +1,73 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+// Package atomicfile writes files atomically, so readers (e.g. next bingo run after interrupted one) see either the old or
+// the new complete content and never a truncated file.
+package atomicfile
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// rename moves the temporary file into place. It's a variable, so tests can simulate failure between write and rename.
+var rename = renameFile
+
+// WriteFile writes data to the file like os.WriteFile, but into temporary file in the same directory first, which is
+// then renamed into place. The existing file keeps its permissions, a new one is created with perm (before umask).
+// The file should not be held open during WriteFile, since some systems (e.g. Windows) do not allow replacing open files.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return Write(path, perm, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Write is like WriteFile, but content is written by the given function, e.g. template execution.
+func Write(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	tmpPath := path + "." + strconv.Itoa(os.Getpid()) + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return errors.Wrap(err, "create temporary file")
+	}
+	defer func() {
+		if err != nil {
+			// Best effort, temporary file is not needed anymore.
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if err := writeAndSync(f, write); err != nil {
+		errcapture.Do(&err, f.Close, "close")
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "close")
+	}
+	if info, err := os.Stat(path); err == nil {
+		if err := os.Chmod(tmpPath, info.Mode().Perm()); err != nil {
+			return errors.Wrap(err, "chmod")
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.Wrap(err, "stat")
+	}
+	if err := rename(tmpPath, path); err != nil {
+		return errors.Wrap(err, "rename")
+	}
+	return nil
+}
+
+func writeAndSync(f *os.File, write func(w io.Writer) error) error {
+	if err := write(f); err != nil {
+		return errors.Wrap(err, "write")
+	}
+	// Sync, so the renamed file is not empty after crash on file systems that reorder metadata and data writes.
+	if err := f.Sync(); err != nil {
+		return errors.Wrap(err, "sync")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "variables.env")
+
+	testutil.Ok(t, WriteFile(path, []byte("old"), 0666))
+	testutil.Ok(t, os.Chmod(path, 0600))
+	testutil.Ok(t, WriteFile(path, []byte("new content"), 0666))
+
+	b, err := os.ReadFile(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "new content", string(b))
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		testutil.Ok(t, err)
+		testutil.Equals(t, os.FileMode(0600), info.Mode().Perm())
+	}
+	expectOnly(t, dir, "variables.env")
+}
+
+func TestWriteFile_RenameFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tool.mod")
+	testutil.Ok(t, os.WriteFile(path, []byte("module _\n"), 0666))
+
+	// Simulate interruption after temporary file is written, but before it's renamed.
+	rename = func(string, string) error { return errors.New("interrupted") }
+	t.Cleanup(func() { rename = renameFile })
+
+	testutil.NotOk(t, WriteFile(path, []byte("module _\n\ngo 1.21\n"), 0666))
+	b, err := os.ReadFile(path)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "module _\n", string(b))
+	expectOnly(t, dir, "tool.mod")
+}
+
+func expectOnly(t *testing.T, dir string, name string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(entries))
+	testutil.Equals(t, name, entries[0].Name())
+}
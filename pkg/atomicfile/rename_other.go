@@ -0,0 +1,13 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+//go:build !windows
+
+package atomicfile
+
+import "os"
+
+// renameFile replaces the existing file atomically (rename(2)).
+func renameFile(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
@@ -0,0 +1,36 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package atomicfile
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	renameRetries       = 10
+	renameRetryInterval = 50 * time.Millisecond
+)
+
+// renameFile replaces the existing file (os.Rename uses MoveFileEx with MOVEFILE_REPLACE_EXISTING). It retries if the
+// existing file is still open (e.g. by antivirus or indexer), which is common and short-lived on Windows.
+func renameFile(oldPath, newPath string) (err error) {
+	for i := 0; i < renameRetries; i++ {
+		if err = os.Rename(oldPath, newPath); err == nil || !isSharingErr(err) {
+			return err
+		}
+		time.Sleep(renameRetryInterval)
+	}
+	return err
+}
+
+func isSharingErr(err error) bool {
+	le, ok := err.(*os.LinkError)
+	if !ok {
+		return false
+	}
+	return le.Err == windows.ERROR_ACCESS_DENIED || le.Err == windows.ERROR_SHARING_VIOLATION
+}
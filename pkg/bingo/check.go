@@ -0,0 +1,71 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// CheckModFiles checks that module files in modDir are well-formed bingo module files: they parse, have the module line
+// bingo writes, go directive and direct require with valid build attributes (see ModFile.Validate), and are named after
+// valid binary name. Nothing is fetched or installed, so it's fast enough e.g. for pre-commit hooks.
+// It returns number of checked module files and problems found, at most one per module file, sorted by module file.
+func CheckModFiles(modDir string) (checked int, problems []error, _ error) {
+	modFiles, err := filepath.Glob(filepath.Join(modDir, "*.mod"))
+	if err != nil {
+		return 0, nil, err
+	}
+	sort.Strings(modFiles)
+	for _, f := range modFiles {
+		// Temporary module files are left only by interrupted bingo get and are not used by other commands.
+		if filepath.Base(f) == FakeRootModFileName || isTmpModFile(f) {
+			continue
+		}
+		checked++
+		if err := checkModFile(f); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	return checked, problems, nil
+}
+
+// checkModFile returns error describing the first problem of the module file, if any. See CheckModFiles.
+func checkModFile(modFile string) (err error) {
+	name, _ := NameFromModFile(modFile)
+	if err := ValidateBinaryName(name); err != nil {
+		return errors.Wrapf(err, "%s: module file has to be named after the tool", modFile)
+	}
+
+	f, err := mod.OpenFileForRead(modFile)
+	if err != nil {
+		return errors.Wrapf(err, "%s: not a valid module file", modFile)
+	}
+	defer errcapture.Do(&err, f.Close, "close")
+
+	// Only read the file: OpenModFile would fix the module line silently and rewrite the file in parsed form.
+	if _, comment := f.Module(); comment != metaComment {
+		return errors.Newf("%s: not a bingo module file; module line has to have %q comment", modFile, metaComment)
+	}
+	if f.GoVersion() == "" {
+		return errors.Newf("%s: missing go directive", modFile)
+	}
+	if !hasDirectRequire(f) {
+		return errors.Newf("%s: no direct require found; empty module?", modFile)
+	}
+	return validateModFile(f)
+}
+
+func hasDirectRequire(f mod.FileForRead) bool {
+	for _, r := range f.RequireDirectives() {
+		if !r.Indirect {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestCheckModFiles(t *testing.T) {
+	modDir := t.TempDir()
+	for name, content := range map[string]string{
+		FakeRootModFileName: "module _\n",
+		"ok.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/ok v1.0.0 // cmd/ok CGO_ENABLED=0 -tags=x
+`,
+		// Spacing not as bingo would write it, so rewrite would be noticed.
+		"ok.1.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require   github.com/x/ok   v1.1.0   // cmd/ok
+`,
+		"ok.tmp.mod": "broken",
+		"syntax.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+requir github.com/x/syntax v1.0.0
+`,
+		"foreign.mod": `module github.com/x/foreign
+
+go 1.14
+
+require github.com/x/tool v1.0.0
+`,
+		"nogo.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+require github.com/x/nogo v1.0.0
+`,
+		"empty.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+`,
+		"attrs.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/attrs v1.0.0 // -o=bin
+`,
+		"bad name.mod": `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v1.0.0
+`,
+	} {
+		testutil.Ok(t, os.WriteFile(filepath.Join(modDir, name), []byte(content), os.ModePerm))
+	}
+
+	before, err := os.ReadFile(filepath.Join(modDir, "ok.1.mod"))
+	testutil.Ok(t, err)
+
+	checked, problems, err := CheckModFiles(modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 8, checked)
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, strings.TrimPrefix(p.Error(), modDir+string(filepath.Separator)))
+	}
+	testutil.Equals(t, 6, len(got), strings.Join(got, "\n"))
+	for i, expected := range []string{
+		`attrs.mod:5: require github.com/x/attrs@v1.0.0: build flag "-o=bin" is set by bingo and can't be overridden`,
+		`bad name.mod: module file has to be named after the tool: binary name "bad name"`,
+		`empty.mod: no direct require found; empty module?`,
+		`foreign.mod: not a bingo module file; module line has to have`,
+		`nogo.mod: missing go directive`,
+		`syntax.mod: not a valid module file: parse:`,
+	} {
+		testutil.Assert(t, strings.HasPrefix(got[i], expected), "expected %q prefix, got %q", expected, got[i])
+	}
+
+	// Checks are read only.
+	after, err := os.ReadFile(filepath.Join(modDir, "ok.1.mod"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, string(before), string(after))
+}
@@ -0,0 +1,165 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bwplotka/bingo/pkg/atomicfile"
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// BinChecksumFileName is a name of the file in mod directory that records checksums of installed binaries.
+// Each line has "<name> <version> <goos>/<goarch> <sha256 hex>" form and lines are sorted, so it's easy to review.
+const BinChecksumFileName = ".bingosum"
+
+// ErrBinChecksumMismatch means binary does not match checksum recorded in the checksum file.
+var ErrBinChecksumMismatch = errors.New("checksum mismatch")
+
+// binChecksumMtx guards checksum file against concurrent installs within the process.
+var binChecksumMtx sync.Mutex
+
+// BinChecksumKey identifies binary in checksum file.
+type BinChecksumKey struct {
+	Name    string
+	Version string
+	GOOS    string
+	GOARCH  string
+}
+
+func (k BinChecksumKey) String() string {
+	return fmt.Sprintf("%s %s %s/%s", k.Name, k.Version, k.GOOS, k.GOARCH)
+}
+
+func binChecksum(binPath string) (_ string, err error) {
+	f, err := os.Open(binPath)
+	if err != nil {
+		return "", err
+	}
+	defer errcapture.Do(&err, f.Close, "close")
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrapf(err, "hash %v", binPath)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readBinChecksums(modDir string) (_ map[string]string, err error) {
+	sums := map[string]string{}
+
+	f, err := os.Open(filepath.Join(modDir, BinChecksumFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sums, nil
+		}
+		return nil, err
+	}
+	defer errcapture.Do(&err, f.Close, "close")
+
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		t := strings.TrimSpace(s.Text())
+		if t == "" {
+			continue
+		}
+		i := strings.LastIndex(t, " ")
+		if i < 0 {
+			return nil, errors.Newf("%v:%d: malformed line %q", BinChecksumFileName, line, t)
+		}
+		sums[t[:i]] = t[i+1:]
+	}
+	return sums, s.Err()
+}
+
+// WriteBinChecksum computes SHA-256 of the given binary and records it in the checksum file in modDir.
+// Existing entry for the same key is replaced.
+func WriteBinChecksum(modDir string, key BinChecksumKey, binPath string) error {
+	sum, err := binChecksum(binPath)
+	if err != nil {
+		return err
+	}
+	return updateBinChecksums(modDir, func(sums map[string]string) bool {
+		sums[key.String()] = sum
+		return true
+	})
+}
+
+// RemoveBinChecksum removes checksum recorded for the key, so binary is considered unverified. It's a no-op if there is none.
+func RemoveBinChecksum(modDir string, key BinChecksumKey) error {
+	return updateBinChecksums(modDir, func(sums map[string]string) bool {
+		if _, ok := sums[key.String()]; !ok {
+			return false
+		}
+		delete(sums, key.String())
+		return true
+	})
+}
+
+// updateBinChecksums reads the checksum file, applies update and writes the file back if update returns true. The file is
+// locked during that (see mod.Lock), so concurrent bingo processes don't lose each other's entries, and written atomically,
+// so readers never see it partially written.
+func updateBinChecksums(modDir string, update func(sums map[string]string) bool) (err error) {
+	// File lock is reentrant within the process, so concurrent installs of this process are serialized by the mutex.
+	binChecksumMtx.Lock()
+	defer binChecksumMtx.Unlock()
+
+	l, err := mod.Lock(filepath.Join(modDir, BinChecksumFileName), mod.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer errcapture.Do(&err, l.Unlock, "unlock")
+
+	sums, err := readBinChecksums(modDir)
+	if err != nil {
+		return errors.Wrap(err, "read checksums")
+	}
+	if !update(sums) {
+		return nil
+	}
+
+	lines := make([]string, 0, len(sums))
+	for k, v := range sums {
+		lines = append(lines, k+" "+v)
+	}
+	sort.Strings(lines)
+	return atomicfile.WriteFile(filepath.Join(modDir, BinChecksumFileName), []byte(strings.Join(lines, "\n")+"\n"), 0666)
+}
+
+// VerifyBinChecksum checks if the given binary matches the checksum recorded for the key.
+// It returns false if there is no checksum recorded and error if checksum does not match.
+func VerifyBinChecksum(modDir string, key BinChecksumKey, binPath string) (recorded bool, _ error) {
+	binChecksumMtx.Lock()
+	sums, err := readBinChecksums(modDir)
+	binChecksumMtx.Unlock()
+	if err != nil {
+		return false, errors.Wrap(err, "read checksums")
+	}
+
+	expected, ok := sums[key.String()]
+	if !ok {
+		return false, nil
+	}
+
+	sum, err := binChecksum(binPath)
+	if err != nil {
+		return true, err
+	}
+	if sum != expected {
+		return true, errors.Wrapf(ErrBinChecksumMismatch, "%v (%v): got %v, recorded in %v %v; binary might be tampered with; "+
+			"delete it or rerun with --force to rebuild it", binPath, key.String(), sum, BinChecksumFileName, expected)
+	}
+	return true, nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestBinChecksum(t *testing.T) {
+	modDir := t.TempDir()
+	bin := filepath.Join(t.TempDir(), "faillint-v1.5.0")
+	testutil.Ok(t, os.WriteFile(bin, []byte("binary"), os.ModePerm))
+
+	key := BinChecksumKey{Name: "faillint", Version: "v1.5.0", GOOS: "linux", GOARCH: "amd64"}
+
+	recorded, err := VerifyBinChecksum(modDir, key, bin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, recorded)
+
+	testutil.Ok(t, WriteBinChecksum(modDir, key, bin))
+	testutil.Ok(t, WriteBinChecksum(modDir, BinChecksumKey{Name: "buildable", Version: "v1.0.0", GOOS: "darwin", GOARCH: "arm64"}, bin))
+	expectContent(t, `buildable v1.0.0 darwin/arm64 9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd
+faillint v1.5.0 linux/amd64 9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd
+`, filepath.Join(modDir, BinChecksumFileName))
+
+	recorded, err = VerifyBinChecksum(modDir, key, bin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, true, recorded)
+
+	// Different platform is a different binary.
+	recorded, err = VerifyBinChecksum(modDir, BinChecksumKey{Name: "faillint", Version: "v1.5.0", GOOS: "darwin", GOARCH: "arm64"}, bin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, recorded)
+
+	testutil.Ok(t, os.WriteFile(bin, []byte("tampered"), os.ModePerm))
+	recorded, err = VerifyBinChecksum(modDir, key, bin)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, true, recorded)
+
+	// Rewrite replaces the entry.
+	testutil.Ok(t, WriteBinChecksum(modDir, key, bin))
+	expectContent(t, `buildable v1.0.0 darwin/arm64 9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd
+faillint v1.5.0 linux/amd64 d121be3103007b41edf96f8262925f8c7d61894afe9a041843b631f69445bc57
+`, filepath.Join(modDir, BinChecksumFileName))
+
+	testutil.Ok(t, RemoveBinChecksum(modDir, key))
+	testutil.Ok(t, RemoveBinChecksum(modDir, key))
+	expectContent(t, `buildable v1.0.0 darwin/arm64 9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd
+`, filepath.Join(modDir, BinChecksumFileName))
+	recorded, err = VerifyBinChecksum(modDir, key, bin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, recorded)
+
+	// Concurrent writes don't lose entries.
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			testutil.Ok(t, WriteBinChecksum(modDir, BinChecksumKey{Name: fmt.Sprintf("tool%d", i), Version: "v1.0.0", GOOS: "linux", GOARCH: "amd64"}, bin))
+		}(i)
+	}
+	wg.Wait()
+	sums, err := readBinChecksums(modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 21, len(sums))
+}
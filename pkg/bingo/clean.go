@@ -0,0 +1,152 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/semver"
+)
+
+// Orphans returns paths of files left behind by tools that are no longer pinned in modDir:
+//   - versioned binaries (<name>-<version>[-<GOOS>_<GOARCH>]) installed in gobin that no module file pins a binary for,
+//     and links pointing to them,
+//   - sum and meta files in modDir without module file.
+//
+// Files from modDir are returned first. If pruneMod is true, module files without valid direct require (with their sum and meta files) are returned too
+// and their binaries are considered orphaned. Otherwise, binaries named after such module files are kept.
+func Orphans(modDir, gobin string, pruneMod bool) (orphans []string, err error) {
+	modFiles, err := filepath.Glob(filepath.Join(modDir, "*.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	pinned := map[string]struct{}{}
+	modFileBases := map[string]struct{}{}
+	for _, f := range modFiles {
+		if filepath.Base(f) == FakeRootModFileName || isTmpModFile(f) {
+			continue
+		}
+
+		names, err := pinnedBinaryNames(f)
+		if err != nil {
+			if pruneMod {
+				orphans = append(orphans, f)
+				continue
+			}
+			name, _ := NameFromModFile(f)
+			names = []string{name}
+		}
+		for _, n := range names {
+			pinned[n] = struct{}{}
+		}
+		modFileBases[strings.TrimSuffix(f, ".mod")] = struct{}{}
+	}
+
+	for _, ext := range []string{".sum", ".meta"} {
+		files, err := filepath.Glob(filepath.Join(modDir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			if filepath.Base(f) == "go"+ext || isTmpModFile(f) {
+				continue
+			}
+			if _, ok := modFileBases[strings.TrimSuffix(f, ext)]; !ok {
+				orphans = append(orphans, f)
+			}
+		}
+	}
+	sort.Strings(orphans)
+
+	entries, err := os.ReadDir(gobin)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return orphans, nil
+		}
+		return nil, errors.Wrapf(err, "read GOBIN %v", gobin)
+	}
+
+	orphanedBins := map[string]struct{}{}
+	var binOrphans, links []string
+	for _, e := range entries {
+		if e.Type()&os.ModeSymlink != 0 {
+			links = append(links, e.Name())
+			continue
+		}
+		if !e.Type().IsRegular() {
+			continue
+		}
+		name, ok := binaryNameFromVersioned(e.Name())
+		if !ok {
+			// Not installed by bingo.
+			continue
+		}
+		if _, ok := pinned[name]; ok {
+			continue
+		}
+		orphanedBins[e.Name()] = struct{}{}
+		binOrphans = append(binOrphans, filepath.Join(gobin, e.Name()))
+	}
+	for _, l := range links {
+		target, err := os.Readlink(filepath.Join(gobin, l))
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Dir(target)
+		if _, ok := orphanedBins[filepath.Base(target)]; ok && (dir == "." || dir == filepath.Clean(gobin)) {
+			binOrphans = append(binOrphans, filepath.Join(gobin, l))
+		}
+	}
+	sort.Strings(binOrphans)
+	return append(orphans, binOrphans...), nil
+}
+
+// pinnedBinaryNames returns names of all binaries pinned in the given module file.
+func pinnedBinaryNames(modFile string) (_ []string, err error) {
+	mf, err := OpenModFile(modFile)
+	if err != nil {
+		return nil, err
+	}
+	defer errcapture.Do(&err, mf.Close, "close")
+
+	pkgs := mf.DirectPackages()
+	if len(pkgs) == 0 {
+		return nil, errors.Newf("no direct package found in %s; empty module?", modFile)
+	}
+	name, _ := NameFromModFile(modFile)
+	return BinaryNames(name, pkgs)
+}
+
+// binaryNameFromVersioned returns binary name from file name of binary installed by bingo, so
+// <name>-<version>[-<GOOS>_<GOARCH>][.exe]. It returns false if file name is not in this form.
+func binaryNameFromVersioned(fileName string) (string, bool) {
+	s := strings.TrimSuffix(fileName, ".exe")
+	// Name might contain "-v" too, so try all occurrences starting from the first one.
+	for i := strings.Index(s, "-v"); i > 0; {
+		v := s[i+1:]
+		if j := strings.LastIndex(v, "-"); j > 0 && strings.Contains(v[j:], "_") {
+			// Platform suffix, semver pre-release can't contain "_".
+			v = v[:j]
+		}
+		if semver.IsValid(v) {
+			return s[:i], true
+		}
+		next := strings.Index(s[i+1:], "-v")
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", false
+}
+
+func isTmpModFile(f string) bool {
+	return strings.Contains(filepath.Base(f), ".tmp.")
+}
@@ -0,0 +1,85 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestBinaryNameFromVersioned(t *testing.T) {
+	for _, tcase := range []struct {
+		fileName     string
+		expectedName string
+		expectedOk   bool
+	}{
+		{fileName: "faillint-v1.5.0", expectedName: "faillint", expectedOk: true},
+		{fileName: "faillint-v1.5.0.exe", expectedName: "faillint", expectedOk: true},
+		{fileName: "faillint-v1.5.0-linux_arm64", expectedName: "faillint", expectedOk: true},
+		{fileName: "go-bindata-v3.1.1+incompatible", expectedName: "go-bindata", expectedOk: true},
+		{fileName: "kube-vet-v0.0.0-20200101000000-abcdefabcdef", expectedName: "kube-vet", expectedOk: true},
+		{fileName: "buildable-v1.0.0-rc.1", expectedName: "buildable", expectedOk: true},
+		{fileName: "faillint"},
+		{fileName: "faillint-v"},
+		{fileName: "go-vet"},
+		{fileName: "-v1.0.0"},
+	} {
+		t.Run(tcase.fileName, func(t *testing.T) {
+			name, ok := binaryNameFromVersioned(tcase.fileName)
+			testutil.Equals(t, tcase.expectedOk, ok)
+			testutil.Equals(t, tcase.expectedName, name)
+		})
+	}
+}
+
+func TestOrphans(t *testing.T) {
+	modDir := t.TempDir()
+	gobin := t.TempDir()
+
+	for f, content := range map[string]string{
+		"faillint.mod": "module _\n\ngo 1.14\n\nrequire github.com/fatih/faillint v1.5.0\n",
+		"faillint.sum": "",
+		// Array with additional package named after its directory.
+		"buildable.1.mod": "module _\n\ngo 1.14\n\nrequire github.com/bwplotka/bingo v0.1.0 // testdata/module/buildable\n\n// also: testdata/module/buildable2\n",
+		"broken.mod":      "module _\n\ngo 1.14\n",
+		"broken.sum":      "",
+		"removed.sum":     "",
+		"removed.meta":    "[]\n",
+		"go.mod":          "module _\n",
+		"go.sum":          "",
+	} {
+		testutil.Ok(t, os.WriteFile(filepath.Join(modDir, f), []byte(content), os.ModePerm))
+	}
+	for _, f := range []string{"faillint-v1.5.0", "faillint-v1.4.0", "buildable-v0.1.0", "buildable2-v0.1.0", "broken-v1.0.0", "removed-v1.0.0", "removed-v1.0.0-linux_arm64", "go-installed"} {
+		testutil.Ok(t, os.WriteFile(filepath.Join(gobin, f), []byte("binary"), os.ModePerm))
+	}
+	testutil.Ok(t, os.Symlink(filepath.Join(gobin, "removed-v1.0.0"), filepath.Join(gobin, "removed")))
+	testutil.Ok(t, os.Symlink(filepath.Join(gobin, "faillint-v1.5.0"), filepath.Join(gobin, "faillint")))
+
+	orphans, err := Orphans(modDir, gobin, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{
+		filepath.Join(modDir, "removed.meta"),
+		filepath.Join(modDir, "removed.sum"),
+		filepath.Join(gobin, "removed"),
+		filepath.Join(gobin, "removed-v1.0.0"),
+		filepath.Join(gobin, "removed-v1.0.0-linux_arm64"),
+	}, orphans)
+
+	orphans, err = Orphans(modDir, gobin, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{
+		filepath.Join(modDir, "broken.mod"),
+		filepath.Join(modDir, "broken.sum"),
+		filepath.Join(modDir, "removed.meta"),
+		filepath.Join(modDir, "removed.sum"),
+		filepath.Join(gobin, "broken-v1.0.0"),
+		filepath.Join(gobin, "removed"),
+		filepath.Join(gobin, "removed-v1.0.0"),
+		filepath.Join(gobin, "removed-v1.0.0-linux_arm64"),
+	}, orphans)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
+)
+
+// ConstraintsFile is a file in mod directory with module versions that bingo get refuses to pin, one rule per line:
+//
+//	<module path pattern> <version constraint> [# reason]
+//
+// Module path pattern is matched as GOPRIVATE-style prefix pattern (see module.MatchPrefixPatterns), version constraint
+// uses Masterminds/semver syntax (e.g. ">= v1.2.0, < v1.2.5" or "*" for all versions). Empty lines and lines starting
+// with # are ignored.
+const ConstraintsFile = ".bingoconstraints"
+
+// VersionConstraint is a single deny rule from ConstraintsFile.
+type VersionConstraint struct {
+	// Line is a line number of the rule in ConstraintsFile.
+	Line          int
+	ModulePattern string
+	Versions      string
+	Reason        string
+
+	c *semver.Constraints
+}
+
+func (c VersionConstraint) String() string {
+	s := fmt.Sprintf("%v:%d: %v %v", ConstraintsFile, c.Line, c.ModulePattern, c.Versions)
+	if c.Reason != "" {
+		s += " (" + c.Reason + ")"
+	}
+	return s
+}
+
+// Matches returns true if given module version is denied by this rule. Pre-release and pseudo-versions are matched as
+// their release counterpart, so denying a range also denies commits leading to releases in it.
+func (c VersionConstraint) Matches(m module.Version) bool {
+	if !module.MatchPrefixPatterns(c.ModulePattern, m.Path) {
+		return false
+	}
+	v, err := semver.NewVersion(m.Version)
+	if err != nil {
+		return false
+	}
+	release, err := v.SetPrerelease("")
+	if err != nil {
+		return false
+	}
+	return c.c.Check(&release)
+}
+
+// VersionConstraints returns deny rules from ConstraintsFile in the given mod directory, or nil if there is no such file.
+func VersionConstraints(modDir string) ([]VersionConstraint, error) {
+	b, err := os.ReadFile(filepath.Join(modDir, ConstraintsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cs []VersionConstraint
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		c := VersionConstraint{Line: i + 1}
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			c.Reason = strings.TrimSpace(line[idx+1:])
+			line = strings.TrimSpace(line[:idx])
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, errors.Newf("%v:%d: expected <module path pattern> <version constraint>, got %q", ConstraintsFile, i+1, line)
+		}
+		c.ModulePattern = fields[0]
+		c.Versions = strings.Join(fields[1:], " ")
+		if c.c, err = semver.NewConstraint(c.Versions); err != nil {
+			return nil, errors.Wrapf(err, "%v:%d: version constraint %q", ConstraintsFile, i+1, c.Versions)
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// DeniedBy returns the first of given rules that denies the module version, if any.
+func DeniedBy(cs []VersionConstraint, m module.Version) (VersionConstraint, bool) {
+	for _, c := range cs {
+		if c.Matches(m) {
+			return c, true
+		}
+	}
+	return VersionConstraint{}, false
+}
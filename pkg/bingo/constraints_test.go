@@ -0,0 +1,61 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestVersionConstraints(t *testing.T) {
+	dir := t.TempDir()
+
+	cs, err := VersionConstraints(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(cs))
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(dir, ConstraintsFile), []byte(
+		"# Known bad versions.\n"+
+			"github.com/example/tool >= v1.2.0, < v1.2.5 # CVE-2023-1234\n\n"+
+			"github.com/abandoned *\n"), 0600))
+	cs, err = VersionConstraints(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(cs))
+	testutil.Equals(t, ".bingoconstraints:2: github.com/example/tool >= v1.2.0, < v1.2.5 (CVE-2023-1234)", cs[0].String())
+	testutil.Equals(t, ".bingoconstraints:4: github.com/abandoned *", cs[1].String())
+
+	for _, tcase := range []struct {
+		m          module.Version
+		deniedLine int
+	}{
+		{m: module.Version{Path: "github.com/example/tool", Version: "v1.1.9"}},
+		{m: module.Version{Path: "github.com/example/tool", Version: "v1.2.0"}, deniedLine: 2},
+		{m: module.Version{Path: "github.com/example/tool", Version: "v1.2.4"}, deniedLine: 2},
+		// Pseudo-version of commit after v1.2.3 is matched as v1.2.4.
+		{m: module.Version{Path: "github.com/example/tool", Version: "v1.2.4-0.20230102150405-abcdefabcdef"}, deniedLine: 2},
+		{m: module.Version{Path: "github.com/example/tool", Version: "v1.2.5"}},
+		{m: module.Version{Path: "github.com/example/tool2", Version: "v1.2.4"}},
+		{m: module.Version{Path: "github.com/abandoned/tool", Version: "v0.1.0"}, deniedLine: 4},
+		{m: module.Version{Path: "github.com/abandoned", Version: "v3.0.0+incompatible"}, deniedLine: 4},
+	} {
+		t.Run(tcase.m.String(), func(t *testing.T) {
+			denied, ok := DeniedBy(cs, tcase.m)
+			testutil.Equals(t, tcase.deniedLine != 0, ok)
+			testutil.Equals(t, tcase.deniedLine, denied.Line)
+		})
+	}
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(dir, ConstraintsFile), []byte("github.com/example/tool\n"), 0600))
+	_, err = VersionConstraints(dir)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, `.bingoconstraints:1: expected <module path pattern> <version constraint>, got "github.com/example/tool"`, err.Error())
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(dir, ConstraintsFile), []byte("github.com/example/tool >> v1\n"), 0600))
+	_, err = VersionConstraints(dir)
+	testutil.NotOk(t, err)
+}
@@ -0,0 +1,147 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DiffLines returns line by line diff between old and new content. Each line is prefixed with "-" if removed,
+// "+" if added or " " if unchanged. It returns nil if there are no changes.
+func DiffLines(old, new string) []string {
+	if old == new {
+		return nil
+	}
+	a, b := splitLines(old), splitLines(new)
+
+	// Longest common subsequence; mod files are small, so quadratic space is fine.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+				continue
+			}
+			lcs[i][j] = lcs[i+1][j]
+			if lcs[i][j+1] > lcs[i][j] {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ret := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ret = append(ret, " "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ret = append(ret, "-"+a[i])
+			i++
+		default:
+			ret = append(ret, "+"+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ret = append(ret, "-"+a[i])
+	}
+	for ; j < len(b); j++ {
+		ret = append(ret, "+"+b[j])
+	}
+	return ret
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// DiffAgainst returns line diff (see DiffLines) between the given file on disk (treated as empty if it does not exist)
+// and the current content of the module file.
+func (mf *ModFile) DiffAgainst(file string) ([]string, error) {
+	old, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	current, err := os.ReadFile(mf.Filepath())
+	if err != nil {
+		return nil, err
+	}
+	return DiffLines(string(old), string(current)), nil
+}
+
+// UnifiedDiff returns diff between old and new content in unified format (as `diff -u` prints it) with 3 lines of
+// context. Use /dev/null as the old or new name for created and removed files. It returns empty string if there are no
+// changes.
+func UnifiedDiff(oldName, newName, old, new string) string {
+	const context = 3
+
+	lines := DiffLines(old, new)
+	if len(lines) == 0 {
+		return ""
+	}
+
+	// Positions of each line in the old and new content.
+	oldPos, newPos := make([]int, len(lines)+1), make([]int, len(lines)+1)
+	for i, l := range lines {
+		oldPos[i+1], newPos[i+1] = oldPos[i], newPos[i]
+		if l[0] != '+' {
+			oldPos[i+1]++
+		}
+		if l[0] != '-' {
+			newPos[i+1]++
+		}
+	}
+
+	b := strings.Builder{}
+	b.WriteString("--- " + oldName + "\n+++ " + newName + "\n")
+	for i := 0; i < len(lines); {
+		if lines[i][0] == ' ' {
+			i++
+			continue
+		}
+
+		// Extend hunk as long as the next change is within the context of the previous one.
+		start, end := i-context, i+1
+		if start < 0 {
+			start = 0
+		}
+		for j := end; j < len(lines) && j <= end+2*context; j++ {
+			if lines[j][0] != ' ' {
+				end = j + 1
+			}
+		}
+		i = end
+		if end += context; end > len(lines) {
+			end = len(lines)
+		}
+
+		b.WriteString("@@ -" + hunkRange(oldPos[start], oldPos[end]-oldPos[start]) + " +" + hunkRange(newPos[start], newPos[end]-newPos[start]) + " @@\n")
+		for _, l := range lines[start:end] {
+			b.WriteString(l + "\n")
+		}
+	}
+	return b.String()
+}
+
+func hunkRange(before, count int) string {
+	switch count {
+	case 0:
+		return strconv.Itoa(before) + ",0"
+	case 1:
+		return strconv.Itoa(before + 1)
+	default:
+		return strconv.Itoa(before+1) + "," + strconv.Itoa(count)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestDiffLines(t *testing.T) {
+	for _, tcase := range []struct {
+		old, new string
+		expected []string
+	}{
+		{old: "", new: ""},
+		{old: "a\nb\n", new: "a\nb\n"},
+		{old: "", new: "a\nb\n", expected: []string{"+a", "+b"}},
+		{old: "a\nb\n", new: "", expected: []string{"-a", "-b"}},
+		{
+			old:      "module _\n\ngo 1.14\n\nrequire github.com/fatih/faillint v1.4.0\n",
+			new:      "module _\n\ngo 1.14\n\nrequire github.com/fatih/faillint v1.5.0\n",
+			expected: []string{" module _", " ", " go 1.14", " ", "-require github.com/fatih/faillint v1.4.0", "+require github.com/fatih/faillint v1.5.0"},
+		},
+		{
+			old:      "a\nc\n",
+			new:      "a\nb\nc\nd\n",
+			expected: []string{" a", "+b", " c", "+d"},
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			testutil.Equals(t, tcase.expected, DiffLines(tcase.old, tcase.new))
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, tcase := range []struct {
+		name     string
+		old, new string
+		expected string
+	}{
+		{name: "no changes", old: "a\nb\n", new: "a\nb\n"},
+		{name: "created", old: "", new: "a\nb\n", expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{name: "removed", old: "a\n", new: "", expected: "--- old\n+++ new\n@@ -1 +0,0 @@\n-a\n"},
+		{
+			name:     "changed require",
+			old:      "module _\n\ngo 1.14\n\nrequire github.com/fatih/faillint v1.4.0\n",
+			new:      "module _\n\ngo 1.14\n\nrequire github.com/fatih/faillint v1.5.0\n",
+			expected: "--- old\n+++ new\n@@ -2,4 +2,4 @@\n \n go 1.14\n \n-require github.com/fatih/faillint v1.4.0\n+require github.com/fatih/faillint v1.5.0\n",
+		},
+		{
+			name:     "distant changes are split into hunks",
+			old:      "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			new:      "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n",
+			expected: "--- old\n+++ new\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -9,4 +10,3 @@\n 9\n 10\n 11\n-12\n",
+		},
+		{
+			name:     "close changes are merged into one hunk",
+			old:      "1\n2\n3\n4\n5\n6\n7\n",
+			new:      "0\n1\n2\n3\n4\n5\n6\n",
+			expected: "--- old\n+++ new\n@@ -1,7 +1,7 @@\n+0\n 1\n 2\n 3\n 4\n 5\n 6\n-7\n",
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			testutil.Equals(t, tcase.expected, UnifiedDiff("old", "new", tcase.old, tcase.new))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Env represents paths and Go details bingo resolves for the project, as printed by `bingo env`. JSON schema is stable.
+type Env struct {
+	// Go is an absolute path to the go command bingo uses.
+	Go string `json:"go"`
+	// GoVersion is a version of the go command, e.g. "1.21.4".
+	GoVersion string `json:"go_version"`
+	// GoRoot is a GOROOT of the go command.
+	GoRoot string `json:"goroot"`
+	// GoBin is an absolute path to the directory with installed binaries.
+	GoBin string `json:"gobin"`
+	// ModDir is an absolute path to the bingo module directory (e.g. .bingo).
+	ModDir string `json:"mod_dir"`
+	// VariablesEnv and VariablesMk are absolute paths to the generated helpers with binary variables.
+	VariablesEnv string `json:"variables_env"`
+	VariablesMk  string `json:"variables_mk"`
+	// MakefileTargets is an absolute path to the generated Makefile with per-tool targets. Empty if not enabled.
+	MakefileTargets string    `json:"makefile_targets"`
+	Tools           []EnvTool `json:"tools"`
+}
+
+// EnvTool represents binary paths of the single pinned tool.
+type EnvTool struct {
+	// Name is a tool name, as used in `bingo get <name>`.
+	Name string `json:"name"`
+	// EnvVarName is a name of the variable with binary path(s) in the generated helpers.
+	EnvVarName string `json:"env_var"`
+	// BinaryPaths are absolute paths to the versioned binaries, one per pinned version.
+	BinaryPaths []string `json:"binary_paths"`
+}
+
+// NewEnv returns Env with paths computed for the given absolute mod directory and GOBIN. Go details are left to fill by
+// the caller.
+func NewEnv(modDir, gobin string, pkgs PackageRenderables) (Env, error) {
+	mk, err := makefileTargetsPath(modDir)
+	if err != nil {
+		return Env{}, err
+	}
+	if mk != "" {
+		mk = filepath.Join(modDir, mk)
+	}
+	e := Env{
+		GoBin:           gobin,
+		ModDir:          modDir,
+		VariablesEnv:    filepath.Join(modDir, helperFile("env")),
+		VariablesMk:     filepath.Join(modDir, helperFile("mk")),
+		MakefileTargets: mk,
+		// Ensure empty arrays are not rendered as null.
+		Tools: make([]EnvTool, 0, len(pkgs)),
+	}
+	for _, p := range pkgs {
+		t := EnvTool{Name: p.Name, EnvVarName: p.EnvVarName, BinaryPaths: make([]string, 0, len(p.Versions))}
+		for _, v := range p.Versions {
+			t.BinaryPaths = append(t.BinaryPaths, filepath.Join(gobin, p.BinaryFile(v.Version)))
+		}
+		e.Tools = append(e.Tools, t)
+	}
+	return e, nil
+}
+
+// PrintJSON prints env as JSON object.
+func (e Env) PrintJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// PrintShell prints env as KEY=value lines, quoted for POSIX shells, so it can be evaluated with `eval $(bingo env)`.
+// Variables of tools are named as in the generated helpers; multiple versions are separated with space.
+func (e Env) PrintShell(w io.Writer) error {
+	vars := [][2]string{
+		{"BINGO_GO", e.Go},
+		{"BINGO_GO_VERSION", e.GoVersion},
+		{"BINGO_GOROOT", e.GoRoot},
+		{"GOBIN", e.GoBin},
+		{"BINGO_DIR", e.ModDir},
+		{"BINGO_VARIABLES_ENV", e.VariablesEnv},
+		{"BINGO_VARIABLES_MK", e.VariablesMk},
+		{"BINGO_MAKEFILE_TARGETS", e.MakefileTargets},
+	}
+	for _, t := range e.Tools {
+		vars = append(vars, [2]string{t.EnvVarName, strings.Join(t.BinaryPaths, " ")})
+	}
+	for _, v := range vars {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", v[0], shellQuote(v[1])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var shellSafeRegexp = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]*$`)
+
+// shellQuote returns value quoted with single quotes, unless it has only characters safe in POSIX shells.
+func shellQuote(v string) string {
+	if shellSafeRegexp.MatchString(v) {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
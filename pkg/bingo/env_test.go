@@ -0,0 +1,82 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestEnv(t *testing.T) {
+	root := t.TempDir()
+	modDir := filepath.Join(root, ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+	pkgs := PackageRenderables{
+		{
+			Name: "faillint", BinaryName: "faillint", EnvVarName: "FAILLINT",
+			Versions: []PackageVersionRenderable{{Version: "v1.5.0", ModFile: "faillint.mod"}},
+		},
+		{
+			Name: "buildable", BinaryName: "buildable", EnvVarName: "BUILDABLE_ARRAY",
+			Versions:     []PackageVersionRenderable{{Version: "v1.0.0", ModFile: "buildable.mod"}, {Version: "v1.1.0", ModFile: "buildable.1.mod"}},
+			BuildEnvVars: []string{"GOOS=linux", "GOARCH=arm64"},
+		},
+	}
+
+	e, err := NewEnv(modDir, "/gobin", pkgs)
+	testutil.Ok(t, err)
+	testutil.Equals(t, Env{
+		GoBin:        "/gobin",
+		ModDir:       modDir,
+		VariablesEnv: filepath.Join(modDir, "variables.env"),
+		VariablesMk:  filepath.Join(modDir, "Variables.mk"),
+		Tools: []EnvTool{
+			{Name: "faillint", EnvVarName: "FAILLINT", BinaryPaths: []string{"/gobin/faillint-v1.5.0"}},
+			{Name: "buildable", EnvVarName: "BUILDABLE_ARRAY", BinaryPaths: []string{"/gobin/buildable-v1.0.0-linux_arm64", "/gobin/buildable-v1.1.0-linux_arm64"}},
+		},
+	}, e)
+
+	testutil.Ok(t, SetMakefileTargetsPath(modDir, filepath.Join(root, "tools.mk")))
+	e, err = NewEnv(modDir, "/gobin", nil)
+	testutil.Ok(t, err)
+	testutil.Equals(t, filepath.Join(root, "tools.mk"), e.MakefileTargets)
+	testutil.Equals(t, []EnvTool{}, e.Tools)
+
+	e = Env{
+		Go: "/usr/local/go/bin/go", GoVersion: "1.21.4", GoRoot: "/usr/local/go", GoBin: "/home/it's me/bin", ModDir: "/repo/.bingo",
+		VariablesEnv: "/repo/.bingo/variables.env", VariablesMk: "/repo/.bingo/Variables.mk",
+		Tools: []EnvTool{{Name: "buildable", EnvVarName: "BUILDABLE_ARRAY", BinaryPaths: []string{"/bin/buildable-v1.0.0", "/bin/buildable-v1.1.0"}}},
+	}
+	b := bytes.Buffer{}
+	testutil.Ok(t, e.PrintShell(&b))
+	testutil.Equals(t, `BINGO_GO=/usr/local/go/bin/go
+BINGO_GO_VERSION=1.21.4
+BINGO_GOROOT=/usr/local/go
+GOBIN='/home/it'\''s me/bin'
+BINGO_DIR=/repo/.bingo
+BINGO_VARIABLES_ENV=/repo/.bingo/variables.env
+BINGO_VARIABLES_MK=/repo/.bingo/Variables.mk
+BINGO_MAKEFILE_TARGETS=
+BUILDABLE_ARRAY='/bin/buildable-v1.0.0 /bin/buildable-v1.1.0'
+`, b.String())
+
+	b.Reset()
+	testutil.Ok(t, Env{}.PrintJSON(&b))
+	testutil.Equals(t, `{
+  "go": "",
+  "go_version": "",
+  "goroot": "",
+  "gobin": "",
+  "mod_dir": "",
+  "variables_env": "",
+  "variables_mk": "",
+  "makefile_targets": "",
+  "tools": null
+}
+`, b.String())
+}
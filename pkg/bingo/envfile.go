@@ -0,0 +1,111 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/errors"
+)
+
+// validateEnvFilePath returns error if the env file path is not a clean path within the module directory.
+func validateEnvFilePath(p string) error {
+	if p == "" || path.IsAbs(p) || path.Clean(p) != p || p == ".." || strings.HasPrefix(p, "../") {
+		return errors.Newf("env file %q has to be a clean path relative to the module directory", p)
+	}
+	return nil
+}
+
+// parseEnvFile parses env file content. Each line is either KEY=VALUE build environment variable (value can contain
+// spaces) or build flags separated with spaces, as in GOFLAGS (e.g. "-tags=a,b -trimpath"). Empty lines and lines
+// starting with # are ignored.
+func parseEnvFile(b []byte) (envs envars.EnvSlice, flags []string, _ error) {
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '-' {
+			for _, f := range strings.Fields(line) {
+				if err := validateBuildFlag(f); err != nil {
+					return nil, nil, errors.Wrapf(err, "line %d", n)
+				}
+				flags = append(flags, f)
+			}
+			continue
+		}
+		if !buildEnvRegexp.MatchString(line) {
+			return nil, nil, errors.Newf("line %d: %q is neither build env in KEY=VALUE form with upper case KEY nor build flags starting with '-'", n, line)
+		}
+		if err := validateBuildEnv(line); err != nil {
+			return nil, nil, errors.Wrapf(err, "line %d", n)
+		}
+		switch k := envKey(line); k {
+		case "GOOS", "GOARCH":
+			// Platform is part of the binary name, so it has to be visible in the module file.
+			return nil, nil, errors.Newf("line %d: %v has to be set in the module file, not in env file", n, k)
+		}
+		envs = append(envs, line)
+	}
+	return envs, flags, s.Err()
+}
+
+// WithEnvFile returns copy of the package with build environment variables and flags from its env file (see
+// EnvFileAttribute) merged into BuildEnvs and BuildFlags. Env file is resolved relative to modDir. Env variables and
+// flags set inline in the module file take precedence; each overridden entry is logged. It returns unchanged package if
+// there is no env file.
+func (m Package) WithEnvFile(logger logging.Logger, modDir string) (Package, error) {
+	if m.EnvFile == "" {
+		return m, nil
+	}
+	file := filepath.Join(modDir, filepath.FromSlash(m.EnvFile))
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return Package{}, errors.Wrapf(err, "read env file of %v", m.Path())
+	}
+	fileEnvs, fileFlags, err := parseEnvFile(b)
+	if err != nil {
+		return Package{}, errors.Wrapf(err, "parse env file %v", file)
+	}
+
+	for _, e := range fileEnvs {
+		if v, ok := m.BuildEnvs.Lookup(envKey(e)); ok {
+			logger.Warnf("%v: build env %v from env file %v is overridden by %v=%v set in the module file\n", m.Path(), e, m.EnvFile, envKey(e), v)
+		}
+	}
+	inlineFlags := map[string]string{}
+	for _, f := range m.BuildFlags {
+		inlineFlags[flagName(f)] = f
+	}
+	var flags []string
+	for _, f := range fileFlags {
+		if inline, ok := inlineFlags[flagName(f)]; ok {
+			logger.Warnf("%v: build flag %v from env file %v is overridden by %v set in the module file\n", m.Path(), f, m.EnvFile, inline)
+			continue
+		}
+		flags = append(flags, f)
+	}
+
+	ret := m
+	ret.BuildEnvs = envars.MergeEnvSlices(fileEnvs, m.BuildEnvs...)
+	ret.BuildFlags = append(flags, m.BuildFlags...)
+	return ret, nil
+}
+
+func envKey(env string) string {
+	return strings.SplitN(env, "=", 2)[0]
+}
+
+// flagName returns flag name without value, e.g. "-tags" for "-tags=a,b".
+func flagName(flag string) string {
+	return strings.SplitN(flag, "=", 2)[0]
+}
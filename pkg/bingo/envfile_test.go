@@ -0,0 +1,135 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	for _, tcase := range []struct {
+		name          string
+		content       string
+		expectedEnvs  envars.EnvSlice
+		expectedFlags []string
+		expectedErr   string
+	}{
+		{name: "empty"},
+		{
+			name:          "envs and flags",
+			content:       "# Comment.\nCGO_ENABLED=1\n\nCGO_CFLAGS=-I/opt/a -I/opt/b\n-tags=a,b -trimpath\n  -ldflags=-s  \n",
+			expectedEnvs:  envars.EnvSlice{"CGO_ENABLED=1", "CGO_CFLAGS=-I/opt/a -I/opt/b"},
+			expectedFlags: []string{"-tags=a,b", "-trimpath", "-ldflags=-s"},
+		},
+		{
+			name:        "lower case env",
+			content:     "CGO_ENABLED=1\ncgo=1\n",
+			expectedErr: `line 2: "cgo=1" is neither build env in KEY=VALUE form with upper case KEY nor build flags starting with '-'`,
+		},
+		{
+			name:        "platform",
+			content:     "GOOS=linux\n",
+			expectedErr: "line 1: GOOS has to be set in the module file, not in env file",
+		},
+		{
+			name:        "bingo flag",
+			content:     "-trimpath -o=bin/tool\n",
+			expectedErr: `line 1: build flag "-o=bin/tool" is set by bingo and can't be overridden`,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			envs, flags, err := parseEnvFile([]byte(tcase.content))
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expectedEnvs, envs)
+			testutil.Equals(t, tcase.expectedFlags, flags)
+		})
+	}
+}
+
+func TestPackage_WithEnvFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	testutil.Ok(t, os.WriteFile(filepath.Join(tmpDir, "tool.env"), []byte("CGO_ENABLED=1\nCGO_CFLAGS=-I${SDK}/include\n-tags=a,b -trimpath\n"), os.ModePerm))
+
+	logs := bytes.Buffer{}
+	logger := logging.NewStd(log.New(&logs, "", 0), logging.LevelDebug)
+	pkg := Package{
+		Module:     module.Version{Path: "github.com/x/tool", Version: "v1.0.0"},
+		EnvFile:    "tool.env",
+		BuildEnvs:  envars.EnvSlice{"CGO_ENABLED=0"},
+		BuildFlags: []string{"-tags=c"},
+	}
+
+	t.Run("no env file", func(t *testing.T) {
+		p := pkg
+		p.EnvFile = ""
+		got, err := p.WithEnvFile(logger, tmpDir)
+		testutil.Ok(t, err)
+		testutil.Equals(t, p, got)
+	})
+	t.Run("inline attributes take precedence", func(t *testing.T) {
+		got, err := pkg.WithEnvFile(logger, tmpDir)
+		testutil.Ok(t, err)
+		testutil.Equals(t, envars.EnvSlice{"CGO_CFLAGS=-I${SDK}/include", "CGO_ENABLED=0"}, got.BuildEnvs)
+		testutil.Equals(t, []string{"-trimpath", "-tags=c"}, got.BuildFlags)
+		testutil.Equals(t, "tool.env", got.EnvFile)
+		// Original package is not modified.
+		testutil.Equals(t, envars.EnvSlice{"CGO_ENABLED=0"}, pkg.BuildEnvs)
+
+		testutil.Equals(t, "WARNING: github.com/x/tool: build env CGO_ENABLED=1 from env file tool.env is overridden by CGO_ENABLED=0 set in the module file\n"+
+			"WARNING: github.com/x/tool: build flag -tags=a,b from env file tool.env is overridden by -tags=c set in the module file\n", logs.String())
+	})
+	t.Run("missing env file", func(t *testing.T) {
+		p := pkg
+		p.EnvFile = "missing.env"
+		_, err := p.WithEnvFile(logger, tmpDir)
+		testutil.NotOk(t, err)
+		testutil.Assert(t, errors.Is(err, os.ErrNotExist))
+	})
+}
+
+func TestModFile_EnvFile(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client env-file=client.env
+
+require github.com/x/tool v1.0.0 // cmd/tool name=tool env-file=tool.env CGO_ENABLED=0 -tags=c
+`), os.ModePerm))
+
+	mf, err := OpenModFile(testFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.Ok(t, mf.Validate())
+	testutil.Equals(t, "tool.env", mf.EnvFile())
+	testutil.Equals(t, "client.env", mf.DirectPackages()[1].EnvFile)
+
+	// Attribute is kept on rewrite.
+	testutil.Ok(t, mf.SetBuildFlags([]string{"-trimpath"}))
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client env-file=client.env
+
+require github.com/x/tool v1.0.0 // cmd/tool name=tool env-file=tool.env CGO_ENABLED=0 -trimpath
+`, testFile)
+}
@@ -0,0 +1,290 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/bwplotka/bingo/pkg/version"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+var goModVersionRegexp = regexp.MustCompile("^v[0-9]*$")
+
+// DefaultBinaryName returns binary name bingo uses for the given package path, so the last element of the package path,
+// unless it's a major version suffix (e.g. /v2).
+func DefaultBinaryName(pkgPath string) string {
+	name := path.Base(pkgPath)
+	if pkgSplit := strings.Split(pkgPath, "/"); len(pkgSplit) > 3 && goModVersionRegexp.MatchString(name) {
+		// It's common pattern to name urls with versions in go modules. Exclude that.
+		name = pkgSplit[len(pkgSplit)-2]
+	}
+	return strings.ToLower(name)
+}
+
+// SideBySideName returns name of the tool variant that pins the given version of the package next to other major
+// versions of the same tool, e.g. "migrate-v2" for name "migrate" and version v2.1.0. Major version is taken from the
+// version if it's a semantic version (e.g. v2.1.0 or v2), otherwise from the major version suffix of the package path
+// (e.g. github.com/x/migrate/v2). Error is returned if neither tells the major version, e.g. for "latest" query and the
+// package path without suffix.
+func SideBySideName(name, pkgPath, version string) (string, error) {
+	if semver.IsValid(version) {
+		return name + "-" + semver.Major(version), nil
+	}
+	elems := strings.Split(pkgPath, "/")
+	for i := len(elems) - 1; i > 0; i-- {
+		if goModVersionRegexp.MatchString(elems[i]) && elems[i] != "v0" && elems[i] != "v1" {
+			return name + "-" + elems[i], nil
+		}
+	}
+	return "", errors.Newf("cannot tell major version of %v@%v to name it side by side; specify semantic version, e.g. %v@v1.0.0", pkgPath, version, pkgPath)
+}
+
+// SideBySideOf returns name of the tool the side by side variant (see SideBySideName) pinning the given version was named
+// after, or empty if the name is not one of such variant.
+func SideBySideOf(name, version string) string {
+	if !semver.IsValid(version) {
+		return ""
+	}
+	base := strings.TrimSuffix(name, "-"+semver.Major(version))
+	if base == name || base == "" {
+		return ""
+	}
+	return base
+}
+
+// GetOptions configures Get.
+type GetOptions struct {
+	// ModDir is a directory where bingo module files are maintained (e.g. ".bingo"). It's created if it does not exist.
+	ModDir string
+	// GOBIN is a directory binaries are installed into. If empty, it's deducted the same way as go install does.
+	GOBIN string
+	// Name is a tool name used for the module file and binary. If empty, DefaultBinaryName of the package is used.
+	Name string
+
+	ModulePath string
+	// Version is a module version or version query understood by go (e.g. "latest", which is the default).
+	Version string
+	// RelPath is a package path relative to ModulePath. Empty if the module path is the package path.
+	RelPath    string
+	BuildFlags []string
+	BuildEnvs  []string
+	// Comment is a human readable description of the tool recorded in the module file. If empty, existing one is kept.
+	Comment string
+	// Toolchain is a Go toolchain name (e.g. "go1.22.0") the tool is built with, recorded in the module file. If empty,
+	// existing one is kept.
+	Toolchain string
+	// PostInstall is a command run on the built binary, recorded in the module file. If empty, existing one is kept.
+	// See Package.PostInstall.
+	PostInstall string
+	// Timeout limits how long each command run for the tool (e.g. go get or go build) can take, so unreachable proxy does
+	// not block forever. Command is killed on expiry and Get returns error matching context.DeadlineExceeded. If zero,
+	// commands are limited only by the context. See runner.ContextWithCommandTimeout.
+	Timeout time.Duration
+
+	// NoBuild makes Get only update the module file (see UpdateModFile) and helper variables, without building the binary.
+	NoBuild bool
+	// Link makes Get also create <name> symlink to the versioned binary.
+	Link bool
+	// LinkDir is an additional directory <name> symlink is created in if Link is true. If empty, symlink is created in GOBIN only.
+	LinkDir string
+	// Rebuild tells if binary matching the recorded checksum is rebuilt anyway. See Rebuild.
+	Rebuild Rebuild
+	// Logger is used to log progress. If nil, logs are discarded.
+	Logger logging.Logger
+	// Progress receives progress events, e.g. to render them in UI. If nil, events are discarded.
+	Progress Progress
+}
+
+// Get pins the package from the given options in its own module file in ModDir, builds it to GOBIN and regenerates
+// helper variables, like `bingo get <package>@<version>` does. Existing module file of the tool (if any) is used as a base,
+// so its replace and exclude directives are kept. Replace directives from ReplaceTemplateFileName are merged in. Module
+// and sum files with unchanged content are not rewritten (see ReplaceIfChanged).
+func Get(ctx context.Context, r *runner.Runner, opts GetOptions) (err error) {
+	if opts.ModulePath == "" {
+		return errors.New("module path is required")
+	}
+	if opts.ModDir == "" {
+		return errors.New("module directory is required")
+	}
+	if opts.NoBuild && opts.Link {
+		return errors.New("link cannot be created without build")
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = NopProgress{}
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = logging.Discard
+	}
+	if opts.Version == "" {
+		opts.Version = "latest"
+	}
+	if opts.Timeout > 0 {
+		ctx = runner.ContextWithCommandTimeout(ctx, opts.Timeout)
+	}
+
+	target := Package{
+		Module:     module.Version{Path: opts.ModulePath},
+		RelPath:    opts.RelPath,
+		BuildEnvs:  opts.BuildEnvs,
+		BuildFlags: opts.BuildFlags,
+	}
+	name := opts.Name
+	if name == "" {
+		name = DefaultBinaryName(target.Path())
+	}
+	if err := ValidateBinaryName(name); err != nil {
+		return err
+	}
+	if err := validateTargetName(name); err != nil {
+		return err
+	}
+
+	progress.OnStart(name)
+	defer func() { progress.OnDone(name, err) }()
+	progress.OnPhase(name, PhaseResolve)
+
+	if err := EnsureModDir(logger, opts.ModDir); err != nil {
+		return errors.Wrap(err, "ensure mod dir")
+	}
+
+	outModFile := filepath.Join(opts.ModDir, name+".mod")
+	tmpModFilePath := filepath.Join(opts.ModDir, name+".tmp.mod")
+	defer func() {
+		if rerr := removeAllGlob(strings.TrimSuffix(tmpModFilePath, ".mod") + ".*"); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	modFile, err := CreateFromExistingOrNew(ctx, r, logger, outModFile, tmpModFilePath)
+	if err != nil {
+		return errors.Wrap(err, "create tmp mod file")
+	}
+	defer errcapture.Do(&err, modFile.Close, "close")
+
+	if err := modFile.Validate(); err != nil {
+		return errors.Wrapf(err, "malformed %v; fix it manually", outModFile)
+	}
+	if !modFile.IsDirectivesAutoFetchDisabled() {
+		tmpl, err := ReadReplaceTemplate(opts.ModDir)
+		if err != nil {
+			return err
+		}
+		if err := modFile.SetReplaceTemplate(tmpl); err != nil {
+			return errors.Wrapf(err, "merge %v", ReplaceTemplateFileName)
+		}
+	}
+
+	// Resolve version query on the tmp module file, so it does not depend on the current project module.
+	fetchEnvs := target.ModuleFetchEnvs()
+	if modFile.IsSumDBDisabled() {
+		fetchEnvs = SumDBDisabledEnvs(target.Module.Path, fetchEnvs)
+	}
+	if fetchEnvs, err = PrivateModuleEnvs(target.Module.Path, os.Getenv(PrivateModulesEnv), fetchEnvs); err != nil {
+		return err
+	}
+	v, err := r.With(ctx, modFile.Filepath(), opts.ModDir, fetchEnvs).List("-m", "-f={{.Version}}", opts.ModulePath+"@"+opts.Version)
+	if err != nil {
+		return errors.Wrapf(err, "resolve %v@%v", opts.ModulePath, opts.Version)
+	}
+	target.Module.Version = v
+
+	if old := modFile.DirectPackage(); old != nil && old.Module.Path == target.Module.Path {
+		target.Name = old.Name
+		target.EnvFile = old.EnvFile
+		target.WorkDir = old.WorkDir
+		target.PostInstall = old.PostInstall
+		target.VerifyCmd = old.VerifyCmd
+		target.VerifyOutput = old.VerifyOutput
+	}
+	if opts.Name != "" {
+		target.Name = opts.Name
+	}
+	if opts.PostInstall != "" {
+		target.PostInstall = opts.PostInstall
+	}
+	if err := modFile.SetDirectRequire(target); err != nil {
+		return errors.Wrap(err, "set direct require")
+	}
+	if opts.Comment != "" {
+		if err := modFile.SetComment(opts.Comment); err != nil {
+			return errors.Wrap(err, "set comment")
+		}
+	}
+	if opts.Toolchain != "" {
+		if err := modFile.SetToolchain(opts.Toolchain); err != nil {
+			return errors.Wrap(err, "set toolchain")
+		}
+	}
+
+	ic, err := newInstallContext(ctx, logger, r, opts.ModDir, name, modFile)
+	if err != nil {
+		return errors.Wrap(err, "install")
+	}
+	progress.OnPhase(name, PhaseDownload)
+	if opts.Rebuild == RebuildClean && !opts.NoBuild {
+		if err := ic.removeFromModCache(); err != nil {
+			return errors.Wrap(err, "install")
+		}
+	}
+	if err := ic.updateModFile(); err != nil {
+		return errors.Wrap(err, "install")
+	}
+	if !opts.NoBuild {
+		progress.OnPhase(name, PhaseBuild)
+		if err := ic.build(opts.GOBIN, opts.Link, opts.LinkDir, opts.Rebuild); err != nil {
+			return errors.Wrap(err, "install")
+		}
+	}
+
+	// We were working on tmp file, do atomic rename, unless nothing changed.
+	if _, err := ReplaceIfChanged(modFile.Filepath(), outModFile); err != nil {
+		return errors.Wrap(err, "rename mod file")
+	}
+	if _, err := ReplaceIfChanged(SumFilePath(modFile.Filepath()), SumFilePath(outModFile)); err != nil {
+		return errors.Wrap(err, "rename sum file")
+	}
+	if !opts.NoBuild {
+		if err := os.Rename(MetaFilePath(modFile.Filepath()), MetaFilePath(outModFile)); err != nil {
+			return errors.Wrap(err, "rename meta file")
+		}
+	}
+
+	pkgs, err := ListPinnedMainPackages(logger, opts.ModDir, false)
+	if err != nil {
+		return errors.Wrap(err, "list pinned")
+	}
+	return GenHelpers(opts.ModDir, version.Version, r.Reproducible(), pkgs)
+}
+
+// removeAllGlob removes all files matching glob, except lock files (see mod.Lock), since other processes might use them.
+func removeAllGlob(glob string) error {
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if mod.IsLockFile(f) {
+			continue
+		}
+		if err := os.RemoveAll(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwplotka/bingo/internal/fakecmd"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestDefaultBinaryName(t *testing.T) {
+	for pkgPath, expected := range map[string]string{
+		"github.com/fatih/faillint":                         "faillint",
+		"golang.org/x/tools/cmd/goimports":                  "goimports",
+		"github.com/bwplotka/bingo-testmodule/v2":           "bingo-testmodule",
+		"github.com/prometheus/prometheus/cmd/Prometheus":   "prometheus",
+		"github.com/bwplotka/bingo-testmodule/buildable/v2": "buildable",
+	} {
+		testutil.Equals(t, expected, DefaultBinaryName(pkgPath))
+	}
+}
+
+func TestRemoveAllGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"tool.tmp.mod", "tool.tmp.sum", "tool.tmp.mod.lock", "tool.mod"} {
+		testutil.Ok(t, os.WriteFile(filepath.Join(dir, f), nil, os.ModePerm))
+	}
+	testutil.Ok(t, removeAllGlob(filepath.Join(dir, "tool.tmp.*")))
+
+	entries, err := os.ReadDir(dir)
+	testutil.Ok(t, err)
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	// Lock file might be held by concurrent process, so it's kept.
+	testutil.Equals(t, []string{"tool.mod", "tool.tmp.mod.lock"}, left)
+}
+
+func TestSideBySideName(t *testing.T) {
+	for _, tcase := range []struct {
+		pkgPath, version, expected string
+	}{
+		{pkgPath: "github.com/x/migrate", version: "v1.4.0", expected: "migrate-v1"},
+		{pkgPath: "github.com/x/migrate", version: "v0.3.1", expected: "migrate-v0"},
+		{pkgPath: "github.com/x/migrate", version: "v2.0.0+incompatible", expected: "migrate-v2"},
+		{pkgPath: "github.com/x/migrate/v2", version: "v2.1.0", expected: "migrate-v2"},
+		{pkgPath: "github.com/x/migrate/v3/cmd/migrate", version: "latest", expected: "migrate-v3"},
+		{pkgPath: "github.com/x/migrate/v3", version: "", expected: "migrate-v3"},
+		{pkgPath: "github.com/x/migrate", version: "latest"},
+		{pkgPath: "github.com/x/migrate/api/v1", version: ""},
+	} {
+		t.Run(tcase.pkgPath+"@"+tcase.version, func(t *testing.T) {
+			name, err := SideBySideName("migrate", tcase.pkgPath, tcase.version)
+			if tcase.expected == "" {
+				testutil.NotOk(t, err)
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, name)
+			// Variant is recognised by its pinned version.
+			testutil.Equals(t, "migrate", SideBySideOf(name, "v"+strings.TrimPrefix(name, "migrate-v")+".9.0"))
+		})
+	}
+	testutil.Equals(t, "", SideBySideOf("migrate-v1", "v2.0.0"))
+	testutil.Equals(t, "", SideBySideOf("migrate", "v1.0.0"))
+	testutil.Equals(t, "", SideBySideOf("-v1", "v1.0.0"))
+}
+
+func TestGet_Errors(t *testing.T) {
+	testutil.NotOk(t, Get(context.Background(), nil, GetOptions{ModDir: t.TempDir()}))
+	testutil.NotOk(t, Get(context.Background(), nil, GetOptions{ModulePath: "github.com/fatih/faillint"}))
+	testutil.NotOk(t, Get(context.Background(), nil, GetOptions{ModDir: t.TempDir(), ModulePath: "github.com/fatih/faillint", Name: "bin/faillint"}))
+	testutil.NotOk(t, Get(context.Background(), nil, GetOptions{ModDir: t.TempDir(), ModulePath: "github.com/x/cmd"}))
+	testutil.NotOk(t, Get(context.Background(), nil, GetOptions{ModDir: t.TempDir(), ModulePath: "github.com/fatih/faillint", NoBuild: true, Link: true}))
+}
+
+type recordingProgress struct {
+	events []string
+}
+
+func (p *recordingProgress) OnStart(tool string) { p.events = append(p.events, "start "+tool) }
+
+func (p *recordingProgress) OnPhase(tool, phase string) {
+	p.events = append(p.events, "phase "+tool+" "+phase)
+}
+
+func (p *recordingProgress) OnDone(tool string, err error) {
+	p.events = append(p.events, fmt.Sprintf("done %v %v", tool, err != nil))
+}
+
+func TestGet_Progress(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that creates module and sum files, resolves any version query to v1.0.0 (unless FAIL_LIST is set) and builds
+	// empty binary.
+	goCmd := fakecmd.Go(t, dir, `
+  mod) for a in "$@"; do case "$a" in -modfile=*) printf 'module _\n\ngo 1.14\n' > "${a#-modfile=}" ;; esac; done ;;
+  list) [ -n "$FAIL_LIST" ] && exit 1; case "$*" in *" -m "*) echo v1.0.0 ;; *) echo main ;; esac ;;
+  get) for a in "$@"; do case "$a" in -modfile=*) f="${a#-modfile=}"; touch "${f%.mod}.sum" ;; esac; done ;;`)
+
+	r, err := runner.NewRunner(context.Background(), nil, false, goCmd)
+	testutil.Ok(t, err)
+
+	for _, tcase := range []struct {
+		name     string
+		noBuild  bool
+		failList bool
+		expected []string
+	}{
+		{
+			name:     "get",
+			expected: []string{"start tool", "phase tool resolve", "phase tool download", "phase tool build", "done tool false"},
+		},
+		{
+			name:     "no build",
+			noBuild:  true,
+			expected: []string{"start tool", "phase tool resolve", "phase tool download", "done tool false"},
+		},
+		{
+			name:     "failure",
+			failList: true,
+			expected: []string{"start tool", "phase tool resolve", "done tool true"},
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			if tcase.failList {
+				t.Setenv("FAIL_LIST", "1")
+			}
+			p := &recordingProgress{}
+			err := Get(context.Background(), r, GetOptions{
+				ModDir:     filepath.Join(t.TempDir(), ".bingo"),
+				GOBIN:      t.TempDir(),
+				ModulePath: "github.com/x/tool",
+				NoBuild:    tcase.noBuild,
+				Progress:   p,
+			})
+			testutil.Equals(t, tcase.failList, err != nil)
+			testutil.Equals(t, tcase.expected, p.events)
+		})
+	}
+}
+
+func TestGet_ModOnlyIfChanged(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that creates module and sum files, resolves any version query to v1.0.0 and builds empty binary.
+	d := newTestModDir(t, dir, `
+  mod) for a in "$@"; do case "$a" in -modfile=*) printf 'module _\n\ngo 1.14\n' > "${a#-modfile=}" ;; esac; done ;;
+  list) case "$*" in *" -m "*) echo v1.0.0 ;; *) echo main ;; esac ;;
+  get) for a in "$@"; do case "$a" in -modfile=*) f="${a#-modfile=}"; touch "${f%.mod}.sum" ;; esac; done ;;`)
+	modDir, r := d.modDir, d.r
+
+	opts := GetOptions{ModDir: modDir, GOBIN: d.gobin, ModulePath: "github.com/x/tool", Version: "v1.0.0"}
+	testutil.Ok(t, Get(context.Background(), r, opts))
+
+	modFile := filepath.Join(modDir, "tool.mod")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	testutil.Ok(t, os.Chtimes(modFile, past, past))
+
+	// No-op get leaves the module file untouched.
+	testutil.Ok(t, Get(context.Background(), r, opts))
+	fi, err := os.Stat(modFile)
+	testutil.Ok(t, err)
+	testutil.Equals(t, past, fi.ModTime())
+	_, err = os.Stat(filepath.Join(modDir, "tool.tmp.mod"))
+	testutil.Assert(t, os.IsNotExist(err), "expected temporary module file to be removed, got %v", err)
+
+	// Changed pin rewrites it.
+	opts.Comment = "The tool."
+	testutil.Ok(t, Get(context.Background(), r, opts))
+	fi, err = os.Stat(modFile)
+	testutil.Ok(t, err)
+	testutil.Assert(t, fi.ModTime().After(past), "expected module file to be rewritten")
+}
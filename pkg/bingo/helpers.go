@@ -4,42 +4,286 @@
 package bingo
 
 import (
+	"go/parser"
+	"go/token"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"text/template"
+	"unicode"
 
+	"github.com/bwplotka/bingo/pkg/atomicfile"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errcapture"
 	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
 )
 
+// MakefileTargetsPathFile is a file in mod directory that records path (relative to mod directory) of the optional Makefile
+// with per-tool targets, so it's regenerated together with other helpers.
+const MakefileTargetsPathFile = ".genmakefile"
+
+// SetMakefileTargetsPath records the given path (relative to the current directory) of Makefile with per-tool targets
+// generated by GenHelpers from now on.
+func SetMakefileTargetsPath(relModDir, path string) error {
+	return writeGeneratedPath(relModDir, MakefileTargetsPathFile, path)
+}
+
+// makefileTargetsPath returns path (relative to mod directory) of Makefile with per-tool targets or empty string if not enabled.
+func makefileTargetsPath(modDir string) (string, error) {
+	return readGeneratedPath(modDir, MakefileTargetsPathFile)
+}
+
+// LibraryStubPathFile is a file in mod directory that records path (relative to mod directory) of the optional Go file
+// importing all pinned tools, so it's regenerated together with other helpers.
+const LibraryStubPathFile = ".genlibrary"
+
+// SetLibraryStubPath records the given path (relative to the current directory) of Go file generated by GenHelpers from
+// now on. The file is build-tagged with "tools" and imports all pinned tools, so their modules are tracked by go.mod of the
+// main module, with go:generate directive per tool that syncs go.mod with the version pinned by bingo.
+func SetLibraryStubPath(relModDir, path string) error {
+	return writeGeneratedPath(relModDir, LibraryStubPathFile, path)
+}
+
+// libraryStubPath returns path (relative to mod directory) of the Go file importing tools or empty string if not enabled.
+func libraryStubPath(modDir string) (string, error) {
+	return readGeneratedPath(modDir, LibraryStubPathFile)
+}
+
+// writeGeneratedPath records the given path (relative to the current directory) of generated file in the given file of
+// mod directory, relative to mod directory.
+func writeGeneratedPath(relModDir, file, path string) error {
+	absModDir, err := filepath.Abs(relModDir)
+	if err != nil {
+		return err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(absModDir, absPath)
+	if err != nil {
+		return errors.Wrapf(err, "path of %v relative to %v", path, relModDir)
+	}
+	return os.WriteFile(filepath.Join(relModDir, file), []byte(filepath.ToSlash(rel)+"\n"), 0666)
+}
+
+// readGeneratedPath returns path (relative to mod directory) of generated file recorded in the given file of mod
+// directory or empty string if there is no such file.
+func readGeneratedPath(modDir, file string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(modDir, file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return filepath.FromSlash(strings.TrimSpace(string(b))), nil
+}
+
+// OutputDirFile is a file in mod directory that records path (relative to mod directory) of the directory where binaries
+// are installed instead of GOBIN, so all bingo commands and helpers use it.
+const OutputDirFile = ".outputdir"
+
+// SetOutputDir records the given path (relative to the current directory) of the directory where binaries are installed
+// from now on instead of GOBIN. Empty path removes the record, so GOBIN is used again.
+func SetOutputDir(relModDir, path string) error {
+	if path == "" {
+		if err := os.Remove(filepath.Join(relModDir, OutputDirFile)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return writeGeneratedPath(relModDir, OutputDirFile, path)
+}
+
+// outputDir returns path (relative to mod directory) of the directory where binaries are installed or empty string if
+// GOBIN is used.
+func outputDir(modDir string) (string, error) {
+	return readGeneratedPath(modDir, OutputDirFile)
+}
+
+// VariablesTemplateFile is an optional file in mod directory with Go text/template used instead of the default one to
+// generate variables.env, e.g. to follow other variable naming convention. The template gets the same data as the default
+// one: .MainPackages with .Name, .EnvVarName, .ModPath, .PackagePath, .Versions (with .Version) and .BinaryFile <version>
+// of each tool, .Version of bingo, and .OutputDir and .ShellOutputDir. Functions upper, lower, replace and camelCase are
+// available to transform names.
+const VariablesTemplateFile = "variables.tmpl"
+
+// helperFuncs are functions available in helper templates.
+var helperFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// Replace takes the string last, so it can be used in pipelines, e.g. {{ .Name | replace "-" "_" }}.
+	"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"camelCase": camelCase,
+}
+
+// camelCase returns the given name with non-alphanumeric characters removed and characters following them in upper case,
+// e.g. "golangciLint" for "golangci-lint".
+func camelCase(name string) string {
+	var (
+		b     strings.Builder
+		upper bool
+	)
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = b.Len() > 0
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// variablesTemplate returns the template from VariablesTemplateFile in mod directory or the default one if there is no
+// such file. It fails if the template is not valid.
+func variablesTemplate(modDir string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(modDir, VariablesTemplateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return templatesByFileExt["env"], nil
+		}
+		return "", err
+	}
+	if _, err := template.New(VariablesTemplateFile).Funcs(helperFuncs).Parse(string(b)); err != nil {
+		return "", errors.Wrapf(err, "invalid template in %v", filepath.Join(modDir, VariablesTemplateFile))
+	}
+	return string(b), nil
+}
+
+// BinDir returns absolute path of the directory where binaries of the tools pinned in modDir are installed. It's the
+// output directory recorded with SetOutputDir if any, otherwise GOBIN (see GoBin).
+func BinDir(runnable runner.Runnable, modDir string) (string, error) {
+	dir, err := outputDir(modDir)
+	if err != nil {
+		return "", errors.Wrap(err, "read output directory")
+	}
+	if dir == "" {
+		return GoBin(runnable)
+	}
+	absModDir, err := filepath.Abs(modDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(absModDir, dir), nil
+}
+
+// helperFile returns name of the helper file with variables for the given extension (e.g. "env" or "mk").
+func helperFile(ext string) string {
+	if ext == "mk" {
+		// Exception: for backward compatibility.
+		return "Variables.mk"
+	}
+	return "variables." + ext
+}
+
 // RemoveHelpers deletes helpers from mod directory.
 func RemoveHelpers(modDir string) error {
-	for ext := range templatesByFileExt {
-		v := "variables." + ext
-		if ext == "mk" {
-			// Exception: for backward compatibility.
-			v = "Variables.mk"
+	mk, err := makefileTargetsPath(modDir)
+	if err != nil {
+		return err
+	}
+	if mk != "" {
+		if err := os.RemoveAll(filepath.Join(modDir, mk)); err != nil {
+			return err
+		}
+	}
+	stub, err := libraryStubPath(modDir)
+	if err != nil {
+		return err
+	}
+	if stub != "" {
+		if err := os.RemoveAll(filepath.Join(modDir, stub)); err != nil {
+			return err
 		}
-		if err := os.RemoveAll(filepath.Join(modDir, v)); err != nil {
+	}
+	for ext := range templatesByFileExt {
+		if err := os.RemoveAll(filepath.Join(modDir, helperFile(ext))); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// RegenHelpers regenerates helpers (see GenHelpers) from module files currently pinned in the mod directory, or removes
+// them if there are none. Nothing is resolved or built, so it's cheap to run e.g. after helpers were deleted or the
+// repository was cloned on a new machine. Malformed module files are skipped.
+func RegenHelpers(logger logging.Logger, relModDir, version string, reproducible bool) error {
+	pkgs, err := ListPinnedMainPackages(logger, relModDir, false)
+	if err != nil {
+		return errors.Wrap(err, "list pinned")
+	}
+	if len(pkgs) == 0 {
+		return RemoveHelpers(relModDir)
+	}
+	return GenHelpers(relModDir, version, reproducible, pkgs)
+}
+
 // GenHelpers generates helpers to allows reliable binaries use. Regenerate if needed.
 // It is expected to have at least one mod file.
+// Helpers are written under the lock of variables.env, so concurrent bingo processes don't interleave writes.
+// If reproducible is true, Variables.mk builds tools with the same reproducible build flags as runner.WithReproducible.
 // TODO(bwplotka): Allow installing those optionally?
-func GenHelpers(relModDir, version string, pkgs []PackageRenderable) error {
+func GenHelpers(relModDir, version string, reproducible bool, pkgs []PackageRenderable) (err error) {
+	l, err := mod.Lock(filepath.Join(relModDir, helperFile("env")), mod.LockTimeout)
+	if err != nil {
+		return err
+	}
+	defer errcapture.Do(&err, l.Unlock, "unlock")
+
 	for ext, tmpl := range templatesByFileExt {
-		v := "variables." + ext
-		if ext == "mk" {
-			// Exception: for backward compatibility.
-			v = "Variables.mk"
+		if ext == "env" {
+			if tmpl, err = variablesTemplate(relModDir); err != nil {
+				return err
+			}
 		}
-		if err := genHelper(v, tmpl, relModDir, version, pkgs); err != nil {
+		v := helperFile(ext)
+		if err := genHelper(v, tmpl, relModDir, version, reproducible, pkgs); err != nil {
 			return errors.Wrap(err, v)
 		}
 	}
+
+	if err := genLibraryStub(relModDir, version, reproducible, pkgs); err != nil {
+		return err
+	}
+
+	mk, err := makefileTargetsPath(relModDir)
+	if err != nil {
+		return errors.Wrap(err, "read Makefile targets path")
+	}
+	if mk == "" {
+		return nil
+	}
+	if err := genHelper(mk, makefileTargetsTemplate, relModDir, version, reproducible, pkgs); err != nil {
+		return errors.Wrap(err, mk)
+	}
+	return nil
+}
+
+// genLibraryStub generates Go file importing pinned tools, if enabled with SetLibraryStubPath.
+func genLibraryStub(relModDir, version string, reproducible bool, pkgs []PackageRenderable) error {
+	stub, err := libraryStubPath(relModDir)
+	if err != nil {
+		return errors.Wrap(err, "read library stub path")
+	}
+	if stub == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(relModDir, stub)), os.ModePerm); err != nil {
+		return errors.Wrap(err, "create library stub directory")
+	}
+	if err := genHelper(stub, libraryStubTemplate, relModDir, version, reproducible, pkgs); err != nil {
+		return errors.Wrap(err, stub)
+	}
 	return nil
 }
 
@@ -48,31 +292,103 @@ type templateData struct {
 	GobinPath    string
 	MainPackages []PackageRenderable
 	RelModDir    string
+	// OutputDir is a path of the output directory relative to mod directory (see SetOutputDir). Empty if GOBIN is used.
+	OutputDir string
+	// ShellOutputDir is an absolute path of the output directory as shell expression, evaluated in the current directory.
+	ShellOutputDir string
+	// GoPackage is a package name of the generated Go file (see SetLibraryStubPath). Empty for other files.
+	GoPackage string
+	// GoImports are pinned packages with the version (the first one for tools pinned in many versions) imported by the
+	// generated Go file, sorted and without duplicates. Empty for other files.
+	GoImports []Package
+	// Reproducible is true if tools are built with reproducible build flags (see runner.WithReproducible).
+	Reproducible bool
 }
 
-func genHelper(f, tmpl, relModDir, version string, pkgs []PackageRenderable) error {
-	t, err := template.New(f).Parse(tmpl)
+func genHelper(f, tmpl, relModDir, version string, reproducible bool, pkgs []PackageRenderable) error {
+	t, err := template.New(f).Funcs(helperFuncs).Parse(tmpl)
 	if err != nil {
 		return errors.Wrap(err, "parse template")
 	}
 
+	// Mod directory relative to the generated file.
+	relToFile, err := filepath.Rel(filepath.Dir(filepath.Join(relModDir, f)), relModDir)
+	if err != nil {
+		return errors.Wrap(err, "relative mod directory")
+	}
 	data := templateData{
 		Version:      version,
 		MainPackages: pkgs,
+		RelModDir:    filepath.ToSlash(relToFile),
+		Reproducible: reproducible,
+	}
+	if filepath.Ext(f) == ".go" {
+		if data.GoPackage, err = goPackageName(filepath.Join(relModDir, f)); err != nil {
+			return errors.Wrap(err, "package name")
+		}
+		data.GoImports = goImports(pkgs)
+	}
+	outDir, err := outputDir(relModDir)
+	if err != nil {
+		return errors.Wrap(err, "read output directory")
+	}
+	if outDir != "" {
+		data.OutputDir = filepath.ToSlash(outDir)
+		data.ShellOutputDir = filepath.ToSlash(filepath.Join(relModDir, outDir))
+		if !filepath.IsAbs(data.ShellOutputDir) {
+			data.ShellOutputDir = "$(pwd)/" + data.ShellOutputDir
+		}
 	}
 
-	fb, err := os.Create(filepath.Join(relModDir, f))
+	// Written atomically, so interrupted run never leaves truncated helper that breaks sourcing or including it.
+	return atomicfile.Write(filepath.Join(relModDir, f), 0666, func(w io.Writer) error {
+		return t.Execute(w, data)
+	})
+}
+
+// goPackageName returns package name of Go files (except tests) in the directory of the given file, including the file
+// itself, or name of the directory if there are none (or "tools" if it's not valid identifier), so the file compiles
+// together with them.
+func goPackageName(file string) (string, error) {
+	dir := filepath.Dir(file)
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
 	if err != nil {
-		return errors.Wrap(err, "create")
+		return "", err
 	}
-	defer func() {
-		if cerr := fb.Close(); cerr != nil {
-			if err != nil {
-				err = errors.Wrapf(err, "additionally error on close: %v", cerr)
-				return
-			}
-			err = cerr
+	for _, m := range matches {
+		if strings.HasSuffix(m, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), m, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		return f.Name.Name, nil
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if name := filepath.Base(absDir); token.IsIdentifier(name) {
+		return name, nil
+	}
+	return "tools", nil
+}
+
+// goImports returns packages of the given tools with their first version, sorted by path and without duplicates.
+func goImports(pkgs []PackageRenderable) []Package {
+	seen := map[string]struct{}{}
+	var ret []Package
+	for _, p := range pkgs {
+		if _, ok := seen[p.PackagePath]; ok || len(p.Versions) == 0 {
+			continue
 		}
-	}()
-	return t.Execute(fb, data)
+		seen[p.PackagePath] = struct{}{}
+
+		relPath := strings.TrimPrefix(strings.TrimPrefix(p.PackagePath, p.ModPath), "/")
+		ret = append(ret, Package{Module: module.Version{Path: p.ModPath, Version: p.Versions[0].Version}, RelPath: relPath})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Path() < ret[j].Path() })
+	return ret
 }
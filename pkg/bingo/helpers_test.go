@@ -0,0 +1,313 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"go/format"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bwplotka/bingo/internal/fakecmd"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestGenHelpers_MakefileTargets(t *testing.T) {
+	root := t.TempDir()
+	modDir := filepath.Join(root, ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	pkgs := []PackageRenderable{
+		{
+			Name: "faillint", BinaryName: "faillint", EnvVarName: "FAILLINT",
+			Versions: []PackageVersionRenderable{{Version: "v1.5.0", ModFile: "faillint.mod"}},
+		},
+		{
+			Name: "buildable", BinaryName: "buildable", EnvVarName: "BUILDABLE_ARRAY",
+			Versions: []PackageVersionRenderable{{Version: "v1.0.0", ModFile: "buildable.mod"}, {Version: "v1.1.0", ModFile: "buildable.1.mod"}},
+		},
+	}
+
+	// Not generated unless enabled.
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	_, err := os.Stat(filepath.Join(root, "tools.mk"))
+	testutil.Equals(t, true, os.IsNotExist(err))
+
+	testutil.Ok(t, SetMakefileTargetsPath(modDir, filepath.Join(root, "tools.mk")))
+	expectContent(t, "../tools.mk\n", filepath.Join(modDir, MakefileTargetsPathFile))
+
+	expected := `# Auto generated binary variables and targets managed by https://github.com/bwplotka/bingo v0.9. DO NOT EDIT.
+# Regenerated on every 'bingo get'. Include it in your main Makefile instead of .bingo/Variables.mk, so e.g 'make <tool>'
+# ensures the pinned version of the tool is installed, using 'bingo get'.
+BINGO_DIR := $(dir $(lastword $(MAKEFILE_LIST))).bingo
+GOPATH    ?= $(shell go env GOPATH)
+GOBIN     ?= $(firstword $(subst :, ,${GOPATH}))/bin
+BINGO_CMD ?= bingo
+
+FAILLINT := $(GOBIN)/faillint-v1.5.0
+$(FAILLINT): $(BINGO_DIR)/faillint.mod
+	@echo "(re)installing $(FAILLINT)"
+	@$(BINGO_CMD) get --moddir=$(BINGO_DIR) faillint
+	@# Binary matching recorded checksum is not rebuilt, mark it as up to date with the module file.
+	@touch $@
+
+.PHONY: faillint
+faillint: $(FAILLINT)
+
+BUILDABLE_ARRAY := $(GOBIN)/buildable-v1.0.0 $(GOBIN)/buildable-v1.1.0
+$(BUILDABLE_ARRAY): $(BINGO_DIR)/buildable.mod $(BINGO_DIR)/buildable.1.mod
+	@echo "(re)installing $(BUILDABLE_ARRAY)"
+	@$(BINGO_CMD) get --moddir=$(BINGO_DIR) buildable
+	@# Binary matching recorded checksum is not rebuilt, mark it as up to date with the module file.
+	@touch $@
+
+.PHONY: buildable
+buildable: $(BUILDABLE_ARRAY)
+`
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	expectContent(t, expected, filepath.Join(root, "tools.mk"))
+
+	// Regeneration is idempotent.
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	expectContent(t, expected, filepath.Join(root, "tools.mk"))
+
+	testutil.Ok(t, RemoveHelpers(modDir))
+	_, err = os.Stat(filepath.Join(root, "tools.mk"))
+	testutil.Equals(t, true, os.IsNotExist(err))
+}
+
+func TestGenHelpers_OutputDir(t *testing.T) {
+	root := t.TempDir()
+	modDir := filepath.Join(root, ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	pkgs := []PackageRenderable{{
+		Name: "faillint", BinaryName: "faillint", EnvVarName: "FAILLINT",
+		Versions: []PackageVersionRenderable{{Version: "v1.5.0", ModFile: "faillint.mod"}},
+	}}
+
+	testutil.Ok(t, SetOutputDir(modDir, filepath.Join(root, "third_party", "bin")))
+	expectContent(t, "../third_party/bin\n", filepath.Join(modDir, OutputDirFile))
+
+	// Runner is not needed, since GOBIN is not used.
+	binDir, err := BinDir(nil, modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, filepath.Join(root, "third_party", "bin"), binDir)
+
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	expectContent(t, `# Auto generated binary variables helper managed by https://github.com/bwplotka/bingo v0.9. DO NOT EDIT.
+# All tools are designed to be build inside $GOBIN.
+# Those variables will work only until 'bingo get' was invoked, or if tools were installed via Makefile's Variables.mk.
+GOBIN="`+filepath.ToSlash(filepath.Join(root, "third_party", "bin"))+`"
+
+
+FAILLINT="${GOBIN}/faillint-v1.5.0"
+
+`, filepath.Join(modDir, "variables.env"))
+
+	b, err := os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(string(b), "\nGOPATH ?= $(shell go env GOPATH)\nGOBIN  := $(abspath $(BINGO_DIR)/../third_party/bin)\nGO     ?= $(shell which go)\n"), string(b))
+
+	testutil.Ok(t, SetOutputDir(modDir, ""))
+	_, err = os.Stat(filepath.Join(modDir, OutputDirFile))
+	testutil.Assert(t, os.IsNotExist(err))
+	// Removing again is a no-op.
+	testutil.Ok(t, SetOutputDir(modDir, ""))
+}
+
+func TestGenHelpers_VariablesTemplate(t *testing.T) {
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	pkgs := []PackageRenderable{
+		{
+			Name: "golangci-lint", BinaryName: "golangci-lint", EnvVarName: "GOLANGCI_LINT",
+			ModPath: "github.com/golangci/golangci-lint", PackagePath: "github.com/golangci/golangci-lint/cmd/golangci-lint",
+			Versions: []PackageVersionRenderable{{Version: "v1.35.2", ModFile: "golangci-lint.mod"}},
+		},
+	}
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, VariablesTemplateFile), []byte(`{{- range $p := .MainPackages }}
+{{- range $p.Versions }}
+export TOOL_{{ $p.Name | replace "-" "_" | upper }}="$GOBIN/{{ $p.BinaryFile .Version }}" # {{ $p.ModPath }}@{{ .Version }}
+{{ camelCase $p.Name }}Bin={{ $p.BinaryFile .Version }}
+{{- end }}
+{{- end }}
+`), os.ModePerm))
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	expectContent(t, `
+export TOOL_GOLANGCI_LINT="$GOBIN/golangci-lint-v1.35.2" # github.com/golangci/golangci-lint@v1.35.2
+golangciLintBin=golangci-lint-v1.35.2
+`, filepath.Join(modDir, "variables.env"))
+
+	// Other helpers use default templates.
+	b, err := os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(string(b), "GOLANGCI_LINT := $(GOBIN)/golangci-lint-v1.35.2\n"), string(b))
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, VariablesTemplateFile), []byte("{{ range .MainPackages }}\n"), os.ModePerm))
+	err = GenHelpers(modDir, "v0.9", true, pkgs)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.HasPrefix(err.Error(), "invalid template in "+filepath.Join(modDir, VariablesTemplateFile)+": "), err.Error())
+}
+
+func TestGenHelpers_LdflagsTemplate(t *testing.T) {
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	pkgs := []PackageRenderable{{
+		Name: "tool", BinaryName: "tool", EnvVarName: "TOOL", ModPath: "github.com/x/tool", PackagePath: "github.com/x/tool",
+		Versions:   []PackageVersionRenderable{{Version: "v1.0.0", ModFile: "tool.mod"}},
+		BuildFlags: []string{"-tags=x", "-ldflags=-X main.version={{.GitDescribe}}"},
+	}}
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+
+	b, err := os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, !strings.Contains(string(b), "{{"), string(b))
+	testutil.Assert(t, strings.Contains(string(b), "\n\t@# -ldflags with placeholders can be filled only by 'bingo get', so it's omitted here."), string(b))
+	testutil.Assert(t, strings.Contains(string(b), "$(GO) build -mod=mod $(BINGO_TRIMPATH) $(BINGO_BUILDVCS) -tags=x -modfile=tool.mod "), string(b))
+}
+
+func TestGenHelpers_Reproducible(t *testing.T) {
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	pkgs := []PackageRenderable{{
+		Name: "tool", BinaryName: "tool", EnvVarName: "TOOL", ModPath: "github.com/x/tool", PackagePath: "github.com/x/tool",
+		Versions:   []PackageVersionRenderable{{Version: "v1.0.0", ModFile: "tool.mod"}},
+		BuildFlags: []string{"-trimpath=false"},
+	}}
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	b, err := os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(string(b), "\nBINGO_TRIMPATH := -trimpath\n"), string(b))
+	// Flags of the tool take precedence.
+	testutil.Assert(t, strings.Contains(string(b), "$(GO) build -mod=mod $(BINGO_BUILDVCS) -trimpath=false -modfile=tool.mod "), string(b))
+
+	// As with bingo get --reproducible=false.
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", false, pkgs))
+	b, err = os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, !strings.Contains(string(b), "BINGO_TRIMPATH"), string(b))
+	testutil.Assert(t, strings.Contains(string(b), "$(GO) build -mod=mod -trimpath=false -modfile=tool.mod "), string(b))
+}
+
+func TestGenHelpers_EnvVarReferences(t *testing.T) {
+	dir := t.TempDir()
+	modDir := filepath.Join(dir, ".bingo")
+	gobin := filepath.Join(dir, "bin")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	pkgs := []PackageRenderable{{
+		Name: "tool", BinaryName: "tool", EnvVarName: "TOOL", ModPath: "github.com/x/tool", PackagePath: "github.com/x/tool",
+		Versions:     []PackageVersionRenderable{{Version: "v1.0.0", ModFile: "tool.mod"}},
+		BuildEnvVars: []string{"CGO_CPPFLAGS=-D$ARCH", "CGO_CFLAGS=-I${MYSDK}/include -O2", "CGO_LDFLAGS=-Wl,-rpath,$$ORIGIN"},
+	}}
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", false, pkgs))
+	b, err := os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(string(b), ` CGO_CPPFLAGS=-D$${ARCH} CGO_CFLAGS="-I$${MYSDK}/include -O2" CGO_LDFLAGS=-Wl,-rpath,\$$ORIGIN $(GO) build `), string(b))
+
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make is not installed")
+	}
+	// References are expanded from the environment at build time, as bingo get does, and escaped $ is kept.
+	goCmd := fakecmd.Go(t, dir, `build) echo "$CGO_CPPFLAGS|$CGO_CFLAGS|$CGO_LDFLAGS" > "$ENVS_FILE"; for a in "$@"; do case "$a" in -o=*) echo bin > "${a#-o=}" ;; esac; done ;;`)
+	envsFile := filepath.Join(dir, "envs")
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "tool.mod"), nil, os.ModePerm))
+	cmd := exec.Command("make", "-f", filepath.Join(modDir, "Variables.mk"), "GO="+goCmd, "GOBIN="+gobin, filepath.Join(gobin, "tool-v1.0.0"))
+	cmd.Env = append(os.Environ(), "ENVS_FILE="+envsFile, "ARCH=arm64", "MYSDK=/opt/sdk", "ORIGIN=wrong")
+	out, err := cmd.CombinedOutput()
+	testutil.Ok(t, err, string(out))
+	expectContent(t, "-Darm64|-I/opt/sdk/include -O2|-Wl,-rpath,$ORIGIN\n", envsFile)
+}
+
+func TestGenHelpers_LibraryStub(t *testing.T) {
+	dir := t.TempDir()
+	modDir := filepath.Join(dir, ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	pkgs := []PackageRenderable{
+		{
+			Name: "golangci-lint", BinaryName: "golangci-lint", EnvVarName: "GOLANGCI_LINT",
+			ModPath: "github.com/golangci/golangci-lint", PackagePath: "github.com/golangci/golangci-lint/cmd/golangci-lint",
+			Versions: []PackageVersionRenderable{{Version: "v1.35.2", ModFile: "golangci-lint.mod"}},
+		},
+		{
+			Name: "faillint", BinaryName: "faillint", EnvVarName: "FAILLINT",
+			ModPath: "github.com/fatih/faillint", PackagePath: "github.com/fatih/faillint",
+			Versions: []PackageVersionRenderable{{Version: "v1.5.0", ModFile: "faillint.mod"}, {Version: "v1.4.0", ModFile: "faillint.1.mod"}},
+		},
+	}
+
+	stub := filepath.Join(dir, "internal", "tools", "tools.go")
+	testutil.Ok(t, SetLibraryStubPath(modDir, stub))
+	b, err := os.ReadFile(filepath.Join(modDir, LibraryStubPathFile))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "../internal/tools/tools.go\n", string(b))
+
+	expected := `// Code generated by https://github.com/bwplotka/bingo v0.9. DO NOT EDIT.
+
+//go:build tools
+// +build tools
+
+// Regenerated on every 'bingo get'. Imports below make go.mod of the main module track pinned tools, so they can be run
+// with 'go run <package>', e.g. in go:generate directives. Run 'go generate' on this file to sync go.mod with versions
+// pinned by bingo.
+package tools
+
+//go:generate go get github.com/fatih/faillint@v1.5.0
+//go:generate go get github.com/golangci/golangci-lint/cmd/golangci-lint@v1.35.2
+
+import (
+	_ "github.com/fatih/faillint"
+	_ "github.com/golangci/golangci-lint/cmd/golangci-lint"
+)
+`
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	expectContent(t, expected, stub)
+	formatted, err := format.Source([]byte(expected))
+	testutil.Ok(t, err)
+	testutil.Equals(t, expected, string(formatted))
+
+	// Regenerating is idempotent.
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	expectContent(t, expected, stub)
+
+	// Package name of other files in the directory is kept.
+	testutil.Ok(t, os.Remove(stub))
+	testutil.Ok(t, os.WriteFile(filepath.Join(dir, "internal", "tools", "doc.go"), []byte("// Package mytools.\npackage mytools\n"), os.ModePerm))
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	expectContent(t, strings.Replace(expected, "package tools\n", "package mytools\n", 1), stub)
+
+	testutil.Ok(t, RemoveHelpers(modDir))
+	_, err = os.Stat(stub)
+	testutil.Assert(t, os.IsNotExist(err), "expected library stub removed, got %v", err)
+}
+
+func TestRegenHelpers(t *testing.T) {
+	modDir := filepath.Join(t.TempDir(), ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+	logger := logging.Discard
+
+	modFile := filepath.Join(modDir, "faillint.mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte("module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT\n\ngo 1.14\n\nrequire github.com/fatih/faillint v1.5.0\n"), os.ModePerm))
+	testutil.Ok(t, RegenHelpers(logger, modDir, "v0.9", true))
+	b, err := os.ReadFile(filepath.Join(modDir, "variables.env"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(string(b), `FAILLINT="${GOBIN}/faillint-v1.5.0"`), string(b))
+	_, err = os.Stat(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+
+	// Helpers are removed if no tool is pinned anymore.
+	testutil.Ok(t, os.Remove(modFile))
+	testutil.Ok(t, RegenHelpers(logger, modDir, "v0.9", true))
+	_, err = os.Stat(filepath.Join(modDir, "variables.env"))
+	testutil.Assert(t, os.IsNotExist(err), "expected variables.env removed, got %v", err)
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/efficientgo/core/errors"
+)
+
+// IgnoreFile is a file in mod directory with tool name patterns (see path.Match), one per line, of tools that bingo get
+// without arguments skips. Empty lines and lines starting with # are ignored. Tools referenced explicitly are got anyway.
+const IgnoreFile = ".bingoignore"
+
+// IgnorePatterns returns tool name patterns from IgnoreFile in the given mod directory, or nil if there is no such file.
+func IgnorePatterns(modDir string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(modDir, IgnoreFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var patterns []string
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, errors.Wrapf(err, "%v:%d: pattern %q", IgnoreFile, i+1, line)
+		}
+		patterns = append(patterns, strings.ToLower(line))
+	}
+	return patterns, nil
+}
+
+// FilterIgnored splits pinned tools into ones with name not matching and matching any of the given patterns (see path.Match).
+func (pkgs PackageRenderables) FilterIgnored(patterns []string) (kept PackageRenderables, ignored PackageRenderables) {
+	for _, p := range pkgs {
+		if matchesAny(patterns, p.Name) {
+			ignored = append(ignored, p)
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return kept, ignored
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		// Patterns are validated when loaded.
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
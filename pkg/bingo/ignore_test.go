@@ -0,0 +1,35 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestIgnorePatterns(t *testing.T) {
+	dir := t.TempDir()
+
+	patterns, err := IgnorePatterns(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string(nil), patterns)
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(dir, IgnoreFile), []byte("# Heavy tools.\nprotoc-gen-*\n\n  golangci-LINT  \n"), 0600))
+	patterns, err = IgnorePatterns(dir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"protoc-gen-*", "golangci-lint"}, patterns)
+
+	pkgs := PackageRenderables{{Name: "faillint"}, {Name: "golangci-lint"}, {Name: "protoc-gen-go"}, {Name: "protoc-gen-go-grpc"}}
+	kept, ignored := pkgs.FilterIgnored(patterns)
+	testutil.Equals(t, PackageRenderables{{Name: "faillint"}}, kept)
+	testutil.Equals(t, PackageRenderables{{Name: "golangci-lint"}, {Name: "protoc-gen-go"}, {Name: "protoc-gen-go-grpc"}}, ignored)
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(dir, IgnoreFile), []byte("faillint\nproto[\n"), 0600))
+	_, err = IgnorePatterns(dir)
+	testutil.NotOk(t, err)
+	testutil.Equals(t, `.bingoignore:2: pattern "proto[": syntax error in pattern`, err.Error())
+}
@@ -0,0 +1,1083 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/bwplotka/bingo/pkg/version"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
+	"mvdan.cc/sh/v3/shell"
+)
+
+// GoBin mimics the way go install finds where to install go tool.
+func GoBin(runnable runner.Runnable) (string, error) {
+	binPath, err := runnable.GoEnv("GOBIN")
+	if err != nil {
+		return "", errors.Wrap(err, "go env GOBIN")
+	}
+	if binPath != "" {
+		return binPath, nil
+	}
+
+	gpath, err := runnable.GoEnv("GOPATH")
+	if err != nil {
+		return "", errors.Wrap(err, "go env GOPATH")
+	}
+	return filepath.Join(gpath, "bin"), nil
+}
+
+// BinaryNames returns binary names for given direct packages of the tool.
+func BinaryNames(name string, pkgs []Package) ([]string, error) {
+	names := make([]string, 0, len(pkgs))
+	for i, pkg := range pkgs {
+		pkgName := name
+		switch {
+		case pkg.Name != "":
+			pkgName = pkg.Name
+		case i > 0:
+			// Additional packages from the same module are named after their package directory.
+			pkgName = path.Base(pkg.RelPath)
+		}
+		if err := validateTargetName(pkgName); err != nil {
+			return nil, errors.Wrap(err, pkg.String())
+		}
+		names = append(names, pkgName)
+	}
+	return names, nil
+}
+
+// BinChecksumKeyFor returns checksum key of the binary built in the given context (with package build environment variables).
+func BinChecksumKeyFor(modCtx runner.Runnable, name string, pkg Package) (BinChecksumKey, error) {
+	platform, err := modCtx.GoEnv("GOOS", "GOARCH")
+	if err != nil {
+		return BinChecksumKey{}, errors.Wrap(err, "go env GOOS GOARCH")
+	}
+	goos, goarch, _ := cut(platform, "\n")
+	return BinChecksumKey{Name: name, Version: pkg.Module.Version, GOOS: goos, GOARCH: goarch}, nil
+}
+
+// IsUpToDate returns true if binary exists and matches the recorded checksum. It returns error on checksum mismatch.
+func IsUpToDate(modDir string, key BinChecksumKey, binPath string) (bool, error) {
+	if _, err := os.Stat(binPath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return VerifyBinChecksum(modDir, key, binPath)
+}
+
+// IsInstallUpToDate returns true if the last Install of the module file installed all its binaries from the same module
+// and sum file content, with the same go version and build attributes (see BinMeta), and the binaries (and links, if
+// link is true) are still in place and match recorded checksums, so installing it again would change nothing. It's
+// conservative: anything it can't confirm, e.g. meta file written by older bingo or local replace, makes it return false.
+func IsInstallUpToDate(ctx context.Context, logger logging.Logger, r *runner.Runner, modDir, gobin, name string, link bool, linkDir string, modFile *ModFile) (bool, error) {
+	if modFile.Validate() != nil {
+		return false, nil
+	}
+	ic, err := newInstallContext(ctx, logger, r, modDir, name, modFile)
+	if err != nil {
+		return false, err
+	}
+	if gobin == "" {
+		gobin, err = BinDir(ic.modCtx, modDir)
+		if err != nil {
+			return false, errors.Wrap(err, "deduct GOBIN")
+		}
+	}
+	metas, err := ReadBinMeta(modFile.Filepath())
+	if err != nil {
+		return false, err
+	}
+	hash, err := modFileHash(modFile.Filepath(), ic.modCtx.GoVersion().String())
+	if err != nil {
+		return false, err
+	}
+	installed := make(map[string]BinMeta, len(metas))
+	for _, m := range metas {
+		installed[m.Name] = m
+	}
+	for i, pkg := range ic.pkgs {
+		m, ok := installed[ic.names[i]]
+		binPath := versionedBinPath(gobin, ic.names[i], pkg)
+		if !ok || modFile.IsLocallyReplaced(pkg.Module.Path) || m.ModFileHash != hash || m.BuildKey != buildKey(modFile, pkg) || m.BinaryPath != binPath {
+			return false, nil
+		}
+		key, err := BinChecksumKeyFor(r.With(ctx, modFile.Filepath(), modDir, packageBuildEnvs(modFile, ic.toolchainEnvs, pkg)), ic.names[i], pkg)
+		if err != nil {
+			return false, err
+		}
+		// Checksum mismatch is reported by Install.
+		if upToDate, err := IsUpToDate(modDir, key, binPath); err != nil || !upToDate {
+			return false, nil
+		}
+		if !link {
+			continue
+		}
+		linkName := ic.names[i] + pkg.PlatformSuffix() + pkg.ExeSuffix()
+		for _, dir := range []string{gobin, linkDir} {
+			if dir == "" {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(dir, linkName)); err != nil {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// Rebuild tells if Build rebuilds binaries that exist and match recorded checksums.
+type Rebuild int
+
+const (
+	// RebuildIfChanged rebuilds only binaries that don't exist, don't match recorded checksums or were built with different
+	// build attributes or go directive, as recorded in the meta file (see BinMeta.BuildKey). It's the default.
+	RebuildIfChanged Rebuild = iota
+	// RebuildForce always rebuilds binaries, e.g. after Go was upgraded.
+	RebuildForce
+	// RebuildClean always rebuilds binaries with all packages (go build -a), without using the build cache. Install also
+	// removes the module from the module cache first, so it's downloaded and verified again, e.g. if the cache got corrupted.
+	RebuildClean
+)
+
+// Install updates the given module file with UpdateModFile and builds its direct packages with Build.
+func Install(ctx context.Context, logger logging.Logger, r *runner.Runner, modDir, gobin, name string, link bool, linkDir string, rebuild Rebuild, modFile *ModFile) error {
+	ic, err := newInstallContext(ctx, logger, r, modDir, name, modFile)
+	if err != nil {
+		return err
+	}
+	if rebuild == RebuildClean {
+		if err := ic.removeFromModCache(); err != nil {
+			return err
+		}
+	}
+	if err := ic.updateModFile(); err != nil {
+		return err
+	}
+	return ic.build(gobin, link, linkDir, rebuild)
+}
+
+// removeFromModCache removes the version of the direct module from the module cache, unless it's locally replaced.
+func (ic *installContext) removeFromModCache() error {
+	m := ic.modFile.DirectPackage().Module
+	if ic.modFile.IsLocallyReplaced(m.Path) {
+		return nil
+	}
+	cache, err := ic.r.GoModCache(ic.ctx)
+	if err != nil {
+		return err
+	}
+	if err := RemoveFromModCache(cache, m); err != nil {
+		return errors.Wrapf(err, "remove %v from module cache", m.String())
+	}
+	ic.logger.Infof("removed %v from module cache %v\n", m.String(), cache)
+	return nil
+}
+
+// RemoveFromModCache removes extracted source and downloaded files of the given module version from the module cache.
+// Files go writes are read-only, so they are made writable first.
+func RemoveFromModCache(cache string, m module.Version) error {
+	escPath, err := module.EscapePath(m.Path)
+	if err != nil {
+		return err
+	}
+	escVersion, err := module.EscapeVersion(m.Version)
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cache, "cache", "download", escPath, "@v", escVersion+".*"))
+	if err != nil {
+		return err
+	}
+	paths = append(paths, filepath.Join(cache, escPath+"@"+escVersion))
+	for _, p := range paths {
+		if err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return os.Chmod(path, 0777)
+			}
+			return nil
+		}); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateModFile checks that all direct packages of the given module file are main packages and resolves their dependencies
+// with go get -d, so the module file and its sum file are complete for build. Nothing is built, so it can be used to update
+// module files on one machine and Build them on another.
+func UpdateModFile(ctx context.Context, logger logging.Logger, r *runner.Runner, modDir, name string, modFile *ModFile) error {
+	ic, err := newInstallContext(ctx, logger, r, modDir, name, modFile)
+	if err != nil {
+		return err
+	}
+	return ic.updateModFile()
+}
+
+// Build builds all direct packages of the given module file (with build attributes from their env files merged) into gobin (BinDir if empty) as <binary name>-<version> binaries and records
+// their checksums in modDir and metadata in the meta file (see MetaFilePath) of the module file. Binaries matching recorded checksums are not rebuilt, unless rebuild says so. If link is true, <binary name> symlink
+// to the versioned binary is also created in gobin and, if not empty, in linkDir. Module file is expected to be complete (see UpdateModFile).
+func Build(ctx context.Context, logger logging.Logger, r *runner.Runner, modDir, gobin, name string, link bool, linkDir string, rebuild Rebuild, modFile *ModFile) error {
+	ic, err := newInstallContext(ctx, logger, r, modDir, name, modFile)
+	if err != nil {
+		return err
+	}
+	return ic.build(gobin, link, linkDir, rebuild)
+}
+
+// installContext is a state shared by install stages of the single module file.
+type installContext struct {
+	ctx     context.Context
+	logger  logging.Logger
+	r       *runner.Runner
+	modDir  string
+	modFile *ModFile
+
+	pkgs          []Package
+	names         []string
+	toolchainEnvs envars.EnvSlice
+	fetchEnvs     envars.EnvSlice
+	modCtx        runner.Runnable
+}
+
+func newInstallContext(ctx context.Context, logger logging.Logger, r *runner.Runner, modDir, name string, modFile *ModFile) (_ *installContext, err error) {
+	pkgs := modFile.DirectPackages()
+	for i := range pkgs {
+		if pkgs[i], err = pkgs[i].WithEnvFile(logger, modDir); err != nil {
+			return nil, err
+		}
+	}
+	names, err := BinaryNames(name, pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	toolchainEnvs, err := toolchainEnvs(logger, r.With(ctx, modFile.Filepath(), modDir, nil), modFile.Toolchain())
+	if err != nil {
+		return nil, err
+	}
+	// Module fetch settings (e.g. GOPROXY) of the tool apply also to resolving and downloading its dependencies.
+	fetchEnvs := envars.EnvSlice(envars.MergeEnvSlices(toolchainEnvs, pkgs[0].ModuleFetchEnvs()...))
+	if modFile.IsSumDBDisabled() {
+		logger.Warnf("%v: %v is set, so %v is not verified against the Go checksum database. "+
+			"Nothing detects if its server serves different (e.g. malicious) code to you than to others; only later downloads "+
+			"of the same version are checked against the tool's .sum file. Use it only for trusted servers the checksum database can't reach.\n",
+			name, NoSumDBDirective, pkgs[0].Module.Path)
+		fetchEnvs = SumDBDisabledEnvs(pkgs[0].Module.Path, fetchEnvs)
+	}
+	if fetchEnvs, err = PrivateModuleEnvs(pkgs[0].Module.Path, os.Getenv(PrivateModulesEnv), fetchEnvs); err != nil {
+		return nil, err
+	}
+	return &installContext{
+		ctx:           ctx,
+		logger:        logger,
+		r:             r,
+		modDir:        modDir,
+		modFile:       modFile,
+		pkgs:          pkgs,
+		names:         names,
+		toolchainEnvs: toolchainEnvs,
+		fetchEnvs:     fetchEnvs,
+		modCtx:        r.With(ctx, modFile.Filepath(), modDir, fetchEnvs),
+	}, nil
+}
+
+func (ic *installContext) updateModFile() error {
+	if ic.modFile.IsVendored() {
+		if ic.isVendorUpToDate() {
+			// Everything is in VendorDir already, so there is nothing to get (and no network might be available).
+			for _, pkg := range ic.pkgs {
+				if err := checkMainPackage(ic.modCtx, pkg); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// Resolve dependencies against real modules, so vendored sources are refreshed.
+		if err := ic.modFile.dropVendorReplaces(); err != nil {
+			return err
+		}
+	}
+
+	getArgs := make([]string, 0, len(ic.pkgs))
+	for _, pkg := range ic.pkgs {
+		// Check if path is pointing to non-buildable package, before go fails deep in the build.
+		if err := checkMainPackage(ic.modCtx, pkg); err != nil {
+			return err
+		}
+		if ic.modFile.IsLocallyReplaced(pkg.Module.Path) {
+			// Local code has no version to get, go build fetches its dependencies.
+			continue
+		}
+		getArgs = append(getArgs, pkg.String())
+	}
+
+	if len(getArgs) > 0 {
+		if ic.modFile.IsGoVersionPinned() && ic.modFile.GoVersion() != "" && version.AtLeast(ic.modCtx.GoVersion(), version.Go121) {
+			// Since Go 1.21, go get raises go directive to what dependencies require. Ask for the pinned one instead, so
+			// go get fails if it's not enough. Older Go never changes it.
+			getArgs = append(getArgs, "go@"+ic.modFile.GoVersion())
+		}
+		// Use go get -d to recreate .sum file
+		// TODO(bwplotka): Do it only if not present or if we update mod to new version?
+		if out, err := ic.modCtx.GetD(getArgs...); err != nil {
+			return errors.Wrap(err, out)
+		}
+		if ic.modFile.IsVendored() {
+			return ic.vendor()
+		}
+		return nil
+	}
+	// Local module without dependencies has no sums, but .sum file is expected next to the module file.
+	f, err := os.OpenFile(SumFilePath(ic.modFile.Filepath()), os.O_CREATE|os.O_RDONLY, 0666)
+	if err != nil {
+		return errors.Wrap(err, "create sum file")
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(err, "close sum file")
+	}
+	return nil
+}
+
+// checkMainPackage returns error if the package is not a main package, e.g. RelPath points to a library or the module root
+// (empty RelPath) is not a command. Error lists main packages of the module (if any) to use instead.
+func checkMainPackage(modCtx runner.Runnable, pkg Package) error {
+	// Package build flags go after defaults, so e.g. their -mod takes precedence.
+	listArgs := append([]string{"-mod=mod"}, pkg.BuildFlags...)
+	listOutput, err := modCtx.List(append(listArgs, "-f={{.Name}}", pkg.Path())...)
+	if err == nil && strings.HasSuffix(listOutput, "main") {
+		return nil
+	}
+
+	what := "package " + pkg.Path()
+	if pkg.RelPath == "" {
+		what = "module root package " + pkg.Path()
+	}
+	hint := ""
+	if mains := mainPackages(modCtx, listArgs, pkg.Module.Path); len(mains) > 0 {
+		hint = fmt.Sprintf("; use one of main packages of module %v instead: %v", pkg.Module.String(), strings.Join(mains, ", "))
+	}
+	if err != nil {
+		return errors.Wrapf(err, "list %v%v", what, hint)
+	}
+	return errors.Newf("%v is non-main (go list output %q), nothing to get and build%v", what, listOutput, hint)
+}
+
+// mainPackages returns paths of main packages in the module, best effort. Nil is returned if they can't be listed.
+func mainPackages(modCtx runner.Runnable, listArgs []string, modulePath string) []string {
+	out, err := modCtx.List(append(listArgs, `-f={{if eq .Name "main"}}{{.ImportPath}}{{end}}`, modulePath+"/...")...)
+	if err != nil {
+		return nil
+	}
+	var ret []string
+	for _, l := range strings.Split(out, "\n") {
+		// Output contains also go logs, e.g. about downloading.
+		if l = strings.TrimSpace(l); l == modulePath || strings.HasPrefix(l, modulePath+"/") {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+func (ic *installContext) build(gobin string, link bool, linkDir string, rebuild Rebuild) (err error) {
+	if gobin == "" {
+		gobin, err = BinDir(ic.modCtx, ic.modDir)
+		if err != nil {
+			return errors.Wrap(err, "deduct GOBIN")
+		}
+	}
+
+	lastKeys, err := lastBuildKeys(ic.modFile.Filepath())
+	if err != nil {
+		ic.logger.Warnf("cannot read build keys of the last build, rebuilding binaries: %v\n", err)
+	}
+	modHash, err := modFileHash(ic.modFile.Filepath(), ic.modCtx.GoVersion().String())
+	if err != nil {
+		return errors.Wrap(err, "hash module file")
+	}
+
+	metas := make([]BinMeta, 0, len(ic.pkgs))
+	for i, pkg := range ic.pkgs {
+		// Checksum of the binary does not tell if it was built with current build attributes, so compare build keys too.
+		key := buildKey(ic.modFile, pkg)
+		pkgRebuild := rebuild
+		if last := lastKeys[ic.names[i]]; last != key && rebuild == RebuildIfChanged {
+			if last != "" {
+				ic.logger.Infof("%v: build attributes or go directive changed since the last build; rebuilding\n", ic.names[i])
+			}
+			pkgRebuild = RebuildForce
+		}
+		binPath, err := installPackage(ic.ctx, ic.logger, ic.r, ic.modDir, gobin, ic.names[i], link, linkDir, pkgRebuild, ic.modFile, ic.toolchainEnvs, pkg)
+		if err != nil {
+			return err
+		}
+		m := newBinMeta(ic.names[i], pkg, binPath)
+		m.BuildKey = key
+		m.ModFileHash = modHash
+		metas = append(metas, m)
+	}
+	return WriteBinMeta(ic.modFile.Filepath(), metas)
+}
+
+// BuildCommands returns go build commands that Build would run for binaries of the module file, without running them.
+// Commands refer to the module file with the given path, or modFile.Filepath() if empty, e.g. when modFile is a temporary
+// copy that replaces the file at modFilePath.
+func BuildCommands(ctx context.Context, logger logging.Logger, r *runner.Runner, modDir, gobin, name, modFilePath string, modFile *ModFile) ([]runner.Command, error) {
+	ic, err := newInstallContext(ctx, logger, r, modDir, name, modFile)
+	if err != nil {
+		return nil, err
+	}
+	if gobin == "" {
+		gobin, err = BinDir(ic.modCtx, ic.modDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "deduct GOBIN")
+		}
+	}
+	if modFilePath == "" {
+		modFilePath = modFile.Filepath()
+	}
+
+	cmds := make([]runner.Command, 0, len(ic.pkgs))
+	for i, pkg := range ic.pkgs {
+		envs := packageBuildEnvs(modFile, ic.toolchainEnvs, pkg)
+		buildCtx, err := packageBuildRunnable(ctx, r, modDir, modFilePath, envs, pkg)
+		if err != nil {
+			return nil, err
+		}
+		buildFlags, err := expandLdflags(ctx, r, r.With(ctx, modFile.Filepath(), modDir, envs), modDir, modFile, envs, pkg, pkg.BuildFlags)
+		if err != nil {
+			return nil, err
+		}
+		c, err := buildCtx.BuildCommand(pkg.Path(), versionedBinPath(gobin, ic.names[i], pkg), buildFlags...)
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds, nil
+}
+
+// ShellCommand renders the command as a single POSIX shell line, e.g. to run it manually outside bingo.
+func ShellCommand(c runner.Command) string {
+	parts := make([]string, 0, len(c.Env)+len(c.Args)+3)
+	if c.Dir != "" {
+		parts = append(parts, "cd", shellQuote(c.Dir), "&&")
+	}
+	for _, e := range c.Env {
+		k, v, _ := cut(e, "=")
+		parts = append(parts, k+"="+shellQuote(v))
+	}
+	for _, a := range c.Args {
+		if a == "" {
+			a = "''"
+		} else {
+			a = shellQuote(a)
+		}
+		parts = append(parts, a)
+	}
+	return strings.Join(parts, " ")
+}
+
+// BinaryPath returns path the binary of the package is installed at in gobin, so <name>-<version>[-<GOOS>_<GOARCH>][.exe].
+// Name is the package Name or, if empty, DefaultBinaryName of the package path. Note that binary of the direct package
+// is named after the tool (module file name) instead, so set Name to what BinaryNames returns for packages of the pinned tool.
+func BinaryPath(p Package, gobin string) string {
+	name := p.Name
+	if name == "" {
+		name = DefaultBinaryName(p.Path())
+	}
+	return filepath.Join(gobin, fmt.Sprintf("%s-%s%s%s", name, p.Module.Version, p.PlatformSuffix(), p.ExeSuffix()))
+}
+
+// versionedBinPath returns path of the binary with the given name built for the package in gobin.
+func versionedBinPath(gobin, name string, pkg Package) string {
+	pkg.Name = name
+	return BinaryPath(pkg, gobin)
+}
+
+// packageBuildEnvs returns environment variables the package is built with. Package build envs take precedence,
+// e.g. explicit GOTOOLCHAIN.
+func packageBuildEnvs(modFile *ModFile, toolchainEnvs envars.EnvSlice, pkg Package) envars.EnvSlice {
+	envs := envars.EnvSlice(envars.MergeEnvSlices(toolchainEnvs, pkg.BuildEnvs...))
+	if modFile.IsSumDBDisabled() {
+		envs = SumDBDisabledEnvs(pkg.Module.Path, envs)
+	}
+	if modFile.IsVendored() {
+		// Vendored tools are built from VendorDir only, so nothing missing there is silently downloaded.
+		envs = envars.MergeEnvSlices(envs, "GOPROXY=off")
+	}
+	return envs
+}
+
+// packageBuildRunnable returns runnable go build of the package runs with, in its work directory (see Package.WorkDir).
+func packageBuildRunnable(ctx context.Context, r *runner.Runner, modDir, modFilePath string, envs envars.EnvSlice, pkg Package) (runner.Runnable, error) {
+	if pkg.WorkDir == "" {
+		return r.With(ctx, modFilePath, modDir, envs), nil
+	}
+	dir := filepath.Join(modDir, filepath.FromSlash(pkg.WorkDir))
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return nil, errors.Newf("%v: work dir %v has to be an existing directory within the module directory", pkg.Path(), dir)
+	}
+	return r.With(ctx, modFilePath, dir, envs), nil
+}
+
+func installPackage(ctx context.Context, logger logging.Logger, r *runner.Runner, modDir, gobin, name string, link bool, linkDir string, rebuild Rebuild, modFile *ModFile, toolchainEnvs envars.EnvSlice, pkg Package) (string, error) {
+	// go install does not define -modfile flag, so we mimic go install with go build -o instead.
+	binPath := versionedBinPath(gobin, name, pkg)
+
+	// New context with new environment files.
+	envs := packageBuildEnvs(modFile, toolchainEnvs, pkg)
+	modCtx := r.With(ctx, modFile.Filepath(), modDir, envs)
+	// Check before any other go command, since all of them fail on unsupported experiment.
+	if err := checkGoExperiment(modCtx, envs, pkg); err != nil {
+		return "", err
+	}
+
+	buildCtx, err := packageBuildRunnable(ctx, r, modDir, modFile.Filepath(), envs, pkg)
+	if err != nil {
+		return "", err
+	}
+
+	sumKey, err := BinChecksumKeyFor(modCtx, name, pkg)
+	if err != nil {
+		return "", err
+	}
+	// Binary built from local replace changes with local code, so it's always rebuilt and never checksummed.
+	local := modFile.IsLocallyReplaced(pkg.Module.Path)
+	upToDate := false
+	if !local && rebuild == RebuildIfChanged {
+		upToDate, err = IsUpToDate(modDir, sumKey, binPath)
+		if err != nil {
+			return "", errors.Wrap(err, "verify existing binary")
+		}
+	}
+	buildFlags := pkg.BuildFlags
+	if rebuild == RebuildClean {
+		buildFlags = append([]string{"-a"}, buildFlags...)
+	}
+
+	if local || !upToDate {
+		if err := checkCrossCGO(pkg, envs); err != nil {
+			return "", err
+		}
+		if buildFlags, err = expandLdflags(ctx, r, modCtx, modDir, modFile, envs, pkg, buildFlags); err != nil {
+			return "", err
+		}
+	}
+
+	switch {
+	case local:
+		if err := buildCtx.Build(pkg.Path(), binPath, buildFlags...); err != nil {
+			return "", errors.Wrap(err, "build versioned from local replace")
+		}
+		if err := runPostInstall(modCtx, envs, name, pkg, binPath); err != nil {
+			return "", err
+		}
+		if err := runVerifyCmd(modCtx, envs, name, pkg, binPath); err != nil {
+			return "", err
+		}
+	case !upToDate:
+		if err := buildCtx.Build(pkg.Path(), binPath, buildFlags...); err != nil {
+			if strings.Contains(err.Error(), "module declares its path as: ") &&
+				strings.Contains(err.Error(), fmt.Sprintf("but was required as: %v", pkg.Path())) {
+
+				// TODO(bwplotka): Add native mode for forks.
+				logger.Infof("The %v module is a potential fork, since go.mod has mismatching module."+
+					" Building forks is not supported yet. See https://github.com/bwplotka/bingo/issues/110.\n", pkg.Path())
+			}
+			return "", errors.Wrap(err, "build versioned")
+		}
+		// Checksum is recorded after post-install, since it's the post-processed binary that is used.
+		if err := runPostInstall(modCtx, envs, name, pkg, binPath); err != nil {
+			// Binary the hook failed on can't be trusted, so drop its checksum; it's rebuilt on the next install.
+			if rerr := RemoveBinChecksum(modDir, sumKey); rerr != nil {
+				logger.Warnf("cannot remove checksum of %v: %v\n", binPath, rerr)
+			}
+			return "", err
+		}
+		if err := runVerifyCmd(modCtx, envs, name, pkg, binPath); err != nil {
+			// Same for binary that failed verification, so it's not skipped as up to date next time.
+			if rerr := RemoveBinChecksum(modDir, sumKey); rerr != nil {
+				logger.Warnf("cannot remove checksum of %v: %v\n", binPath, rerr)
+			}
+			return "", err
+		}
+
+		if _, err := VerifyBinChecksum(modDir, sumKey, binPath); err != nil {
+			logger.Warnf("rebuilt binary %v differs from the recorded one (build is not reproducible?); recording new checksum: %v\n", binPath, err)
+		}
+		if err := WriteBinChecksum(modDir, sumKey, binPath); err != nil {
+			return "", errors.Wrap(err, "record checksum")
+		}
+	default:
+		logger.Infof("%v already built and matches recorded checksum; skipping build\n", binPath)
+	}
+
+	if !link {
+		return binPath, nil
+	}
+
+	if err := linkBinary(logger, binPath, filepath.Join(gobin, name+pkg.PlatformSuffix()+pkg.ExeSuffix())); err != nil {
+		return "", err
+	}
+	if linkDir != "" {
+		if err := os.MkdirAll(linkDir, os.ModePerm); err != nil {
+			return "", errors.Wrapf(err, "create link directory %v", linkDir)
+		}
+		if err := linkBinary(logger, binPath, filepath.Join(linkDir, name+pkg.PlatformSuffix()+pkg.ExeSuffix())); err != nil {
+			return "", err
+		}
+	}
+	return binPath, nil
+}
+
+// checkCrossCGO returns error if the package is cross compiled with cgo enabled, but no C cross compiler is set in envs
+// (merged on top of the environment), since go build would fail with cryptic linker errors otherwise.
+func checkCrossCGO(pkg Package, envs envars.EnvSlice) error {
+	env := envars.EnvSlice(envars.MergeEnvSlices(os.Environ(), envs...))
+	if v, _ := env.Lookup("CGO_ENABLED"); v != "1" {
+		return nil
+	}
+	goos, goarch := runtime.GOOS, runtime.GOARCH
+	if v, _ := env.Lookup("GOOS"); v != "" {
+		goos = v
+	}
+	if v, _ := env.Lookup("GOARCH"); v != "" {
+		goarch = v
+	}
+	// Darwin C compiler builds for all its architectures, so only changing GOOS needs cross compiler there.
+	if goos == runtime.GOOS && (goarch == runtime.GOARCH || goos == "darwin") {
+		return nil
+	}
+	if v, _ := env.Lookup("CC"); v != "" {
+		return nil
+	}
+	return errors.Newf("%v is cross compiled for %v/%v with CGO_ENABLED=1, but CC is not set, so go build would use the host C compiler; "+
+		"set CC (and CXX for C++ code) to C cross compiler of the target (e.g. CC=aarch64-linux-gnu-gcc) in the build envs or environment, or build with CGO_ENABLED=0",
+		pkg.Path(), goos, goarch)
+}
+
+// checkGoExperiment returns error if the package is built with GOEXPERIMENT (see Package.GoExperiment) that go the package
+// is built with (with envs) does not support, so it's reported clearly instead of failing go commands with cryptic errors.
+func checkGoExperiment(modCtx runner.Runnable, envs envars.EnvSlice, pkg Package) error {
+	experiment := pkg.GoExperiment()
+	if experiment == "" {
+		return nil
+	}
+	// Go validates GOEXPERIMENT of every command, including go env.
+	_, err := modCtx.GoEnv("GOEXPERIMENT")
+	if err == nil {
+		return nil
+	}
+	const unknownPrefix = "unknown GOEXPERIMENT "
+	msg := err.Error()
+	i := strings.Index(msg, unknownPrefix)
+	if i < 0 || len(strings.Fields(msg[i+len(unknownPrefix):])) == 0 {
+		return errors.Wrapf(err, "%v: check GOEXPERIMENT=%v", pkg.Path(), experiment)
+	}
+	unknown := strings.Fields(msg[i+len(unknownPrefix):])[0]
+	goVersion := "go" + modCtx.GoVersion().String()
+	if tc, _ := envs.Lookup("GOTOOLCHAIN"); strings.HasPrefix(tc, "go1") {
+		goVersion = strings.SplitN(tc, "+", 2)[0]
+	}
+	return errors.Newf("%v: GOEXPERIMENT=%v requests experiment %q that %v does not support (it might have been removed or "+
+		"graduated to default behavior); remove it from build envs of the package or build with go version that supports it (e.g. with --toolchain)",
+		pkg.Path(), experiment, unknown, goVersion)
+}
+
+// postInstallData is data available to PostInstall and VerifyCmd command (and VerifyOutput) placeholders.
+type postInstallData struct {
+	// Bin is an absolute path of the built binary.
+	Bin string
+	// Name is a binary name, without version.
+	Name    string
+	Version string
+}
+
+// ValidatePostInstall returns error if the given post-install command can't be parsed. See Package.PostInstall.
+func ValidatePostInstall(command string) error {
+	_, err := postInstallArgs(command, nil, postInstallData{})
+	return err
+}
+
+// postInstallArgs splits post-install command into arguments like shell does (expanding variables from envs on top of
+// the environment) and fills placeholders of each argument, so values with spaces stay a single argument.
+func postInstallArgs(command string, envs envars.EnvSlice, data postInstallData) ([]string, error) {
+	return commandArgs("post-install", command, envs, data)
+}
+
+// commandArgs splits command of the given kind into arguments as described in postInstallArgs.
+func commandArgs(kind, command string, envs envars.EnvSlice, data postInstallData) ([]string, error) {
+	if strings.ContainsAny(command, "\r\n") {
+		return nil, errors.Newf("%s command has to be a single line, got %q", kind, command)
+	}
+	env := envars.EnvSlice(envars.MergeEnvSlices(os.Environ(), envs...))
+	fields, err := shell.Fields(command, func(k string) string {
+		v, _ := env.Lookup(k)
+		return v
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s command %q", kind, command)
+	}
+	if len(fields) == 0 {
+		return nil, errors.Newf("%s command is empty", kind)
+	}
+
+	args := make([]string, 0, len(fields))
+	for _, f := range fields {
+		tmpl, err := template.New(kind).Option("missingkey=error").Parse(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parse %s command %q; placeholders have to be written without spaces, e.g. {{.Bin}}", kind, command)
+		}
+		b := strings.Builder{}
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, errors.Wrapf(err, "%s command %q; supported placeholders are {{.Bin}}, {{.Name}} and {{.Version}}", kind, command)
+		}
+		args = append(args, b.String())
+	}
+	return args, nil
+}
+
+// validateVerifyCmd returns error if the given verify command can't be parsed. See Package.VerifyCmd.
+func validateVerifyCmd(command string) error {
+	_, err := commandArgs("verify", command, nil, postInstallData{})
+	return err
+}
+
+// verifyOutput fills placeholders of expected verify command output. See Package.VerifyOutput.
+func verifyOutput(expected string, data postInstallData) (string, error) {
+	tmpl, err := template.New("verify-output").Option("missingkey=error").Parse(expected)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse verify output %q", expected)
+	}
+	b := strings.Builder{}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrapf(err, "verify output %q; supported placeholders are {{.Bin}}, {{.Name}} and {{.Version}}", expected)
+	}
+	return b.String(), nil
+}
+
+// runPostInstall runs PostInstall command of the package (if any) on the built binary, with the same environment the
+// binary was built with.
+func runPostInstall(modCtx runner.Runnable, envs envars.EnvSlice, name string, pkg Package, binPath string) error {
+	if pkg.PostInstall == "" {
+		return nil
+	}
+	absBinPath, err := filepath.Abs(binPath)
+	if err != nil {
+		return errors.Wrap(err, "abs")
+	}
+	args, err := postInstallArgs(pkg.PostInstall, envs, postInstallData{Bin: absBinPath, Name: name, Version: pkg.Module.Version})
+	if err != nil {
+		return err
+	}
+	if _, err := modCtx.Exec(args[0], args[1:]...); err != nil {
+		return errors.Wrapf(err, "post-install %q of %v", pkg.PostInstall, binPath)
+	}
+	return nil
+}
+
+// runVerifyCmd runs VerifyCmd command of the package (if any) on the built binary, with the same environment the binary
+// was built with. It returns error if the command fails or its output does not contain VerifyOutput, e.g. when the binary
+// reports different version than the pinned module version.
+func runVerifyCmd(modCtx runner.Runnable, envs envars.EnvSlice, name string, pkg Package, binPath string) error {
+	if pkg.VerifyCmd == "" {
+		return nil
+	}
+	absBinPath, err := filepath.Abs(binPath)
+	if err != nil {
+		return errors.Wrap(err, "abs")
+	}
+	data := postInstallData{Bin: absBinPath, Name: name, Version: pkg.Module.Version}
+	args, err := commandArgs("verify", pkg.VerifyCmd, envs, data)
+	if err != nil {
+		return err
+	}
+	out, err := modCtx.Exec(args[0], args[1:]...)
+	if err != nil {
+		return errors.Wrapf(err, "verify %q of %v", pkg.VerifyCmd, binPath)
+	}
+	if pkg.VerifyOutput == "" {
+		return nil
+	}
+	expected, err := verifyOutput(pkg.VerifyOutput, data)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, expected) {
+		return errors.Newf("verify %q of %v: output %q does not contain %q", pkg.VerifyCmd, binPath, out, expected)
+	}
+	return nil
+}
+
+// linkBinary atomically (re)points linkPath to binPath, so linkPath always points to an existing binary. If symlinks
+// are not supported (e.g. Windows without privilege), binary is copied instead.
+func linkBinary(logger logging.Logger, binPath, linkPath string) error {
+	tmpPath := linkPath + ".tmp"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return errors.Wrap(err, "rm")
+	}
+	if err := os.Symlink(binPath, tmpPath); err != nil {
+		logger.Warnf("cannot create symlink %v: %v; copying %v instead\n", linkPath, err, binPath)
+		if err := copyFile(binPath, tmpPath); err != nil {
+			return errors.Wrap(err, "copy")
+		}
+	}
+	if err := os.Rename(tmpPath, linkPath); err != nil {
+		return errors.Wrapf(err, "link %v", linkPath)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) (err error) {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer errcapture.Do(&err, s.Close, "close source")
+
+	info, err := s.Stat()
+	if err != nil {
+		return err
+	}
+	d, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer errcapture.Do(&err, d.Close, "close destination")
+
+	_, err = io.Copy(d, s)
+	return err
+}
+
+// toolchainEnvs returns environment variables that make go commands use exactly the given toolchain (e.g. "go1.22.0"),
+// as recorded in toolchain directive of the tool module file. Go downloads the toolchain if needed. It returns error if
+// the toolchain can't be used, e.g. GOTOOLCHAIN=local is in effect and local Go is older.
+func toolchainEnvs(logger logging.Logger, modCtx runner.Runnable, toolchain string) (envars.EnvSlice, error) {
+	if toolchain == "" || toolchain == "default" {
+		return nil, nil
+	}
+	if !version.AtLeast(modCtx.GoVersion(), version.Go121) {
+		return nil, errors.Newf("toolchain %v is requested, but go %v does not support toolchain switching; use Go 1.21+", toolchain, modCtx.GoVersion().String())
+	}
+	want, err := version.Parse(toolchain)
+	if err != nil {
+		return nil, errors.Wrap(err, "toolchain")
+	}
+
+	policy, err := modCtx.GoEnv("GOTOOLCHAIN")
+	if err != nil {
+		return nil, errors.Wrap(err, "go env GOTOOLCHAIN")
+	}
+	switch {
+	case policy == "local":
+		if modCtx.GoVersion().LessThan(want) {
+			return nil, errors.Newf("toolchain %v is requested, but GOTOOLCHAIN=local is in effect and local go is %v, so it can't be fetched; "+
+				"unset GOTOOLCHAIN or use newer Go", toolchain, modCtx.GoVersion().String())
+		}
+		logger.Warnf("toolchain %v is requested, but GOTOOLCHAIN=local is in effect; building with local go %v\n", toolchain, modCtx.GoVersion().String())
+		return nil, nil
+	case policy == "path" || strings.HasSuffix(policy, "+path"):
+		// Don't download, but look for the toolchain in PATH.
+		return envars.EnvSlice{"GOTOOLCHAIN=" + toolchain + "+path"}, nil
+	}
+	return envars.EnvSlice{"GOTOOLCHAIN=" + toolchain}, nil
+}
+
+func validateTargetName(targetName string) error {
+	if targetName == "cmd" {
+		return errors.Newf("package would be installed with ambiguous name %s. This is a common, but slightly annoying package layout"+
+			"It's advised to choose unique name with -n flag", targetName)
+	}
+	if targetName == strings.TrimSuffix(FakeRootModFileName, ".mod") {
+		return errors.Newf("requested binary with name %q`. This is impossible, choose different name using -n flag", strings.TrimSuffix(FakeRootModFileName, ".mod"))
+	}
+	return nil
+}
+
+const modREADMEFmt = `# Project Development Dependencies.
+
+This is directory which stores Go modules with pinned buildable package that is used within this repository, managed by https://github.com/bwplotka/
+
+* Run ` + "`" + "bingo get" + "`" + ` to install all tools having each own module file in this directory.
+* Run ` + "`" + "bingo get <tool>" + "`" + ` to install <tool> that have own module file in this directory.
+* For Makefile: Make sure to put ` + "`" + "include %s/Variables.mk" + "`" + ` in your Makefile, then use $(<upper case tool name>) variable where <tool> is the %s/<tool>.mod.
+* For shell: Run ` + "`" + "source %s/variables.env" + "`" + ` to source all environment variable for each tool.
+* See https://github.com/bwplotka/bingo or -h on how to add, remove or change binaries dependencies.
+
+## Requirements
+
+* Go 1.14+
+`
+
+const gitignore = `
+# Ignore everything
+*
+
+# But not these files:
+!.gitignore
+!*.mod
+!*.sum
+!README.md
+!Variables.mk
+!variables.env
+!.bingosum
+!.genmakefile
+!.genlibrary
+!.outputdir
+!config.yaml
+!_vendor/
+!_vendor/**
+!*.env
+!replace.tmpl
+!variables.tmpl
+!.bingoignore
+!.bingoconstraints
+`
+
+// gitignoreTemporary are .gitignore lines of temporary files, after all whitelisted ones, so they are ignored everywhere.
+const gitignoreTemporary = `
+*tmp.mod
+*tmp.sum
+backup/
+`
+
+// EnsureModDir creates bingo module directory (if it does not exist) with the fake root go.mod, README and .gitignore.
+func EnsureModDir(logger logging.Logger, relModDir string) error {
+	_, err := os.Stat(relModDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "stat bingo module dir %s", relModDir)
+		}
+
+		logger.Infof("Bingo not used before here, creating directory for pinned modules for you at %s\n", relModDir)
+		if err := os.MkdirAll(relModDir, os.ModePerm); err != nil {
+			return errors.Wrapf(err, "create moddir %s", relModDir)
+		}
+	}
+
+	// Hack against:
+	// "A file named go.mod must still be present in order to determine the module root directory, but it is not accessed."
+	// Ref: https://golang.org/doc/go1.14#go-flags
+	// TODO(bwplotka): Remove it: https://github.com/bwplotka/bingo/issues/20
+	if err := os.WriteFile(
+		filepath.Join(relModDir, FakeRootModFileName),
+		[]byte("module _ // Fake go.mod auto-created by 'bingo' for go -moddir compatibility with non-Go projects. Commit this file, together with other .mod files."),
+		0666,
+	); err != nil {
+		return err
+	}
+
+	// README.
+	if err := os.WriteFile(
+		filepath.Join(relModDir, "README.md"),
+		[]byte(fmt.Sprintf(modREADMEFmt, relModDir, relModDir, relModDir)),
+		0666,
+	); err != nil {
+		return err
+	}
+	// gitignore.
+	content := gitignore
+	if paths := toolPathsToCommit(relModDir); len(paths) > 0 {
+		content += "# Work directories and env files of tools (see workdir= and env-file= attributes).\n" + strings.Join(paths, "\n") + "\n"
+	}
+	return os.WriteFile(filepath.Join(relModDir, ".gitignore"), []byte(content+gitignoreTemporary), 0666)
+}
+
+// toolPathsToCommit returns .gitignore lines that re-include work directories (with all their files) and env files of
+// tools pinned in modDir, as everything else in subdirectories is ignored. Git does not
+// look into ignored directories, so their parent directories are re-included too. Module files that can't be read are
+// skipped, so they can be fixed manually.
+func toolPathsToCommit(modDir string) []string {
+	modFiles, err := filepath.Glob(filepath.Join(modDir, "*.mod"))
+	if err != nil {
+		return nil
+	}
+	lines := map[string]struct{}{}
+	includeParents := func(path string) {
+		for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+			lines["!/"+filepath.ToSlash(dir)+"/"] = struct{}{}
+		}
+	}
+	for _, f := range modFiles {
+		if filepath.Base(f) == FakeRootModFileName || isTmpModFile(f) {
+			continue
+		}
+		for _, p := range pinnedPackagesMeta(f) {
+			if p.WorkDir != "" {
+				includeParents(p.WorkDir)
+				lines["!/"+filepath.ToSlash(p.WorkDir)+"/"] = struct{}{}
+				lines["!/"+filepath.ToSlash(p.WorkDir)+"/**"] = struct{}{}
+			}
+			if p.EnvFile != "" {
+				includeParents(p.EnvFile)
+				lines["!/"+filepath.ToSlash(p.EnvFile)] = struct{}{}
+			}
+		}
+	}
+	ret := make([]string, 0, len(lines))
+	for l := range lines {
+		ret = append(ret, l)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// pinnedPackagesMeta returns build attributes of direct and additional packages of the module file, without rewriting it.
+// It returns nil if the file can't be read or its attributes are malformed.
+func pinnedPackagesMeta(modFile string) (pkgs []Package) {
+	f, err := mod.OpenFileForRead(modFile)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+
+	if validateModFile(f) != nil {
+		return nil
+	}
+	for _, r := range f.RequireDirectives() {
+		if !r.Indirect {
+			pkgs = append(pkgs, parseDirectPackageMeta(strings.Trim(r.ExtraSuffixComment, "\n")))
+			break
+		}
+	}
+	for _, c := range f.Comments() {
+		if strings.HasPrefix(c, AlsoDirective) {
+			pkgs = append(pkgs, parseDirectPackageMeta(strings.TrimSpace(strings.TrimPrefix(c, AlsoDirective))))
+		}
+	}
+	return pkgs
+}
+
+// cut is strings.Cut, which is not available in Go 1.17.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
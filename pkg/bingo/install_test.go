@@ -0,0 +1,601 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/bwplotka/bingo/internal/fakecmd"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestLinkBinary(t *testing.T) {
+	logger := logging.Discard
+	gobin := t.TempDir()
+	linkDir := t.TempDir()
+
+	v1 := filepath.Join(gobin, "faillint-v1.4.0")
+	v2 := filepath.Join(gobin, "faillint-v1.5.0")
+	testutil.Ok(t, os.WriteFile(v1, []byte("v1"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(v2, []byte("v2"), os.ModePerm))
+
+	linkPath := filepath.Join(linkDir, "faillint")
+	testutil.Ok(t, linkBinary(logger, v1, linkPath))
+	target, err := os.Readlink(linkPath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, v1, target)
+
+	// Re-pointed on new version.
+	testutil.Ok(t, linkBinary(logger, v2, linkPath))
+	target, err = os.Readlink(linkPath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, v2, target)
+	b, err := os.ReadFile(linkPath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "v2", string(b))
+
+	// Replaces regular file too, e.g. copied binary.
+	testutil.Ok(t, os.Remove(linkPath))
+	testutil.Ok(t, copyFile(v1, linkPath))
+	testutil.Ok(t, linkBinary(logger, v2, linkPath))
+	target, err = os.Readlink(linkPath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, v2, target)
+
+	entries, err := os.ReadDir(linkDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(entries))
+}
+
+func TestPostInstallArgs(t *testing.T) {
+	data := postInstallData{Bin: "/my bin/faillint-v1.5.0", Name: "faillint", Version: "v1.5.0"}
+	for _, tcase := range []struct {
+		command  string
+		envs     []string
+		expected []string
+		err      bool
+	}{
+		{command: "strip {{.Bin}}", expected: []string{"strip", "/my bin/faillint-v1.5.0"}},
+		{command: `sh -c 'echo "{{.Name}} {{.Version}}" > $OUT'`, expected: []string{"sh", "-c", `echo "faillint v1.5.0" > $OUT`}},
+		{command: "codesign -s $IDENTITY {{.Bin}}", envs: []string{"IDENTITY=dev"}, expected: []string{"codesign", "-s", "dev", "/my bin/faillint-v1.5.0"}},
+		{command: "", err: true},
+		{command: "strip\n{{.Bin}}", err: true},
+		{command: "strip {{ .Bin }}", err: true},
+		{command: "strip {{.Path}}", err: true},
+		{command: "strip 'unterminated", err: true},
+		{command: "strip $(echo {{.Bin}})", err: true},
+	} {
+		t.Run(tcase.command, func(t *testing.T) {
+			args, err := postInstallArgs(tcase.command, tcase.envs, data)
+			if tcase.err {
+				testutil.NotOk(t, err)
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, args)
+			testutil.Ok(t, ValidatePostInstall(tcase.command))
+		})
+	}
+}
+
+func TestShellCommand(t *testing.T) {
+	testutil.Equals(t, "go build -o=bin/tool pkg", ShellCommand(runner.Command{Args: []string{"go", "build", "-o=bin/tool", "pkg"}}))
+	testutil.Equals(t,
+		`cd '/my repo/.bingo' && CGO_ENABLED=1 GOFLAGS='-mod=mod -v' GOWORK=off go build '-modfile=/my repo/.bingo/tool.mod' '-ldflags=-X main.v=it'\''s' '' pkg`,
+		ShellCommand(runner.Command{
+			Dir:  "/my repo/.bingo",
+			Env:  []string{"CGO_ENABLED=1", "GOFLAGS=-mod=mod -v", "GOWORK=off"},
+			Args: []string{"go", "build", "-modfile=/my repo/.bingo/tool.mod", "-ldflags=-X main.v=it's", "", "pkg"},
+		}),
+	)
+}
+
+func TestBinaryPath(t *testing.T) {
+	gobin := filepath.Join("home", "gobin")
+	for _, tcase := range []struct {
+		pkg      Package
+		expected string
+	}{
+		{pkg: Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}}, expected: "tool-v1.0.0"},
+		{pkg: Package{Module: module.Version{Path: "github.com/x/tool/v2", Version: "v2.1.0"}}, expected: "tool-v2.1.0"},
+		{pkg: Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}, RelPath: "cmd/tool-cli"}, expected: "tool-cli-v1.0.0"},
+		{pkg: Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}, RelPath: "cmd/cli", Name: "tool"}, expected: "tool-v1.0.0"},
+		{
+			pkg:      Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}, BuildEnvs: []string{"GOOS=linux", "GOARCH=arm64"}},
+			expected: "tool-v1.0.0-linux_arm64",
+		},
+		{
+			pkg:      Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}, BuildEnvs: []string{"GOOS=windows", "GOARCH=amd64"}},
+			expected: "tool-v1.0.0-windows_amd64.exe",
+		},
+		{
+			pkg:      Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}, RelPath: "cmd/cli", Name: "tool", BuildEnvs: []string{"GOOS=windows"}},
+			expected: "tool-v1.0.0-windows_" + runtime.GOARCH + ".exe",
+		},
+	} {
+		t.Run(tcase.expected, func(t *testing.T) {
+			testutil.Equals(t, filepath.Join(gobin, tcase.expected), BinaryPath(tcase.pkg, gobin))
+		})
+	}
+}
+
+func TestCheckCrossCGO(t *testing.T) {
+	t.Setenv("CGO_ENABLED", "")
+	t.Setenv("CC", "")
+
+	crossGOOS := "windows"
+	if runtime.GOOS == "windows" {
+		crossGOOS = "linux"
+	}
+	crossGOARCH := "arm64"
+	if runtime.GOARCH == "arm64" {
+		crossGOARCH = "amd64"
+	}
+
+	pkg := Package{Module: module.Version{Path: "github.com/x/tool"}}
+	for _, tcase := range []struct {
+		envs []string
+		err  bool
+	}{
+		{envs: []string{"CGO_ENABLED=1"}},
+		{envs: []string{"GOOS=" + crossGOOS, "GOARCH=" + crossGOARCH}},
+		{envs: []string{"CGO_ENABLED=0", "GOOS=" + crossGOOS}},
+		{envs: []string{"CGO_ENABLED=1", "GOOS=" + crossGOOS}, err: true},
+		{envs: []string{"CGO_ENABLED=1", "GOOS=" + runtime.GOOS, "GOARCH=" + crossGOARCH}, err: runtime.GOOS != "darwin"},
+		{envs: []string{"CGO_ENABLED=1", "GOOS=" + crossGOOS, "CC=zig cc"}},
+	} {
+		t.Run(strings.Join(tcase.envs, " "), func(t *testing.T) {
+			err := checkCrossCGO(pkg, tcase.envs)
+			if !tcase.err {
+				testutil.Ok(t, err)
+				return
+			}
+			testutil.NotOk(t, err)
+			testutil.Assert(t, strings.Contains(err.Error(), "CGO_ENABLED=1, but CC is not set"), "unexpected error %v", err)
+		})
+	}
+
+	// CC from the environment is used too.
+	t.Setenv("CC", "aarch64-linux-gnu-gcc")
+	testutil.Ok(t, checkCrossCGO(pkg, []string{"CGO_ENABLED=1", "GOOS=" + crossGOOS}))
+}
+
+func TestCheckGoExperiment(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that supports only arenas experiment.
+	goCmd := fakecmd.Go(t, dir, `
+  env) for e in $(echo "$GOEXPERIMENT" | tr , ' '); do
+      case "$e" in arenas|noarenas) ;; *) echo "go: unknown GOEXPERIMENT ${e#no}" >&2; exit 2 ;; esac
+    done
+    echo "$GOEXPERIMENT" ;;`)
+	r, err := runner.NewRunner(context.Background(), nil, false, goCmd)
+	testutil.Ok(t, err)
+
+	for _, tcase := range []struct {
+		envs        []string
+		expectedErr string
+	}{
+		{envs: []string{"CGO_ENABLED=0"}},
+		{envs: []string{"GOEXPERIMENT=arenas"}},
+		{
+			envs:        []string{"GOEXPERIMENT=arenas,rangefunc"},
+			expectedErr: `github.com/x/tool: GOEXPERIMENT=arenas,rangefunc requests experiment "rangefunc" that go1.21.0 does not support`,
+		},
+		{
+			envs:        []string{"GOEXPERIMENT=norangefunc", "GOTOOLCHAIN=go1.22.0+auto"},
+			expectedErr: `github.com/x/tool: GOEXPERIMENT=norangefunc requests experiment "rangefunc" that go1.22.0 does not support`,
+		},
+	} {
+		t.Run(strings.Join(tcase.envs, " "), func(t *testing.T) {
+			pkg := Package{Module: module.Version{Path: "github.com/x/tool"}, BuildEnvs: tcase.envs}
+			err := checkGoExperiment(r.With(context.Background(), "", "", pkg.BuildEnvs), pkg.BuildEnvs, pkg)
+			if tcase.expectedErr == "" {
+				testutil.Ok(t, err)
+				return
+			}
+			testutil.NotOk(t, err)
+			testutil.Assert(t, strings.HasPrefix(err.Error(), tcase.expectedErr), "unexpected error %v", err)
+		})
+	}
+	testutil.Equals(t, "arenas", Package{BuildEnvs: []string{"CGO_ENABLED=1", "GOEXPERIMENT=arenas"}}.GoExperiment())
+}
+
+func TestInstall_BuildFlags(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that records each call and builds empty binary.
+	d := newTestModDir(t, dir, "")
+	modDir, gobin := d.modDir, d.gobin
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("GO_CALLS_FILE", callsFile)
+
+	d.writeModFile(t, "tidy", "require github.com/x/tidy v1.0.0 // -mod=mod -trimpath")
+	d.writeModFile(t, "other", "require github.com/x/other v1.0.0")
+	d.writeModFile(t, "strict", "require github.com/x/strict v1.0.0 // -mod=readonly")
+	for _, name := range []string{"tidy", "other", "strict"} {
+		testutil.Ok(t, d.install(t, name, RebuildIfChanged))
+	}
+
+	b, err := os.ReadFile(callsFile)
+	testutil.Ok(t, err)
+	var lists, builds []string
+	for _, c := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		switch {
+		case strings.HasPrefix(c, "list "):
+			lists = append(lists, c)
+		case strings.HasPrefix(c, "build "):
+			builds = append(builds, c)
+		}
+	}
+	// Package -mod flag takes precedence over the default one and does not leak to other tools.
+	testutil.Equals(t, []string{
+		"list -modfile=" + filepath.Join(modDir, "tidy.mod") + " -mod=mod -mod=mod -trimpath -f={{.Name}} github.com/x/tidy",
+		"list -modfile=" + filepath.Join(modDir, "other.mod") + " -mod=mod -f={{.Name}} github.com/x/other",
+		"list -modfile=" + filepath.Join(modDir, "strict.mod") + " -mod=mod -mod=readonly -f={{.Name}} github.com/x/strict",
+	}, lists)
+	testutil.Equals(t, []string{
+		"build -modfile=" + filepath.Join(modDir, "tidy.mod") + " -o=" + filepath.Join(gobin, "tidy-v1.0.0") + " -mod=mod -trimpath github.com/x/tidy",
+		"build -modfile=" + filepath.Join(modDir, "other.mod") + " -o=" + filepath.Join(gobin, "other-v1.0.0") + " github.com/x/other",
+		"build -modfile=" + filepath.Join(modDir, "strict.mod") + " -o=" + filepath.Join(gobin, "strict-v1.0.0") + " -mod=readonly github.com/x/strict",
+	}, builds)
+}
+
+func TestInstall_VerifyCmd(t *testing.T) {
+	// Fake go that builds binary always reporting v1.0.0 version.
+	d := newTestModDir(t, t.TempDir(), `build) for a in "$@"; do case "$a" in -o=*) printf '#!/bin/sh\n[ "$1" = --version ] && echo "tool version v1.0.0"\n' > "${a#-o=}"; chmod +x "${a#-o=}" ;; esac; done ;;`)
+	install := func(require string) error {
+		t.Helper()
+
+		d.writeModFile(t, "tool", "require "+require)
+		return d.install(t, "tool", RebuildIfChanged)
+	}
+
+	testutil.Ok(t, install(`github.com/x/tool v1.0.0 // verify-cmd="{{.Bin}} --version" verify-output={{.Version}}`))
+	testutil.Ok(t, install(`github.com/x/tool v1.1.0 // verify-cmd="{{.Bin}} --version"`))
+
+	// Binary reports different version than pinned one.
+	err := install(`github.com/x/tool v1.2.0 // verify-cmd="{{.Bin}} --version" verify-output={{.Version}}`)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), `output "tool version v1.0.0" does not contain "v1.2.0"`), "got %v", err)
+	key := BinChecksumKey{Name: "tool", Version: "v1.2.0", GOOS: "linux", GOARCH: "amd64"}
+	upToDate, err := IsUpToDate(d.modDir, key, filepath.Join(d.gobin, "tool-v1.2.0"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, upToDate)
+
+	// Failing command fails install too.
+	err = install(`github.com/x/tool v1.3.0 // verify-cmd="{{.Bin}} --help"`)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), `verify "{{.Bin}} --help" of `), "got %v", err)
+}
+
+func TestIsInstallUpToDate(t *testing.T) {
+	// Fake go that builds empty binary.
+	d := newTestModDir(t, t.TempDir(), "")
+	modFile := filepath.Join(d.modDir, "tool.mod")
+	writeModFile := func(attrs string) {
+		d.writeModFile(t, "tool", "require github.com/x/tool v1.0.0"+attrs)
+	}
+	upToDate := func(link bool) bool {
+		mf, err := OpenModFile(modFile)
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, mf.Close()) }()
+
+		ok, err := IsInstallUpToDate(context.Background(), logging.Discard, d.r, d.modDir, d.gobin, "tool", link, "", mf)
+		testutil.Ok(t, err)
+		return ok
+	}
+	install := func() {
+		testutil.Ok(t, d.install(t, "tool", RebuildIfChanged))
+	}
+
+	t.Run("new tool", func(t *testing.T) {
+		writeModFile("")
+		testutil.Equals(t, false, upToDate(false))
+	})
+	t.Run("unchanged", func(t *testing.T) {
+		install()
+		testutil.Equals(t, true, upToDate(false))
+		testutil.Equals(t, true, upToDate(false))
+	})
+	t.Run("link missing", func(t *testing.T) {
+		testutil.Equals(t, false, upToDate(true))
+	})
+	t.Run("changed build attributes", func(t *testing.T) {
+		writeModFile(" // -trimpath")
+		testutil.Equals(t, false, upToDate(false))
+		install()
+		testutil.Equals(t, true, upToDate(false))
+	})
+	t.Run("changed sum file", func(t *testing.T) {
+		testutil.Ok(t, os.WriteFile(SumFilePath(modFile), []byte("github.com/x/tool v1.0.0 h1:abc=\n"), os.ModePerm))
+		testutil.Equals(t, false, upToDate(false))
+		install()
+		testutil.Equals(t, true, upToDate(false))
+	})
+	t.Run("binary removed", func(t *testing.T) {
+		testutil.Ok(t, os.Remove(filepath.Join(d.gobin, "tool-v1.0.0")))
+		testutil.Equals(t, false, upToDate(false))
+	})
+	t.Run("binary tampered", func(t *testing.T) {
+		install()
+		testutil.Ok(t, os.WriteFile(filepath.Join(d.gobin, "tool-v1.0.0"), []byte("evil\n"), os.ModePerm))
+		testutil.Equals(t, false, upToDate(false))
+	})
+}
+
+func TestInstall_ChecksumMismatch(t *testing.T) {
+	d := newTestModDir(t, t.TempDir(), "")
+	d.writeModFile(t, "tool", "require github.com/x/tool v1.0.0")
+	install := func(rebuild Rebuild) error { return d.install(t, "tool", rebuild) }
+	binPath := filepath.Join(d.gobin, "tool-v1.0.0")
+	testutil.Ok(t, install(RebuildIfChanged))
+
+	// Binary changed outside bingo might be tampered with, so it's never rebuilt silently.
+	testutil.Ok(t, os.WriteFile(binPath, []byte("evil\n"), os.ModePerm))
+	err := install(RebuildIfChanged)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, ErrBinChecksumMismatch), err.Error())
+	testutil.Assert(t, strings.Contains(err.Error(), "delete it or rerun with --force to rebuild it"), err.Error())
+	expectContent(t, "evil\n", binPath)
+
+	// Unless asked to.
+	testutil.Ok(t, install(RebuildForce))
+	expectContent(t, "bin\n", binPath)
+	testutil.Ok(t, install(RebuildIfChanged))
+}
+
+func TestInstall_GODEBUG(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that records GODEBUG of each build and builds empty binary.
+	d := newTestModDir(t, dir, `build) for a in "$@"; do case "$a" in -o=*) echo "$(basename "${a#-o=}") GODEBUG=$GODEBUG" >> "$CALLS_FILE"; echo bin > "${a#-o=}" ;; esac; done ;;`)
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+	t.Setenv("GODEBUG", "")
+
+	d.writeModFile(t, "legacy", "require github.com/x/legacy v1.0.0 // GODEBUG=gotypesalias=0,x509sha1=1")
+	d.writeModFile(t, "other", "require github.com/x/other v1.0.0")
+	for _, name := range []string{"legacy", "other"} {
+		testutil.Ok(t, d.install(t, name, RebuildIfChanged))
+	}
+
+	b, err := os.ReadFile(callsFile)
+	testutil.Ok(t, err)
+	// GODEBUG is set for the build of the tool it is pinned for only.
+	testutil.Equals(t, []string{
+		"legacy-v1.0.0 GODEBUG=gotypesalias=0,x509sha1=1",
+		"other-v1.0.0 GODEBUG=",
+	}, strings.Split(strings.TrimSpace(string(b)), "\n"))
+}
+
+func TestInstall_WorkDir(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that records working directory of each build and builds empty binary.
+	d := newTestModDir(t, dir, `build) for a in "$@"; do case "$a" in -o=*) echo "$(basename "${a#-o=}") $(pwd)" >> "$CALLS_FILE"; echo bin > "${a#-o=}" ;; esac; done ;;`)
+	modDir := d.modDir
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+
+	testutil.Ok(t, os.MkdirAll(filepath.Join(modDir, "tool"), os.ModePerm))
+	d.writeModFile(t, "tool", "require github.com/x/tool v1.0.0 // workdir=tool -pgo=default.pgo")
+	d.writeModFile(t, "other", "require github.com/x/other v1.0.0")
+	d.writeModFile(t, "missing", "require github.com/x/missing v1.0.0 // workdir=missing")
+	testutil.Ok(t, d.install(t, "tool", RebuildIfChanged))
+	testutil.Ok(t, d.install(t, "other", RebuildIfChanged))
+	err := d.install(t, "missing", RebuildIfChanged)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), "work dir "+filepath.Join(modDir, "missing")+" has to be an existing directory within the module directory"), err.Error())
+
+	b, err := os.ReadFile(callsFile)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{
+		"tool-v1.0.0 " + filepath.Join(modDir, "tool"),
+		"other-v1.0.0 " + modDir,
+	}, strings.Split(strings.TrimSpace(string(b)), "\n"))
+}
+
+func TestEnsureModDir_Gitignore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	modDir := filepath.Join(dir, ".bingo")
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "hugo.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/gohugoio/hugo v0.83.1 // workdir=tools/hugo -pgo=default.pgo
+`), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "faillint.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/fatih/faillint v1.5.0 // env-file=envs/faillint.vars
+`), os.ModePerm))
+	testutil.Ok(t, EnsureModDir(logging.Discard, modDir))
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	testutil.Ok(t, err, string(out))
+
+	isIgnored := func(file string) bool {
+		cmd := exec.Command("git", "check-ignore", "-q", "--no-index", filepath.Join(".bingo", file))
+		cmd.Dir = dir
+		err := cmd.Run()
+		if exitErr := (&exec.ExitError{}); errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return false
+		}
+		testutil.Ok(t, err)
+		return true
+	}
+
+	// Every file bingo owns (or reads) in the module directory has to be committed.
+	for _, f := range []string{
+		".gitignore", FakeRootModFileName, "README.md", "Variables.mk", "variables.env",
+		"faillint.mod", "faillint.sum", "faillint.1.mod", "hugo.env",
+		BinChecksumFileName, MakefileTargetsPathFile, LibraryStubPathFile, OutputDirFile, VariablesTemplateFile,
+		ReplaceTemplateFileName, IgnoreFile, ConstraintsFile, "config.yaml",
+		filepath.Join(VendorDir, "modules.txt"),
+		filepath.Join("tools", "hugo", "default.pgo"), filepath.Join("tools", "hugo", "hugo.env"),
+		filepath.Join("envs", "faillint.vars"),
+	} {
+		testutil.Assert(t, !isIgnored(f), "%v is ignored", f)
+	}
+	// Local and temporary files, as well as directories no tool uses, are not.
+	for _, f := range []string{
+		"faillint.meta", "faillint.tmp.mod", "faillint.tmp.sum", BinChecksumFileName + ".lock", filepath.Join(MigrationBackupDir, "tools.mod"),
+		filepath.Join("tools", "other", "default.pgo"), filepath.Join("envs", "other.vars"), filepath.Join("tools", "hugo", "hugo.tmp.mod"),
+	} {
+		testutil.Assert(t, isIgnored(f), "%v is not ignored", f)
+	}
+}
+
+func TestUpdateModFileAndBuild(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that records each call and builds empty binary.
+	d := newTestModDir(t, dir, "")
+	modDir, gobin, r := d.modDir, d.gobin, d.r
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("GO_CALLS_FILE", callsFile)
+	calls := func() []string {
+		b, err := os.ReadFile(callsFile)
+		testutil.Ok(t, err)
+		testutil.Ok(t, os.Remove(callsFile))
+		var ret []string
+		for _, c := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			ret = append(ret, strings.Fields(c)[0])
+		}
+		return ret
+	}
+
+	modFile := d.writeModFile(t, "tool", "require github.com/x/tool v1.0.0")
+
+	logger := logging.Discard
+	mf, err := OpenModFile(modFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.Ok(t, UpdateModFile(context.Background(), logger, r, modDir, "tool", mf))
+	testutil.Equals(t, []string{"list", "get"}, calls())
+	_, err = os.Stat(filepath.Join(gobin, "tool-v1.0.0"))
+	testutil.Assert(t, os.IsNotExist(err), "expected no binary built")
+	_, err = os.Stat(MetaFilePath(modFile))
+	testutil.Assert(t, os.IsNotExist(err), "expected no meta file written")
+
+	testutil.Ok(t, Build(context.Background(), logger, r, modDir, gobin, "tool", false, "", RebuildIfChanged, mf))
+	c := calls()
+	testutil.Equals(t, "build", c[len(c)-1])
+	for _, call := range c {
+		testutil.Assert(t, call != "list" && call != "get", "expected mod file not updated on build, got %v", c)
+	}
+	_, err = os.Stat(filepath.Join(gobin, "tool-v1.0.0"))
+	testutil.Ok(t, err)
+	metas, err := ReadBinMeta(modFile)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(metas))
+
+	// Binary matching recorded checksum is not rebuilt, unless forced.
+	testutil.Ok(t, Build(context.Background(), logger, r, modDir, gobin, "tool", false, "", RebuildIfChanged, mf))
+	testutil.Equals(t, []string{"env"}, calls())
+	testutil.Ok(t, Build(context.Background(), logger, r, modDir, gobin, "tool", false, "", RebuildForce, mf))
+	testutil.Equals(t, []string{"env", "build"}, calls())
+
+	// Binary built with different build attributes is rebuilt, even if it matches recorded checksum.
+	testutil.Ok(t, mf.SetBuildFlags([]string{"-tags=yolo"}))
+	testutil.Ok(t, Build(context.Background(), logger, r, modDir, gobin, "tool", false, "", RebuildIfChanged, mf))
+	testutil.Equals(t, []string{"env", "build"}, calls())
+	testutil.Ok(t, Build(context.Background(), logger, r, modDir, gobin, "tool", false, "", RebuildIfChanged, mf))
+	testutil.Equals(t, []string{"env"}, calls())
+	testutil.Ok(t, mf.SetBuildEnvs([]string{"CGO_ENABLED=0"}))
+	testutil.Ok(t, Build(context.Background(), logger, r, modDir, gobin, "tool", false, "", RebuildIfChanged, mf))
+	testutil.Equals(t, []string{"env", "build"}, calls())
+}
+
+func TestRemoveFromModCache(t *testing.T) {
+	cache := t.TempDir()
+	for _, f := range []string{
+		"github.com/!x/tool@v1.0.0/main.go",
+		"github.com/!x/tool@v1.1.0/main.go",
+		"cache/download/github.com/!x/tool/@v/v1.0.0.mod",
+		"cache/download/github.com/!x/tool/@v/v1.0.0.zip",
+		"cache/download/github.com/!x/tool/@v/v1.1.0.mod",
+		"cache/download/github.com/!x/tool/@v/list",
+	} {
+		testutil.Ok(t, os.MkdirAll(filepath.Dir(filepath.Join(cache, f)), os.ModePerm))
+		testutil.Ok(t, os.WriteFile(filepath.Join(cache, f), nil, 0444))
+	}
+	// Go makes extracted module read-only.
+	testutil.Ok(t, os.Chmod(filepath.Join(cache, "github.com/!x/tool@v1.0.0"), 0555))
+
+	testutil.Ok(t, RemoveFromModCache(cache, module.Version{Path: "github.com/X/tool", Version: "v1.0.0"}))
+	var left []string
+	testutil.Ok(t, filepath.Walk(cache, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(cache, path)
+			left = append(left, filepath.ToSlash(rel))
+		}
+		return err
+	}))
+	testutil.Equals(t, []string{
+		"cache/download/github.com/!x/tool/@v/list",
+		"cache/download/github.com/!x/tool/@v/v1.1.0.mod",
+		"github.com/!x/tool@v1.1.0/main.go",
+	}, left)
+
+	// Removing module that is not cached is a no-op.
+	testutil.Ok(t, RemoveFromModCache(cache, module.Version{Path: "github.com/x/other", Version: "v1.0.0"}))
+}
+
+func TestCheckMainPackage(t *testing.T) {
+	// Fake go that lists packages of github.com/x/tool module, where only cmd/tool is a main package.
+	goCmd := fakecmd.Go(t, t.TempDir(), `
+  list) for last; do :; done
+    case "$last" in
+      github.com/x/tool/...) echo "go: downloading github.com/x/tool v1.0.0"; echo; echo github.com/x/tool/cmd/tool; echo ;;
+      github.com/x/tool) echo tool ;;
+      github.com/x/tool/cmd/tool) echo main ;;
+      *) echo "no required module provides package $last"; exit 1 ;;
+    esac ;;`)
+	r, err := runner.NewRunner(context.Background(), nil, false, goCmd)
+	testutil.Ok(t, err)
+	modCtx := r.With(context.Background(), "", "", nil)
+
+	mod := module.Version{Path: "github.com/x/tool", Version: "v1.0.0"}
+	testutil.Ok(t, checkMainPackage(modCtx, Package{Module: mod, RelPath: "cmd/tool"}))
+
+	err = checkMainPackage(modCtx, Package{Module: mod})
+	testutil.NotOk(t, err)
+	testutil.Equals(t, `module root package github.com/x/tool is non-main (go list output "tool"), nothing to get and build; `+
+		`use one of main packages of module github.com/x/tool@v1.0.0 instead: github.com/x/tool/cmd/tool`, err.Error())
+
+	err = checkMainPackage(modCtx, Package{Module: mod, RelPath: "cmd/nope"})
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.HasPrefix(err.Error(), "list package github.com/x/tool/cmd/nope; use one of main packages of module github.com/x/tool@v1.0.0 instead: github.com/x/tool/cmd/tool: "), "got %v", err)
+}
+
+func TestUpdateModFile_GoVersionPinned(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that records get arguments.
+	d := newTestModDir(t, dir, `get) echo "$@" > "$CALLS_FILE" ;;`)
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+
+	mf, err := OpenModFile(d.writeModFile(t, "tool", "// bingo:pin_go\n\nrequire github.com/x/tool v1.0.0"))
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.Ok(t, UpdateModFile(context.Background(), logging.Discard, d.r, d.modDir, "tool", mf))
+	b, err := os.ReadFile(callsFile)
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.HasSuffix(strings.TrimSpace(string(b)), "github.com/x/tool@v1.0.0 go@1.14"), "expected pinned go version requested, got %q", string(b))
+}
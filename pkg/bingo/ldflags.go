@@ -0,0 +1,172 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
+)
+
+// ldflagsData is data available to -ldflags build flag placeholders, e.g. -ldflags="-X main.version={{.GitDescribe}}". It
+// describes git state of the tool's module version (not the repository bingo runs in), so tools that stamp their own
+// version at build time report the pinned one.
+type ldflagsData struct {
+	describe, sha, date string
+}
+
+// GitDescribe returns git describe like name of the module version, so the version itself (e.g. v1.2.3 or pseudo-version)
+// or `git describe --tags --always --dirty` output of the local replace directory.
+func (d ldflagsData) GitDescribe() string { return d.describe }
+
+// GitSHA returns commit hash of the module version. It's abbreviated to 12 characters if only the pseudo-version tells it.
+func (d ldflagsData) GitSHA() (string, error) {
+	if d.sha == "" {
+		return "", errors.New("commit hash of the module version is not known, since go does not report its origin (go older than 1.19 or proxy without origin information?)")
+	}
+	return d.sha, nil
+}
+
+// Date returns commit time of the module version in RFC3339 format and UTC. It's not the build time, so rebuilt
+// binaries stay reproducible.
+func (d ldflagsData) Date() string { return d.date }
+
+// ldflagsTemplate returns -ldflags value of the build flag if it has placeholders, or false otherwise.
+func ldflagsTemplate(flag string) (string, bool) {
+	name, value, ok := cut(strings.TrimLeft(flag, "-"), "=")
+	if !ok || name != "ldflags" || !strings.Contains(value, "{{") {
+		return "", false
+	}
+	return value, true
+}
+
+// executeLdflags fills placeholders of -ldflags value.
+func executeLdflags(value string, data ldflagsData) (string, error) {
+	tmpl, err := template.New("ldflags").Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse -ldflags %q", value)
+	}
+	b := strings.Builder{}
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrapf(err, "-ldflags %q; supported placeholders are {{.GitDescribe}}, {{.GitSHA}} and {{.Date}}", value)
+	}
+	return b.String(), nil
+}
+
+// validateLdflags returns error if placeholders of -ldflags build flag (if any) can't be parsed.
+func validateLdflags(flag string) error {
+	value, ok := ldflagsTemplate(flag)
+	if !ok {
+		return nil
+	}
+	_, err := executeLdflags(value, ldflagsData{describe: "v", sha: "sha", date: "date"})
+	return err
+}
+
+// expandLdflags returns build flags with placeholders of -ldflags filled with git information of the package module
+// version. Flags are returned as they are if no -ldflags has placeholders, so git information is resolved only if needed.
+func expandLdflags(ctx context.Context, r *runner.Runner, modCtx runner.Runnable, modDir string, modFile *ModFile, envs envars.EnvSlice, pkg Package, flags []string) ([]string, error) {
+	var (
+		data     ldflagsData
+		resolved bool
+		ret      = make([]string, 0, len(flags))
+	)
+	for _, f := range flags {
+		value, ok := ldflagsTemplate(f)
+		if !ok {
+			ret = append(ret, f)
+			continue
+		}
+		if !resolved {
+			var err error
+			if data, err = moduleGitInfo(ctx, r, modCtx, modDir, modFile, envs, pkg); err != nil {
+				return nil, errors.Wrapf(err, "%v: resolve git information for -ldflags placeholders", pkg.Path())
+			}
+			resolved = true
+		}
+		expanded, err := executeLdflags(value, data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%v", pkg.Path())
+		}
+		ret = append(ret, "-ldflags="+expanded)
+	}
+	return ret, nil
+}
+
+// moduleGitInfo returns git information of the package module version. Module downloaded to the module cache is not a git
+// repository, so it's derived from the version (and its origin, as reported by go) instead. Only local replace
+// directory is asked with git, as it's what the tool is built from.
+func moduleGitInfo(ctx context.Context, r *runner.Runner, modCtx runner.Runnable, modDir string, modFile *ModFile, envs envars.EnvSlice, pkg Package) (ldflagsData, error) {
+	for _, rep := range modFile.LocalReplaces() {
+		if rep.Old.Path != pkg.Module.Path || (rep.Old.Version != "" && rep.Old.Version != pkg.Module.Version) {
+			continue
+		}
+		dir := filepath.FromSlash(rep.New.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(modDir, dir)
+		}
+		return localGitInfo(r.With(ctx, "", dir, envs))
+	}
+
+	v := pkg.Module.Version
+	data := ldflagsData{describe: v}
+	if module.IsPseudoVersion(v) {
+		// Pseudo-version tells both commit time and (abbreviated) hash, so no need to ask go, e.g. for vendored tools.
+		rev, err := module.PseudoVersionRev(v)
+		if err != nil {
+			return ldflagsData{}, err
+		}
+		t, err := module.PseudoVersionTime(v)
+		if err != nil {
+			return ldflagsData{}, err
+		}
+		data.sha, data.date = rev, t.UTC().Format(time.RFC3339)
+	}
+
+	mods, err := modCtx.ModList(pkg.Module.Path + "@" + v)
+	if err != nil {
+		if data.sha != "" {
+			return data, nil
+		}
+		return ldflagsData{}, err
+	}
+	if len(mods) == 0 {
+		return ldflagsData{}, errors.Newf("unexpected empty output of go list -m -json %v@%v", pkg.Module.Path, v)
+	}
+	if m := mods[0]; m.Origin != nil && m.Origin.Hash != "" {
+		data.sha = m.Origin.Hash
+	}
+	if m := mods[0]; data.date == "" && m.Time != nil {
+		data.date = m.Time.UTC().Format(time.RFC3339)
+	}
+	return data, nil
+}
+
+// localGitInfo returns git information of the git work tree the runnable runs in, e.g. local replace directory.
+func localGitInfo(gitCtx runner.Runnable) (ldflagsData, error) {
+	describe, err := gitCtx.Exec("git", "describe", "--tags", "--always", "--dirty")
+	if err != nil {
+		return ldflagsData{}, errors.Wrap(err, "git describe")
+	}
+	sha, err := gitCtx.Exec("git", "rev-parse", "HEAD")
+	if err != nil {
+		return ldflagsData{}, errors.Wrap(err, "git rev-parse")
+	}
+	date, err := gitCtx.Exec("git", "log", "-1", "--format=%cI")
+	if err != nil {
+		return ldflagsData{}, errors.Wrap(err, "git log")
+	}
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return ldflagsData{}, errors.Wrapf(err, "parse commit time %q", date)
+	}
+	return ldflagsData{describe: describe, sha: sha, date: t.UTC().Format(time.RFC3339)}, nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestExpandLdflags(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that knows origin of v1.2.3 only and fails to query other versions, like offline proxy.
+	d := newTestModDir(t, dir, `
+  list) for a in "$@"; do q="$a"; done
+    case "$q" in
+      *@v1.2.3) echo '{"Path": "github.com/x/tool", "Version": "v1.2.3", "Time": "2023-01-02T16:04:05+01:00", "Origin": {"VCS": "git", "Hash": "0123456789abcdef0123456789abcdef01234567", "Ref": "refs/tags/v1.2.3"}}' ;;
+      *@v1.0.0) echo '{"Path": "github.com/x/tool", "Version": "v1.0.0", "Time": "2022-01-02T15:04:05Z"}' ;;
+      *) echo "go: module lookup disabled by GOPROXY=off" >&2; exit 1 ;;
+    esac ;;`)
+	r, modDir := d.r, d.modDir
+
+	mf, err := OpenModFile(d.writeModFile(t, "tool", "require github.com/x/tool v1.2.3"))
+	testutil.Ok(t, err)
+	t.Cleanup(func() { testutil.Ok(t, mf.Close()) })
+
+	const stamp = `-ldflags=-X main.version={{.GitDescribe}} -X main.commit={{.GitSHA}} -X main.date={{.Date}}`
+	for _, tcase := range []struct {
+		version     string
+		flags       []string
+		expected    []string
+		expectedErr string
+	}{
+		{version: "v1.2.3", flags: []string{"-tags=x", "-ldflags=-s -w"}, expected: []string{"-tags=x", "-ldflags=-s -w"}},
+		{
+			version:  "v1.2.3",
+			flags:    []string{"-tags=x", stamp},
+			expected: []string{"-tags=x", "-ldflags=-X main.version=v1.2.3 -X main.commit=0123456789abcdef0123456789abcdef01234567 -X main.date=2023-01-02T15:04:05Z"},
+		},
+		{
+			version:  "v0.0.0-20230102150405-abcdef123456",
+			flags:    []string{"--ldflags=-X main.version={{.GitDescribe}} -X main.commit={{.GitSHA}} -X main.date={{.Date}}"},
+			expected: []string{"-ldflags=-X main.version=v0.0.0-20230102150405-abcdef123456 -X main.commit=abcdef123456 -X main.date=2023-01-02T15:04:05Z"},
+		},
+		{version: "v1.0.0", flags: []string{"-ldflags=-X main.date={{.Date}}"}, expected: []string{"-ldflags=-X main.date=2022-01-02T15:04:05Z"}},
+		{version: "v1.0.0", flags: []string{stamp}, expectedErr: "commit hash of the module version is not known"},
+		{version: "v1.1.0", flags: []string{stamp}, expectedErr: "module lookup disabled by GOPROXY=off"},
+	} {
+		t.Run(tcase.version+" "+strings.Join(tcase.flags, " "), func(t *testing.T) {
+			pkg := Package{Module: module.Version{Path: "github.com/x/tool", Version: tcase.version}, BuildFlags: tcase.flags}
+			flags, err := expandLdflags(context.Background(), r, r.With(context.Background(), mf.Filepath(), modDir, nil), modDir, mf, nil, pkg, pkg.BuildFlags)
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Assert(t, strings.Contains(err.Error(), tcase.expectedErr), "unexpected error %v", err)
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, flags)
+		})
+	}
+
+	t.Run("local replace", func(t *testing.T) {
+		if _, err := exec.LookPath("git"); err != nil {
+			t.Skip("git is not installed")
+		}
+		localDir := filepath.Join(dir, "tool")
+		testutil.Ok(t, os.MkdirAll(localDir, os.ModePerm))
+		t.Setenv("GIT_COMMITTER_DATE", "2023-01-02T16:04:05+01:00")
+		t.Setenv("GIT_AUTHOR_DATE", "2023-01-02T16:04:05+01:00")
+		for _, args := range [][]string{
+			{"init", "-q"},
+			{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+			{"tag", "v0.1.0"},
+		} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = localDir
+			out, err := cmd.CombinedOutput()
+			testutil.Ok(t, err, string(out))
+		}
+		cmd := exec.Command("git", "rev-parse", "HEAD")
+		cmd.Dir = localDir
+		sha, err := cmd.Output()
+		testutil.Ok(t, err)
+
+		testutil.Ok(t, mf.SetLocalReplace("github.com/x/tool", localDir))
+		pkg := Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.2.3"}, BuildFlags: []string{stamp}}
+		flags, err := expandLdflags(context.Background(), r, r.With(context.Background(), mf.Filepath(), modDir, nil), modDir, mf, nil, pkg, pkg.BuildFlags)
+		testutil.Ok(t, err)
+		testutil.Equals(t, []string{"-ldflags=-X main.version=v0.1.0 -X main.commit=" + strings.TrimSpace(string(sha)) + " -X main.date=2023-01-02T15:04:05Z"}, flags)
+	})
+
+	testutil.Ok(t, validateBuildFlag(stamp))
+	testutil.NotOk(t, validateBuildFlag("-ldflags=-X main.version={{.Version}}"))
+	testutil.NotOk(t, validateBuildFlag("-ldflags=-X main.version={{.GitDescribe"))
+}
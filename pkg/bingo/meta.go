@@ -0,0 +1,168 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// BinMeta describes binary as it was installed from a module file. It's written to the meta file alongside
+// the module file on every install, so drift between module files and installed binaries can be detected.
+type BinMeta struct {
+	Name string `json:"name"`
+	// Package is a package with module version in path@version form.
+	Package    string   `json:"package"`
+	BuildEnvs  []string `json:"build_envs,omitempty"`
+	BuildFlags []string `json:"build_flags,omitempty"`
+	BinaryPath string   `json:"binary_path"`
+	// BuildKey is a build key (see Package.BuildKey) of the package, extended with go and toolchain directives of the
+	// module file. Binary built with a different key is rebuilt on the next install.
+	BuildKey string `json:"build_key,omitempty"`
+	// ModFileHash is a hash of the module and sum file content and go version the binary was installed with. See
+	// IsInstallUpToDate.
+	ModFileHash string `json:"mod_file_hash,omitempty"`
+}
+
+// MetaFilePath returns path of the meta file for the given module file. Meta files are local state, so they are not committed.
+func MetaFilePath(modFilePath string) string {
+	return strings.TrimSuffix(modFilePath, ".mod") + ".meta"
+}
+
+func newBinMeta(name string, pkg Package, binPath string) BinMeta {
+	return BinMeta{
+		Name:       name,
+		Package:    pkg.String(),
+		BuildEnvs:  pkg.BuildEnvs,
+		BuildFlags: pkg.BuildFlags,
+		BinaryPath: binPath,
+	}
+}
+
+// buildKey returns build key of the package (see Package.BuildKey) extended with directives of the module file that
+// affect the build, so e.g. editing go directive rebuilds binaries too.
+func buildKey(modFile *ModFile, pkg Package) string {
+	return hashFields(pkg.BuildKey(), modFile.GoVersion(), modFile.Toolchain())
+}
+
+// modFileHash returns hash of the module file and its sum file (if any) content, together with the go version.
+func modFileHash(modFilePath string, goVersion string) (string, error) {
+	mod, err := os.ReadFile(modFilePath)
+	if err != nil {
+		return "", err
+	}
+	sum, err := os.ReadFile(SumFilePath(modFilePath))
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return hashFields(string(mod), string(sum), goVersion), nil
+}
+
+// lastBuildKeys returns build keys recorded by the last build of the module file by binary name. Temporary module files
+// (<name>.tmp.mod) replace <name>.mod on success, so keys of the latter are returned for them.
+func lastBuildKeys(modFilePath string) (map[string]string, error) {
+	if strings.HasSuffix(modFilePath, ".tmp.mod") {
+		modFilePath = strings.TrimSuffix(modFilePath, ".tmp.mod") + ".mod"
+	}
+	metas, err := ReadBinMeta(modFilePath)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string, len(metas))
+	for _, m := range metas {
+		keys[m.Name] = m.BuildKey
+	}
+	return keys, nil
+}
+
+// WriteBinMeta writes meta file for the given module file.
+func WriteBinMeta(modFilePath string, metas []BinMeta) error {
+	b, err := json.MarshalIndent(metas, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(MetaFilePath(modFilePath), append(b, '\n'), 0666)
+}
+
+// ReadBinMeta reads meta file for the given module file. It returns nil if tool was never installed.
+func ReadBinMeta(modFilePath string) (metas []BinMeta, err error) {
+	f, err := os.Open(MetaFilePath(modFilePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer errcapture.Do(&err, f.Close, "close")
+
+	if err := json.NewDecoder(f).Decode(&metas); err != nil {
+		return nil, errors.Wrapf(err, "decode %v", MetaFilePath(modFilePath))
+	}
+	return metas, nil
+}
+
+// CheckInstalled compares packages pinned in the given module file against binaries installed in gobin, as recorded
+// in the meta file. It returns all found mismatches, empty if all binaries are installed as pinned.
+func CheckInstalled(modFilePath, gobin string) (mismatches []string, err error) {
+	mf, err := OpenModFile(modFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer errcapture.Do(&err, mf.Close, "close")
+
+	pkgs := mf.DirectPackages()
+	if len(pkgs) == 0 {
+		return nil, errors.Newf("no direct package found in %s; empty module?", modFilePath)
+	}
+	name, _ := NameFromModFile(modFilePath)
+	names, err := BinaryNames(name, pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	metas, err := ReadBinMeta(modFilePath)
+	if err != nil {
+		return nil, err
+	}
+	installed := make(map[string]BinMeta, len(metas))
+	for _, m := range metas {
+		installed[m.Name] = m
+	}
+
+	for i, pkg := range pkgs {
+		expected := newBinMeta(names[i], pkg, versionedBinPath(gobin, names[i], pkg))
+		m, ok := installed[names[i]]
+		if !ok {
+			mismatches = append(mismatches, names[i]+": pinned "+expected.Package+", but never installed; run bingo get "+name)
+			continue
+		}
+		if m.Package != expected.Package {
+			mismatches = append(mismatches, names[i]+": pinned "+expected.Package+", but installed "+m.Package)
+		}
+		if attrs(m.BuildEnvs) != attrs(expected.BuildEnvs) {
+			mismatches = append(mismatches, names[i]+": pinned build envs "+attrs(expected.BuildEnvs)+", but installed with "+attrs(m.BuildEnvs))
+		}
+		if attrs(m.BuildFlags) != attrs(expected.BuildFlags) {
+			mismatches = append(mismatches, names[i]+": pinned build flags "+attrs(expected.BuildFlags)+", but installed with "+attrs(m.BuildFlags))
+		}
+		if _, err := os.Stat(expected.BinaryPath); err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			mismatches = append(mismatches, names[i]+": binary "+expected.BinaryPath+" does not exist")
+		}
+	}
+	return mismatches, nil
+}
+
+func attrs(a []string) string {
+	if len(a) == 0 {
+		return "none"
+	}
+	return strings.Join(a, " ")
+}
@@ -0,0 +1,50 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestCheckInstalled(t *testing.T) {
+	modDir := t.TempDir()
+	gobin := t.TempDir()
+	modFile := filepath.Join(modDir, "faillint.mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/fatih/faillint v1.5.0 // -tags=yolo
+`), os.ModePerm))
+
+	m, err := CheckInstalled(modFile, gobin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"faillint: pinned github.com/fatih/faillint@v1.5.0, but never installed; run bingo get faillint"}, m)
+
+	binPath := filepath.Join(gobin, "faillint-v1.5.0")
+	pkg := Package{Module: module.Version{Path: "github.com/fatih/faillint", Version: "v1.4.0"}}
+	testutil.Ok(t, WriteBinMeta(modFile, []BinMeta{newBinMeta("faillint", pkg, binPath)}))
+
+	m, err = CheckInstalled(modFile, gobin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{
+		"faillint: pinned github.com/fatih/faillint@v1.5.0, but installed github.com/fatih/faillint@v1.4.0",
+		"faillint: pinned build flags -tags=yolo, but installed with none",
+		"faillint: binary " + binPath + " does not exist",
+	}, m)
+
+	pkg.Module.Version = "v1.5.0"
+	pkg.BuildFlags = []string{"-tags=yolo"}
+	testutil.Ok(t, WriteBinMeta(modFile, []BinMeta{newBinMeta("faillint", pkg, binPath)}))
+	testutil.Ok(t, os.WriteFile(binPath, []byte("binary"), os.ModePerm))
+
+	m, err = CheckInstalled(modFile, gobin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(m))
+}
@@ -0,0 +1,278 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/cpy"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// MigrationBackupDir is a directory within the module directory where MigrateModFile backs up legacy module files.
+const MigrationBackupDir = "backup"
+
+// LegacyModFiles returns module files bingo can't work with as they are: module files in modDir with more than one direct
+// require (all tools pinned in a single file, as old setups did) and, if fromDir is not empty, all module files in fromDir
+// (e.g. module directory with a different name). Files are only read, since OpenModFile keeps only the first direct require.
+func LegacyModFiles(modDir, fromDir string) (ret []string, _ error) {
+	files, err := modFilesIn(modDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		l, err := readLegacyModFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read %v", f)
+		}
+		if len(l.directRequires()) > 1 {
+			ret = append(ret, f)
+		}
+	}
+	if fromDir == "" {
+		return ret, nil
+	}
+
+	files, err = modFilesIn(fromDir)
+	if err != nil {
+		return nil, err
+	}
+	return append(ret, files...), nil
+}
+
+// modFilesIn returns module files in dir, except the fake go.mod and tmp files of bingo get.
+func modFilesIn(dir string) (ret []string, _ error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.mod"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if filepath.Base(f) == FakeRootModFileName || strings.HasSuffix(f, ".tmp.mod") {
+			continue
+		}
+		ret = append(ret, f)
+	}
+	return ret, nil
+}
+
+// legacyModFile is a content of legacy module file, read at once, so the file is not held open during migration.
+type legacyModFile struct {
+	path      string
+	goVersion string
+	requires  []mod.RequireDirective
+	replaces  []mod.ReplaceDirective
+	excludes  []mod.ExcludeDirective
+	retracts  []mod.RetractDirective
+}
+
+func readLegacyModFile(modFile string) (_ legacyModFile, err error) {
+	m, err := mod.OpenFileForRead(modFile)
+	if err != nil {
+		return legacyModFile{}, err
+	}
+	defer errcapture.Do(&err, m.Close, "close")
+
+	return legacyModFile{
+		path:      modFile,
+		goVersion: m.GoVersion(),
+		requires:  m.RequireDirectives(),
+		replaces:  m.ReplaceDirectives(),
+		excludes:  m.ExcludeDirectives(),
+		retracts:  m.RetractDirectives(),
+	}, nil
+}
+
+func (l legacyModFile) directRequires() (ret []mod.RequireDirective) {
+	for _, r := range l.requires {
+		if !r.Indirect {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// Migration describes changes done (or planned) by MigrateModFile.
+type Migration struct {
+	// Created are names of module files created in the module directory, one per tool.
+	Created []string
+	// Existing are names of module files that already pinned the same package and version, so they were left as they are.
+	Existing []string
+	// Backup is a path of the backed up legacy module file. Empty on dry run.
+	Backup string
+}
+
+// MigrateModFile converts legacy module file (see LegacyModFiles) into module files in modDir, one per direct require, with
+// the same version, build attributes and go, replace, exclude and retract directives. Local replaces are rebased to modDir.
+// Tools are named with name attribute or after the package, except single tool module file, which keeps its name (e.g
+// foo.1.mod of array). Legacy module file (and its sum file) is backed up in MigrationBackupDir and removed, so migration
+// is done only once. Module file of the same package and version that already exists is left as it is, so interrupted
+// migration can be resumed. Nothing is written on dryRun.
+func MigrateModFile(ctx context.Context, r *runner.Runner, logger logging.Logger, modDir, legacyFile string, dryRun bool) (Migration, error) {
+	legacy, err := readLegacyModFile(legacyFile)
+	if err != nil {
+		return Migration{}, err
+	}
+
+	var targets []Package
+	for _, req := range legacy.directRequires() {
+		meta := strings.Trim(req.ExtraSuffixComment, "\n")
+		if err := validateDirectPackageMeta(meta); err != nil {
+			return Migration{}, errors.Wrapf(err, "%v", req.Module.String())
+		}
+		t := parseDirectPackageMeta(meta)
+		t.Module = req.Module
+		targets = append(targets, t)
+	}
+	if len(targets) == 0 {
+		return Migration{}, errors.Newf("no direct package found in %s; empty module?", legacyFile)
+	}
+
+	outFiles := make([]string, len(targets))
+	seen := map[string]string{}
+	for i, t := range targets {
+		outFiles[i] = filepath.Base(legacyFile)
+		if len(targets) > 1 {
+			name := t.Name
+			if name == "" {
+				name = DefaultBinaryName(t.Path())
+			}
+			if err := ValidateBinaryName(name); err != nil {
+				return Migration{}, errors.Wrapf(err, "%v", t.String())
+			}
+			outFiles[i] = name + ".mod"
+		}
+		if other, ok := seen[outFiles[i]]; ok {
+			return Migration{}, errors.Newf("%v and %v would be both pinned in %v; set different name attributes manually first", other, t.String(), outFiles[i])
+		}
+		seen[outFiles[i]] = t.String()
+	}
+
+	var ret Migration
+	var toCreate []int
+	for i, t := range targets {
+		outModFile := filepath.Join(modDir, outFiles[i])
+		if outModFile == legacyFile {
+			toCreate = append(toCreate, i)
+			continue
+		}
+		if _, err := os.Stat(outModFile); err != nil {
+			if !os.IsNotExist(err) {
+				return Migration{}, err
+			}
+			toCreate = append(toCreate, i)
+			continue
+		}
+		existing, _, err := modDirectPackageAndComment(outModFile)
+		if err != nil {
+			return Migration{}, errors.Wrapf(err, "%v already exists", outFiles[i])
+		}
+		if existing.Module != t.Module || existing.RelPath != t.RelPath {
+			return Migration{}, errors.Newf("%v already exists and pins %v instead of %v; rename or remove it first", outFiles[i], existing.String(), t.String())
+		}
+		ret.Existing = append(ret.Existing, outFiles[i])
+	}
+	for _, i := range toCreate {
+		ret.Created = append(ret.Created, outFiles[i])
+	}
+	if dryRun {
+		return ret, nil
+	}
+
+	backupDir := filepath.Join(modDir, MigrationBackupDir)
+	if err := os.MkdirAll(backupDir, os.ModePerm); err != nil {
+		return Migration{}, errors.Wrap(err, "create backup dir")
+	}
+	ret.Backup = filepath.Join(backupDir, filepath.Base(legacyFile))
+	if _, err := os.Lstat(ret.Backup); err == nil {
+		return Migration{}, errors.Newf("backup %v already exists; move it away first", ret.Backup)
+	}
+	if err := cpy.File(legacyFile, ret.Backup); err != nil {
+		return Migration{}, errors.Wrap(err, "back up module file")
+	}
+	if err := cpy.File(SumFilePath(legacyFile), SumFilePath(ret.Backup)); err != nil && !os.IsNotExist(err) {
+		return Migration{}, errors.Wrap(err, "back up sum file")
+	}
+
+	legacy.replaces = rebaseLocalReplaces(legacy.replaces, filepath.Dir(legacyFile), modDir)
+	tmpFiles := make([]string, 0, len(toCreate))
+	for _, i := range toCreate {
+		tmpFile := filepath.Join(modDir, strings.TrimSuffix(outFiles[i], ".mod")+".tmp.mod")
+		if err := createMigratedModFile(ctx, r, logger, legacy, tmpFile, targets[i]); err != nil {
+			return Migration{}, errors.Wrapf(err, "create %v", outFiles[i])
+		}
+		tmpFiles = append(tmpFiles, tmpFile)
+	}
+
+	for _, f := range []string{legacyFile, SumFilePath(legacyFile), MetaFilePath(legacyFile)} {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return Migration{}, errors.Wrapf(err, "remove %v", f)
+		}
+	}
+	for j, i := range toCreate {
+		outModFile := filepath.Join(modDir, outFiles[i])
+		if err := os.Rename(tmpFiles[j], outModFile); err != nil {
+			return Migration{}, errors.Wrap(err, "rename mod file")
+		}
+		if err := os.Rename(SumFilePath(tmpFiles[j]), SumFilePath(outModFile)); err != nil && !os.IsNotExist(err) {
+			return Migration{}, errors.Wrap(err, "rename sum file")
+		}
+	}
+	return ret, nil
+}
+
+func createMigratedModFile(ctx context.Context, r *runner.Runner, logger logging.Logger, legacy legacyModFile, modFile string, target Package) (err error) {
+	mf, err := CreateFromExistingOrNew(ctx, r, logger, "", modFile)
+	if err != nil {
+		return err
+	}
+	defer errcapture.Do(&err, mf.Close, "close")
+
+	if legacy.goVersion != "" {
+		if err := mf.SetGoVersion(legacy.goVersion); err != nil {
+			return err
+		}
+	}
+	if err := mf.SetReplaceDirectives(legacy.replaces...); err != nil {
+		return err
+	}
+	if err := mf.SetExcludeDirectives(legacy.excludes...); err != nil {
+		return err
+	}
+	if err := mf.SetRetractDirectives(legacy.retracts...); err != nil {
+		return err
+	}
+	if err := mf.SetDirectPackages(target); err != nil {
+		return err
+	}
+	// Sums of other tools are dropped by the next go get.
+	if err := cpy.File(SumFilePath(legacy.path), SumFilePath(modFile)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "copy sum file")
+	}
+	return nil
+}
+
+// rebaseLocalReplaces returns replaces with local directories relative to fromDir made relative to toDir.
+func rebaseLocalReplaces(replaces []mod.ReplaceDirective, fromDir, toDir string) []mod.ReplaceDirective {
+	ret := make([]mod.ReplaceDirective, 0, len(replaces))
+	for _, r := range replaces {
+		if r.New.Version == "" && !filepath.IsAbs(r.New.Path) {
+			if rel, err := filepath.Rel(toDir, filepath.Join(fromDir, filepath.FromSlash(r.New.Path))); err == nil {
+				r.New.Path = filepath.ToSlash(rel)
+				if !strings.HasPrefix(r.New.Path, "../") {
+					// Go requires local paths to start with ./ or ../.
+					r.New.Path = "./" + r.New.Path
+				}
+			}
+		}
+		ret = append(ret, r)
+	}
+	return ret
+}
@@ -0,0 +1,101 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestMigrateModFile(t *testing.T) {
+	logger := logging.Discard
+	r, err := runner.NewRunner(context.TODO(), nil, false, "go")
+	testutil.Ok(t, err)
+
+	tmpDir := t.TempDir()
+	fromDir := filepath.Join(tmpDir, "tools")
+	modDir := filepath.Join(tmpDir, ".bingo")
+	testutil.Ok(t, os.MkdirAll(fromDir, os.ModePerm))
+	testutil.Ok(t, os.MkdirAll(modDir, os.ModePerm))
+
+	legacyFile := filepath.Join(fromDir, "tools.mod")
+	testutil.Ok(t, os.WriteFile(legacyFile, []byte(`module tools
+
+go 1.16
+
+require (
+	github.com/x/server v1.0.0 // cmd/server
+	github.com/y/lint v0.2.0 // name=golint CGO_ENABLED=0 -tags=netgo
+	github.com/z/dep v0.1.0 // indirect
+)
+
+replace github.com/x/server => ./server
+`), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(SumFilePath(legacyFile), []byte("github.com/x/server v1.0.0 h1:abc=\n"), os.ModePerm))
+
+	files, err := LegacyModFiles(modDir, fromDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{legacyFile}, files)
+
+	m, err := MigrateModFile(context.TODO(), r, logger, modDir, legacyFile, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, Migration{Created: []string{"server.mod", "golint.mod"}}, m)
+	_, err = os.Stat(filepath.Join(modDir, "server.mod"))
+	testutil.Assert(t, os.IsNotExist(err), "expected nothing written on dry run")
+
+	m, err = MigrateModFile(context.TODO(), r, logger, modDir, legacyFile, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, Migration{Created: []string{"server.mod", "golint.mod"}, Backup: filepath.Join(modDir, MigrationBackupDir, "tools.mod")}, m)
+
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.16
+
+replace github.com/x/server => ../tools/server
+
+require github.com/x/server v1.0.0 // cmd/server
+`, filepath.Join(modDir, "server.mod"))
+	expectContent(t, "github.com/x/server v1.0.0 h1:abc=\n", filepath.Join(modDir, "server.sum"))
+
+	mf, err := OpenModFile(filepath.Join(modDir, "golint.mod"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, "golint", mf.DirectPackage().Name)
+	testutil.Equals(t, []string{"CGO_ENABLED=0"}, []string(mf.DirectPackage().BuildEnvs))
+	testutil.Equals(t, []string{"-tags=netgo"}, mf.DirectPackage().BuildFlags)
+	testutil.Equals(t, 1, len(mf.ReplaceDirectives()))
+	testutil.Ok(t, mf.Close())
+
+	_, err = os.Stat(legacyFile)
+	testutil.Assert(t, os.IsNotExist(err), "expected legacy file removed")
+	expectContent(t, "github.com/x/server v1.0.0 h1:abc=\n", SumFilePath(m.Backup))
+
+	// Migrated files are not legacy anymore.
+	files, err = LegacyModFiles(modDir, fromDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(files))
+}
+
+func TestRebaseLocalReplaces(t *testing.T) {
+	for _, tcase := range []struct {
+		path, fromDir, toDir, expected string
+	}{
+		{path: "../server", fromDir: "/repo/tools", toDir: "/repo/.bingo", expected: "../server"},
+		{path: "./server", fromDir: "/repo", toDir: "/repo/.bingo", expected: "../server"},
+		{path: "./tools/server", fromDir: "/repo", toDir: "/repo/tools", expected: "./server"},
+		{path: "/abs/server", fromDir: "/repo", toDir: "/repo/.bingo", expected: "/abs/server"},
+	} {
+		t.Run(tcase.path, func(t *testing.T) {
+			replaces := rebaseLocalReplaces([]mod.ReplaceDirective{{New: module.Version{Path: tcase.path}}}, tcase.fromDir, tcase.toDir)
+			testutil.Equals(t, tcase.expected, replaces[0].New.Path)
+		})
+	}
+}
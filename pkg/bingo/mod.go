@@ -4,25 +4,36 @@
 package bingo
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 
+	"github.com/Masterminds/semver"
 	"github.com/bwplotka/bingo/pkg/cpy"
 
 	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
 	"github.com/bwplotka/bingo/pkg/mod"
 	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/bwplotka/bingo/pkg/version"
 	"github.com/efficientgo/core/errcapture"
 	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/modfile"
 	"golang.org/x/mod/module"
+	modsemver "golang.org/x/mod/semver"
 )
 
 const (
@@ -30,13 +41,131 @@ const (
 	FakeRootModFileName = "go.mod"
 
 	NoDirectiveCommand = "bingo:no_directive_fetch"
+	// NoSumDBDirective disables verification of the tool module against the Go checksum database (see ModFile.IsSumDBDisabled),
+	// e.g. for tools hosted on servers the checksum database can't reach.
+	NoSumDBDirective = "bingo:no_sumdb"
+	// GoVersionPinnedDirective keeps go directive of the module file as it is when the tool is updated (see
+	// ModFile.IsGoVersionPinned), instead of letting go get raise it to what dependencies require.
+	GoVersionPinnedDirective = "bingo:pin_go"
+	// VendorDirective makes bingo copy sources of the tool's modules into VendorDir and build the tool from them without
+	// network access (see ModFile.IsVendored).
+	VendorDirective = "bingo:vendor"
+	// LatestDirective records that the tool was requested at the latest version, so update re-resolves go's "latest" module
+	// query instead of the highest released version. See Package.Latest.
+	LatestDirective = "bingo:latest"
+	// AlsoDirective marks additional package (relative path with optional build attributes) built from the same module as the direct one.
+	AlsoDirective = "also:"
+	// CommentDirective holds human readable description of the tool, e.g. what it is used for.
+	CommentDirective = "bingo:comment"
+	// PostInstallDirective holds command run on every binary of the tool after it's built, e.g. "bingo:post-install strip {{.Bin}}".
+	// See Package.PostInstall.
+	PostInstallDirective = "bingo:post-install"
+	// NameAttribute sets explicit binary name of the package, e.g. "name=myserver". By default binary is named
+	// after the mod file (direct package) or the package directory (additional packages).
+	NameAttribute = "name="
+	// EnvFileAttribute references file (relative to the module directory) with additional build environment variables and
+	// flags of the package, e.g. "env-file=foo.env". See Package.WithEnvFile.
+	EnvFileAttribute = "env-file="
+	// WorkDirAttribute sets directory (relative to the module directory) the package is built from, e.g. "workdir=tools/foo".
+	// See Package.WorkDir.
+	WorkDirAttribute = "workdir="
+	// BranchAttribute records branch the pinned pseudo-version was resolved from, e.g. "branch=main", so update
+	// re-resolves the branch tip instead of the latest release.
+	BranchAttribute = "branch="
+	// TagAttribute records non-semver git tag the pinned pseudo-version was resolved from, e.g. "tag=release-2024-01", so
+	// it's clear which release is pinned and update re-resolves the tag.
+	TagAttribute = "tag="
+	// VerifyCmdAttribute holds command run on the binary of the package after it's built, e.g. verify-cmd="{{.Bin}} --version".
+	// See Package.VerifyCmd.
+	VerifyCmdAttribute = "verify-cmd="
+	// VerifyOutputAttribute holds text output of the verify command has to contain, e.g. verify-output={{.Version}}.
+	// See Package.VerifyOutput.
+	VerifyOutputAttribute = "verify-output="
 
-	PackageRenderablesPrintHeader = "Name\tBinary Name\tPackage @ Version\tBuild EnvVars\tBuild Flags\n" +
-		"----\t-----------\t-----------------\t-------------\t-----------\n"
+	// LocalReplaceVersion is a version of modules built from local replace directory (see ModFile.SetLocalReplace). It's the
+	// same version go uses for replaced modules that were never released.
+	LocalReplaceVersion = "v0.0.0-00010101000000-000000000000"
+
+	PackageRenderablesPrintHeader = "Name\tBinary Name\tPackage @ Version\tBuild EnvVars\tBuild Flags\tComment\n" +
+		"----\t-----------\t-----------------\t-------------\t-----------\t-------\n"
 
 	metaComment = "Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT"
+	// anonymousModule is the default module path of bingo module files, see CreateFromExistingOrNewWithModule.
+	anonymousModule = "_"
+)
+
+var (
+	buildEnvRegexp   = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*=`)
+	binaryNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+	branchNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9._/-]+$`)
 )
 
+// ValidateBinaryName returns error if the given name is not a safe binary file name. Allowed characters [A-z0-9._-].
+func ValidateBinaryName(name string) error {
+	if !binaryNameRegexp.MatchString(name) || name == "." || name == ".." {
+		return errors.Newf("binary name %q has to be a file name with only [A-z0-9._-] characters", name)
+	}
+	return nil
+}
+
+// ValidateBranchName returns error if the given name is not a branch name bingo can record. Allowed characters [A-z0-9._/-].
+func ValidateBranchName(branch string) error {
+	if !branchNameRegexp.MatchString(branch) || strings.HasPrefix(branch, "-") || strings.HasPrefix(branch, "/") || strings.HasSuffix(branch, "/") || strings.Contains(branch, "..") {
+		return errors.Newf("branch name %q has to be a git branch name with only [A-z0-9._/-] characters", branch)
+	}
+	return nil
+}
+
+// ValidateTagName returns error if the given name is not a tag name bingo can record. Allowed characters [A-z0-9._/-].
+func ValidateTagName(tag string) error {
+	if ValidateBranchName(tag) != nil {
+		return errors.Newf("tag name %q has to be a git tag name with only [A-z0-9._/-] characters", tag)
+	}
+	return nil
+}
+
+// validateWorkDir returns error if the work directory is not a clean path within the module directory.
+func validateWorkDir(dir string) error {
+	if dir == "" || path.IsAbs(dir) || filepath.IsAbs(dir) || path.Clean(dir) != dir || dir == ".." || strings.HasPrefix(dir, "../") {
+		return errors.Newf("work dir %q has to be a clean path relative to the module directory", dir)
+	}
+	return nil
+}
+
+// bingoBuildFlags are go build flags bingo sets on its own, so packages can't override them.
+var bingoBuildFlags = map[string]struct{}{"o": {}, "modfile": {}}
+
+// validateBuildFlag returns error if the given build flag would conflict with flags bingo sets or its -ldflags placeholders
+// can't be parsed. Other flags, including -mod (e.g. -mod=mod for tools that need their go.mod updated during build), are
+// passed to go as they are.
+func validateBuildFlag(flag string) error {
+	name, _, _ := cut(strings.TrimLeft(flag, "-"), "=")
+	if _, ok := bingoBuildFlags[name]; ok {
+		return errors.Newf("build flag %q is set by bingo and can't be overridden", flag)
+	}
+	return validateLdflags(flag)
+}
+
+// godebugSettingRegexp matches a single GODEBUG setting, e.g. gotypesalias=0.
+var godebugSettingRegexp = regexp.MustCompile(`^[A-Za-z0-9_.]+=[^,=]*$`)
+
+// validateBuildEnv returns error if the build env is not in KEY=VALUE form or its value is known to be invalid, e.g.
+// GODEBUG that is not comma-separated list of key=value settings.
+// NOTE: GODEBUG build env applies to go build (go command and compiler) only, not to the built tool when it runs.
+func validateBuildEnv(env string) error {
+	if !buildEnvRegexp.MatchString(env) {
+		return errors.Newf("build env %q has to be in KEY=VALUE form with upper case KEY", env)
+	}
+	if k, v, _ := cut(env, "="); k == "GODEBUG" && v != "" {
+		for _, setting := range strings.Split(v, ",") {
+			if !godebugSettingRegexp.MatchString(setting) {
+				return errors.Newf("build env %q has to be comma-separated list of key=value settings, got %q", env, setting)
+			}
+		}
+	}
+	return nil
+}
+
 // NameFromModFile returns binary name from module file path.
 func NameFromModFile(modFile string) (name string, oneOfMany bool) {
 	n := strings.Split(strings.TrimSuffix(filepath.Base(modFile), ".mod"), ".")
@@ -56,6 +185,33 @@ type Package struct {
 	// If Module.Path is empty and RelPath specified, it means that we don't know what is a module what is the package path.
 	RelPath string
 
+	// Name is an explicit binary name set with NameAttribute. Empty if binary is named by default.
+	Name string
+	// EnvFile is a path (relative to the module directory) of env file set with EnvFileAttribute. Empty if not set.
+	EnvFile string
+	// WorkDir is a directory (relative to the module directory) go build of the package runs in, set with WorkDirAttribute,
+	// e.g. for build flags with relative paths (-pgo=, -overlay=) or tools that expect specific build context. It has to be
+	// within the module directory, so go still finds its go.mod. Empty if the package is built from the module directory.
+	WorkDir string
+	// Branch is a branch the version was resolved from, set with BranchAttribute. Empty for version pins.
+	Branch string
+	// Tag is a non-semver git tag the version was resolved from, set with TagAttribute. Empty for version pins.
+	Tag string
+	// VerifyCmd is a command run after the binary is built (and post-processed, see PostInstall), set with VerifyCmdAttribute.
+	// Install fails if it exits with non-zero code, e.g. for sanity check of critical tools. It's split and templated the same
+	// way as PostInstall. Empty if not set.
+	VerifyCmd string
+	// VerifyOutput is a text VerifyCmd output has to contain, set with VerifyOutputAttribute, e.g. {{.Version}} to catch
+	// binaries that report different version than the pinned module version. Templated as PostInstall. Empty if not checked.
+	VerifyOutput string
+	// PostInstall is a command run after the binary is built, recorded with PostInstallDirective. It's shared by all
+	// packages of the module file. Arguments are split like in shell and can use {{.Bin}}, {{.Name}} and {{.Version}} template
+	// placeholders. Empty if not set.
+	PostInstall string
+	// Latest is true if the version was resolved from the "latest" module query, recorded with LatestDirective. The concrete
+	// version is still pinned, so builds are reproducible. It's shared by all packages of the module file.
+	Latest bool
+
 	// BuildEnvs are environment variables to be used during go build process.
 	BuildEnvs envars.EnvSlice
 	// BuildFlags are flags to be used during go build process.
@@ -63,7 +219,8 @@ type Package struct {
 }
 
 // String returns a representation of the Package suitable for `go` tools and logging.
-// (Module.Path/RelPath@Module.Version, or Module.Path/RelPath if Version is empty).
+// (Module.Path/RelPath@Module.Version, or Module.Path/RelPath if Version is empty). See RequireLine for the form with
+// build attributes as written in the module file.
 func (m Package) String() string {
 	if m.Module.Version == "" {
 		return m.Path()
@@ -71,22 +228,170 @@ func (m Package) String() string {
 	return m.Path() + "@" + m.Module.Version
 }
 
+// RequireLine returns the package in the canonical form bingo writes as the direct require of the module file, without
+// "require" keyword, e.g. "github.com/prometheus/prometheus v2.4.3+incompatible // cmd/prometheus CGO_ENABLED=1 -tags=yolo".
+// Module file with this line parses back to the same Package, except PostInstall and Latest, which are recorded as separate directives.
+func (m Package) RequireLine() string {
+	line := modfile.AutoQuote(m.Module.Path) + " " + modfile.AutoQuote(m.Module.Version)
+	if meta := directPackageMeta(m); len(meta) > 0 {
+		line += " // " + strings.Join(meta, " ")
+	}
+	return line
+}
+
 // Path returns a full package path. package path is platform independent, usually in
 // the form of "a/b/c" with forward slashes
 func (m Package) Path() string {
 	return path.Join(m.Module.Path, m.RelPath)
 }
 
+// TargetGOOS returns GOOS the package is built for. It's taken from BuildEnvs, or host GOOS if not set there.
+func (m Package) TargetGOOS() string {
+	if v, ok := m.BuildEnvs.Lookup("GOOS"); ok && v != "" {
+		return v
+	}
+	return runtime.GOOS
+}
+
+// TargetGOARCH returns GOARCH the package is built for. It's taken from BuildEnvs, or host GOARCH if not set there.
+func (m Package) TargetGOARCH() string {
+	if v, ok := m.BuildEnvs.Lookup("GOARCH"); ok && v != "" {
+		return v
+	}
+	return runtime.GOARCH
+}
+
+// PlatformSuffix returns "-<GOOS>_<GOARCH>" suffix for binaries of packages that set GOOS or GOARCH in BuildEnvs,
+// so cross compiled binaries do not overwrite native ones. It returns empty string otherwise.
+func (m Package) PlatformSuffix() string {
+	_, goos := m.BuildEnvs.Lookup("GOOS")
+	_, goarch := m.BuildEnvs.Lookup("GOARCH")
+	if !goos && !goarch {
+		return ""
+	}
+	return "-" + m.TargetGOOS() + "_" + m.TargetGOARCH()
+}
+
+// ExeSuffix returns ".exe" for packages that set GOOS=windows in BuildEnvs, so cross compiled Windows binaries can be run
+// there as they are. Native binaries have no suffix on any host, so generated Variables.mk and variables.env are the same
+// everywhere. It returns empty string otherwise.
+func (m Package) ExeSuffix() string {
+	if v, _ := m.BuildEnvs.Lookup("GOOS"); v == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// GoExperiment returns toolchain experiments the package is built with, as set with GOEXPERIMENT in BuildEnvs (e.g.
+// "rangefunc" or "arenas,nocoverageredesign"). It returns empty string if not set.
+func (m Package) GoExperiment() string {
+	v, _ := m.BuildEnvs.Lookup("GOEXPERIMENT")
+	return v
+}
+
+// BuildKey returns a stable hash of everything in the package that affects the built binary: module, version, package
+// path, binary name, build flags, build environment variables (in any order), work directory and post-install command. Binary recorded
+// with a different key is rebuilt, even if it exists for the same version.
+func (m Package) BuildKey() string {
+	envs := append([]string(nil), m.BuildEnvs...)
+	sort.Strings(envs)
+	relPath := m.RelPath
+	if relPath == "." {
+		// Module root package, the same as empty relative path.
+		relPath = ""
+	}
+	fields := []string{m.Module.Path, m.Module.Version, relPath, m.Name, m.PostInstall, strings.Join(m.BuildFlags, "\x00")}
+	if m.WorkDir != "" {
+		// Only set if used, so keys of packages built from the module directory stay the same.
+		fields = append(fields, WorkDirAttribute+m.WorkDir)
+	}
+	return hashFields(append(fields, envs...)...)
+}
+
+// hashFields returns hex encoded SHA-256 of the given fields. Fields are separated, so "a", "bc" and "ab", "c" hash differently.
+func hashFields(fields ...string) string {
+	h := sha256.New()
+	for _, f := range fields {
+		_, _ = io.WriteString(h, strconv.Quote(f))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// moduleFetchEnvs are names of go environment variables that control how modules are fetched and verified.
+var moduleFetchEnvs = map[string]struct{}{
+	"GOPROXY": {}, "GONOPROXY": {}, "GOPRIVATE": {}, "GOSUMDB": {}, "GONOSUMDB": {}, "GOINSECURE": {}, "GOVCS": {}, "GOFLAGS": {}, "NETRC": {},
+}
+
+// ModuleFetchEnvs returns build environment variables that control how modules are fetched and verified (e.g. GOPROXY,
+// GONOSUMDB or GOFLAGS), so they can be applied also for resolving and downloading the package, not only for build.
+func (m Package) ModuleFetchEnvs() (ret envars.EnvSlice) {
+	for _, e := range m.BuildEnvs {
+		if _, ok := moduleFetchEnvs[strings.SplitN(e, "=", 2)[0]]; ok {
+			ret = append(ret, e)
+		}
+	}
+	return ret
+}
+
+// SumDBDisabledEnvs returns envs with the given module path added to GONOSUMDB patterns (taken from envs or the
+// environment, with the same GOPRIVATE fallback go has), so go skips checksum database lookups for this module only.
+// Other modules, including dependencies of the tool, are still verified, as are sums already recorded in the .sum file.
+func SumDBDisabledEnvs(modulePath string, envs envars.EnvSlice) envars.EnvSlice {
+	patterns, ok := envs.Lookup("GONOSUMDB")
+	if !ok {
+		patterns, ok = os.LookupEnv("GONOSUMDB")
+	}
+	if !ok {
+		if patterns, ok = envs.Lookup("GOPRIVATE"); !ok {
+			patterns = os.Getenv("GOPRIVATE")
+		}
+	}
+	if patterns != "" {
+		patterns += ","
+	}
+	return envars.MergeEnvSlices(envs, "GONOSUMDB="+patterns+modulePath)
+}
+
+// IsSumDBDisabledInFile returns true if the given module file exists and has NoSumDBDirective. Unlike OpenModFile, it
+// does not lock the file. See ModFile.IsSumDBDisabled.
+func IsSumDBDisabledInFile(modFile string) (_ bool, err error) {
+	m, err := mod.OpenFileForRead(modFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer errcapture.Do(&err, m.Close, "close")
+
+	for _, c := range m.Comments() {
+		if strings.Contains(c, NoSumDBDirective) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // ModFile is a wrapper over module file with bingo specific data.
 type ModFile struct {
 	*mod.File
 
-	directPackage               *Package
+	directPackage *Package
+	// additionalPackages are packages from the same module as directPackage. Their Module field is not set.
+	additionalPackages          []Package
 	directivesAutoFetchDisabled bool
+	sumDBDisabled               bool
+	goVersionPinned             bool
+	vendored                    bool
+	comment                     string
+
+	// malformedErr is a validation error of build attributes as found on the disk during last reload.
+	malformedErr error
 }
 
 // OpenModFile opens bingo mod file.
 // It also adds meta if missing and trims all require direct module imports except first within the parsed syntax.
+// The file is locked against edits from other processes until Close (see mod.OpenFile).
 // It's a caller responsibility to Close the file when not using anymore.
 func OpenModFile(modFile string) (_ *ModFile, err error) {
 	f, err := mod.OpenFile(modFile)
@@ -101,7 +406,7 @@ func OpenModFile(modFile string) (_ *ModFile, err error) {
 
 	m, comment := f.Module()
 	if m == "" {
-		m = "_"
+		m = anonymousModule
 	}
 	if comment != metaComment {
 		if err := f.SetModule(m, metaComment); err != nil {
@@ -117,15 +422,100 @@ func (mf *ModFile) IsDirectivesAutoFetchDisabled() bool {
 	return mf.directivesAutoFetchDisabled
 }
 
+// IsSumDBDisabled returns true if the module file has NoSumDBDirective, so the tool module is fetched without checksum
+// database verification. See SumDBDisabledEnvs.
+func (mf *ModFile) IsSumDBDisabled() bool {
+	return mf.sumDBDisabled
+}
+
+// IsGoVersionPinned returns true if the module file has GoVersionPinnedDirective, so its go directive is kept when the
+// tool is updated. Update fails if the new version requires newer Go.
+func (mf *ModFile) IsGoVersionPinned() bool {
+	return mf.goVersionPinned
+}
+
+// SetGoVersionPinned adds or removes GoVersionPinnedDirective. See IsGoVersionPinned.
+func (mf *ModFile) SetGoVersionPinned(pinned bool) error {
+	if pinned == mf.goVersionPinned {
+		return nil
+	}
+	mf.goVersionPinned = pinned
+	if !pinned {
+		return mf.DropComments(GoVersionPinnedDirective)
+	}
+	return mf.AddComment(GoVersionPinnedDirective)
+}
+
+// IsVendored returns true if the module file has VendorDirective, so sources of modules needed to build the tool are
+// copied into VendorDir and replace directives point to them. See Vendor.
+func (mf *ModFile) IsVendored() bool {
+	return mf.vendored
+}
+
+// SetVendored adds or removes VendorDirective. Removing it drops replace directives pointing to VendorDir too, so the
+// tool is built from the module cache again. Sources in VendorDir are not removed, since other tools can use them.
+func (mf *ModFile) SetVendored(vendored bool) error {
+	if vendored == mf.vendored {
+		return nil
+	}
+	mf.vendored = vendored
+	if vendored {
+		return mf.AddComment(VendorDirective)
+	}
+	if err := mf.DropComments(VendorDirective); err != nil {
+		return err
+	}
+	return mf.dropVendorReplaces()
+}
+
 func (mf *ModFile) Reload() error {
 	if err := mf.File.Reload(); err != nil {
 		return err
 	}
+	// Validate before we rewrite the file in parsed (potentially lossy) form.
+	malformedErr := mf.validate()
 
+	mf.additionalPackages = mf.additionalPackages[:0]
+	mf.comment = ""
+	mf.sumDBDisabled = false
+	mf.goVersionPinned = false
+	mf.vendored = false
+	var (
+		postInstall string
+		latest      bool
+	)
 	for _, c := range mf.Comments() {
+		// Check comment and post-install first, so their free text can't be mistaken for other directives.
+		if strings.HasPrefix(c, CommentDirective) {
+			mf.comment = strings.TrimSpace(strings.TrimPrefix(c, CommentDirective))
+			continue
+		}
+		if strings.HasPrefix(c, PostInstallDirective) {
+			postInstall = strings.TrimSpace(strings.TrimPrefix(c, PostInstallDirective))
+			continue
+		}
 		if strings.Contains(c, NoDirectiveCommand) {
 			mf.directivesAutoFetchDisabled = true
-			break
+			continue
+		}
+		if strings.Contains(c, NoSumDBDirective) {
+			mf.sumDBDisabled = true
+			continue
+		}
+		if strings.Contains(c, GoVersionPinnedDirective) {
+			mf.goVersionPinned = true
+			continue
+		}
+		if strings.Contains(c, VendorDirective) {
+			mf.vendored = true
+			continue
+		}
+		if strings.Contains(c, LatestDirective) {
+			latest = true
+			continue
+		}
+		if strings.HasPrefix(c, AlsoDirective) {
+			mf.additionalPackages = append(mf.additionalPackages, parseDirectPackageMeta(strings.TrimSpace(strings.TrimPrefix(c, AlsoDirective))))
 		}
 	}
 
@@ -138,25 +528,114 @@ func (mf *ModFile) Reload() error {
 
 		directPackage = &Package{Module: r.Module}
 		if len(r.ExtraSuffixComment) > 0 {
-			directPackage.RelPath, directPackage.BuildEnvs, directPackage.BuildFlags = parseDirectPackageMeta(strings.Trim(r.ExtraSuffixComment, "\n"))
+			*directPackage = parseDirectPackageMeta(strings.Trim(r.ExtraSuffixComment, "\n"))
+			directPackage.Module = r.Module
 		}
+		directPackage.PostInstall = postInstall
+		directPackage.Latest = latest
 		break
 	}
 
-	if directPackage != nil {
-		return mf.SetDirectRequire(*directPackage)
+	if directPackage == nil {
+		mf.malformedErr = malformedErr
+		return nil
 	}
-	return nil
+	if malformedErr != nil {
+		// Don't rewrite malformed file, so it can be fixed manually.
+		mf.directPackage = directPackage
+		mf.malformedErr = malformedErr
+		return nil
+	}
+
+	targets := []Package{*directPackage}
+	for _, p := range mf.additionalPackages {
+		p.Module = directPackage.Module
+		targets = append(targets, p)
+	}
+	return mf.SetDirectPackages(targets...)
 }
 
 func SumFilePath(modFilePath string) string {
 	return strings.TrimSuffix(modFilePath, ".mod") + ".sum"
 }
 
+// ReplaceIfChanged renames src file to dst, unless dst has the same content already. In this case src is removed and
+// dst is not written at all, so its modification time is kept, e.g. no-op get does not cause VCS changes or trigger
+// rebuilds of targets depending on the module file. It returns true if dst was replaced.
+func ReplaceIfChanged(src, dst string) (bool, error) {
+	srcContent, err := os.ReadFile(src)
+	if err != nil {
+		return false, err
+	}
+	if dstContent, err := os.ReadFile(dst); err == nil && bytes.Equal(srcContent, dstContent) {
+		return false, os.Remove(src)
+	}
+	return true, os.Rename(src, dst)
+}
+
 // CreateFromExistingOrNew creates and opens new bingo enhanced module file.
 // If existing file exists and is not malformed it copies this as the source, otherwise completely new is created.
+// The new file is locked against edits from other processes before it's created and until Close, so concurrent bingo
+// processes creating the same file wait for each other (up to mod.LockTimeout).
 // It's a caller responsibility to Close the file when not using anymore.
-func CreateFromExistingOrNew(ctx context.Context, r *runner.Runner, logger *log.Logger, existingFile, modFile string) (*ModFile, error) {
+func CreateFromExistingOrNew(ctx context.Context, r *runner.Runner, logger logging.Logger, existingFile, modFile string) (*ModFile, error) {
+	return CreateFromExistingOrNewWithGoVersion(ctx, r, logger, existingFile, modFile, "")
+}
+
+// CreateFromExistingOrNewWithGoVersion is like CreateFromExistingOrNew, but sets go directive to the given language
+// version (e.g. "1.18" or "1.21.4") instead of the one `go mod init` of the runner's Go writes. It's useful for tools
+// that break on newer go.mod semantics. Empty goVersion keeps the directive of the existing or newly created file.
+func CreateFromExistingOrNewWithGoVersion(ctx context.Context, r *runner.Runner, logger logging.Logger, existingFile, modFile, goVersion string) (*ModFile, error) {
+	if goVersion != "" {
+		if !modfile.GoVersionRE.MatchString(goVersion) {
+			return nil, errors.Newf("invalid go version %q; expected language version like 1.18 or 1.21.4", goVersion)
+		}
+	}
+
+	mf, err := createFromExistingOrNew(ctx, r, logger, existingFile, modFile, anonymousModule)
+	if err != nil || goVersion == "" {
+		return mf, err
+	}
+	if err := mf.SetGoVersion(goVersion); err != nil {
+		errcapture.Do(&err, mf.Close, "close")
+		return nil, errors.Wrap(err, "set go version")
+	}
+	return mf, nil
+}
+
+// CreateFromExistingOrNewWithModule is like CreateFromExistingOrNew, but sets module path of the file to the given one
+// (e.g. "github.com/org/repo/.bingo/foo") instead of "_", for go tooling that refuses anonymous modules. Empty modulePath
+// keeps the module of the existing file or "_" for new one. OpenModFile reads files with any module path the same.
+func CreateFromExistingOrNewWithModule(ctx context.Context, r *runner.Runner, logger logging.Logger, existingFile, modFile, modulePath string) (*ModFile, error) {
+	if modulePath == "" {
+		return CreateFromExistingOrNew(ctx, r, logger, existingFile, modFile)
+	}
+	if strings.ContainsAny(modulePath, " \t\r\n\"'`") {
+		return nil, errors.Newf("invalid module path %q; expected path like github.com/org/repo/.bingo/tool", modulePath)
+	}
+
+	mf, err := createFromExistingOrNew(ctx, r, logger, existingFile, modFile, modulePath)
+	if err != nil {
+		return nil, err
+	}
+	if p, _ := mf.Module(); p != modulePath {
+		if err := mf.File.SetModule(modulePath, metaComment); err != nil {
+			errcapture.Do(&err, mf.Close, "close")
+			return nil, errors.Wrap(err, "set module")
+		}
+	}
+	return mf, nil
+}
+
+// createFromExistingOrNew creates module file from the existing one or, if there is none, new one with newModule path.
+func createFromExistingOrNew(ctx context.Context, r *runner.Runner, logger logging.Logger, existingFile, modFile, newModule string) (_ *ModFile, err error) {
+	// Lock before removal, so other process can't use the file while it's recreated. Opened file holds the lock further.
+	l, err := mod.Lock(modFile, mod.LockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer errcapture.Do(&err, l.Unlock, "unlock")
+
 	if err := os.RemoveAll(modFile); err != nil {
 		return nil, errors.Wrap(err, "rm")
 	}
@@ -183,103 +662,685 @@ func CreateFromExistingOrNew(ctx context.Context, r *runner.Runner, logger *log.
 				}
 				return OpenModFile(modFile)
 			}
-			logger.Printf("bingo tool module file %v is malformed; it will be recreated; err: %v\n", existingFile, err)
+			logger.Warnf("bingo tool module file %v is malformed; it will be recreated; err: %v\n", existingFile, err)
 		}
 	}
 
 	// Create from scratch.
-	if err := r.ModInit(ctx, filepath.Dir(existingFile), modFile, "_"); err != nil {
+	if err := r.ModInit(ctx, filepath.Dir(existingFile), modFile, newModule); err != nil {
 		return nil, errors.Wrap(err, "mod init")
 	}
 	return OpenModFile(modFile)
 }
 
-func parseDirectPackageMeta(line string) (relPath string, buildEnv []string, buildFlags []string) {
-	elem := strings.Split(line, " ")
+// splitMeta splits build attributes on spaces, except spaces within double quotes (e.g -ldflags="-X main.version=v1.0.0 -s"),
+// so attribute values can contain spaces. Quoted parts are unquoted as Go strings. Unterminated quote is returned as error
+// together with the best effort split, where quoted part spans until the end of the line.
+func splitMeta(line string) (ret []string, err error) {
+	var (
+		tok   strings.Builder
+		inTok bool
+	)
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			if inTok {
+				ret = append(ret, tok.String())
+				tok.Reset()
+				inTok = false
+			}
+		case '"':
+			inTok = true
+			end := i + 1
+			for ; end < len(line) && line[end] != '"'; end++ {
+				if line[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(line) {
+				err = errors.Newf("unterminated quote in %q", line[i:])
+				tok.WriteString(line[i+1:])
+				i = len(line)
+				continue
+			}
+			v, uerr := strconv.Unquote(line[i : end+1])
+			if uerr != nil {
+				v = line[i+1 : end]
+			}
+			tok.WriteString(v)
+			i = end
+		default:
+			inTok = true
+			tok.WriteByte(line[i])
+		}
+	}
+	if inTok {
+		ret = append(ret, tok.String())
+	}
+	return ret, err
+}
+
+// quoteMeta quotes value of the build env or flag if it contains spaces or quotes, so splitMeta parses it back as one attribute.
+func quoteMeta(attr string) string {
+	if !strings.ContainsAny(attr, ` "`) {
+		return attr
+	}
+	if i := strings.Index(attr, "="); i >= 0 && !strings.ContainsAny(attr[:i], ` "`) {
+		return attr[:i+1] + strconv.Quote(attr[i+1:])
+	}
+	return strconv.Quote(attr)
+}
+
+func quoteMetas(attrs []string) []string {
+	ret := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		ret = append(ret, quoteMeta(a))
+	}
+	return ret
+}
+
+// parseDirectPackageMeta parses build attributes into package without module set.
+func parseDirectPackageMeta(line string) (p Package) {
+	elem, _ := splitMeta(line)
 	for i, l := range elem {
 		if l == "" {
 			continue
 		}
 
 		if l[0] == '-' {
-			buildFlags = elem[i:]
+			p.BuildFlags = elem[i:]
 			break
 		}
 
+		if strings.HasPrefix(l, NameAttribute) {
+			p.Name = strings.TrimPrefix(l, NameAttribute)
+			continue
+		}
+		if strings.HasPrefix(l, EnvFileAttribute) {
+			p.EnvFile = strings.TrimPrefix(l, EnvFileAttribute)
+			continue
+		}
+		if strings.HasPrefix(l, WorkDirAttribute) {
+			p.WorkDir = strings.TrimPrefix(l, WorkDirAttribute)
+			continue
+		}
+		if strings.HasPrefix(l, BranchAttribute) {
+			p.Branch = strings.TrimPrefix(l, BranchAttribute)
+			continue
+		}
+		if strings.HasPrefix(l, TagAttribute) {
+			p.Tag = strings.TrimPrefix(l, TagAttribute)
+			continue
+		}
+		if strings.HasPrefix(l, VerifyCmdAttribute) {
+			p.VerifyCmd = strings.TrimPrefix(l, VerifyCmdAttribute)
+			continue
+		}
+		if strings.HasPrefix(l, VerifyOutputAttribute) {
+			p.VerifyOutput = strings.TrimPrefix(l, VerifyOutputAttribute)
+			continue
+		}
+
 		if !strings.Contains(l, "=") {
-			relPath = l
+			p.RelPath = l
 			continue
 		}
-		buildEnv = append(buildEnv, l)
+		p.BuildEnvs = append(p.BuildEnvs, l)
 	}
-	return relPath, buildEnv, buildFlags
+	return p
 }
 
-func (mf *ModFile) DirectPackage() *Package {
-	return mf.directPackage
+// Validate re-parses build attributes of all direct packages and returns error describing the first malformed token, if any.
+// Build attributes are expected in "[relative path] [name=binary name] [env-file=file] [workdir=dir] [branch=branch] [tag=tag] [verify-cmd=command] [verify-output=text] [ENV=value ...] [-flag ...]" form.
+// Attributes are checked as they were on the disk during last Reload, unless direct require was set since then.
+func (mf *ModFile) Validate() error {
+	if mf.malformedErr != nil {
+		return mf.malformedErr
+	}
+	return mf.validate()
 }
 
-// SetDirectRequire removes all require statements and set to the given one. It supports package level versioning.
-func (mf *ModFile) SetDirectRequire(target Package) (err error) {
-	r := mod.RequireDirective{Module: target.Module}
-
-	var meta []string
+func (mf *ModFile) validate() error {
+	return validateModFile(mf.File)
+}
 
-	// Add sub package info if needed.
-	if target.RelPath != "" && target.RelPath != "." {
-		meta = append(meta, target.RelPath)
+// validateModFile is like ModFile.Validate, but works on module file opened for read, so it can be used without
+// rewriting the file (see CheckModFiles).
+func validateModFile(f mod.FileForRead) error {
+	for _, r := range f.RequireDirectives() {
+		if r.Indirect {
+			continue
+		}
+		if err := validateDirectPackageMeta(strings.Trim(r.ExtraSuffixComment, "\n")); err != nil {
+			return errors.Wrapf(err, "%s:%d: require %s", f.Filepath(), r.Line, r.Module.String())
+		}
+		break
 	}
-	meta = append(meta, target.BuildEnvs...)
-	meta = append(meta, target.BuildFlags...)
-
-	if len(meta) > 0 {
-		r.ExtraSuffixComment = strings.Join(meta, " ")
+	for _, c := range f.Comments() {
+		if strings.HasPrefix(c, PostInstallDirective) {
+			if err := ValidatePostInstall(strings.TrimSpace(strings.TrimPrefix(c, PostInstallDirective))); err != nil {
+				return errors.Wrapf(err, "%s: comment %q", f.Filepath(), c)
+			}
+			continue
+		}
+		if !strings.HasPrefix(c, AlsoDirective) {
+			continue
+		}
+		if err := validateDirectPackageMeta(strings.TrimSpace(strings.TrimPrefix(c, AlsoDirective))); err != nil {
+			return errors.Wrapf(err, "%s: comment %q", f.Filepath(), c)
+		}
 	}
-	mf.directPackage = &target
-	return mf.SetRequireDirectives(r)
+	return nil
 }
 
-// ModDirectPackage return the first direct package from bingo enhanced module file. The package suffix (if any) is
-// encoded in the line comment, in the same line as module and version.
-func ModDirectPackage(modFile string) (pkg Package, err error) {
-	mf, err := OpenModFile(modFile)
-	if err != nil {
-		return Package{}, err
-	}
-	defer errcapture.Do(&err, mf.Close, "close")
-
-	if mf.directPackage == nil {
-		return Package{}, errors.Newf("no direct package found in %s; empty module?", mf.Filepath())
+// ParseBuildComment parses build comment of the require directive in bingo module file (e.g. "cmd/foo CGO_ENABLED=1
+// -tags=x") into relative package path, build environment variables and build flags, with the same splitting (including
+// quoted values) and validation rules as bingo uses for module files. Other build attributes (e.g. name=) are validated,
+// but not returned. Empty relative path means the package is the module itself.
+func ParseBuildComment(comment string) (relPath string, envs, flags []string, err error) {
+	comment = strings.TrimSpace(comment)
+	if err := validateDirectPackageMeta(comment); err != nil {
+		return "", nil, nil, err
 	}
-	return *mf.directPackage, nil
+	p := parseDirectPackageMeta(comment)
+	return p.RelPath, p.BuildEnvs, p.BuildFlags, nil
 }
 
-// ModIndirectModules return the all indirect mod from any module file.
-func ModIndirectModules(modFile string) (mods []module.Version, err error) {
-	m, err := mod.OpenFile(modFile)
+func validateDirectPackageMeta(line string) error {
+	elem, err := splitMeta(line)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	for _, r := range m.RequireDirectives() {
-		if !r.Indirect {
+	var relPath string
+	flags, verifyCmd, verifyOut := false, false, false
+	for _, l := range elem {
+		if l == "" {
 			continue
 		}
 
-		mods = append(mods, r.Module)
+		if flags || l[0] == '-' {
+			flags = true
+			if l[0] != '-' {
+				return errors.Newf("build flag %q has to start with '-'; flags have to be last and values joined with '=' (e.g -tags=yolo)", l)
+			}
+			if err := validateBuildFlag(l); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if strings.HasPrefix(l, NameAttribute) {
+			if err := ValidateBinaryName(strings.TrimPrefix(l, NameAttribute)); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(l, EnvFileAttribute) {
+			if err := validateEnvFilePath(strings.TrimPrefix(l, EnvFileAttribute)); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(l, WorkDirAttribute) {
+			if err := validateWorkDir(strings.TrimPrefix(l, WorkDirAttribute)); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(l, BranchAttribute) {
+			if err := ValidateBranchName(strings.TrimPrefix(l, BranchAttribute)); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(l, TagAttribute) {
+			if err := ValidateTagName(strings.TrimPrefix(l, TagAttribute)); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(l, VerifyCmdAttribute) {
+			if err := validateVerifyCmd(strings.TrimPrefix(l, VerifyCmdAttribute)); err != nil {
+				return err
+			}
+			verifyCmd = true
+			continue
+		}
+		if strings.HasPrefix(l, VerifyOutputAttribute) {
+			if _, err := verifyOutput(strings.TrimPrefix(l, VerifyOutputAttribute), postInstallData{}); err != nil {
+				return err
+			}
+			verifyOut = true
+			continue
+		}
+
+		if strings.Contains(l, "=") {
+			if err := validateBuildEnv(l); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if relPath != "" {
+			return errors.Newf("unexpected token %q; relative package path %q already specified", l, relPath)
+		}
+		relPath = l
+		if path.IsAbs(relPath) || path.Clean(relPath) != relPath || relPath == ".." || strings.HasPrefix(relPath, "../") {
+			return errors.Newf("relative package path %q has to be clean relative path within module", relPath)
+		}
 	}
-	return mods, nil
+	if verifyOut && !verifyCmd {
+		return errors.Newf("%v attribute requires %v attribute", strings.TrimSuffix(VerifyOutputAttribute, "="), strings.TrimSuffix(VerifyCmdAttribute, "="))
+	}
+	return nil
 }
 
-// PackageVersionRenderable is used in variables.go. Modify with care.
-type PackageVersionRenderable struct {
-	Version string
+// Comment returns human readable description of the tool recorded with CommentDirective or empty string if none.
+func (mf *ModFile) Comment() string {
+	return mf.comment
+}
+
+// SetComment records human readable description of the tool as CommentDirective. Empty comment removes it.
+func (mf *ModFile) SetComment(comment string) error {
+	comment = strings.TrimSpace(comment)
+	if strings.ContainsAny(comment, "\r\n") {
+		return errors.Newf("comment has to be a single line, got %q", comment)
+	}
+	mf.comment = comment
+	if mf.directPackage != nil && mf.malformedErr == nil {
+		// Rewrite all, so directives keep the same order as after reload.
+		return mf.SetDirectPackages(mf.DirectPackages()...)
+	}
+	return mf.writeComment()
+}
+
+// ParsedGoVersion returns the go directive of the module file (see GoVersion) parsed with version.Parse, e.g. to check with
+// version.AtLeast if the local toolchain is new enough to build the tool. It returns nil version if there is no go directive.
+func (mf *ModFile) ParsedGoVersion() (*semver.Version, error) {
+	v := mf.GoVersion()
+	if v == "" {
+		return nil, nil
+	}
+	return version.Parse(v)
+}
+
+func (mf *ModFile) writeComment() error {
+	return mf.writeDirective(CommentDirective, mf.comment)
+}
+
+// writeDirective replaces comment directive lines with the given one or drops them if value is empty.
+func (mf *ModFile) writeDirective(directive, value string) error {
+	if value == "" && !mf.hasDirective(directive) {
+		// Nothing to do, don't reformat the file.
+		return nil
+	}
+	if err := mf.DropComments(directive); err != nil {
+		return err
+	}
+	if value == "" {
+		return nil
+	}
+	return mf.AddComment(directive + " " + value)
+}
+
+func (mf *ModFile) writeLatest(latest bool) error {
+	if !mf.hasDirective(LatestDirective) && !latest {
+		return nil
+	}
+	if err := mf.DropComments(LatestDirective); err != nil {
+		return err
+	}
+	if !latest {
+		return nil
+	}
+	return mf.AddComment(LatestDirective)
+}
+
+func (mf *ModFile) hasDirective(directive string) bool {
+	for _, c := range mf.Comments() {
+		if strings.HasPrefix(c, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalReplaces returns replace directives pointing to local directories, e.g. set with SetLocalReplace. Replaces pointing
+// to vendored sources are not local ones (see ModFile.IsVendored).
+func (mf *ModFile) LocalReplaces() (ret []mod.ReplaceDirective) {
+	for _, r := range mf.ReplaceDirectives() {
+		// Replacement without version is always a local directory.
+		if r.New.Version == "" && !isVendorReplace(r) {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// IsLocallyReplaced returns true if any version of the given module is replaced with local directory.
+func (mf *ModFile) IsLocallyReplaced(modulePath string) bool {
+	for _, r := range mf.LocalReplaces() {
+		if r.Old.Path == modulePath && r.Old.Version == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLocalReplace replaces all versions of the given module with the local directory, keeping other replace directives.
+// Relative directory is resolved by go from the module directory, so it's written with "./" or "../" prefix.
+func (mf *ModFile) SetLocalReplace(path, dir string) error {
+	if err := module.CheckImportPath(path); err != nil {
+		return errors.Wrapf(err, "replaced module path %q", path)
+	}
+	if dir == "" {
+		return errors.Newf("local directory for replaced module %v is required", path)
+	}
+	dir = filepath.ToSlash(dir)
+	if !filepath.IsAbs(dir) && dir != "." && dir != ".." && !strings.HasPrefix(dir, "./") && !strings.HasPrefix(dir, "../") {
+		dir = "./" + dir
+	}
+
+	replaces := []mod.ReplaceDirective{}
+	for _, r := range mf.ReplaceDirectives() {
+		if r.Old.Path == path {
+			continue
+		}
+		replaces = append(replaces, r)
+	}
+	replaces = append(replaces, mod.ReplaceDirective{Old: module.Version{Path: path}, New: module.Version{Path: dir}})
+	return mf.SetReplaceDirectives(replaces...)
+}
+
+// Rename renames the tool pinned in this module file to the new name, so <name>[.<n>].mod becomes <newName>[.<n>].mod.
+// Sum file is moved too. Name attribute of the direct package is dropped, so the binary follows the new tool name.
+// It fails if the new module file already exists. Binaries and meta files are not touched, so the caller is expected
+// to reinstall the tool.
+func (mf *ModFile) Rename(newName string) error {
+	if err := ValidateBinaryName(newName); err != nil {
+		return err
+	}
+	if mf.malformedErr != nil {
+		return errors.Wrap(mf.malformedErr, "cannot rename malformed module file; fix it manually first")
+	}
+	if mf.directPackage == nil {
+		return errors.Newf("no direct package found in %s; empty module?", mf.Filepath())
+	}
+
+	name, _ := NameFromModFile(mf.Filepath())
+	if name == newName {
+		return errors.Newf("tool is already named %v", newName)
+	}
+	oldPath := mf.Filepath()
+	newPath := filepath.Join(filepath.Dir(oldPath), newName+strings.TrimPrefix(filepath.Base(oldPath), name))
+
+	oldSumPath, newSumPath := SumFilePath(oldPath), SumFilePath(newPath)
+	if _, err := os.Lstat(newSumPath); err == nil {
+		return errors.Newf("%v already exists", newSumPath)
+	}
+	if err := mf.File.Rename(newPath); err != nil {
+		return err
+	}
+	if err := os.Rename(oldSumPath, newSumPath); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "rename sum file")
+	}
+
+	pkgs := mf.DirectPackages()
+	pkgs[0].Name = ""
+	return mf.SetDirectPackages(pkgs...)
+}
+
+func (mf *ModFile) DirectPackage() *Package {
+	return mf.directPackage
+}
+
+// EnvFile returns env file path (relative to the module directory) of the direct package or empty string if there is none.
+func (mf *ModFile) EnvFile() string {
+	if mf.directPackage == nil {
+		return ""
+	}
+	return mf.directPackage.EnvFile
+}
+
+// DirectPackages returns all packages built from the direct module, starting with DirectPackage.
+// Additional packages are declared with AlsoDirective comments and share module path and version with the direct package.
+func (mf *ModFile) DirectPackages() []Package {
+	if mf.directPackage == nil {
+		return nil
+	}
+	ret := []Package{*mf.directPackage}
+	for _, p := range mf.additionalPackages {
+		p.Module = mf.directPackage.Module
+		p.PostInstall = mf.directPackage.PostInstall
+		p.Latest = mf.directPackage.Latest
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+// SetDirectPackages sets direct require to the first given package and records the remaining ones
+// as AlsoDirective comments. All packages have to share the same module.
+func (mf *ModFile) SetDirectPackages(targets ...Package) error {
+	if len(targets) == 0 {
+		return errors.New("at least one package is required")
+	}
+
+	relPaths := map[string]struct{}{targets[0].RelPath: {}}
+	for _, t := range targets[1:] {
+		if t.Module != targets[0].Module {
+			return errors.Newf("all packages have to be from the same module %v, got %v", targets[0].Module.String(), t.Module.String())
+		}
+		if t.RelPath == "" || t.RelPath == "." {
+			return errors.Newf("additional package from module %v has to have relative path", t.Module.String())
+		}
+		if _, ok := relPaths[t.RelPath]; ok {
+			return errors.Newf("package %v specified more than once", t.Path())
+		}
+		relPaths[t.RelPath] = struct{}{}
+	}
+
+	if err := mf.DropComments(AlsoDirective); err != nil {
+		return err
+	}
+	mf.additionalPackages = mf.additionalPackages[:0]
+	for _, t := range targets[1:] {
+		if err := mf.AddComment(AlsoDirective + " " + strings.Join(directPackageMeta(t), " ")); err != nil {
+			return err
+		}
+		mf.additionalPackages = append(mf.additionalPackages, Package{
+			RelPath: t.RelPath, Name: t.Name, EnvFile: t.EnvFile, WorkDir: t.WorkDir, VerifyCmd: t.VerifyCmd, VerifyOutput: t.VerifyOutput,
+			BuildEnvs: t.BuildEnvs, BuildFlags: t.BuildFlags,
+		})
+	}
+	return mf.SetDirectRequire(targets[0])
+}
+
+func directPackageMeta(target Package) (meta []string) {
+	// Add sub package info if needed.
+	if target.RelPath != "" && target.RelPath != "." {
+		meta = append(meta, target.RelPath)
+	}
+	if target.Name != "" {
+		meta = append(meta, NameAttribute+target.Name)
+	}
+	if target.EnvFile != "" {
+		meta = append(meta, EnvFileAttribute+target.EnvFile)
+	}
+	if target.WorkDir != "" {
+		meta = append(meta, WorkDirAttribute+target.WorkDir)
+	}
+	if target.Branch != "" {
+		meta = append(meta, BranchAttribute+target.Branch)
+	}
+	if target.Tag != "" {
+		meta = append(meta, TagAttribute+target.Tag)
+	}
+	if target.VerifyCmd != "" {
+		meta = append(meta, quoteMeta(VerifyCmdAttribute+target.VerifyCmd))
+	}
+	if target.VerifyOutput != "" {
+		meta = append(meta, quoteMeta(VerifyOutputAttribute+target.VerifyOutput))
+	}
+	meta = append(meta, quoteMetas(target.BuildEnvs)...)
+	meta = append(meta, quoteMetas(target.BuildFlags)...)
+	return meta
+}
+
+// SetDirectRequire removes all require statements and set to the given one. It supports package level versioning.
+// Additional packages (if any) are kept and follow the module version of the given package.
+func (mf *ModFile) SetDirectRequire(target Package) (err error) {
+	if target.PostInstall != "" {
+		if err := ValidatePostInstall(target.PostInstall); err != nil {
+			return err
+		}
+	}
+	r := mod.RequireDirective{Module: target.Module}
+
+	if meta := directPackageMeta(target); len(meta) > 0 {
+		r.ExtraSuffixComment = strings.Join(meta, " ")
+	}
+	mf.directPackage = &target
+	mf.malformedErr = nil
+	if err := mf.SetRequireDirectives(r); err != nil {
+		return err
+	}
+	// Rewritten require lands at the end, keep comment and directives after it, the same as go get leaves it.
+	if err := mf.writeComment(); err != nil {
+		return err
+	}
+	if err := mf.writeDirective(PostInstallDirective, target.PostInstall); err != nil {
+		return err
+	}
+	return mf.writeLatest(target.Latest)
+}
+
+// SetModule changes module path of the tool, e.g. when the module moved to other repository, to the given one with the
+// given version, keeping relative paths and build attributes of all direct packages. Empty version keeps the current one.
+// NOTE: It shadows mod.File.SetModule, which sets the module directive of the module file itself.
+func (mf *ModFile) SetModule(newPath string, version string) error {
+	if mf.directPackage == nil {
+		return errors.Newf("no direct package found in %s; set direct require first", mf.Filepath())
+	}
+	if err := module.CheckPath(newPath); err != nil {
+		return errors.Wrapf(err, "module path %q", newPath)
+	}
+	if version == "" {
+		version = mf.directPackage.Module.Version
+	}
+	pkgs := mf.DirectPackages()
+	for i := range pkgs {
+		pkgs[i].Module = module.Version{Path: newPath, Version: version}
+	}
+	return mf.SetDirectPackages(pkgs...)
+}
+
+// SetBuildFlags sets build flags of the current direct package, keeping its module, relative path and build envs.
+func (mf *ModFile) SetBuildFlags(flags []string) error {
+	if mf.directPackage == nil {
+		return errors.Newf("no direct package found in %s; set direct require first", mf.Filepath())
+	}
+	for _, f := range flags {
+		if !strings.HasPrefix(f, "-") {
+			return errors.Newf("build flag %q has to start with '-'", f)
+		}
+		if err := validateBuildFlag(f); err != nil {
+			return err
+		}
+	}
+	target := *mf.directPackage
+	target.BuildFlags = flags
+	return mf.SetDirectRequire(target)
+}
+
+// SetBuildEnvs sets build environment variables of the current direct package, keeping its module, relative path and build flags.
+func (mf *ModFile) SetBuildEnvs(envs []string) error {
+	if mf.directPackage == nil {
+		return errors.Newf("no direct package found in %s; set direct require first", mf.Filepath())
+	}
+	for _, e := range envs {
+		if err := validateBuildEnv(e); err != nil {
+			return err
+		}
+	}
+	target := *mf.directPackage
+	target.BuildEnvs = envs
+	return mf.SetDirectRequire(target)
+}
+
+// ModDirectPackage return the first direct package from bingo enhanced module file. The package suffix (if any) is
+// encoded in the line comment, in the same line as module and version.
+func ModDirectPackage(modFile string) (pkg Package, err error) {
+	pkg, _, err = modDirectPackageAndComment(modFile)
+	return pkg, err
+}
+
+func modDirectPackageAndComment(modFile string) (pkg Package, comment string, err error) {
+	pkgs, comment, err := modDirectPackagesAndComment(modFile)
+	if err != nil {
+		return Package{}, "", err
+	}
+	return pkgs[0], comment, nil
+}
+
+// modDirectPackagesAndComment returns all direct packages (see ModFile.DirectPackages) and the comment of the module file.
+func modDirectPackagesAndComment(modFile string) (pkgs []Package, comment string, err error) {
+	mf, err := OpenModFile(modFile)
+	if err != nil {
+		return nil, "", err
+	}
+	defer errcapture.Do(&err, mf.Close, "close")
+
+	if mf.directPackage == nil {
+		return nil, "", errors.Newf("no direct package found in %s; empty module?", mf.Filepath())
+	}
+	return mf.DirectPackages(), mf.Comment(), nil
+}
+
+// ModIndirectModules return the all indirect mod from any module file.
+func ModIndirectModules(modFile string) (mods []module.Version, err error) {
+	m, err := mod.OpenFileForRead(modFile)
+	if err != nil {
+		return nil, err
+	}
+	defer errcapture.Do(&err, m.Close, "close")
+
+	for _, r := range m.RequireDirectives() {
+		if !r.Indirect {
+			continue
+		}
+
+		mods = append(mods, r.Module)
+	}
+	return mods, nil
+}
+
+// ModReplaceDirectives returns replace directives of the module file, e.g. fetched from the tool's own go.mod (unless
+// NoDirectiveCommand is set) or set with ModFile.SetLocalReplace.
+func ModReplaceDirectives(modFile string) (_ []mod.ReplaceDirective, err error) {
+	m, err := mod.OpenFileForRead(modFile)
+	if err != nil {
+		return nil, err
+	}
+	defer errcapture.Do(&err, m.Close, "close")
+
+	return m.ReplaceDirectives(), nil
+}
+
+// PackageVersionRenderable is used in variables.go. Modify with care.
+type PackageVersionRenderable struct {
+	Version string
 	ModFile string
 }
 
 // PackageRenderable is used in variables.go. Modify with care.
 type PackageRenderable struct {
-	Name        string
+	Name string
+	// BinaryName is a name of the binary, different from Name if set explicitly with NameAttribute or for Additional package.
+	BinaryName string
+	// Additional is true for package declared with AlsoDirective. It's built by bingo get of the tool (Name), together
+	// with the direct package.
+	Additional  bool
 	ModPath     string
 	PackagePath string
 	EnvVarName  string
@@ -287,6 +1348,134 @@ type PackageRenderable struct {
 
 	BuildFlags   []string
 	BuildEnvVars []string
+	// EnvFileBuildFlags and EnvFileBuildEnvVars are build flags and envs from the env file (see Package.WithEnvFile), that
+	// are not overridden in the module file.
+	EnvFileBuildFlags   []string
+	EnvFileBuildEnvVars []string
+	// WorkDir is a directory (relative to the module directory) the package is built from. See Package.WorkDir.
+	WorkDir string
+
+	// Comment is a human readable description of the tool. See CommentDirective.
+	Comment string
+}
+
+// QuotedBuildFlags returns build flags with values containing spaces quoted, as in the module file, e.g for shell commands.
+func (p PackageRenderable) QuotedBuildFlags() []string {
+	return quoteMetas(p.BuildFlags)
+}
+
+// MakefileBuildFlags returns quoted build flags the package is built with, including ones from the env file, but without
+// -ldflags with placeholders, as only bingo get can fill them.
+func (p PackageRenderable) MakefileBuildFlags() []string {
+	flags := make([]string, 0, len(p.EnvFileBuildFlags)+len(p.BuildFlags))
+	for _, f := range append(append([]string{}, p.EnvFileBuildFlags...), p.BuildFlags...) {
+		if _, ok := ldflagsTemplate(f); !ok {
+			flags = append(flags, f)
+		}
+	}
+	return quoteMetas(flags)
+}
+
+// makefileReproducibleFlags are make variables of Variables.mk with build flags added by runner.WithReproducible, by flag name.
+var makefileReproducibleFlags = []struct{ name, variable string }{
+	{name: "trimpath", variable: "$(BINGO_TRIMPATH)"},
+	{name: "buildvcs", variable: "$(BINGO_BUILDVCS)"},
+}
+
+// MakefileReproducibleFlags returns make variables with reproducible build flags the package is built with, if enabled,
+// as bingo get does with runner.WithReproducible: flags the package sets on its own (e.g. -trimpath=false) are skipped.
+func (p PackageRenderable) MakefileReproducibleFlags(enabled bool) []string {
+	if !enabled {
+		return nil
+	}
+	given := map[string]struct{}{}
+	for _, f := range append(append([]string{}, p.EnvFileBuildFlags...), p.BuildFlags...) {
+		name, _, _ := cut(strings.TrimLeft(f, "-"), "=")
+		given[name] = struct{}{}
+	}
+	var vars []string
+	for _, f := range makefileReproducibleFlags {
+		if _, ok := given[f.name]; !ok {
+			vars = append(vars, f.variable)
+		}
+	}
+	return vars
+}
+
+// MakefileBuildEnvVars returns quoted build envs the package is built with, including ones from the env file, escaped
+// for Variables.mk recipes. See makefileEnvVar.
+func (p PackageRenderable) MakefileBuildEnvVars() []string {
+	envs := p.BuildEnvVars
+	if len(p.EnvFileBuildEnvVars) > 0 {
+		envs = envars.MergeEnvSlices(p.EnvFileBuildEnvVars, p.BuildEnvVars...)
+	}
+	ret := make([]string, 0, len(envs))
+	for _, e := range envs {
+		ret = append(ret, makefileEnvVar(e))
+	}
+	return ret
+}
+
+// makefileEnvVar returns quoted env for make recipe. Make would expand $VAR and ${VAR} references on its own (e.g. $ARCH
+// as $(A)RCH), so they are escaped for the shell to expand them from the environment at build time, as bingo get does.
+// Escaped $$ stays literal $ for the shell too, e.g. CGO_LDFLAGS=-Wl,-rpath,$$ORIGIN is written as
+// CGO_LDFLAGS=-Wl,-rpath,\$$ORIGIN.
+func makefileEnvVar(env string) string {
+	return os.Expand(quoteMeta(env), func(name string) string {
+		if name == "$" {
+			return `\$$`
+		}
+		return "$${" + name + "}"
+	})
+}
+
+// HasLdflagsTemplate returns true if -ldflags build flag has placeholders. See MakefileBuildFlags.
+func (p PackageRenderable) HasLdflagsTemplate() bool {
+	for _, f := range append(append([]string{}, p.EnvFileBuildFlags...), p.BuildFlags...) {
+		if _, ok := ldflagsTemplate(f); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// QuotedBuildEnvVars returns build envs with values containing spaces quoted, as in the module file, e.g for shell commands.
+func (p PackageRenderable) QuotedBuildEnvVars() []string {
+	return quoteMetas(p.BuildEnvVars)
+}
+
+// PlatformSuffix returns binary name suffix for cross compiled tool. See Package.PlatformSuffix.
+func (p PackageRenderable) PlatformSuffix() string {
+	return Package{BuildEnvs: p.BuildEnvVars}.PlatformSuffix()
+}
+
+// ExeSuffix returns binary file extension of the tool. See Package.ExeSuffix.
+func (p PackageRenderable) ExeSuffix() string {
+	return Package{BuildEnvs: p.BuildEnvVars}.ExeSuffix()
+}
+
+// BinaryFile returns file name of the tool binary in the given version, as installed in GOBIN.
+func (p PackageRenderable) BinaryFile(version string) string {
+	return p.BinaryName + "-" + version + p.PlatformSuffix() + p.ExeSuffix()
+}
+
+// TargetPlatform returns "<GOOS>/<GOARCH>" the tool is built for.
+func (p PackageRenderable) TargetPlatform() string {
+	pkg := Package{BuildEnvs: p.BuildEnvVars}
+	return pkg.TargetGOOS() + "/" + pkg.TargetGOARCH()
+}
+
+// SideBySideOf returns name of the tool this one is side by side variant of (see SideBySideName), or empty if it's not.
+func (p PackageRenderable) SideBySideOf() string {
+	if len(p.Versions) == 0 {
+		return ""
+	}
+	return SideBySideOf(p.Name, p.Versions[0].Version)
+}
+
+// matches returns true if the tool is the target or its side by side variant, or target is empty.
+func (p PackageRenderable) matches(target string) bool {
+	return target == "" || p.Name == target || p.SideBySideOf() == target
 }
 
 func (p PackageRenderable) ToPackages() []Package {
@@ -313,47 +1502,380 @@ func (pkgs PackageRenderables) PrintTab(target string, w io.Writer) error {
 	defer func() { _ = tw.Flush() }()
 
 	_, _ = fmt.Fprint(tw, PackageRenderablesPrintHeader)
+	found := false
 	for _, p := range pkgs {
-		if target != "" && p.Name != target {
+		if !p.matches(target) {
 			continue
 		}
+		found = true
 		for _, v := range p.Versions {
 			fields := []string{
 				p.Name,
-				p.Name + "-" + v.Version,
+				p.BinaryFile(v.Version),
 				p.PackagePath + "@" + v.Version,
-				strings.Join(p.BuildEnvVars, " "),
-				strings.Join(p.BuildFlags, " "),
+				strings.Join(p.QuotedBuildEnvVars(), " "),
+				strings.Join(p.QuotedBuildFlags(), " "),
+				p.Comment,
 			}
 			_, _ = fmt.Fprintln(tw, strings.Join(fields, "\t"))
 		}
-		if target != "" {
-			return nil
+	}
+
+	if target != "" && !found {
+		return errors.Newf("Pinned tool %s not found", target)
+	}
+	return nil
+}
+
+// PrintReplacesTab prints replace directives of module files of all or only target's versions in modDir as table, one
+// directive per row, in the same form as in the module file (e.g. "github.com/x/dep v1.0.0 => github.com/y/dep v1.1.0").
+func (pkgs PackageRenderables) PrintReplacesTab(modDir, target string, w io.Writer) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 1, 8, 1, '\t', tabwriter.AlignRight)
+	defer func() { _ = tw.Flush() }()
+
+	_, _ = fmt.Fprint(tw, "Name\tMod File\tReplace\n")
+	_, _ = fmt.Fprint(tw, "----\t--------\t-------\n")
+	found := false
+	for _, p := range pkgs {
+		if !p.matches(target) {
+			continue
+		}
+		found = true
+		for _, v := range p.Versions {
+			replaces, err := ModReplaceDirectives(filepath.Join(modDir, v.ModFile))
+			if err != nil {
+				return errors.Wrapf(err, "read %v", v.ModFile)
+			}
+			for _, r := range replaces {
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s => %s\n", p.Name, v.ModFile, replaceModuleString(r.Old), replaceModuleString(r.New))
+			}
 		}
 	}
 
-	if target != "" {
+	if target != "" && !found {
 		return errors.Newf("Pinned tool %s not found", target)
 	}
 	return nil
 }
 
+func replaceModuleString(m module.Version) string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + " " + m.Version
+}
+
+// IsNamePattern returns true if target is a tool name pattern (see path.Match), e.g. "proto*", and not a tool name or package path.
+func IsNamePattern(target string) bool {
+	return !strings.Contains(target, "/") && strings.ContainsAny(target, "*?[")
+}
+
+// FilterByName returns pinned tools with name matching the given pattern (see path.Match). It returns error listing
+// available tool names if none matches.
+func (pkgs PackageRenderables) FilterByName(pattern string) (PackageRenderables, error) {
+	var (
+		ret   PackageRenderables
+		names []string
+	)
+	for _, p := range pkgs {
+		ok, err := path.Match(pattern, p.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "pattern %q", pattern)
+		}
+		if ok {
+			ret = append(ret, p)
+		}
+		names = append(names, p.Name)
+	}
+	if len(ret) == 0 {
+		if len(names) == 0 {
+			return nil, errors.Newf("no pinned tool matches %q; no tools are pinned", pattern)
+		}
+		sort.Strings(names)
+		return nil, errors.Newf("no pinned tool matches %q; available tools: %v", pattern, strings.Join(names, ", "))
+	}
+	return ret, nil
+}
+
+// FilterByInstalled returns pinned tools with only versions that are (if installed is true) or are not (otherwise)
+// installed in gobin. Tools without such versions are omitted.
+func (pkgs PackageRenderables) FilterByInstalled(gobin string, installed bool) (PackageRenderables, error) {
+	var ret PackageRenderables
+	for _, p := range pkgs {
+		var versions []PackageVersionRenderable
+		for _, v := range p.Versions {
+			ok, err := isInstalled(filepath.Join(gobin, p.BinaryFile(v.Version)))
+			if err != nil {
+				return nil, err
+			}
+			if ok == installed {
+				versions = append(versions, v)
+			}
+		}
+		if len(versions) > 0 {
+			p.Versions = versions
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+func isInstalled(binPath string) (bool, error) {
+	if _, err := os.Stat(binPath); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListEntry represents single pinned binary in version as printed by `bingo list -o json`. This schema is stable.
+type ListEntry struct {
+	// Name is a tool name, as used in `bingo get <name>`.
+	Name       string `json:"name"`
+	ModulePath string `json:"module_path"`
+	Version    string `json:"version"`
+	// RelPath is a package path relative to the module path. Empty if the module path is a package path.
+	RelPath    string   `json:"rel_path"`
+	BuildFlags []string `json:"build_flags"`
+	BuildEnvs  []string `json:"build_envs"`
+	// BinaryPath is an absolute path to the versioned binary.
+	BinaryPath string `json:"binary_path"`
+	// Installed is true if the binary exists at BinaryPath.
+	Installed bool `json:"installed"`
+	// ModFile is a module file name within the bingo module directory.
+	ModFile string `json:"mod_file"`
+	// Platform is "<GOOS>/<GOARCH>" the binary is built for.
+	Platform string `json:"platform"`
+	// Comment is a human readable description of the tool. Empty if not set.
+	Comment string `json:"comment"`
+	// Deps are modules the installed binary was built with, as read by SetDependencies. Omitted if not read or
+	// the binary is not installed.
+	Deps []ListDependency `json:"deps,omitempty"`
+}
+
+// ListDependency represents module the binary was built with, as printed by `bingo list --deps -o json`. This schema is stable.
+type ListDependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Replace is a module the dependency was replaced with during build. Its version is empty for local directories.
+	Replace *ListDependency `json:"replace,omitempty"`
+}
+
+// SetDependencies reads modules each installed binary of the given entries was built with (see runner.Dependencies)
+// and sets them as entry Deps. It runs go for every binary, so it might be slow.
+func SetDependencies(ctx context.Context, r *runner.Runner, entries []ListEntry) error {
+	for i, e := range entries {
+		if !e.Installed {
+			continue
+		}
+		deps, err := r.Dependencies(ctx, e.BinaryPath)
+		if err != nil {
+			return errors.Wrapf(err, "read dependencies of %v", e.BinaryPath)
+		}
+		entries[i].Deps = make([]ListDependency, 0, len(deps))
+		for _, d := range deps {
+			dep := ListDependency{Path: d.Path, Version: d.Version.Version}
+			if d.Replace != nil {
+				dep.Replace = &ListDependency{Path: d.Replace.Path, Version: d.Replace.Version}
+			}
+			entries[i].Deps = append(entries[i].Deps, dep)
+		}
+	}
+	return nil
+}
+
+// ListEntries returns all or only target's list entries, for binaries installed in gobin.
+func (pkgs PackageRenderables) ListEntries(target string, gobin string) ([]ListEntry, error) {
+	entries := make([]ListEntry, 0, len(pkgs))
+	found := false
+	for _, p := range pkgs {
+		if !p.matches(target) {
+			continue
+		}
+		found = true
+		for _, v := range p.Versions {
+			binPath := filepath.Join(gobin, p.BinaryFile(v.Version))
+			installed, err := isInstalled(binPath)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, ListEntry{
+				Name:       p.Name,
+				ModulePath: p.ModPath,
+				Version:    v.Version,
+				RelPath:    strings.TrimPrefix(strings.TrimPrefix(p.PackagePath, p.ModPath), "/"),
+				// Ensure empty arrays are not rendered as null.
+				BuildFlags: append([]string{}, p.BuildFlags...),
+				BuildEnvs:  append([]string{}, p.BuildEnvVars...),
+				BinaryPath: binPath,
+				Installed:  installed,
+				ModFile:    v.ModFile,
+				Platform:   p.TargetPlatform(),
+				Comment:    p.Comment,
+			})
+		}
+	}
+
+	if target != "" && !found {
+		return nil, errors.Newf("Pinned tool %s not found", target)
+	}
+	return entries, nil
+}
+
+// PrintJSON prints list entries as JSON array.
+func (pkgs PackageRenderables) PrintJSON(target string, gobin string, w io.Writer) error {
+	entries, err := pkgs.ListEntries(target, gobin)
+	if err != nil {
+		return err
+	}
+	return PrintListEntriesJSON(entries, w)
+}
+
+// PrintListEntriesJSON prints the given list entries as JSON array.
+func PrintListEntriesJSON(entries []ListEntry, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// ListModule represents tools built from the same module in the same version, as printed by `bingo list --tree -o json`.
+// This schema is stable.
+type ListModule struct {
+	ModulePath string `json:"module_path"`
+	Version    string `json:"version"`
+	// Entries are the tools built from the module version, in the order they were listed.
+	Entries []ListEntry `json:"entries"`
+}
+
+// GroupListEntries groups the given list entries by module path and version. Groups are sorted by module path, then by
+// version (in semver order).
+func GroupListEntries(entries []ListEntry) []ListModule {
+	var mods []ListModule
+	idx := map[module.Version]int{}
+	for _, e := range entries {
+		key := module.Version{Path: e.ModulePath, Version: e.Version}
+		i, ok := idx[key]
+		if !ok {
+			i = len(mods)
+			idx[key] = i
+			mods = append(mods, ListModule{ModulePath: e.ModulePath, Version: e.Version})
+		}
+		mods[i].Entries = append(mods[i].Entries, e)
+	}
+	sort.SliceStable(mods, func(i, j int) bool {
+		if mods[i].ModulePath != mods[j].ModulePath {
+			return mods[i].ModulePath < mods[j].ModulePath
+		}
+		return modsemver.Compare(mods[i].Version, mods[j].Version) < 0
+	})
+	return mods
+}
+
+// PrintListModulesTab prints the given module groups as tree: module path with version, followed by relative package path
+// ("." for the module root), name and binary file of each tool built from it.
+func PrintListModulesTab(mods []ListModule, w io.Writer) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 0, 8, 2, ' ', 0)
+	defer func() { _ = tw.Flush() }()
+
+	for _, m := range mods {
+		_, _ = fmt.Fprintf(tw, "%s@%s\n", m.ModulePath, m.Version)
+		for i, e := range m.Entries {
+			branch := "├─"
+			if i == len(m.Entries)-1 {
+				branch = "└─"
+			}
+			relPath := e.RelPath
+			if relPath == "" {
+				relPath = "."
+			}
+			_, _ = fmt.Fprintf(tw, "%s %s\t%s\t%s\n", branch, relPath, e.Name, filepath.Base(e.BinaryPath))
+		}
+	}
+	return nil
+}
+
+// PrintListModulesJSON prints the given module groups as JSON array.
+func PrintListModulesJSON(mods []ListModule, w io.Writer) error {
+	if mods == nil {
+		// Ensure empty array is not rendered as null.
+		mods = []ListModule{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(mods)
+}
+
+// BinaryNameConflict is a binary name that more than one pinned tool is built as, so their binaries overwrite each
+// other in GOBIN and their helper variables clash.
+type BinaryNameConflict struct {
+	BinaryName string
+	// Tools are names of the conflicting tools, sorted.
+	Tools []string
+}
+
+// BinaryNameConflicts returns binary names (see BinaryNames) that more than one tool pinned in modDir is built as, sorted
+// by binary name. Versions of the same tool share the binary name, so they don't conflict. Malformed module files are skipped.
+func BinaryNameConflicts(modDir string) ([]BinaryNameConflict, error) {
+	modFiles, err := filepath.Glob(filepath.Join(modDir, "*.mod"))
+	if err != nil {
+		return nil, err
+	}
+	toolsByBinary := map[string]map[string]struct{}{}
+	for _, f := range modFiles {
+		if filepath.Base(f) == FakeRootModFileName || isTmpModFile(f) {
+			continue
+		}
+		names, err := pinnedBinaryNames(f)
+		if err != nil {
+			continue
+		}
+		tool, _ := NameFromModFile(f)
+		for _, n := range names {
+			if toolsByBinary[n] == nil {
+				toolsByBinary[n] = map[string]struct{}{}
+			}
+			toolsByBinary[n][tool] = struct{}{}
+		}
+	}
+
+	var conflicts []BinaryNameConflict
+	for bin, tools := range toolsByBinary {
+		if len(tools) < 2 {
+			continue
+		}
+		c := BinaryNameConflict{BinaryName: bin}
+		for t := range tools {
+			c.Tools = append(c.Tools, t)
+		}
+		sort.Strings(c.Tools)
+		conflicts = append(conflicts, c)
+	}
+	sort.Slice(conflicts, func(i, j int) bool { return conflicts[i].BinaryName < conflicts[j].BinaryName })
+	return conflicts, nil
+}
+
 // ListPinnedMainPackages lists all bingo pinned binaries (Go main packages) in the same order as seen in the filesystem.
-func ListPinnedMainPackages(logger *log.Logger, modDir string, remMalformed bool) (pkgs PackageRenderables, _ error) {
+// Packages declared with AlsoDirective are listed after the direct package of their module file. It returns error if
+// a binary name is used by more than one package of the same tool, or if an additional package shares binary name with
+// another tool.
+func ListPinnedMainPackages(logger logging.Logger, modDir string, remMalformed bool) (pkgs PackageRenderables, _ error) {
 	modFiles, err := filepath.Glob(filepath.Join(modDir, "*.mod"))
 	if err != nil {
 		return nil, err
 	}
-ModLoop:
 	for _, f := range modFiles {
 		if filepath.Base(f) == FakeRootModFileName {
 			continue
 		}
 
-		pkg, err := ModDirectPackage(f)
+		direct, comment, err := modDirectPackagesAndComment(f)
 		if err != nil {
 			if remMalformed {
-				logger.Printf("found malformed module file %v, removing due to error: %v\n", f, err)
+				logger.Warnf("found malformed module file %v, removing due to error: %v\n", f, err)
 				if err := os.RemoveAll(strings.TrimSuffix(f, ".") + "*"); err != nil {
 					return nil, err
 				}
@@ -362,40 +1884,121 @@ ModLoop:
 		}
 
 		name, _ := NameFromModFile(f)
-		varName := strings.ReplaceAll(strings.ReplaceAll(strings.ToUpper(name), ".", "_"), "-", "_")
-		for i, p := range pkgs {
-			if p.Name == name {
-				pkgs[i].EnvVarName = varName + "_ARRAY"
-				// Preserve order. Unfortunately first array mod file has no number, so it's last.
-				if filepath.Base(f) == p.Name+".mod" {
-					pkgs[i].Versions = append([]PackageVersionRenderable{{
-						Version: pkg.Module.Version,
-						ModFile: filepath.Base(f),
-					}}, pkgs[i].Versions...)
-					continue ModLoop
+		binNames, err := BinaryNames(name, direct)
+		if err != nil {
+			return nil, errors.Wrap(err, f)
+		}
+		seen := map[string]struct{}{}
+		for i, pkg := range direct {
+			if _, ok := seen[binNames[i]]; ok {
+				return nil, errors.Newf("%v: binary name %v is used by more than one package; set different name= for one of them", f, binNames[i])
+			}
+			seen[binNames[i]] = struct{}{}
+			for _, p := range pkgs {
+				// Conflicts of direct packages are only warned about (see BinaryNameConflicts), as before additional packages.
+				if p.Name == name || p.BinaryName != binNames[i] {
+					continue
 				}
-
-				pkgs[i].Versions = append(pkgs[i].Versions, PackageVersionRenderable{
-					Version: pkg.Module.Version,
-					ModFile: filepath.Base(f),
-				})
-				continue ModLoop
+				if i > 0 {
+					return nil, errors.Newf("%v: binary name %v of additional package %v is already used by tool %v; set different name= for it", f, binNames[i], pkg.Path(), p.Name)
+				}
+				if p.Additional {
+					return nil, errors.Newf("%v: binary name %v is already used by additional package %v of tool %v; set different name= for one of them", f, binNames[i], p.PackagePath, p.Name)
+				}
+			}
+			if pkgs, err = appendPinnedMainPackage(logger, modDir, f, name, binNames[i], i > 0, comment, pkg, pkgs); err != nil {
+				return nil, err
 			}
 		}
-		pkgs = append(pkgs, PackageRenderable{
-			Name: name,
-			Versions: []PackageVersionRenderable{
-				{Version: pkg.Module.Version, ModFile: filepath.Base(f)},
-			},
-			BuildFlags:   pkg.BuildFlags,
-			BuildEnvVars: pkg.BuildEnvs,
+	}
+	return pkgs, nil
+}
+
+// appendPinnedMainPackage adds the package pinned in the module file to the renderable of its tool and binary, or as
+// a new renderable. See ListPinnedMainPackages.
+func appendPinnedMainPackage(logger logging.Logger, modDir, f, name, binName string, additional bool, comment string, pkg Package, pkgs PackageRenderables) (PackageRenderables, error) {
+	// Errors are reported by bingo get, generated helpers just build without the env file.
+	withEnvFile, err := pkg.WithEnvFile(logging.Discard, modDir)
+	if err != nil {
+		logger.Warnf("%v: %v; ignoring env file\n", f, err)
+		withEnvFile = pkg
+	}
+	var envFileEnvs []string
+	for _, e := range withEnvFile.BuildEnvs {
+		if _, ok := pkg.BuildEnvs.Lookup(envKey(e)); !ok {
+			envFileEnvs = append(envFileEnvs, e)
+		}
+	}
+
+	for i, p := range pkgs {
+		if p.Name != name || p.Additional != additional || (additional && p.BinaryName != binName) {
+			continue
+		}
+		pkgs[i].EnvVarName = envVarName(p.BinaryName) + "_ARRAY"
+		if pkgs[i].Comment == "" {
+			pkgs[i].Comment = comment
+		}
+		// Preserve order. Unfortunately first array mod file has no number, so it's last.
+		if filepath.Base(f) == p.Name+".mod" {
+			pkgs[i].Versions = append([]PackageVersionRenderable{{
+				Version: pkg.Module.Version,
+				ModFile: filepath.Base(f),
+			}}, pkgs[i].Versions...)
+			return pkgs, nil
+		}
 
-			EnvVarName:  varName,
-			PackagePath: pkg.Path(),
-			ModPath:     pkg.Module.Path,
+		pkgs[i].Versions = append(pkgs[i].Versions, PackageVersionRenderable{
+			Version: pkg.Module.Version,
+			ModFile: filepath.Base(f),
 		})
+		return pkgs, nil
 	}
-	return pkgs, nil
+	return append(pkgs, PackageRenderable{
+		Name:       name,
+		BinaryName: binName,
+		Additional: additional,
+		Versions: []PackageVersionRenderable{
+			{Version: pkg.Module.Version, ModFile: filepath.Base(f)},
+		},
+		BuildFlags:          pkg.BuildFlags,
+		BuildEnvVars:        pkg.BuildEnvs,
+		EnvFileBuildFlags:   withEnvFile.BuildFlags[:len(withEnvFile.BuildFlags)-len(pkg.BuildFlags)],
+		EnvFileBuildEnvVars: envFileEnvs,
+		WorkDir:             pkg.WorkDir,
+		Comment:             comment,
+
+		EnvVarName:  envVarName(binName),
+		PackagePath: pkg.Path(),
+		ModPath:     pkg.Module.Path,
+	}), nil
+}
+
+// ToolNames returns sorted, unique names of tools pinned in modDir, as derived from module file names. Module files are not
+// parsed, so it's cheap enough to use e.g. for shell completion. It returns no names if modDir does not exist.
+func ToolNames(modDir string) ([]string, error) {
+	modFiles, err := filepath.Glob(filepath.Join(modDir, "*.mod"))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]struct{}{}
+	names := []string{}
+	for _, f := range modFiles {
+		if filepath.Base(f) == FakeRootModFileName || isTmpModFile(f) {
+			continue
+		}
+		name, _ := NameFromModFile(f)
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func envVarName(binName string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(strings.ToUpper(binName), ".", "_"), "-", "_")
 }
 
 func SortRenderables(pkgs []PackageRenderable) {
@@ -404,7 +2007,17 @@ func SortRenderables(pkgs []PackageRenderable) {
 			return p.Versions[i].Version < p.Versions[j].Version
 		})
 	}
+	// Side by side variants are listed together, after the tool they are named after (if any).
+	group := func(p PackageRenderable) string {
+		if of := p.SideBySideOf(); of != "" {
+			return of
+		}
+		return p.Name
+	}
 	sort.Slice(pkgs, func(i, j int) bool {
+		if gi, gj := group(pkgs[i]), group(pkgs[j]); gi != gj {
+			return gi < gj
+		}
 		if pkgs[i].Name == pkgs[j].Name {
 			return pkgs[i].PackagePath < pkgs[j].PackagePath
 		}
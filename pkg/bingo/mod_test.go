@@ -4,13 +4,20 @@
 package bingo
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/Masterminds/semver"
+	"github.com/bwplotka/bingo/internal/fakecmd"
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/bwplotka/bingo/pkg/mod"
 	"github.com/bwplotka/bingo/pkg/runner"
 	"github.com/bwplotka/bingo/pkg/version"
 	"github.com/efficientgo/core/testutil"
@@ -18,22 +25,23 @@ import (
 )
 
 func goVersion(r *runner.Runner) string {
-	// Starting from Go 1.21, `go mod init` adds complete semver to modfile.
-	// Thus, we return <major>.<minor> for < 1.21, and full semver otherwise.
-	if r.GoVersion().Compare(version.Go121) == -1 {
-		return fmt.Sprintf("%v.%v", r.GoVersion().Major(), r.GoVersion().Minor())
-	}
-
-	return r.GoVersion().String()
+	return version.ModInitGoVersion(r.GoVersion())
 }
 
 func TestCreateFromExistingOrNew(t *testing.T) {
-	logger := log.New(os.Stderr, "", 0)
-	r, err := runner.NewRunner(context.TODO(), logger, false, "go")
+	logger := logging.Discard
+	r, err := runner.NewRunner(context.TODO(), nil, false, "go")
 	testutil.Ok(t, err)
+	t.Cleanup(func() {
+		locks, err := filepath.Glob("test*.mod.lock")
+		testutil.Ok(t, err)
+		for _, l := range locks {
+			testutil.Ok(t, os.Remove(l))
+		}
+	})
 
 	t.Run("create new and close should create empty mod file with basic autogenerated meta", func(t *testing.T) {
-		f, err := CreateFromExistingOrNew(context.TODO(), r, log.New(os.Stderr, "", 0), "non_existing.mod", "test.mod")
+		f, err := CreateFromExistingOrNew(context.TODO(), r, logging.Discard, "non_existing.mod", "test.mod")
 		testutil.Ok(t, err)
 		testutil.Ok(t, f.Close())
 
@@ -43,7 +51,7 @@ go %s
 `, goVersion(r)), "test.mod")
 	})
 	t.Run("create new and close should work and produce same output", func(t *testing.T) {
-		f, err := CreateFromExistingOrNew(context.TODO(), r, log.New(os.Stderr, "", 0), "test.mod", "test2.mod")
+		f, err := CreateFromExistingOrNew(context.TODO(), r, logging.Discard, "test.mod", "test2.mod")
 		testutil.Ok(t, err)
 		testutil.Ok(t, f.Close())
 		expectContent(t, fmt.Sprintf(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
@@ -56,7 +64,7 @@ go %s
 `, goVersion(r)), "test2.mod")
 	})
 	t.Run("create new and set direct require should work", func(t *testing.T) {
-		f, err := CreateFromExistingOrNew(context.TODO(), r, log.New(os.Stderr, "", 0), "", "test3.mod")
+		f, err := CreateFromExistingOrNew(context.TODO(), r, logging.Discard, "", "test3.mod")
 		testutil.Ok(t, err)
 		testutil.Ok(t, f.SetDirectRequire(Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}, RelPath: "thebest"}))
 		testutil.Equals(t, Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}, RelPath: "thebest"}, *f.DirectPackage())
@@ -69,7 +77,7 @@ require github.com/yolo/best/v100 v100.0.0 // thebest
 `, goVersion(r)), "test3.mod")
 	})
 	t.Run("create new and set direct require2 should work", func(t *testing.T) {
-		f, err := CreateFromExistingOrNew(context.TODO(), r, log.New(os.Stderr, "", 0), "", "test4.mod")
+		f, err := CreateFromExistingOrNew(context.TODO(), r, logging.Discard, "", "test4.mod")
 		testutil.Ok(t, err)
 		testutil.Ok(t, f.SetDirectRequire(Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}}))
 		testutil.Equals(t, Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}}, *f.DirectPackage())
@@ -82,7 +90,7 @@ require github.com/yolo/best/v100 v100.0.0
 `, goVersion(r)), "test4.mod")
 	})
 	t.Run("copy and set direct require to something else", func(t *testing.T) {
-		f, err := CreateFromExistingOrNew(context.TODO(), r, log.New(os.Stderr, "", 0), "test3.mod", "test5.mod")
+		f, err := CreateFromExistingOrNew(context.TODO(), r, logging.Discard, "test3.mod", "test5.mod")
 		testutil.Ok(t, err)
 		testutil.Equals(t, Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}, RelPath: "thebest"}, *f.DirectPackage())
 		expectContent(t, fmt.Sprintf(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
@@ -102,6 +110,85 @@ go %s
 require github.com/yolo/not-best v1
 `, goVersion(r)), "test5.mod")
 	})
+	t.Run("create new with explicit go version", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		f, err := CreateFromExistingOrNewWithGoVersion(context.TODO(), r, logger, "", filepath.Join(tmpDir, "test.mod"), "1.18")
+		testutil.Ok(t, err)
+		testutil.Equals(t, "1.18", f.GoVersion())
+		testutil.Ok(t, f.Close())
+		expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.18
+`, filepath.Join(tmpDir, "test.mod"))
+
+		t.Run("copy with explicit go version overrides existing one", func(t *testing.T) {
+			f, err := CreateFromExistingOrNewWithGoVersion(context.TODO(), r, logger, "test3.mod", filepath.Join(tmpDir, "test2.mod"), "1.21.4")
+			testutil.Ok(t, err)
+			testutil.Equals(t, "1.21.4", f.GoVersion())
+			testutil.Equals(t, Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}, RelPath: "thebest"}, *f.DirectPackage())
+			testutil.Ok(t, f.Close())
+		})
+		t.Run("empty go version falls back to runner's one", func(t *testing.T) {
+			f, err := CreateFromExistingOrNewWithGoVersion(context.TODO(), r, logger, "", filepath.Join(tmpDir, "test3.mod"), "")
+			testutil.Ok(t, err)
+			testutil.Equals(t, goVersion(r), f.GoVersion())
+			testutil.Ok(t, f.Close())
+		})
+		t.Run("invalid go version", func(t *testing.T) {
+			for _, v := range []string{"go1.18", "1", "yolo"} {
+				_, err := CreateFromExistingOrNewWithGoVersion(context.TODO(), r, logger, "", filepath.Join(tmpDir, "test4.mod"), v)
+				testutil.NotOk(t, err)
+				testutil.Equals(t, fmt.Sprintf("invalid go version %q; expected language version like 1.18 or 1.21.4", v), err.Error())
+			}
+			_, err := os.Stat(filepath.Join(tmpDir, "test4.mod"))
+			testutil.Assert(t, os.IsNotExist(err))
+		})
+	})
+	t.Run("create new with custom module", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		const modulePath = "github.com/org/repo/.bingo/best"
+		f, err := CreateFromExistingOrNewWithModule(context.TODO(), r, logger, "", filepath.Join(tmpDir, "best.mod"), modulePath)
+		testutil.Ok(t, err)
+		testutil.Ok(t, f.SetDirectPackages(Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}, RelPath: "thebest"}))
+		testutil.Ok(t, f.Close())
+		expectContent(t, fmt.Sprintf(`module github.com/org/repo/.bingo/best // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go %s
+
+require github.com/yolo/best/v100 v100.0.0 // thebest
+`, goVersion(r)), filepath.Join(tmpDir, "best.mod"))
+
+		t.Run("module is kept on open and copy", func(t *testing.T) {
+			f, err := OpenModFile(filepath.Join(tmpDir, "best.mod"))
+			testutil.Ok(t, err)
+			p, _ := f.Module()
+			testutil.Equals(t, modulePath, p)
+			testutil.Ok(t, f.Close())
+
+			f, err = CreateFromExistingOrNew(context.TODO(), r, logger, filepath.Join(tmpDir, "best.mod"), filepath.Join(tmpDir, "best2.mod"))
+			testutil.Ok(t, err)
+			p, _ = f.Module()
+			testutil.Equals(t, modulePath, p)
+			testutil.Equals(t, Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}, RelPath: "thebest"}, *f.DirectPackage())
+			testutil.Ok(t, f.Close())
+		})
+		t.Run("copy with custom module overrides existing one", func(t *testing.T) {
+			f, err := CreateFromExistingOrNewWithModule(context.TODO(), r, logger, filepath.Join(tmpDir, "best.mod"), filepath.Join(tmpDir, "best3.mod"), "_")
+			testutil.Ok(t, err)
+			testutil.Equals(t, Package{Module: module.Version{Path: "github.com/yolo/best/v100", Version: "v100.0.0"}, RelPath: "thebest"}, *f.DirectPackage())
+			testutil.Ok(t, f.Close())
+			expectContent(t, fmt.Sprintf(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go %s
+
+require github.com/yolo/best/v100 v100.0.0 // thebest
+`, goVersion(r)), filepath.Join(tmpDir, "best3.mod"))
+		})
+		t.Run("invalid module", func(t *testing.T) {
+			_, err := CreateFromExistingOrNewWithModule(context.TODO(), r, logger, "", filepath.Join(tmpDir, "best4.mod"), "github.com/org/repo tool")
+			testutil.NotOk(t, err)
+		})
+	})
 }
 
 func expectContent(t *testing.T, expected string, file string) {
@@ -112,6 +199,46 @@ func expectContent(t *testing.T, expected string, file string) {
 	testutil.Equals(t, expected, string(b))
 }
 
+// testModDir is module directory with GOBIN next to it and runner using fake go, as tests installing tools need.
+type testModDir struct {
+	modDir, gobin string
+	r             *runner.Runner
+}
+
+// newTestModDir creates .bingo module directory and bin GOBIN in dir and runner using fake go with the given case arms
+// (see fakecmd.Go).
+func newTestModDir(t *testing.T, dir, arms string) testModDir {
+	t.Helper()
+
+	d := testModDir{modDir: filepath.Join(dir, ".bingo"), gobin: filepath.Join(dir, "bin")}
+	testutil.Ok(t, os.MkdirAll(d.modDir, os.ModePerm))
+	testutil.Ok(t, os.MkdirAll(d.gobin, os.ModePerm))
+
+	var err error
+	d.r, err = runner.NewRunner(context.Background(), nil, false, fakecmd.Go(t, dir, arms))
+	testutil.Ok(t, err)
+	return d
+}
+
+// writeModFile writes module file of the given tool with the given directives after go directive and returns its path.
+func (d testModDir) writeModFile(t *testing.T, name, directives string) string {
+	t.Helper()
+
+	modFile := filepath.Join(d.modDir, name+".mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte("module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT\n\ngo 1.14\n\n"+directives+"\n"), os.ModePerm))
+	return modFile
+}
+
+// install installs the given tool from its module file.
+func (d testModDir) install(t *testing.T, name string, rebuild Rebuild) error {
+	t.Helper()
+
+	mf, err := OpenModFile(filepath.Join(d.modDir, name+".mod"))
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+	return Install(context.Background(), logging.Discard, d.r, d.modDir, d.gobin, name, false, "", rebuild, mf)
+}
+
 func TestModFile(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -243,6 +370,34 @@ require github.com/prometheus/prometheus v2.4.3+incompatible // cmd/prometheus C
 			BuildFlags: []string{"-tags=yolo,linux"},
 		}, *mf.DirectPackage())
 	})
+	t.Run("with build attributes3 and CRLF line endings", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "test.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte("module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT\r\n"+
+			"\r\n"+
+			"go 1.14\r\n"+
+			"\r\n"+
+			"require github.com/prometheus/prometheus v2.4.3+incompatible // cmd/prometheus CGO_ENABLED=1 GOWASM=somefeature -tags=yolo,linux\r\n"), os.ModePerm))
+
+		mf, err := OpenModFile(testFile)
+		testutil.Ok(t, err)
+
+		testutil.Equals(t, false, mf.IsDirectivesAutoFetchDisabled())
+		testutil.Equals(t, Package{
+			Module:     module.Version{Path: "github.com/prometheus/prometheus", Version: "v2.4.3+incompatible"},
+			RelPath:    "cmd/prometheus",
+			BuildEnvs:  []string{"CGO_ENABLED=1", "GOWASM=somefeature"},
+			BuildFlags: []string{"-tags=yolo,linux"},
+		}, *mf.DirectPackage())
+		testutil.Ok(t, mf.Close())
+
+		// Written back with LF only.
+		expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/prometheus/prometheus v2.4.3+incompatible // cmd/prometheus CGO_ENABLED=1 GOWASM=somefeature -tags=yolo,linux
+`, testFile)
+	})
 	t.Run("with build attributes without relpath", func(t *testing.T) {
 		testFile := filepath.Join(tmpDir, "test.mod")
 		testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
@@ -262,4 +417,1382 @@ require github.com/prometheus/prometheus v2.4.3+incompatible // CGO_ENABLED=1 GO
 			BuildFlags: []string{"-tags=yolo,linux"},
 		}, *mf.DirectPackage())
 	})
+	t.Run("set build flags and envs", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "test.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/prometheus/prometheus v2.4.3+incompatible // cmd/prometheus CGO_ENABLED=1 -tags=yolo
+`), os.ModePerm))
+
+		mf, err := OpenModFile(testFile)
+		testutil.Ok(t, err)
+
+		testutil.NotOk(t, mf.SetBuildFlags([]string{"tags=yolo"}))
+		testutil.NotOk(t, mf.SetBuildEnvs([]string{"CGO_ENABLED"}))
+
+		testutil.Ok(t, mf.SetBuildFlags([]string{"-tags=yolo,linux", "-trimpath"}))
+		testutil.Ok(t, mf.SetBuildEnvs([]string{"CGO_ENABLED=0", "GOWASM=somefeature"}))
+		testutil.Equals(t, Package{
+			Module:     module.Version{Path: "github.com/prometheus/prometheus", Version: "v2.4.3+incompatible"},
+			RelPath:    "cmd/prometheus",
+			BuildEnvs:  []string{"CGO_ENABLED=0", "GOWASM=somefeature"},
+			BuildFlags: []string{"-tags=yolo,linux", "-trimpath"},
+		}, *mf.DirectPackage())
+		testutil.Ok(t, mf.Close())
+
+		expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/prometheus/prometheus v2.4.3+incompatible // cmd/prometheus CGO_ENABLED=0 GOWASM=somefeature -tags=yolo,linux -trimpath
+`, testFile)
+	})
+	t.Run("set build flags without direct package", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "test.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+`), os.ModePerm))
+
+		mf, err := OpenModFile(testFile)
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, mf.Close()) }()
+
+		testutil.NotOk(t, mf.SetBuildFlags([]string{"-tags=yolo"}))
+		testutil.NotOk(t, mf.SetBuildEnvs([]string{"CGO_ENABLED=1"}))
+	})
+	t.Run("with additional packages", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "test.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require k8s.io/kubernetes v1.27.1 // cmd/kubectl
+
+// also: cmd/kubelet CGO_ENABLED=0
+`), os.ModePerm))
+
+		mf, err := OpenModFile(testFile)
+		testutil.Ok(t, err)
+
+		kube := module.Version{Path: "k8s.io/kubernetes", Version: "v1.27.1"}
+		testutil.Equals(t, Package{Module: kube, RelPath: "cmd/kubectl"}, *mf.DirectPackage())
+		testutil.Equals(t, []Package{
+			{Module: kube, RelPath: "cmd/kubectl"},
+			{Module: kube, RelPath: "cmd/kubelet", BuildEnvs: []string{"CGO_ENABLED=0"}},
+		}, mf.DirectPackages())
+
+		kube.Version = "v1.28.0"
+		testutil.NotOk(t, mf.SetDirectPackages(Package{Module: kube, RelPath: "cmd/kubectl"}, Package{Module: kube}))
+		testutil.NotOk(t, mf.SetDirectPackages(Package{Module: kube, RelPath: "cmd/kubectl"}, Package{Module: kube, RelPath: "cmd/kubectl"}))
+		testutil.Ok(t, mf.SetDirectPackages(
+			Package{Module: kube, RelPath: "cmd/kubectl"},
+			Package{Module: kube, RelPath: "cmd/kubeadm", BuildFlags: []string{"-tags=yolo"}},
+			Package{Module: kube, RelPath: "cmd/kubelet"},
+		))
+		testutil.Ok(t, mf.Close())
+
+		expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/kubeadm -tags=yolo
+
+// also: cmd/kubelet
+
+require k8s.io/kubernetes v1.28.0 // cmd/kubectl
+`, testFile)
+
+		mf, err = OpenModFile(testFile)
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, mf.Close()) }()
+
+		// Single package set should keep additional packages on the new version.
+		kube.Version = "v1.28.1"
+		testutil.Ok(t, mf.SetDirectRequire(Package{Module: kube, RelPath: "cmd/kubectl"}))
+		testutil.Equals(t, []Package{
+			{Module: kube, RelPath: "cmd/kubectl"},
+			{Module: kube, RelPath: "cmd/kubeadm", BuildFlags: []string{"-tags=yolo"}},
+			{Module: kube, RelPath: "cmd/kubelet"},
+		}, mf.DirectPackages())
+	})
+}
+
+func TestModFile_Validate(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	for _, tcase := range []struct {
+		comment     string
+		expectedErr string
+	}{
+		{comment: ""},
+		{comment: "cmd/prometheus"},
+		{comment: "cmd/prometheus CGO_ENABLED=1 GOWASM=somefeature -tags=yolo,linux"},
+		{comment: "CGO_ENABLED=1 -tags=yolo,linux -trimpath"},
+		{comment: "cmd/prometheus name=prom-server CGO_ENABLED=1 -tags=yolo"},
+		{comment: "cmd/prometheus env-file=prometheus.env CGO_ENABLED=1"},
+		{comment: "cmd/prometheus workdir=prometheus/build CGO_ENABLED=1"},
+		{comment: "cmd/prometheus branch=release/v2 CGO_ENABLED=1"},
+		{comment: "cmd/prometheus tag=release-2024-01 CGO_ENABLED=1"},
+		{comment: "cmd/prometheus -mod=mod -trimpath"},
+		{comment: `cmd/prometheus CGO_CFLAGS="-O2 -g" -ldflags="-X main.version=1.2.3 -s" -trimpath`},
+		{
+			comment:     `cmd/prometheus -ldflags="-X main.version=1.2.3 -s`,
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: unterminated quote in "\"-X main.version=1.2.3 -s"`,
+		},
+		{
+			comment:     "cmd/prometheus -modfile=other.mod",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: build flag "-modfile=other.mod" is set by bingo and can't be overridden`,
+		},
+		{
+			comment:     "cmd/prometheus --o=prometheus",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: build flag "--o=prometheus" is set by bingo and can't be overridden`,
+		},
+		{
+			comment:     "cmd/prometheus branch=-main",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: branch name "-main" has to be a git branch name with only [A-z0-9._/-] characters`,
+		},
+		{
+			comment:     "cmd/prometheus tag=release..2024",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: tag name "release..2024" has to be a git tag name with only [A-z0-9._/-] characters`,
+		},
+		{comment: `cmd/prometheus verify-cmd="{{.Bin}} --version" verify-output="version {{.Version}}"`},
+		{
+			comment:     `cmd/prometheus verify-cmd="{{.Bin}} {{.Path}}"`,
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: verify command "{{.Bin}} {{.Path}}"; supported placeholders are {{.Bin}}, {{.Name}} and {{.Version}}: template: verify:1:2: executing "verify" at <.Path>: can't evaluate field Path in type bingo.postInstallData`,
+		},
+		{
+			comment:     "cmd/prometheus verify-output={{.Version}}",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: verify-output attribute requires verify-cmd attribute`,
+		},
+		{
+			comment:     "cmd/prometheus env-file=../prometheus.env",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: env file "../prometheus.env" has to be a clean path relative to the module directory`,
+		},
+		{
+			comment:     "cmd/prometheus workdir=../prometheus",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: work dir "../prometheus" has to be a clean path relative to the module directory`,
+		},
+		{
+			comment:     "cmd/prometheus workdir=/tmp",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: work dir "/tmp" has to be a clean path relative to the module directory`,
+		},
+		{
+			comment:     "cmd/prometheus name=bin/prometheus",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: binary name "bin/prometheus" has to be a file name with only [A-z0-9._-] characters`,
+		},
+		{
+			comment:     "cmd/prometheus name=",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: binary name "" has to be a file name with only [A-z0-9._-] characters`,
+		},
+		{
+			comment:     "cmd/prometheus -tags yolo",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: build flag "yolo" has to start with '-'; flags have to be last and values joined with '=' (e.g -tags=yolo)`,
+		},
+		{
+			comment:     "cmd/prometheus tags=yolo",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: build env "tags=yolo" has to be in KEY=VALUE form with upper case KEY`,
+		},
+		{
+			comment:     "cmd/prometheus GODEBUG=gotypesalias",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: build env "GODEBUG=gotypesalias" has to be comma-separated list of key=value settings, got "gotypesalias"`,
+		},
+		{
+			comment:     "cmd/prometheus yolo",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: unexpected token "yolo"; relative package path "cmd/prometheus" already specified`,
+		},
+		{
+			comment:     "../cmd/prometheus",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: relative package path "../cmd/prometheus" has to be clean relative path within module`,
+		},
+		{
+			comment:     "cmd/../../prometheus",
+			expectedErr: `require github.com/prometheus/prometheus@v2.4.3+incompatible: relative package path "cmd/../../prometheus" has to be clean relative path within module`,
+		},
+	} {
+		t.Run(tcase.comment, func(t *testing.T) {
+			testFile := filepath.Join(tmpDir, "test.mod")
+			content := `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/prometheus/prometheus v2.4.3+incompatible`
+			if tcase.comment != "" {
+				content += " // " + tcase.comment
+			}
+			testutil.Ok(t, os.WriteFile(testFile, []byte(content+"\n"), os.ModePerm))
+
+			mf, err := OpenModFile(testFile)
+			testutil.Ok(t, err)
+			defer func() { testutil.Ok(t, mf.Close()) }()
+
+			err = mf.Validate()
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, testFile+":5: "+tcase.expectedErr, err.Error())
+				// Malformed file should not be rewritten.
+				expectContent(t, content+"\n", testFile)
+				return
+			}
+			testutil.Ok(t, err)
+		})
+	}
+
+	t.Run("additional package", func(t *testing.T) {
+		testFile := filepath.Join(tmpDir, "test.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/kubelet -tags yolo
+
+require k8s.io/kubernetes v1.27.1 // cmd/kubectl
+`), os.ModePerm))
+
+		mf, err := OpenModFile(testFile)
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, mf.Close()) }()
+
+		err = mf.Validate()
+		testutil.NotOk(t, err)
+		testutil.Equals(t, testFile+`: comment "also: cmd/kubelet -tags yolo": build flag "yolo" has to start with '-'; flags have to be last and values joined with '=' (e.g -tags=yolo)`, err.Error())
+	})
+}
+
+func TestParseBuildComment(t *testing.T) {
+	for _, tcase := range []struct {
+		comment         string
+		expectedRelPath string
+		expectedEnvs    []string
+		expectedFlags   []string
+		expectedErr     string
+	}{
+		{comment: ""},
+		{comment: "cmd/foo", expectedRelPath: "cmd/foo"},
+		{
+			comment:         " cmd/foo CGO_ENABLED=1 -tags=x ",
+			expectedRelPath: "cmd/foo", expectedEnvs: []string{"CGO_ENABLED=1"}, expectedFlags: []string{"-tags=x"},
+		},
+		{
+			comment:      `name=foo2 branch=main CGO_CFLAGS="-O2 -g" -ldflags="-X main.version=1.2.3 -s" -trimpath`,
+			expectedEnvs: []string{"CGO_CFLAGS=-O2 -g"}, expectedFlags: []string{"-ldflags=-X main.version=1.2.3 -s", "-trimpath"},
+		},
+		{comment: "cmd/foo -tags=x CGO_ENABLED=1", expectedErr: `build flag "CGO_ENABLED=1" has to start with '-'; flags have to be last and values joined with '=' (e.g -tags=yolo)`},
+		{comment: "cmd/foo -modfile=other.mod", expectedErr: `build flag "-modfile=other.mod" is set by bingo and can't be overridden`},
+	} {
+		t.Run(tcase.comment, func(t *testing.T) {
+			relPath, envs, flags, err := ParseBuildComment(tcase.comment)
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expectedRelPath, relPath)
+			testutil.Equals(t, tcase.expectedEnvs, envs)
+			testutil.Equals(t, tcase.expectedFlags, flags)
+		})
+	}
+}
+
+func TestPackageRenderables_PrintJSON(t *testing.T) {
+	pkgs := PackageRenderables{
+		{
+			Name:        "buildable",
+			BinaryName:  "buildable",
+			ModPath:     "github.com/bwplotka/bingo-testmodule",
+			PackagePath: "github.com/bwplotka/bingo-testmodule/buildable",
+			Versions: []PackageVersionRenderable{
+				{Version: "v1.0.0", ModFile: "buildable.mod"},
+				{Version: "v1.1.0", ModFile: "buildable.1.mod"},
+			},
+			BuildEnvVars: []string{"CGO_ENABLED=1"},
+		},
+		{
+			Name:        "faillint",
+			BinaryName:  "faillint",
+			ModPath:     "github.com/fatih/faillint",
+			PackagePath: "github.com/fatih/faillint",
+			Versions:    []PackageVersionRenderable{{Version: "v1.5.0", ModFile: "faillint.mod"}},
+			// Cross compiled.
+			BuildEnvVars: []string{"GOOS=linux", "GOARCH=arm64"},
+		},
+	}
+
+	b := bytes.Buffer{}
+	testutil.Ok(t, pkgs.PrintJSON("faillint", "/gobin", &b))
+	testutil.Equals(t, `[
+  {
+    "name": "faillint",
+    "module_path": "github.com/fatih/faillint",
+    "version": "v1.5.0",
+    "rel_path": "",
+    "build_flags": [],
+    "build_envs": [
+      "GOOS=linux",
+      "GOARCH=arm64"
+    ],
+    "binary_path": "/gobin/faillint-v1.5.0-linux_arm64",
+    "installed": false,
+    "mod_file": "faillint.mod",
+    "platform": "linux/arm64",
+    "comment": ""
+  }
+]
+`, b.String())
+
+	entries, err := pkgs.ListEntries("", "/gobin")
+	testutil.Ok(t, err)
+	testutil.Equals(t, []ListEntry{
+		{
+			Name: "buildable", ModulePath: "github.com/bwplotka/bingo-testmodule", Version: "v1.0.0", RelPath: "buildable",
+			BuildFlags: []string{}, BuildEnvs: []string{"CGO_ENABLED=1"}, BinaryPath: "/gobin/buildable-v1.0.0", ModFile: "buildable.mod",
+			Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		},
+		{
+			Name: "buildable", ModulePath: "github.com/bwplotka/bingo-testmodule", Version: "v1.1.0", RelPath: "buildable",
+			BuildFlags: []string{}, BuildEnvs: []string{"CGO_ENABLED=1"}, BinaryPath: "/gobin/buildable-v1.1.0", ModFile: "buildable.1.mod",
+			Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		},
+		{
+			Name: "faillint", ModulePath: "github.com/fatih/faillint", Version: "v1.5.0",
+			BuildFlags: []string{}, BuildEnvs: []string{"GOOS=linux", "GOARCH=arm64"}, BinaryPath: "/gobin/faillint-v1.5.0-linux_arm64", ModFile: "faillint.mod",
+			Platform: "linux/arm64",
+		},
+	}, entries)
+
+	_, err = pkgs.ListEntries("yolo", "/gobin")
+	testutil.NotOk(t, err)
+}
+
+func TestGroupListEntries(t *testing.T) {
+	entries := []ListEntry{
+		{Name: "promtool", ModulePath: "github.com/prometheus/prometheus", Version: "v0.45.0", RelPath: "cmd/promtool", BinaryPath: "/gobin/promtool-v0.45.0"},
+		{Name: "faillint", ModulePath: "github.com/fatih/faillint", Version: "v1.5.0", BinaryPath: "/gobin/faillint-v1.5.0"},
+		{Name: "prometheus", ModulePath: "github.com/prometheus/prometheus", Version: "v0.45.0", RelPath: "cmd/prometheus", BinaryPath: "/gobin/prometheus-v0.45.0"},
+		{Name: "faillint", ModulePath: "github.com/fatih/faillint", Version: "v1.10.0", BinaryPath: "/gobin/faillint-v1.10.0"},
+	}
+	mods := GroupListEntries(entries)
+	testutil.Equals(t, []ListModule{
+		{ModulePath: "github.com/fatih/faillint", Version: "v1.5.0", Entries: entries[1:2]},
+		{ModulePath: "github.com/fatih/faillint", Version: "v1.10.0", Entries: entries[3:4]},
+		{ModulePath: "github.com/prometheus/prometheus", Version: "v0.45.0", Entries: []ListEntry{entries[0], entries[2]}},
+	}, mods)
+
+	b := bytes.Buffer{}
+	testutil.Ok(t, PrintListModulesTab(mods, &b))
+	testutil.Equals(t, `github.com/fatih/faillint@v1.5.0
+└─ .  faillint  faillint-v1.5.0
+github.com/fatih/faillint@v1.10.0
+└─ .  faillint  faillint-v1.10.0
+github.com/prometheus/prometheus@v0.45.0
+├─ cmd/promtool    promtool    promtool-v0.45.0
+└─ cmd/prometheus  prometheus  prometheus-v0.45.0
+`, b.String())
+
+	b.Reset()
+	testutil.Ok(t, PrintListModulesJSON(GroupListEntries(nil), &b))
+	testutil.Equals(t, "[]\n", b.String())
+}
+
+func TestSetDependencies(t *testing.T) {
+	// Fake go that prints build info of any binary.
+	goCmd := fakecmd.Go(t, t.TempDir(), `
+  version) case "$2" in
+      -m) printf '%s: go1.21.4\n\tpath\tgithub.com/fatih/faillint\n\tmod\tgithub.com/fatih/faillint\tv1.5.0\n\tdep\tgolang.org/x/tools\tv0.0.1\n\t=>\t../tools\t(devel)\n\tdep\tgolang.org/x/mod\tv0.12.0\n' "$3" ;;
+      *) echo "go version go1.21.4 linux/amd64" ;;
+    esac ;;`)
+	r, err := runner.NewRunner(context.Background(), nil, false, goCmd)
+	testutil.Ok(t, err)
+
+	entries := []ListEntry{
+		{Name: "faillint", Version: "v1.5.0", BinaryPath: "/gobin/faillint-v1.5.0", Installed: true},
+		{Name: "faillint", Version: "v1.4.0", BinaryPath: "/gobin/faillint-v1.4.0"},
+	}
+	testutil.Ok(t, SetDependencies(context.Background(), r, entries))
+	testutil.Equals(t, []ListDependency{
+		{Path: "golang.org/x/tools", Version: "v0.0.1", Replace: &ListDependency{Path: "../tools", Version: "(devel)"}},
+		{Path: "golang.org/x/mod", Version: "v0.12.0"},
+	}, entries[0].Deps)
+	testutil.Equals(t, []ListDependency(nil), entries[1].Deps)
+
+	b := bytes.Buffer{}
+	testutil.Ok(t, PrintListEntriesJSON(entries[:1], &b))
+	testutil.Assert(t, strings.Contains(b.String(), `"deps": [
+      {
+        "path": "golang.org/x/tools",
+        "version": "v0.0.1",
+        "replace": {
+          "path": "../tools",
+          "version": "(devel)"
+        }
+      },
+      {
+        "path": "golang.org/x/mod",
+        "version": "v0.12.0"
+      }
+    ]`), b.String())
+}
+
+func TestPackageRenderables_FilterByName(t *testing.T) {
+	pkgs := PackageRenderables{{Name: "protoc-gen-go"}, {Name: "faillint"}, {Name: "protoc"}, {Name: "golangci-lint"}}
+
+	for _, tcase := range []struct {
+		pattern     string
+		expected    []string
+		expectedErr string
+	}{
+		{pattern: "proto*", expected: []string{"protoc-gen-go", "protoc"}},
+		{pattern: "*lint", expected: []string{"faillint", "golangci-lint"}},
+		{pattern: "protoc?gen?go", expected: []string{"protoc-gen-go"}},
+		{pattern: "[fg]*", expected: []string{"faillint", "golangci-lint"}},
+		{pattern: "yolo*", expectedErr: `no pinned tool matches "yolo*"; available tools: faillint, golangci-lint, protoc, protoc-gen-go`},
+		{pattern: "[", expectedErr: `pattern "[": syntax error in pattern`},
+	} {
+		t.Run(tcase.pattern, func(t *testing.T) {
+			testutil.Assert(t, IsNamePattern(tcase.pattern))
+
+			matched, err := pkgs.FilterByName(tcase.pattern)
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+
+			var names []string
+			for _, p := range matched {
+				names = append(names, p.Name)
+			}
+			testutil.Equals(t, tcase.expected, names)
+		})
+	}
+
+	_, err := PackageRenderables{}.FilterByName("*")
+	testutil.NotOk(t, err)
+	testutil.Equals(t, `no pinned tool matches "*"; no tools are pinned`, err.Error())
+
+	testutil.Assert(t, !IsNamePattern("faillint"))
+	testutil.Assert(t, !IsNamePattern("github.com/fatih/*"))
+}
+
+func TestPackageRenderables_FilterByInstalled(t *testing.T) {
+	gobin := t.TempDir()
+	pkgs := PackageRenderables{
+		{
+			Name:       "buildable",
+			BinaryName: "buildable",
+			Versions: []PackageVersionRenderable{
+				{Version: "v1.0.0", ModFile: "buildable.mod"},
+				{Version: "v1.1.0", ModFile: "buildable.1.mod"},
+			},
+		},
+		{
+			Name:         "faillint",
+			BinaryName:   "faillint",
+			Versions:     []PackageVersionRenderable{{Version: "v1.5.0", ModFile: "faillint.mod"}},
+			BuildEnvVars: []string{"GOOS=linux", "GOARCH=arm64"},
+		},
+	}
+	testutil.Ok(t, os.WriteFile(filepath.Join(gobin, "buildable-v1.1.0"), []byte("binary"), os.ModePerm))
+	// Native binary is not the cross compiled one.
+	testutil.Ok(t, os.WriteFile(filepath.Join(gobin, "faillint-v1.5.0"), []byte("binary"), os.ModePerm))
+
+	installed, err := pkgs.FilterByInstalled(gobin, true)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(installed))
+	testutil.Equals(t, []PackageVersionRenderable{{Version: "v1.1.0", ModFile: "buildable.1.mod"}}, installed[0].Versions)
+
+	missing, err := pkgs.FilterByInstalled(gobin, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(missing))
+	testutil.Equals(t, []PackageVersionRenderable{{Version: "v1.0.0", ModFile: "buildable.mod"}}, missing[0].Versions)
+	testutil.Equals(t, "faillint", missing[1].Name)
+
+	entries, err := pkgs.ListEntries("buildable", gobin)
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, entries[0].Installed)
+	testutil.Equals(t, true, entries[1].Installed)
+}
+
+func TestPackageRenderables_PrintReplacesTab(t *testing.T) {
+	modDir := t.TempDir()
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "thanos.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+replace (
+	github.com/hashicorp/consul => github.com/hashicorp/consul v1.8.1
+	k8s.io/klog v1.0.0 => ../klog
+)
+
+require github.com/thanos-io/thanos v0.17.2 // cmd/thanos
+`), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "faillint.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/fatih/faillint v1.5.0
+`), os.ModePerm))
+
+	pkgs := PackageRenderables{
+		{Name: "faillint", Versions: []PackageVersionRenderable{{Version: "v1.5.0", ModFile: "faillint.mod"}}},
+		{Name: "thanos", Versions: []PackageVersionRenderable{{Version: "v0.17.2", ModFile: "thanos.mod"}}},
+	}
+	b := &bytes.Buffer{}
+	testutil.Ok(t, pkgs.PrintReplacesTab(modDir, "", b))
+	testutil.Equals(t, "Name\tMod File\tReplace\n"+
+		"----\t--------\t-------\n"+
+		"thanos\tthanos.mod\tgithub.com/hashicorp/consul => github.com/hashicorp/consul v1.8.1\n"+
+		"thanos\tthanos.mod\tk8s.io/klog v1.0.0 => ../klog\n", b.String())
+
+	b.Reset()
+	testutil.Ok(t, pkgs.PrintReplacesTab(modDir, "faillint", b))
+	testutil.Equals(t, 2, strings.Count(b.String(), "\n"))
+	testutil.NotOk(t, pkgs.PrintReplacesTab(modDir, "yolo", b))
+}
+
+func TestPackage_TargetPlatform(t *testing.T) {
+	for _, tcase := range []struct {
+		envs           []string
+		goos, goarch   string
+		platformSuffix string
+	}{
+		{goos: runtime.GOOS, goarch: runtime.GOARCH},
+		{envs: []string{"CGO_ENABLED=0"}, goos: runtime.GOOS, goarch: runtime.GOARCH},
+		{envs: []string{"GOOS=linux", "GOARCH=amd64"}, goos: "linux", goarch: "amd64", platformSuffix: "-linux_amd64"},
+		{envs: []string{"GOOS=windows"}, goos: "windows", goarch: runtime.GOARCH, platformSuffix: "-windows_" + runtime.GOARCH},
+		{envs: []string{"GOARCH=arm64"}, goos: runtime.GOOS, goarch: "arm64", platformSuffix: "-" + runtime.GOOS + "_arm64"},
+	} {
+		t.Run(strings.Join(tcase.envs, " "), func(t *testing.T) {
+			p := Package{BuildEnvs: tcase.envs}
+			testutil.Equals(t, tcase.goos, p.TargetGOOS())
+			testutil.Equals(t, tcase.goarch, p.TargetGOARCH())
+			testutil.Equals(t, tcase.platformSuffix, p.PlatformSuffix())
+		})
+	}
+}
+
+func TestListPinnedMainPackages_BinaryName(t *testing.T) {
+	modDir := t.TempDir()
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "server.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client name=x-client
+
+require github.com/x/server v1.0.0 // cmd/server name=x-server CGO_ENABLED=0 -tags=yolo
+`), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "faillint.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/fatih/faillint v1.5.0
+`), os.ModePerm))
+
+	mf, err := OpenModFile(filepath.Join(modDir, "server.mod"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, []Package{
+		{Module: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}, RelPath: "cmd/server", Name: "x-server", BuildEnvs: []string{"CGO_ENABLED=0"}, BuildFlags: []string{"-tags=yolo"}},
+		{Module: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}, RelPath: "cmd/client", Name: "x-client"},
+	}, mf.DirectPackages())
+	testutil.Ok(t, mf.Close())
+
+	pkgs, err := ListPinnedMainPackages(logging.Discard, modDir, false)
+	testutil.Ok(t, err)
+	SortRenderables(pkgs)
+	testutil.Equals(t, []string{"faillint", "server", "server"}, []string{pkgs[0].Name, pkgs[1].Name, pkgs[2].Name})
+	testutil.Equals(t, []string{"faillint", "x-client", "x-server"}, []string{pkgs[0].BinaryName, pkgs[1].BinaryName, pkgs[2].BinaryName})
+	testutil.Equals(t, []string{"FAILLINT", "X_CLIENT", "X_SERVER"}, []string{pkgs[0].EnvVarName, pkgs[1].EnvVarName, pkgs[2].EnvVarName})
+	testutil.Equals(t, []bool{false, true, false}, []bool{pkgs[0].Additional, pkgs[1].Additional, pkgs[2].Additional})
+}
+
+func TestListPinnedMainPackages_Also(t *testing.T) {
+	modDir := t.TempDir()
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "kubectl.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/kubelet CGO_ENABLED=0
+// also: cmd/kubeadm name=kadm
+
+require k8s.io/kubernetes v1.28.0 // cmd/kubectl
+`), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "kubectl.1.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/kubelet CGO_ENABLED=0
+// also: cmd/kubeadm name=kadm
+
+require k8s.io/kubernetes v1.29.0 // cmd/kubectl
+`), os.ModePerm))
+
+	pkgs, err := ListPinnedMainPackages(logging.Discard, modDir, false)
+	testutil.Ok(t, err)
+	SortRenderables(pkgs)
+	testutil.Equals(t, 3, len(pkgs))
+	for i, expected := range []struct {
+		binName, envVarName, pkgPath string
+		additional                   bool
+	}{
+		{binName: "kadm", envVarName: "KADM_ARRAY", pkgPath: "k8s.io/kubernetes/cmd/kubeadm", additional: true},
+		{binName: "kubectl", envVarName: "KUBECTL_ARRAY", pkgPath: "k8s.io/kubernetes/cmd/kubectl"},
+		{binName: "kubelet", envVarName: "KUBELET_ARRAY", pkgPath: "k8s.io/kubernetes/cmd/kubelet", additional: true},
+	} {
+		testutil.Equals(t, "kubectl", pkgs[i].Name)
+		testutil.Equals(t, expected.binName, pkgs[i].BinaryName)
+		testutil.Equals(t, expected.envVarName, pkgs[i].EnvVarName)
+		testutil.Equals(t, expected.pkgPath, pkgs[i].PackagePath)
+		testutil.Equals(t, expected.additional, pkgs[i].Additional)
+		testutil.Equals(t, []PackageVersionRenderable{{Version: "v1.28.0", ModFile: "kubectl.mod"}, {Version: "v1.29.0", ModFile: "kubectl.1.mod"}}, pkgs[i].Versions)
+	}
+	testutil.Equals(t, []string{"CGO_ENABLED=0"}, pkgs[2].BuildEnvVars)
+
+	entries, err := pkgs.ListEntries("kubectl", "/gobin")
+	testutil.Ok(t, err)
+	testutil.Equals(t, 6, len(entries))
+	testutil.Equals(t, "/gobin/kadm-v1.28.0", entries[0].BinaryPath)
+	testutil.Equals(t, "cmd/kubeadm", entries[0].RelPath)
+
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	b, err := os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	for _, expected := range []string{
+		"\nKUBELET_ARRAY := $(GOBIN)/kubelet-v1.28.0 $(GOBIN)/kubelet-v1.29.0\n$(KUBELET_ARRAY): $(BINGO_DIR)/kubectl.mod $(BINGO_DIR)/kubectl.1.mod\n",
+		"GOWORK=off CGO_ENABLED=0 $(GO) build -mod=mod $(BINGO_TRIMPATH) $(BINGO_BUILDVCS) -modfile=kubectl.mod -o=$(GOBIN)/kubelet-v1.28.0 \"k8s.io/kubernetes/cmd/kubelet\"\n",
+		"-modfile=kubectl.1.mod -o=$(GOBIN)/kadm-v1.29.0 \"k8s.io/kubernetes/cmd/kubeadm\"\n",
+	} {
+		testutil.Assert(t, strings.Contains(string(b), expected), "expected %q in:\n%s", expected, string(b))
+	}
+	b, err = os.ReadFile(filepath.Join(modDir, "variables.env"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(string(b), "\nKADM_ARRAY=\"${GOBIN}/kadm-v1.28.0 ${GOBIN}/kadm-v1.29.0\"\n"), string(b))
+
+	// Additional package can't be built as a binary of another tool.
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "kadm.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/kadm v1.0.0
+`), os.ModePerm))
+	_, err = ListPinnedMainPackages(logging.Discard, modDir, false)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), "binary name kadm"), err.Error())
+	testutil.Ok(t, os.Remove(filepath.Join(modDir, "kadm.mod")))
+
+	// Nor as a binary of the same tool.
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "kubectl.1.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/kubelet name=kubectl
+
+require k8s.io/kubernetes v1.29.0 // cmd/kubectl
+`), os.ModePerm))
+	_, err = ListPinnedMainPackages(logging.Discard, modDir, false)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, strings.Contains(err.Error(), "binary name kubectl is used by more than one package"), err.Error())
+}
+
+func TestListPinnedMainPackages_EnvFileAndWorkDir(t *testing.T) {
+	modDir := t.TempDir()
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "hugo.mod"), []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/gohugoio/hugo v0.83.1 // env-file=hugo.env workdir=tools/hugo CGO_ENABLED=1 -tags=extended
+`), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "hugo.env"), []byte("CGO_ENABLED=0\nCGO_CFLAGS=-O2 -g\n-tags=x -trimpath\n"), os.ModePerm))
+
+	pkgs, err := ListPinnedMainPackages(logging.Discard, modDir, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(pkgs))
+	testutil.Equals(t, []string{"CGO_ENABLED=1"}, pkgs[0].BuildEnvVars)
+	testutil.Equals(t, []string{"-tags=extended"}, pkgs[0].BuildFlags)
+	testutil.Equals(t, []string{"CGO_CFLAGS=-O2 -g"}, pkgs[0].EnvFileBuildEnvVars)
+	testutil.Equals(t, []string{"-trimpath"}, pkgs[0].EnvFileBuildFlags)
+	testutil.Equals(t, "tools/hugo", pkgs[0].WorkDir)
+
+	testutil.Ok(t, GenHelpers(modDir, "v0.9", true, pkgs))
+	b, err := os.ReadFile(filepath.Join(modDir, "Variables.mk"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, strings.Contains(string(b), "\t@cd $(BINGO_DIR)/tools/hugo && GOWORK=off CGO_CFLAGS=\"-O2 -g\" CGO_ENABLED=1 $(GO) build -mod=mod $(BINGO_BUILDVCS) -trimpath -tags=extended -modfile=$(abspath $(BINGO_DIR)/hugo.mod) "), string(b))
+
+	// Broken env file does not break listing, it's reported on install.
+	testutil.Ok(t, os.Remove(filepath.Join(modDir, "hugo.env")))
+	pkgs, err = ListPinnedMainPackages(logging.Discard, modDir, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(pkgs))
+	testutil.Equals(t, 0, len(pkgs[0].EnvFileBuildEnvVars))
+}
+
+func TestToolNames(t *testing.T) {
+	modDir := t.TempDir()
+	for _, f := range []string{"go.mod", "lint.mod", "lint.1.mod", "faillint.mod", "faillint.tmp.mod", "faillint.sum", "variables.env"} {
+		testutil.Ok(t, os.WriteFile(filepath.Join(modDir, f), nil, os.ModePerm))
+	}
+	names, err := ToolNames(modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"faillint", "lint"}, names)
+
+	names, err = ToolNames(filepath.Join(modDir, "not-existing"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{}, names)
+}
+
+func TestBinaryNameConflicts(t *testing.T) {
+	modDir := t.TempDir()
+	for f, content := range map[string]string{
+		// Named after the tool, conflicts with the additional package of the other tool.
+		"server.mod": "require github.com/x/server v1.0.0 // cmd/server\n",
+		"x.mod":      "// also: cmd/server\n\nrequire github.com/x/x v1.0.0 // cmd/x\n",
+		// Named explicitly.
+		"lint.mod": "require github.com/y/lint v1.0.0 // name=faillint\n",
+		// Versions of the same tool don't conflict.
+		"faillint.mod":   "require github.com/fatih/faillint v1.5.0\n",
+		"faillint.1.mod": "require github.com/fatih/faillint v1.4.0\n",
+		"other.mod":      "require github.com/x/other v1.0.0\n",
+	} {
+		testutil.Ok(t, os.WriteFile(filepath.Join(modDir, f), []byte("module _\n\ngo 1.14\n\n"+content), os.ModePerm))
+	}
+
+	conflicts, err := BinaryNameConflicts(modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []BinaryNameConflict{
+		{BinaryName: "faillint", Tools: []string{"faillint", "lint"}},
+		{BinaryName: "server", Tools: []string{"server", "x"}},
+	}, conflicts)
+}
+
+func TestPackageRenderables_SideBySide(t *testing.T) {
+	pkgs := PackageRenderables{
+		{Name: "migrate-v2", Versions: []PackageVersionRenderable{{Version: "v2.1.0", ModFile: "migrate-v2.mod"}}},
+		{Name: "migrate-tool", Versions: []PackageVersionRenderable{{Version: "v1.0.0", ModFile: "migrate-tool.mod"}}},
+		{Name: "migrate-v1", Versions: []PackageVersionRenderable{{Version: "v1.4.0", ModFile: "migrate-v1.mod"}}},
+		{Name: "faillint-v1", Versions: []PackageVersionRenderable{{Version: "v2.0.0", ModFile: "faillint-v1.mod"}}},
+	}
+	SortRenderables(pkgs)
+	// Side by side variants are listed together, as if they were named after the tool.
+	testutil.Equals(t, []string{"faillint-v1", "migrate-v1", "migrate-v2", "migrate-tool"}, []string{pkgs[0].Name, pkgs[1].Name, pkgs[2].Name, pkgs[3].Name})
+
+	entries, err := pkgs.ListEntries("migrate", "/gobin")
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(entries))
+	testutil.Equals(t, []string{"migrate-v1", "migrate-v2"}, []string{entries[0].Name, entries[1].Name})
+
+	entries, err = pkgs.ListEntries("migrate-v2", "/gobin")
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(entries))
+
+	// Name suffix not matching the pinned major version is just a name.
+	_, err = pkgs.ListEntries("faillint", "/gobin")
+	testutil.NotOk(t, err)
+}
+
+func TestPackage_BuildKey(t *testing.T) {
+	pkg := Package{
+		Module:     module.Version{Path: "github.com/x/tool", Version: "v1.0.0"},
+		RelPath:    "cmd/tool",
+		BuildEnvs:  envars.EnvSlice{"CGO_ENABLED=0", "GOOS=linux"},
+		BuildFlags: []string{"-tags=yolo", "-ldflags=-s"},
+	}
+	key := pkg.BuildKey()
+	testutil.Equals(t, 64, len(key))
+
+	// Order of envs does not matter.
+	same := pkg
+	same.BuildEnvs = envars.EnvSlice{"GOOS=linux", "CGO_ENABLED=0"}
+	testutil.Equals(t, key, same.BuildKey())
+
+	// Module root package can be written both ways.
+	root := Package{Module: pkg.Module}
+	rootDot := Package{Module: pkg.Module, RelPath: "."}
+	testutil.Equals(t, root.BuildKey(), rootDot.BuildKey())
+
+	for _, changed := range []func(p *Package){
+		func(p *Package) { p.Module.Version = "v1.0.1" },
+		func(p *Package) { p.RelPath = "cmd/tool2" },
+		func(p *Package) { p.Name = "tool2" },
+		func(p *Package) { p.PostInstall = "upx {{.Bin}}" },
+		func(p *Package) { p.BuildEnvs = envars.EnvSlice{"CGO_ENABLED=1", "GOOS=linux"} },
+		func(p *Package) { p.BuildFlags = []string{"-ldflags=-s", "-tags=yolo"} },
+		func(p *Package) { p.BuildFlags = []string{"-tags=yolo -ldflags=-s"} },
+	} {
+		p := pkg
+		p.BuildEnvs = append(envars.EnvSlice(nil), pkg.BuildEnvs...)
+		changed(&p)
+		testutil.Assert(t, key != p.BuildKey(), "expected different build key for %+v", p)
+	}
+}
+
+func TestPackage_ModuleFetchEnvs(t *testing.T) {
+	modFilePath := filepath.Join(t.TempDir(), "internal.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require internal.example.com/tool v1.0.0 // GOPROXY=https://proxy.internal.example.com,direct GONOSUMDB=internal.example.com CGO_ENABLED=0 GOFLAGS=-mod=mod
+`), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+	testutil.Ok(t, mf.Validate())
+
+	pkg := mf.DirectPackage()
+	testutil.Equals(t, envars.EnvSlice{"GOPROXY=https://proxy.internal.example.com,direct", "GONOSUMDB=internal.example.com", "CGO_ENABLED=0", "GOFLAGS=-mod=mod"}, pkg.BuildEnvs)
+	testutil.Equals(t, envars.EnvSlice{"GOPROXY=https://proxy.internal.example.com,direct", "GONOSUMDB=internal.example.com", "GOFLAGS=-mod=mod"}, pkg.ModuleFetchEnvs())
+	testutil.Equals(t, envars.EnvSlice(nil), Package{BuildEnvs: envars.EnvSlice{"CGO_ENABLED=0"}}.ModuleFetchEnvs())
+}
+
+func TestModFile_SumDBDisabled(t *testing.T) {
+	t.Setenv("GONOSUMDB", "")
+	t.Setenv("GOPRIVATE", "private.example.com")
+	_ = os.Unsetenv("GONOSUMDB")
+
+	modFilePath := filepath.Join(t.TempDir(), "internal.mod")
+	ok, err := IsSumDBDisabledInFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, ok)
+
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// bingo:no_sumdb
+
+require internal.example.com/tool v1.0.0
+`), os.ModePerm))
+	ok, err = IsSumDBDisabledInFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, true, ok)
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+	testutil.Equals(t, true, mf.IsSumDBDisabled())
+	testutil.Equals(t, false, mf.IsDirectivesAutoFetchDisabled())
+
+	// Directive is kept on edits.
+	testutil.Ok(t, mf.SetComment("internal tool"))
+	testutil.Ok(t, mf.Reload())
+	testutil.Equals(t, true, mf.IsSumDBDisabled())
+
+	// GONOSUMDB defaults to GOPRIVATE, so it has to be kept.
+	testutil.Equals(t, envars.EnvSlice{"CGO_ENABLED=0", "GONOSUMDB=private.example.com,internal.example.com"}, SumDBDisabledEnvs("internal.example.com", envars.EnvSlice{"CGO_ENABLED=0"}))
+	testutil.Equals(t, envars.EnvSlice{"GONOSUMDB=other.example.com,internal.example.com"}, SumDBDisabledEnvs("internal.example.com", envars.EnvSlice{"GONOSUMDB=other.example.com"}))
+	t.Setenv("GONOSUMDB", "")
+	testutil.Equals(t, envars.EnvSlice{"GONOSUMDB=internal.example.com"}, SumDBDisabledEnvs("internal.example.com", nil))
+}
+
+func TestModFile_GoVersionPinned(t *testing.T) {
+	modFilePath := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.18
+
+require github.com/x/tool v1.0.0
+`), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, mf.IsGoVersionPinned())
+	testutil.Ok(t, mf.SetGoVersionPinned(true))
+	testutil.Equals(t, true, mf.IsGoVersionPinned())
+
+	// Directive is kept on edits.
+	testutil.Ok(t, mf.SetDirectRequire(Package{Module: module.Version{Path: "github.com/x/tool", Version: "v1.1.0"}}))
+	testutil.Ok(t, mf.Close())
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.18
+
+// bingo:pin_go
+
+require github.com/x/tool v1.1.0
+`, modFilePath)
+
+	mf, err = OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+	testutil.Equals(t, true, mf.IsGoVersionPinned())
+	testutil.Equals(t, "1.18", mf.GoVersion())
+
+	testutil.Ok(t, mf.SetGoVersionPinned(false))
+	testutil.Ok(t, mf.Reload())
+	testutil.Equals(t, false, mf.IsGoVersionPinned())
+}
+
+func TestModFile_Rename(t *testing.T) {
+	modDir := t.TempDir()
+	modFilePath := filepath.Join(modDir, "server.1.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client name=x-client
+
+require github.com/x/server v1.0.0 // cmd/server name=x-server CGO_ENABLED=0
+`), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(SumFilePath(modFilePath), []byte("sum"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "taken.1.mod"), []byte("module _\n"), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.NotOk(t, mf.Rename("taken"))
+	testutil.NotOk(t, mf.Rename("server"))
+	testutil.NotOk(t, mf.Rename("x/y"))
+
+	testutil.Ok(t, mf.Rename("srv"))
+	newModFilePath := filepath.Join(modDir, "srv.1.mod")
+	testutil.Equals(t, newModFilePath, mf.Filepath())
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client name=x-client
+
+require github.com/x/server v1.0.0 // cmd/server CGO_ENABLED=0
+`, newModFilePath)
+	expectContent(t, "sum", SumFilePath(newModFilePath))
+
+	_, err = os.Stat(modFilePath)
+	testutil.Assert(t, os.IsNotExist(err))
+	_, err = os.Stat(SumFilePath(modFilePath))
+	testutil.Assert(t, os.IsNotExist(err))
+
+	names, err := BinaryNames("srv", mf.DirectPackages())
+	testutil.Ok(t, err)
+	testutil.Equals(t, []string{"srv", "x-client"}, names)
+}
+
+func TestModFile_SetModule(t *testing.T) {
+	modFilePath := filepath.Join(t.TempDir(), "server.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client name=x-client
+
+require github.com/old/server v1.0.0 // cmd/server CGO_ENABLED=0 -tags=netgo
+`), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.NotOk(t, mf.SetModule("not a path", ""))
+	testutil.NotOk(t, mf.SetModule("github.com/new/server/", "v1.1.0"))
+
+	testutil.Ok(t, mf.SetModule("github.com/new/server", ""))
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client name=x-client
+
+require github.com/new/server v1.0.0 // cmd/server CGO_ENABLED=0 -tags=netgo
+`, modFilePath)
+
+	testutil.Ok(t, mf.SetModule("github.com/new/server/v2", "v2.0.0"))
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client name=x-client
+
+require github.com/new/server/v2 v2.0.0 // cmd/server CGO_ENABLED=0 -tags=netgo
+`, modFilePath)
+
+	testutil.Ok(t, mf.Reload())
+	pkgs := mf.DirectPackages()
+	testutil.Equals(t, 2, len(pkgs))
+	testutil.Equals(t, "github.com/new/server/v2/cmd/client@v2.0.0", pkgs[1].String())
+	testutil.Equals(t, "x-client", pkgs[1].Name)
+}
+
+func TestModFile_Comment(t *testing.T) {
+	modDir := t.TempDir()
+	modFilePath := filepath.Join(modDir, "server.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// bingo:comment used by lint target; not a bingo:no_directive_fetch
+// also: cmd/client
+
+require github.com/x/server v1.0.0 // cmd/server CGO_ENABLED=0
+`), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "used by lint target; not a bingo:no_directive_fetch", mf.Comment())
+	testutil.Equals(t, false, mf.IsDirectivesAutoFetchDisabled())
+	testutil.Equals(t, []Package{
+		{Module: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}, RelPath: "cmd/server", BuildEnvs: []string{"CGO_ENABLED=0"}},
+		{Module: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}, RelPath: "cmd/client"},
+	}, mf.DirectPackages())
+
+	testutil.NotOk(t, mf.SetComment("multi\nline"))
+	testutil.Ok(t, mf.SetComment("server for e2e tests"))
+	testutil.Ok(t, mf.SetDirectRequire(Package{Module: module.Version{Path: "github.com/x/server", Version: "v1.1.0"}, RelPath: "cmd/server"}))
+	testutil.Equals(t, "server for e2e tests", mf.Comment())
+	testutil.Ok(t, mf.Close())
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client
+
+require github.com/x/server v1.1.0 // cmd/server
+
+// bingo:comment server for e2e tests
+`, modFilePath)
+
+	pkgs, err := ListPinnedMainPackages(logging.Discard, modDir, false)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 2, len(pkgs))
+	testutil.Equals(t, "server for e2e tests", pkgs[0].Comment)
+	testutil.Equals(t, "server for e2e tests", pkgs[1].Comment)
+
+	b := bytes.Buffer{}
+	testutil.Ok(t, pkgs.PrintTab("", &b))
+	testutil.Equals(t, true, strings.HasSuffix(b.String(), "\tserver for e2e tests\n"))
+
+	mf, err = OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.Ok(t, mf.SetComment(""))
+	testutil.Equals(t, "", mf.Comment())
+	testutil.Ok(t, mf.Close())
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client
+
+require github.com/x/server v1.1.0 // cmd/server
+`, modFilePath)
+}
+
+func TestModFile_PostInstall(t *testing.T) {
+	modFilePath := filepath.Join(t.TempDir(), "server.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// bingo:post-install strip {{.Bin}} # not a bingo:no_directive_fetch
+// also: cmd/client
+
+require github.com/x/server v1.0.0 // cmd/server
+`), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, false, mf.IsDirectivesAutoFetchDisabled())
+	testutil.Equals(t, []Package{
+		{Module: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}, RelPath: "cmd/server", PostInstall: "strip {{.Bin}} # not a bingo:no_directive_fetch"},
+		{Module: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}, RelPath: "cmd/client", PostInstall: "strip {{.Bin}} # not a bingo:no_directive_fetch"},
+	}, mf.DirectPackages())
+
+	p := *mf.DirectPackage()
+	p.PostInstall = "upx --best {{.Bin}}"
+	testutil.Ok(t, mf.SetDirectRequire(p))
+	p.PostInstall = "multi\nline"
+	testutil.NotOk(t, mf.SetDirectRequire(p))
+	testutil.Ok(t, mf.Close())
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client
+
+require github.com/x/server v1.0.0 // cmd/server
+
+// bingo:post-install upx --best {{.Bin}}
+`, modFilePath)
+
+	mf, err = OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.Equals(t, "upx --best {{.Bin}}", mf.DirectPackage().PostInstall)
+	p = *mf.DirectPackage()
+	p.PostInstall = ""
+	testutil.Ok(t, mf.SetDirectRequire(p))
+	testutil.Ok(t, mf.Close())
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client
+
+require github.com/x/server v1.0.0 // cmd/server
+`, modFilePath)
+
+	// Malformed command is reported, so it's not silently dropped.
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// bingo:post-install strip {{ .Bin }}
+
+require github.com/x/server v1.0.0 // cmd/server
+`), os.ModePerm))
+	mf, err = OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	testutil.NotOk(t, mf.Validate())
+	testutil.Ok(t, mf.Close())
+}
+
+func TestModFile_LocalReplaces(t *testing.T) {
+	modFilePath := filepath.Join(t.TempDir(), "foo.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+replace github.com/x/dep => github.com/y/dep v1.0.0
+
+require github.com/x/tool v1.0.0 // cmd/foo
+`), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.Equals(t, 0, len(mf.LocalReplaces()))
+	testutil.Equals(t, false, mf.IsLocallyReplaced("github.com/x/dep"))
+
+	testutil.NotOk(t, mf.SetLocalReplace("github.com/x/tool", ""))
+	testutil.Ok(t, mf.SetLocalReplace("github.com/x/tool", "tool"))
+	testutil.Ok(t, mf.SetLocalReplace("github.com/x/dep", "../../dep"))
+	testutil.Equals(t, []mod.ReplaceDirective{
+		{Old: module.Version{Path: "github.com/x/tool"}, New: module.Version{Path: "./tool"}},
+		{Old: module.Version{Path: "github.com/x/dep"}, New: module.Version{Path: "../../dep"}},
+	}, mf.LocalReplaces())
+	testutil.Equals(t, true, mf.IsLocallyReplaced("github.com/x/tool"))
+	testutil.Equals(t, false, mf.IsLocallyReplaced("github.com/x"))
+
+	testutil.Ok(t, mf.SetLocalReplace("github.com/x/tool", "/src/tool"))
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v1.0.0 // cmd/foo
+
+replace github.com/x/dep => ../../dep
+
+replace github.com/x/tool => /src/tool
+`, modFilePath)
+}
+
+func TestModFile_Branch(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v0.0.0-20200519204825-abc123456789 // cmd/tool branch=main
+`), os.ModePerm))
+
+	mf, err := OpenModFile(testFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.Equals(t, "main", mf.DirectPackage().Branch)
+
+	// Version pin drops the branch.
+	p := *mf.DirectPackage()
+	p.Module.Version = "v1.0.0"
+	p.Branch = ""
+	testutil.Ok(t, mf.SetDirectRequire(p))
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v1.0.0 // cmd/tool
+`, testFile)
+}
+
+func TestModFile_Latest(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v1.0.0 // cmd/tool
+`), os.ModePerm))
+
+	mf, err := OpenModFile(testFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+	testutil.Equals(t, false, mf.DirectPackage().Latest)
+
+	p := *mf.DirectPackage()
+	p.Module.Version = "v1.1.0"
+	p.Latest = true
+	testutil.Ok(t, mf.SetDirectRequire(p))
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v1.1.0 // cmd/tool
+
+// bingo:latest
+`, testFile)
+	testutil.Ok(t, mf.Reload())
+	testutil.Equals(t, true, mf.DirectPackage().Latest)
+
+	// Version pin drops the marker.
+	p.Module.Version = "v1.0.0"
+	p.Latest = false
+	testutil.Ok(t, mf.SetDirectRequire(p))
+	testutil.Ok(t, mf.Reload())
+	testutil.Equals(t, false, mf.DirectPackage().Latest)
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v1.0.0 // cmd/tool
+`, testFile)
+}
+
+func TestModFile_QuotedBuildFlags(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tool.mod")
+	content := `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client -ldflags="-X main.name=\"my client\""
+
+require github.com/x/tool v1.0.0 // cmd/tool CGO_CFLAGS="-O2 -g" -tags=a,b -ldflags="-X main.version=1.2.3 -s" -trimpath
+`
+	testutil.Ok(t, os.WriteFile(testFile, []byte(content), os.ModePerm))
+
+	mf, err := OpenModFile(testFile)
+	testutil.Ok(t, err)
+	testutil.Ok(t, mf.Validate())
+	pkgs := mf.DirectPackages()
+	testutil.Equals(t, 2, len(pkgs))
+	testutil.Equals(t, []string{"CGO_CFLAGS=-O2 -g"}, []string(pkgs[0].BuildEnvs))
+	testutil.Equals(t, []string{"-tags=a,b", "-ldflags=-X main.version=1.2.3 -s", "-trimpath"}, pkgs[0].BuildFlags)
+	testutil.Equals(t, []string{`-ldflags=-X main.name="my client"`}, pkgs[1].BuildFlags)
+
+	// Rewrite keeps quotes, so flags are parsed back the same.
+	testutil.Ok(t, mf.SetDirectPackages(pkgs...))
+	testutil.Ok(t, mf.Close())
+	expectContent(t, content, testFile)
+
+	mf, err = OpenModFile(testFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+	testutil.Equals(t, pkgs, mf.DirectPackages())
+
+	// Unquoted flags stay unquoted.
+	testutil.Ok(t, mf.SetBuildFlags([]string{"-tags=yolo", "-trimpath"}))
+	testutil.Equals(t, "cmd/tool CGO_CFLAGS=\"-O2 -g\" -tags=yolo -trimpath", mf.RequireDirectives()[0].ExtraSuffixComment)
+}
+
+func TestModFile_ParsedGoVersion(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(testFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.21
+
+require github.com/x/tool v1.0.0
+`), os.ModePerm))
+
+	mf, err := OpenModFile(testFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.Equals(t, "1.21", mf.GoVersion())
+	v, err := mf.ParsedGoVersion()
+	testutil.Ok(t, err)
+	testutil.Assert(t, version.AtLeast(v, version.Go118), "expected go 1.21 to be newer than 1.18")
+	testutil.Assert(t, !version.AtLeast(v, semver.MustParse("1.22")), "expected go 1.21 to be older than 1.22")
+
+	testutil.Ok(t, mf.SetGoVersion("1.22rc1"))
+	v, err = mf.ParsedGoVersion()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "1.22.0-rc1", v.String())
+}
+
+func TestPackage_RequireLine(t *testing.T) {
+	for _, tcase := range []struct {
+		pkg      Package
+		expected string
+	}{
+		{
+			pkg:      Package{Module: module.Version{Path: "github.com/fatih/faillint", Version: "v1.5.0"}},
+			expected: "github.com/fatih/faillint v1.5.0",
+		},
+		{
+			pkg: Package{
+				Module:     module.Version{Path: "github.com/prometheus/prometheus", Version: "v2.4.3+incompatible"},
+				RelPath:    "cmd/prometheus",
+				BuildEnvs:  []string{"CGO_ENABLED=1", "GOWASM=somefeature"},
+				BuildFlags: []string{"-tags=yolo,linux"},
+			},
+			expected: "github.com/prometheus/prometheus v2.4.3+incompatible // cmd/prometheus CGO_ENABLED=1 GOWASM=somefeature -tags=yolo,linux",
+		},
+		{
+			// No relpath, with build attributes.
+			pkg: Package{
+				Module:     module.Version{Path: "github.com/prometheus/prometheus", Version: "v2.4.3+incompatible"},
+				BuildEnvs:  []string{"CGO_ENABLED=1", "GOWASM=somefeature"},
+				BuildFlags: []string{"-tags=yolo,linux"},
+			},
+			expected: "github.com/prometheus/prometheus v2.4.3+incompatible // CGO_ENABLED=1 GOWASM=somefeature -tags=yolo,linux",
+		},
+		{
+			pkg: Package{
+				Module:  module.Version{Path: "github.com/x/tool", Version: "v0.0.0-20200519204825-abc123456789"},
+				RelPath: "cmd/tool", Name: "tool2", EnvFile: "tool.env", Branch: "main",
+				BuildEnvs: []string{"CGO_ENABLED=0"},
+			},
+			expected: "github.com/x/tool v0.0.0-20200519204825-abc123456789 // cmd/tool name=tool2 env-file=tool.env branch=main CGO_ENABLED=0",
+		},
+		{
+			pkg: Package{
+				Module:  module.Version{Path: "github.com/x/tool", Version: "v1.0.0"},
+				RelPath: "cmd/tool", WorkDir: "tool", BuildFlags: []string{"-pgo=default.pgo"},
+			},
+			expected: "github.com/x/tool v1.0.0 // cmd/tool workdir=tool -pgo=default.pgo",
+		},
+		{
+			pkg: Package{
+				Module:  module.Version{Path: "github.com/x/tool", Version: "v0.0.0-20240105120000-abc123456789"},
+				RelPath: "cmd/tool", Tag: "release-2024-01",
+			},
+			expected: "github.com/x/tool v0.0.0-20240105120000-abc123456789 // cmd/tool tag=release-2024-01",
+		},
+		{
+			pkg: Package{
+				Module:  module.Version{Path: "github.com/x/tool", Version: "v1.0.0"},
+				RelPath: "cmd/tool", VerifyCmd: "{{.Bin}} --version", VerifyOutput: "{{.Version}}",
+			},
+			expected: `github.com/x/tool v1.0.0 // cmd/tool verify-cmd="{{.Bin}} --version" verify-output={{.Version}}`,
+		},
+	} {
+		t.Run(tcase.expected, func(t *testing.T) {
+			testutil.Equals(t, tcase.expected, tcase.pkg.RequireLine())
+
+			// Identical to what module file has after setting the package.
+			testFile := filepath.Join(t.TempDir(), "tool.mod")
+			testutil.Ok(t, os.WriteFile(testFile, []byte("module _\n\ngo 1.14\n"), os.ModePerm))
+			mf, err := OpenModFile(testFile)
+			testutil.Ok(t, err)
+			testutil.Ok(t, mf.SetDirectRequire(tcase.pkg))
+			testutil.Ok(t, mf.Close())
+			expectContent(t, "module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT\n\ngo 1.14\n\nrequire "+tcase.expected+"\n", testFile)
+
+			// Round trip.
+			pkg, err := ModDirectPackage(testFile)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.pkg, pkg)
+		})
+	}
 }
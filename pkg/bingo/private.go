@@ -0,0 +1,68 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
+)
+
+// PrivateModulesEnv is an environment variable with comma separated GOPRIVATE-style glob patterns (e.g. "github.com/myorg/*")
+// of tools fetched from private servers. See PrivateModuleEnvs.
+const PrivateModulesEnv = "BINGO_PRIVATE"
+
+// PrivateModuleEnvs returns envs configured to fetch the given module or package path directly from its (private) server,
+// if it matches any of the given GOPRIVATE-style patterns (see module.MatchPrefixPatterns). Matching patterns are added
+// to GOPRIVATE and, if set explicitly (in envs or the environment), to GONOPROXY and GONOSUMDB, so go neither asks the proxy
+// nor the checksum database about it. Go authenticates to such servers with credentials from the .netrc file (NETRC or
+// ~/.netrc), so NETRC, if set, has to point to existing file. Envs are returned as they are if path matches no pattern.
+func PrivateModuleEnvs(path, patterns string, envs envars.EnvSlice) (envars.EnvSlice, error) {
+	var matching []string
+	for _, p := range strings.Split(patterns, ",") {
+		if p = strings.TrimSpace(p); p != "" && module.MatchPrefixPatterns(p, path) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		return envs, nil
+	}
+
+	netrc, ok := envs.Lookup("NETRC")
+	if !ok {
+		netrc = os.Getenv("NETRC")
+	}
+	if netrc != "" {
+		if _, err := os.Stat(netrc); err != nil {
+			return nil, errors.Wrapf(err, "NETRC file with credentials for private %v", path)
+		}
+	}
+
+	ret := envars.MergeEnvSlices(envs, withPatterns(envs, "GOPRIVATE", matching))
+	for _, key := range []string{"GONOPROXY", "GONOSUMDB"} {
+		if _, ok := envs.Lookup(key); !ok {
+			if _, ok := os.LookupEnv(key); !ok {
+				// Defaults to GOPRIVATE.
+				continue
+			}
+		}
+		ret = envars.MergeEnvSlices(ret, withPatterns(envs, key, matching))
+	}
+	return ret, nil
+}
+
+// withPatterns returns key=value env with the given patterns appended to ones from envs or the environment.
+func withPatterns(envs envars.EnvSlice, key string, patterns []string) string {
+	existing, ok := envs.Lookup(key)
+	if !ok {
+		existing = os.Getenv(key)
+	}
+	if existing != "" {
+		patterns = append([]string{existing}, patterns...)
+	}
+	return key + "=" + strings.Join(patterns, ",")
+}
@@ -0,0 +1,52 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestPrivateModuleEnvs(t *testing.T) {
+	for _, k := range []string{"GOPRIVATE", "GONOPROXY", "GONOSUMDB", "NETRC"} {
+		t.Setenv(k, "")
+		_ = os.Unsetenv(k)
+	}
+	const patterns = "github.com/myorg/*, internal.example.com"
+
+	envs, err := PrivateModuleEnvs("github.com/fatih/faillint", patterns, envars.EnvSlice{"CGO_ENABLED=0"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, envars.EnvSlice{"CGO_ENABLED=0"}, envs)
+
+	envs, err = PrivateModuleEnvs("github.com/myorg/tool/cmd/tool", patterns, envars.EnvSlice{"CGO_ENABLED=0"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, envars.EnvSlice{"CGO_ENABLED=0", "GOPRIVATE=github.com/myorg/*"}, envs)
+
+	// Explicit GONOSUMDB and GONOPROXY do not default to GOPRIVATE, so patterns are added to them too.
+	t.Setenv("GOPRIVATE", "other.example.com")
+	t.Setenv("GONOSUMDB", "sum.example.com")
+	envs, err = PrivateModuleEnvs("internal.example.com/tool", patterns, envars.EnvSlice{"GONOPROXY=proxy.example.com"})
+	testutil.Ok(t, err)
+	testutil.Equals(t, envars.EnvSlice{
+		"GONOPROXY=proxy.example.com,internal.example.com",
+		"GONOSUMDB=sum.example.com,internal.example.com",
+		"GOPRIVATE=other.example.com,internal.example.com",
+	}, envs)
+
+	// Tool fetched with credentials from missing NETRC file would fail with confusing 401 later.
+	t.Setenv("NETRC", filepath.Join(t.TempDir(), "missing"))
+	_, err = PrivateModuleEnvs("internal.example.com/tool", patterns, nil)
+	testutil.NotOk(t, err)
+	_, err = PrivateModuleEnvs("github.com/fatih/faillint", patterns, nil)
+	testutil.Ok(t, err)
+
+	netrc := filepath.Join(t.TempDir(), ".netrc")
+	testutil.Ok(t, os.WriteFile(netrc, []byte("machine internal.example.com login bot password secret\n"), 0600))
+	_, err = PrivateModuleEnvs("internal.example.com/tool", patterns, envars.EnvSlice{"NETRC=" + netrc})
+	testutil.Ok(t, err)
+}
@@ -0,0 +1,35 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+// Phases of getting the tool reported with Progress.OnPhase, in order.
+const (
+	// PhaseResolve means version query is resolved and the module file is updated.
+	PhaseResolve = "resolve"
+	// PhaseDownload means the tool module and its dependencies are downloaded and the sum file is updated (see UpdateModFile).
+	PhaseDownload = "download"
+	// PhaseBuild means binaries are built (see Build).
+	PhaseBuild = "build"
+)
+
+// Progress receives structured progress events of Get, e.g. to render them in UI instead of parsing logs. Methods are called
+// from the goroutine calling Get, so they should not block for long.
+type Progress interface {
+	// OnStart is called when getting the tool starts.
+	OnStart(tool string)
+	// OnPhase is called when getting the tool enters the given phase (e.g. PhaseBuild). Phases are skipped if not needed,
+	// e.g. PhaseBuild with GetOptions.NoBuild.
+	OnPhase(tool, phase string)
+	// OnDone is called once getting the tool is finished, with nil error on success.
+	OnDone(tool string, err error)
+}
+
+// NopProgress is Progress that ignores all events. It can be embedded to implement only some of the methods.
+type NopProgress struct{}
+
+func (NopProgress) OnStart(string) {}
+
+func (NopProgress) OnPhase(string, string) {}
+
+func (NopProgress) OnDone(string, error) {}
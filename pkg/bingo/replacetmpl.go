@@ -0,0 +1,89 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// ReplaceTemplateFileName is a name of the optional file in the module directory with replace directives (in go.mod syntax)
+// shared by all tools, e.g. the same long replace block many tools from one ecosystem need. See ReadReplaceTemplate.
+const ReplaceTemplateFileName = "replace.tmpl"
+
+// ReplaceTemplateDirective records module paths of replace directives merged from ReplaceTemplateFileName, e.g.
+// "bingo:replace_tmpl k8s.io/klog". See ModFile.SetReplaceTemplate.
+const ReplaceTemplateDirective = "bingo:replace_tmpl"
+
+// ReadReplaceTemplate returns replace directives from ReplaceTemplateFileName in modDir or nil if there is no such file.
+// The file can have only replace directives and comments.
+func ReadReplaceTemplate(modDir string) (_ []mod.ReplaceDirective, err error) {
+	tmplFile := filepath.Join(modDir, ReplaceTemplateFileName)
+	f, err := mod.OpenFileForRead(tmplFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "parse %v", tmplFile)
+	}
+	defer errcapture.Do(&err, f.Close, "close")
+
+	if m, _ := f.Module(); m != "" || f.GoVersion() != "" || f.Toolchain() != "" ||
+		len(f.RequireDirectives()) > 0 || len(f.ExcludeDirectives()) > 0 || len(f.RetractDirectives()) > 0 {
+		return nil, errors.Newf("%v can have only replace directives", tmplFile)
+	}
+	return f.ReplaceDirectives(), nil
+}
+
+// SetReplaceTemplate merges replace directives of the template (see ReadReplaceTemplate) into the module file. Template
+// directives take precedence over existing ones (e.g. auto-fetched from the tool module or local replaces) replacing the
+// same module path. Module paths merged from the template are recorded with ReplaceTemplateDirective, so directives
+// removed from the template are removed from the module file on the next merge too. Nil template removes all of them.
+func (mf *ModFile) SetReplaceTemplate(tmpl []mod.ReplaceDirective) error {
+	merged := mf.replaceTemplatePaths()
+	if len(tmpl) == 0 && len(merged) == 0 {
+		// Nothing to do, don't reformat the file.
+		return nil
+	}
+
+	replaces := mf.ReplaceDirectives()
+	for _, p := range merged {
+		replaces = WithoutReplace(replaces, p)
+	}
+	paths := make([]string, 0, len(tmpl))
+	for _, t := range tmpl {
+		replaces = WithoutReplace(replaces, t.Old.Path)
+		paths = append(paths, t.Old.Path)
+	}
+	// Template ones first, so the order does not depend on what was merged before.
+	if err := mf.SetReplaceDirectives(append(append([]mod.ReplaceDirective{}, tmpl...), replaces...)...); err != nil {
+		return err
+	}
+	return mf.writeDirective(ReplaceTemplateDirective, strings.Join(paths, " "))
+}
+
+func (mf *ModFile) replaceTemplatePaths() []string {
+	for _, c := range mf.Comments() {
+		if strings.HasPrefix(c, ReplaceTemplateDirective) {
+			return strings.Fields(strings.TrimPrefix(c, ReplaceTemplateDirective))
+		}
+	}
+	return nil
+}
+
+// WithoutReplace returns replace directives without the ones replacing (any version of) the given module.
+func WithoutReplace(replaces []mod.ReplaceDirective, modulePath string) []mod.ReplaceDirective {
+	ret := make([]mod.ReplaceDirective, 0, len(replaces))
+	for _, r := range replaces {
+		if r.Old.Path != modulePath {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,84 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestReadReplaceTemplate(t *testing.T) {
+	modDir := t.TempDir()
+	tmpl, err := ReadReplaceTemplate(modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, 0, len(tmpl))
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, ReplaceTemplateFileName), []byte(`// Shared by all Prometheus ecosystem tools.
+replace (
+	k8s.io/klog => github.com/simonpasquier/klog-gokit v0.1.0
+	github.com/x/fork => ../fork
+)
+`), os.ModePerm))
+	tmpl, err = ReadReplaceTemplate(modDir)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []mod.ReplaceDirective{
+		{Old: module.Version{Path: "k8s.io/klog"}, New: module.Version{Path: "github.com/simonpasquier/klog-gokit", Version: "v0.1.0"}},
+		{Old: module.Version{Path: "github.com/x/fork"}, New: module.Version{Path: "../fork"}},
+	}, tmpl)
+
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, ReplaceTemplateFileName), []byte("require github.com/x/tool v1.0.0\n"), os.ModePerm))
+	_, err = ReadReplaceTemplate(modDir)
+	testutil.NotOk(t, err)
+}
+
+func TestModFile_SetReplaceTemplate(t *testing.T) {
+	modFilePath := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(modFilePath, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+replace (
+	github.com/x/dep => github.com/y/dep v1.0.0
+	k8s.io/klog v1.0.0 => k8s.io/klog v0.9.0
+)
+
+require github.com/x/tool v1.0.0
+`), os.ModePerm))
+
+	mf, err := OpenModFile(modFilePath)
+	testutil.Ok(t, err)
+
+	tmpl := []mod.ReplaceDirective{
+		{Old: module.Version{Path: "k8s.io/klog"}, New: module.Version{Path: "github.com/simonpasquier/klog-gokit", Version: "v0.1.0"}},
+		{Old: module.Version{Path: "github.com/x/fork"}, New: module.Version{Path: "../fork"}},
+	}
+	expected := []mod.ReplaceDirective{tmpl[0], tmpl[1], {Old: module.Version{Path: "github.com/x/dep"}, New: module.Version{Path: "github.com/y/dep", Version: "v1.0.0"}}}
+	testutil.Ok(t, mf.SetReplaceTemplate(tmpl))
+	testutil.Equals(t, expected, mf.ReplaceDirectives())
+
+	// Merging again (e.g. on the next bingo get) changes nothing.
+	testutil.Ok(t, mf.SetReplaceTemplate(tmpl))
+	testutil.Ok(t, mf.Reload())
+	testutil.Equals(t, expected, mf.ReplaceDirectives())
+
+	// Directives removed from the template are removed from the module file.
+	testutil.Ok(t, mf.SetReplaceTemplate(tmpl[1:]))
+	testutil.Equals(t, expected[1:], mf.ReplaceDirectives())
+	testutil.Ok(t, mf.SetReplaceTemplate(nil))
+	testutil.Equals(t, expected[2:], mf.ReplaceDirectives())
+	testutil.Ok(t, mf.Close())
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+require github.com/x/tool v1.0.0
+
+replace github.com/x/dep => github.com/y/dep v1.0.0
+`, modFilePath)
+}
@@ -11,8 +11,19 @@ var (
 # All tools are designed to be build inside $GOBIN.
 BINGO_DIR := $(dir $(lastword $(MAKEFILE_LIST)))
 GOPATH ?= $(shell go env GOPATH)
+{{- if .OutputDir }}
+GOBIN  := $(abspath $(BINGO_DIR)/{{ .OutputDir }})
+{{- else }}
 GOBIN  ?= $(firstword $(subst :, ,${GOPATH}))/bin
+{{- end }}
 GO     ?= $(shell which go)
+{{- if .Reproducible }}
+
+# Reproducible build flags bingo get builds with (see --reproducible), so binaries built here match checksums recorded by
+# bingo. Tools that set them on their own (e.g. -trimpath=false) are built without them. -buildvcs needs Go 1.18+.
+BINGO_TRIMPATH := -trimpath
+BINGO_BUILDVCS := $(shell $(GO) help build 2>/dev/null | grep -q -- -buildvcs && echo -buildvcs=false)
+{{- end }}
 
 # Below generated variables ensure that every time a tool under each variable is invoked, the correct version
 # will be used; reinstalling only if needed.
@@ -27,27 +38,81 @@ GO     ?= $(shell which go)
 #	@$({{ with (index .MainPackages 0) }}{{ .EnvVarName }}{{ end }}) <flags/args..>
 #
 {{- range $p := .MainPackages }}
-{{ $p.EnvVarName }} :={{- range $p.Versions }} $(GOBIN)/{{ $p.Name }}-{{ .Version }}{{- end }}
+{{ $p.EnvVarName }} :={{- range $p.Versions }} $(GOBIN)/{{ $p.BinaryName }}-{{ .Version }}{{ $p.PlatformSuffix }}{{ $p.ExeSuffix }}{{- end }}
 $({{ $p.EnvVarName }}):{{- range $p.Versions }} $(BINGO_DIR)/{{ .ModFile }}{{- end }}
 	@# Install binary/ries using Go 1.14+ build command. This is using bwplotka/bingo-controlled, separate go module with pinned dependencies.
+{{- if $p.HasLdflagsTemplate }}
+	@# -ldflags with placeholders can be filled only by 'bingo get', so it's omitted here. Use 'bingo get' or tools.mk for stamped binaries.
+{{- end }}
 {{- range $p.Versions }}
-	@echo "(re)installing $(GOBIN)/{{ $p.Name }}-{{ .Version }}"
-	@cd $(BINGO_DIR) && GOWORK=off {{ range $p.BuildEnvVars }}{{ . }} {{ end }}$(GO) build {{ range $p.BuildFlags }}{{ . }} {{ end }}-mod=mod -modfile={{ .ModFile }} -o=$(GOBIN)/{{ $p.Name }}-{{ .Version }} "{{ $p.PackagePath }}"
+	@echo "(re)installing $(GOBIN)/{{ $p.BinaryName }}-{{ .Version }}{{ $p.PlatformSuffix }}{{ $p.ExeSuffix }}"
+	@cd $(BINGO_DIR){{ with $p.WorkDir }}/{{ . }}{{ end }} && GOWORK=off {{ range $p.MakefileBuildEnvVars }}{{ . }} {{ end }}$(GO) build -mod=mod {{ range $p.MakefileReproducibleFlags $.Reproducible }}{{ . }} {{ end }}{{ range $p.MakefileBuildFlags }}{{ . }} {{ end }}-modfile={{ if $p.WorkDir }}$(abspath $(BINGO_DIR)/{{ .ModFile }}){{ else }}{{ .ModFile }}{{ end }} -o=$(GOBIN)/{{ $p.BinaryName }}-{{ .Version }}{{ $p.PlatformSuffix }}{{ $p.ExeSuffix }} "{{ $p.PackagePath }}"
 {{- end }}
 {{ end}}
 `,
 		"env": `# Auto generated binary variables helper managed by https://github.com/bwplotka/bingo {{ .Version }}. DO NOT EDIT.
 # All tools are designed to be build inside $GOBIN.
 # Those variables will work only until 'bingo get' was invoked, or if tools were installed via Makefile's Variables.mk.
+{{- if .OutputDir }}
+GOBIN="{{ .ShellOutputDir }}"
+{{- else }}
 GOBIN=${GOBIN:=$(go env GOBIN)}
 
 if [ -z "$GOBIN" ]; then
 	GOBIN="$(go env GOPATH)/bin"
 fi
+{{- end }}
 
 {{range $p := .MainPackages }}
-{{ $p.EnvVarName }}="{{- range $i, $v := $p.Versions }}{{- if ne $i 0}} {{ end }}${GOBIN}/{{ $p.Name }}-{{ $v.Version }}{{- end }}"
+{{ $p.EnvVarName }}="{{- range $i, $v := $p.Versions }}{{- if ne $i 0}} {{ end }}${GOBIN}/{{ $p.BinaryName }}-{{ $v.Version }}{{ $p.PlatformSuffix }}{{ $p.ExeSuffix }}{{- end }}"
 {{ end}}
 `,
 	}
+
+	// libraryStubTemplate is used for optional Go file importing all tools, see GenHelpers.
+	libraryStubTemplate = `// Code generated by https://github.com/bwplotka/bingo {{ .Version }}. DO NOT EDIT.
+
+//go:build tools
+// +build tools
+
+// Regenerated on every 'bingo get'. Imports below make go.mod of the main module track pinned tools, so they can be run
+// with 'go run <package>', e.g. in go:generate directives. Run 'go generate' on this file to sync go.mod with versions
+// pinned by bingo.
+package {{ .GoPackage }}
+{{ range $p := .GoImports }}
+//go:generate go get {{ $p.String }}
+{{- end }}
+
+import (
+{{- range $p := .GoImports }}
+	_ "{{ $p.Path }}"
+{{- end }}
+)
+`
+
+	// makefileTargetsTemplate is used for optional Makefile with per-tool targets, see GenHelpers.
+	makefileTargetsTemplate = `# Auto generated binary variables and targets managed by https://github.com/bwplotka/bingo {{ .Version }}. DO NOT EDIT.
+# Regenerated on every 'bingo get'. Include it in your main Makefile instead of .bingo/Variables.mk, so e.g 'make <tool>'
+# ensures the pinned version of the tool is installed, using 'bingo get'.
+BINGO_DIR := $(dir $(lastword $(MAKEFILE_LIST))){{ .RelModDir }}
+GOPATH    ?= $(shell go env GOPATH)
+{{- if .OutputDir }}
+GOBIN     := $(abspath $(BINGO_DIR)/{{ .OutputDir }})
+{{- else }}
+GOBIN     ?= $(firstword $(subst :, ,${GOPATH}))/bin
+{{- end }}
+BINGO_CMD ?= bingo
+{{- range $p := .MainPackages }}
+
+{{ $p.EnvVarName }} :={{- range $p.Versions }} $(GOBIN)/{{ $p.BinaryName }}-{{ .Version }}{{ $p.PlatformSuffix }}{{ $p.ExeSuffix }}{{- end }}
+$({{ $p.EnvVarName }}):{{- range $p.Versions }} $(BINGO_DIR)/{{ .ModFile }}{{- end }}
+	@echo "(re)installing $({{ $p.EnvVarName }})"
+	@$(BINGO_CMD) get --moddir=$(BINGO_DIR) {{ $p.Name }}
+	@# Binary matching recorded checksum is not rebuilt, mark it as up to date with the module file.
+	@touch $@
+
+.PHONY: {{ $p.Name }}
+{{ $p.Name }}: $({{ $p.EnvVarName }})
+{{- end }}
+`
 )
@@ -0,0 +1,238 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/mod"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
+)
+
+// VendorDir is a directory within the module directory with sources of modules of vendored tools (see ModFile.IsVendored),
+// one <module path>@<version> directory per module version. It's not named "vendor", since go would treat it as vendor
+// directory of the fake root module and build every tool with -mod=vendor.
+const VendorDir = "_vendor"
+
+// vendorModuleDir returns slash separated path of the module version sources relative to the module directory.
+// Path and version are escaped the same way as in the module cache, so they are safe on case-insensitive file systems.
+func vendorModuleDir(m module.Version) (string, error) {
+	p, err := module.EscapePath(m.Path)
+	if err != nil {
+		return "", err
+	}
+	v, err := module.EscapeVersion(m.Version)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(VendorDir, p+"@"+v), nil
+}
+
+// isVendorReplace returns true if the replace directive points to sources in VendorDir.
+func isVendorReplace(r mod.ReplaceDirective) bool {
+	return r.New.Version == "" && strings.HasPrefix(r.New.Path, "./"+VendorDir+"/")
+}
+
+func (mf *ModFile) dropVendorReplaces() error {
+	replaces := []mod.ReplaceDirective{}
+	for _, r := range mf.ReplaceDirectives() {
+		if !isVendorReplace(r) {
+			replaces = append(replaces, r)
+		}
+	}
+	if len(replaces) == len(mf.ReplaceDirectives()) {
+		return nil
+	}
+	return mf.SetReplaceDirectives(replaces...)
+}
+
+// isVendorUpToDate returns true if every required module is replaced with its version sources in VendorDir, unless it's
+// replaced otherwise, so the tool can be built without network access.
+func (ic *installContext) isVendorUpToDate() bool {
+	vendored := map[string]string{}
+	others := map[string]struct{}{}
+	for _, r := range ic.modFile.ReplaceDirectives() {
+		if !isVendorReplace(r) {
+			others[r.Old.Path] = struct{}{}
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(ic.modDir, filepath.FromSlash(r.New.Path), "go.mod")); err != nil {
+			return false
+		}
+		vendored[r.Old.Path] = r.New.Path
+	}
+	if len(vendored) == 0 {
+		return false
+	}
+	for _, r := range ic.modFile.RequireDirectives() {
+		if _, ok := others[r.Module.Path]; ok {
+			continue
+		}
+		dir, err := vendorModuleDir(r.Module)
+		if err != nil || vendored[r.Module.Path] != "./"+dir {
+			return false
+		}
+	}
+	return true
+}
+
+// vendor copies sources of modules needed to build direct packages into VendorDir and replaces all modules of the build
+// list with them. Other modules of the build list get only their go.mod copied, since go needs it to load the module graph.
+// Modules replaced with local directories or other modules are left as they are.
+func (ic *installContext) vendor() error {
+	needed := map[string]struct{}{}
+	for _, pkg := range ic.pkgs {
+		listCtx := ic.r.With(ic.ctx, ic.modFile.Filepath(), ic.modDir, envars.MergeEnvSlices(ic.fetchEnvs, pkg.BuildEnvs...))
+		listArgs := append([]string{"-mod=mod"}, pkg.BuildFlags...)
+		out, err := listCtx.List(append(listArgs, "-deps", "-f={{with .Module}}{{.Path}}{{end}}", pkg.Path())...)
+		if err != nil {
+			return errors.Wrapf(err, "list dependencies of %v", pkg.Path())
+		}
+		for _, l := range strings.Split(out, "\n") {
+			// Output contains also go logs, e.g. about downloading.
+			if l = strings.TrimSpace(l); l != "" && !strings.Contains(l, " ") {
+				needed[l] = struct{}{}
+			}
+		}
+	}
+	downloads := make([]string, 0, len(needed))
+	for p := range needed {
+		downloads = append(downloads, p)
+	}
+	sort.Strings(downloads)
+	if err := ic.modCtx.ModDownload(downloads...); err != nil {
+		return errors.Wrap(err, "download modules to vendor")
+	}
+
+	mods, err := ic.modCtx.ModList("all")
+	if err != nil {
+		return errors.Wrap(err, "list modules to vendor")
+	}
+	replaces := []mod.ReplaceDirective{}
+	for _, r := range ic.modFile.ReplaceDirectives() {
+		if !isVendorReplace(r) {
+			replaces = append(replaces, r)
+		}
+	}
+	for _, m := range mods {
+		if m.Main {
+			continue
+		}
+		if m.Replace != nil {
+			ic.logger.Debugf("%v is replaced with %v, not vendoring\n", m.Path, m.Replace.Path)
+			continue
+		}
+		dir, err := vendorModuleDir(module.Version{Path: m.Path, Version: m.Version})
+		if err != nil {
+			return errors.Wrapf(err, "vendor %v@%v", m.Path, m.Version)
+		}
+		_, full := needed[m.Path]
+		if err := vendorModule(filepath.Join(ic.modDir, filepath.FromSlash(dir)), m.Path, m.Dir, m.GoMod, full); err != nil {
+			return errors.Wrapf(err, "vendor %v@%v", m.Path, m.Version)
+		}
+		replaces = append(replaces, mod.ReplaceDirective{Old: module.Version{Path: m.Path}, New: module.Version{Path: "./" + dir}})
+	}
+	return ic.modFile.SetReplaceDirectives(replaces...)
+}
+
+// vendorModule copies sources of the module from srcDir (only go.mod if full is false) to dst, unless dst has them already.
+// Sources are copied to a temporary directory first, so interrupted copy is not mistaken for a complete one. Go.mod is always
+// taken from goMod, since modules without go.mod have it synthesized by go.
+func vendorModule(dst, modulePath, srcDir, goMod string, full bool) (err error) {
+	if _, err := os.Stat(filepath.Join(dst, "go.mod")); err == nil {
+		entries, err := os.ReadDir(dst)
+		if err != nil {
+			return err
+		}
+		if !full || len(entries) > 1 {
+			return nil
+		}
+	}
+	if full && srcDir == "" {
+		return errors.Newf("module sources are not downloaded")
+	}
+
+	tmp := dst + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.RemoveAll(tmp)
+		}
+	}()
+	if full {
+		if err := copyTree(srcDir, tmp); err != nil {
+			return err
+		}
+	}
+
+	b := []byte("module " + modulePath + "\n")
+	if goMod != "" {
+		if b, err = os.ReadFile(goMod); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), b, 0666); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// copyTree copies regular files of src directory tree to dst. Files in the module cache are read-only, so copies are
+// made writable, keeping only executable bit of the source.
+func copyTree(src, dst string) error {
+	return filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		mode := os.FileMode(0666)
+		if info.Mode()&0111 != 0 {
+			mode = 0777
+		}
+		return copyRegularFile(p, target, mode)
+	})
+}
+
+func copyRegularFile(src, dst string, mode os.FileMode) (err error) {
+	s, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer errcapture.Do(&err, s.Close, "close source")
+
+	d, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer errcapture.Do(&err, d.Close, "close destination")
+
+	_, err = io.Copy(d, s)
+	return err
+}
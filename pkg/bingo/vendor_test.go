@@ -0,0 +1,108 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestUpdateModFile_Vendored(t *testing.T) {
+	dir := t.TempDir()
+
+	// Read-only module cache with the tool sources and go.mod files of the tool and its dependency.
+	cache := filepath.Join(dir, "cache")
+	toolDir := filepath.Join(cache, "github.com/x/tool@v1.0.0")
+	testutil.Ok(t, os.MkdirAll(filepath.Join(toolDir, "cmd"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(filepath.Join(toolDir, "cmd", "main.go"), []byte("package main\n"), 0444))
+	testutil.Ok(t, os.WriteFile(filepath.Join(toolDir, "gen.sh"), []byte("#!/bin/sh\n"), 0555))
+	testutil.Ok(t, os.WriteFile(filepath.Join(cache, "tool.mod"), []byte("module github.com/x/tool\n\nrequire github.com/x/dep v0.1.0\n"), 0444))
+	testutil.Ok(t, os.WriteFile(filepath.Join(cache, "dep.mod"), []byte("module github.com/x/dep\n"), 0444))
+
+	// Fake go that records get calls and lists the tool as the only module needed for build.
+	d := newTestModDir(t, dir, `
+  list) case "$*" in
+      *" -deps "*) echo "go: downloading github.com/x/tool v1.0.0"; echo github.com/x/tool ;;
+      *" -m -json all") cat <<EOF
+{"Path":"_","Main":true,"Dir":"/somewhere"}
+{"Path":"github.com/x/tool","Version":"v1.0.0","Dir":"`+toolDir+`","GoMod":"`+filepath.Join(cache, "tool.mod")+`"}
+{"Path":"github.com/x/dep","Version":"v0.1.0","GoMod":"`+filepath.Join(cache, "dep.mod")+`"}
+{"Path":"github.com/x/other","Version":"v0.2.0","Replace":{"Path":"../other"}}
+EOF
+      ;;
+      *) echo main ;;
+    esac ;;
+  get) echo "$@" >> "$CALLS_FILE" ;;`)
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+
+	modDir := d.modDir
+	modFile := filepath.Join(modDir, "tool.mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.17
+
+replace github.com/x/other => ../other
+
+require github.com/x/tool v1.0.0 // cmd
+`), os.ModePerm))
+
+	logger := logging.Discard
+	mf, err := OpenModFile(modFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+	testutil.Ok(t, mf.SetVendored(true))
+	testutil.Equals(t, true, mf.IsVendored())
+
+	testutil.Ok(t, UpdateModFile(context.Background(), logger, d.r, modDir, "tool", mf))
+	_, err = os.Stat(callsFile)
+	testutil.Ok(t, err)
+	expectContent(t, `module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.17
+
+require github.com/x/tool v1.0.0 // cmd
+
+// bingo:vendor
+
+replace github.com/x/other => ../other
+
+replace github.com/x/tool => ./_vendor/github.com/x/tool@v1.0.0
+
+replace github.com/x/dep => ./_vendor/github.com/x/dep@v0.1.0
+`, modFile)
+	testutil.Equals(t, 1, len(mf.LocalReplaces()))
+
+	// Tool needed for build is copied fully and writable, other modules have go.mod only.
+	vendoredTool := filepath.Join(modDir, "_vendor/github.com/x/tool@v1.0.0")
+	expectContent(t, "package main\n", filepath.Join(vendoredTool, "cmd", "main.go"))
+	expectContent(t, "module github.com/x/tool\n\nrequire github.com/x/dep v0.1.0\n", filepath.Join(vendoredTool, "go.mod"))
+	fi, err := os.Stat(filepath.Join(vendoredTool, "gen.sh"))
+	testutil.Ok(t, err)
+	testutil.Assert(t, fi.Mode().Perm()&0300 == 0300, "expected writable executable, got %v", fi.Mode())
+	entries, err := os.ReadDir(filepath.Join(modDir, "_vendor/github.com/x/dep@v0.1.0"))
+	testutil.Ok(t, err)
+	testutil.Equals(t, 1, len(entries))
+	testutil.Equals(t, "go.mod", entries[0].Name())
+
+	// Vendored sources are up to date, so nothing is fetched.
+	testutil.Ok(t, os.Remove(callsFile))
+	testutil.Ok(t, UpdateModFile(context.Background(), logger, d.r, modDir, "tool", mf))
+	_, err = os.Stat(callsFile)
+	testutil.Assert(t, os.IsNotExist(err), "expected no go get, got %v", err)
+	testutil.Equals(t, []string{"GOPROXY=off"}, []string(packageBuildEnvs(mf, nil, *mf.DirectPackage())))
+
+	// Disabling vendoring drops vendor replaces, but keeps sources.
+	testutil.Ok(t, mf.SetVendored(false))
+	testutil.Ok(t, mf.Reload())
+	testutil.Equals(t, false, mf.IsVendored())
+	testutil.Equals(t, 1, len(mf.ReplaceDirectives()))
+	_, err = os.Stat(vendoredTool)
+	testutil.Ok(t, err)
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// InstallState is a state of the pinned binary in GOBIN, as reported by VerifyInstalled.
+type InstallState string
+
+const (
+	// InstallStateOK means binary exists and is built from the pinned package and version.
+	InstallStateOK InstallState = "ok"
+	// InstallStateMissing means binary does not exist.
+	InstallStateMissing InstallState = "missing"
+	// InstallStateStale means binary exists, but is built from different package or version (or is not a Go binary).
+	InstallStateStale InstallState = "stale"
+	// InstallStateModified means binary does not match checksum recorded on install.
+	InstallStateModified InstallState = "modified"
+)
+
+// InstalledBinary represents single pinned binary as printed by `bingo verify -o json`. This schema is stable.
+type InstalledBinary struct {
+	// Name is a binary name.
+	Name string `json:"name"`
+	// ModFile is a module file name within the bingo module directory.
+	ModFile string `json:"mod_file"`
+	// Package is a pinned package with module version in path@version form.
+	Package string `json:"package"`
+	// BinaryPath is an absolute path to the versioned binary.
+	BinaryPath string       `json:"binary_path"`
+	State      InstallState `json:"state"`
+	// Details explains state other than ok, e.g. version found in the binary.
+	Details string `json:"details,omitempty"`
+}
+
+// VerifyInstalled checks if binaries of all packages pinned in the given module file are installed in gobin, built from
+// the pinned package and version (as embedded in the binary, see `go version -m`) and match recorded checksums.
+// It does not access network, so it's fast enough to run on every build.
+func VerifyInstalled(ctx context.Context, r *runner.Runner, modFilePath, gobin string) ([]InstalledBinary, error) {
+	return verifyInstalled(modFilePath, gobin, func(binPath string) (runner.BuildInfo, error) {
+		return r.BuildInfo(ctx, binPath)
+	})
+}
+
+func verifyInstalled(modFilePath, gobin string, buildInfo func(binPath string) (runner.BuildInfo, error)) (_ []InstalledBinary, err error) {
+	mf, err := OpenModFile(modFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer errcapture.Do(&err, mf.Close, "close")
+
+	pkgs := mf.DirectPackages()
+	if len(pkgs) == 0 {
+		return nil, errors.Newf("no direct package found in %s; empty module?", modFilePath)
+	}
+	name, _ := NameFromModFile(modFilePath)
+	names, err := BinaryNames(name, pkgs)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]InstalledBinary, 0, len(pkgs))
+	for i, pkg := range pkgs {
+		b := InstalledBinary{
+			Name:       names[i],
+			ModFile:    filepath.Base(modFilePath),
+			Package:    pkg.String(),
+			BinaryPath: versionedBinPath(gobin, names[i], pkg),
+		}
+		if b.State, b.Details, err = verifyBinary(filepath.Dir(modFilePath), names[i], pkg, b.BinaryPath, buildInfo); err != nil {
+			return nil, errors.Wrap(err, b.Name)
+		}
+		ret = append(ret, b)
+	}
+	return ret, nil
+}
+
+func verifyBinary(modDir, name string, pkg Package, binPath string, buildInfo func(binPath string) (runner.BuildInfo, error)) (InstallState, string, error) {
+	if _, err := os.Stat(binPath); err != nil {
+		if os.IsNotExist(err) {
+			return InstallStateMissing, "binary does not exist", nil
+		}
+		return "", "", err
+	}
+
+	info, err := buildInfo(binPath)
+	if err != nil {
+		return InstallStateStale, fmt.Sprintf("cannot read build information: %v", err), nil
+	}
+	if info.Path != pkg.Path() || info.Main.Version != pkg.Module.Version {
+		return InstallStateStale, fmt.Sprintf("binary is built from %v@%v", info.Path, info.Main.Version), nil
+	}
+
+	key := BinChecksumKey{Name: name, Version: pkg.Module.Version, GOOS: pkg.TargetGOOS(), GOARCH: pkg.TargetGOARCH()}
+	if _, err := VerifyBinChecksum(modDir, key, binPath); err != nil {
+		return InstallStateModified, err.Error(), nil
+	}
+	return InstallStateOK, "", nil
+}
+
+// PrintVerifyTab prints verified binaries as table.
+func PrintVerifyTab(bins []InstalledBinary, w io.Writer) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 1, 8, 1, '\t', tabwriter.AlignRight)
+	defer func() { _ = tw.Flush() }()
+
+	_, _ = fmt.Fprint(tw, "Name\tPackage @ Version\tState\tDetails\n")
+	_, _ = fmt.Fprint(tw, "----\t-----------------\t-----\t-------\n")
+	for _, b := range bins {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.Name, b.Package, b.State, b.Details)
+	}
+	return nil
+}
+
+// PrintVerifyJSON prints verified binaries as JSON array.
+func PrintVerifyJSON(bins []InstalledBinary, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(append([]InstalledBinary{}, bins...))
+}
@@ -0,0 +1,73 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package bingo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwplotka/bingo/pkg/runner"
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
+)
+
+func TestVerifyInstalled(t *testing.T) {
+	modDir := t.TempDir()
+	gobin := t.TempDir()
+	modFile := filepath.Join(modDir, "server.mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte(`module _ // Auto generated by https://github.com/bwplotka/bingo. DO NOT EDIT
+
+go 1.14
+
+// also: cmd/client
+
+require github.com/x/server v1.0.0 // cmd/server
+`), os.ModePerm))
+
+	infos := map[string]runner.BuildInfo{}
+	buildInfo := func(binPath string) (runner.BuildInfo, error) {
+		info, ok := infos[binPath]
+		if !ok {
+			return runner.BuildInfo{}, errors.New("no module information found; binary was not built with Go modules")
+		}
+		return info, nil
+	}
+
+	serverBin := filepath.Join(gobin, "server-v1.0.0")
+	clientBin := filepath.Join(gobin, "client-v1.0.0")
+	bins, err := verifyInstalled(modFile, gobin, buildInfo)
+	testutil.Ok(t, err)
+	testutil.Equals(t, []InstalledBinary{
+		{Name: "server", ModFile: "server.mod", Package: "github.com/x/server/cmd/server@v1.0.0", BinaryPath: serverBin, State: InstallStateMissing, Details: "binary does not exist"},
+		{Name: "client", ModFile: "server.mod", Package: "github.com/x/server/cmd/client@v1.0.0", BinaryPath: clientBin, State: InstallStateMissing, Details: "binary does not exist"},
+	}, bins)
+
+	testutil.Ok(t, os.WriteFile(serverBin, []byte("server"), os.ModePerm))
+	testutil.Ok(t, os.WriteFile(clientBin, []byte("client"), os.ModePerm))
+	infos[serverBin] = runner.BuildInfo{Path: "github.com/x/server/cmd/server", Main: module.Version{Path: "github.com/x/server", Version: "v0.9.0"}}
+	bins, err = verifyInstalled(modFile, gobin, buildInfo)
+	testutil.Ok(t, err)
+	testutil.Equals(t, InstallStateStale, bins[0].State)
+	testutil.Equals(t, "binary is built from github.com/x/server/cmd/server@v0.9.0", bins[0].Details)
+	testutil.Equals(t, InstallStateStale, bins[1].State)
+
+	infos[serverBin] = runner.BuildInfo{Path: "github.com/x/server/cmd/server", Main: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}}
+	infos[clientBin] = runner.BuildInfo{Path: "github.com/x/server/cmd/client", Main: module.Version{Path: "github.com/x/server", Version: "v1.0.0"}}
+	key := BinChecksumKey{Name: "server", Version: "v1.0.0", GOOS: Package{}.TargetGOOS(), GOARCH: Package{}.TargetGOARCH()}
+	testutil.Ok(t, WriteBinChecksum(modDir, key, serverBin))
+	bins, err = verifyInstalled(modFile, gobin, buildInfo)
+	testutil.Ok(t, err)
+	testutil.Equals(t, InstallStateOK, bins[0].State)
+	testutil.Equals(t, "", bins[0].Details)
+	// No checksum recorded is fine, e.g. binaries installed before checksums were recorded.
+	testutil.Equals(t, InstallStateOK, bins[1].State)
+
+	testutil.Ok(t, os.WriteFile(serverBin, []byte("tampered"), os.ModePerm))
+	bins, err = verifyInstalled(modFile, gobin, buildInfo)
+	testutil.Ok(t, err)
+	testutil.Equals(t, InstallStateModified, bins[0].State)
+	testutil.Equals(t, InstallStateOK, bins[1].State)
+}
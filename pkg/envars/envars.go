@@ -4,8 +4,11 @@
 package envars
 
 import (
+	"os"
+	"regexp"
 	"strings"
 
+	"github.com/efficientgo/core/errors"
 	"mvdan.cc/sh/v3/expand"
 )
 
@@ -21,6 +24,49 @@ func (e EnvSlice) Lookup(k string) (string, bool) {
 	return "", false
 }
 
+var varNameRegexp = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_]*$")
+
+// Expand returns copy of the slice with $VAR and ${VAR} references in values replaced using lookup (e.g. os.LookupEnv)
+// and $$ replaced with literal $, e.g. CGO_LDFLAGS=-Wl,-rpath,$$ORIGIN. It returns error if referenced variable is not defined or reference is malformed, instead of expanding it to empty string.
+func (e EnvSlice) Expand(lookup func(string) (string, bool)) (EnvSlice, error) {
+	ret := make(EnvSlice, 0, len(e))
+	for _, ev := range e {
+		sp := strings.SplitN(ev, "=", 2)
+		if len(sp) < 2 {
+			ret = append(ret, ev)
+			continue
+		}
+		k, v := sp[0], sp[1]
+		if strings.Contains(v, "${}") {
+			return nil, errors.Newf("env %q: empty variable reference ${}", ev)
+		}
+
+		var err error
+		expanded := os.Expand(v, func(name string) string {
+			if err != nil {
+				return ""
+			}
+			if name == "$" {
+				return "$"
+			}
+			if !varNameRegexp.MatchString(name) {
+				err = errors.Newf("env %q: invalid variable reference %q", ev, name)
+				return ""
+			}
+			val, ok := lookup(name)
+			if !ok {
+				err = errors.Newf("env %q: variable %v is not defined in the environment", ev, name)
+			}
+			return val
+		})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, k+"="+expanded)
+	}
+	return ret, nil
+}
+
 func (e *EnvSlice) Set(kvs ...string) {
 	*e = MergeEnvSlices(*e, kvs...)
 }
@@ -0,0 +1,72 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package envars
+
+import (
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestEnvSlice_Expand(t *testing.T) {
+	env := map[string]string{"MYSDK": "/opt/sdk", "ARCH": "arm64", "EMPTY": ""}
+	lookup := func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+
+	for _, tcase := range []struct {
+		name        string
+		envs        EnvSlice
+		expected    EnvSlice
+		expectedErr string
+	}{
+		{
+			name:     "no references",
+			envs:     EnvSlice{"CGO_ENABLED=0", "GOOS=linux"},
+			expected: EnvSlice{"CGO_ENABLED=0", "GOOS=linux"},
+		},
+		{
+			name:     "defined",
+			envs:     EnvSlice{"CGO_CFLAGS=-I${MYSDK}/include", "GOARCH=$ARCH", "X=${EMPTY}"},
+			expected: EnvSlice{"CGO_CFLAGS=-I/opt/sdk/include", "GOARCH=arm64", "X="},
+		},
+		{
+			name:     "braces around and next to references",
+			envs:     EnvSlice{"CGO_CFLAGS={${MYSDK}}/${ARCH}${ARCH}", "X=a=b"},
+			expected: EnvSlice{"CGO_CFLAGS={/opt/sdk}/arm64arm64", "X=a=b"},
+		},
+		{
+			name:     "escaped",
+			envs:     EnvSlice{"CGO_LDFLAGS=-Wl,-rpath,$$ORIGIN", "X=$$${ARCH}$$$$"},
+			expected: EnvSlice{"CGO_LDFLAGS=-Wl,-rpath,$ORIGIN", "X=$arm64$$"},
+		},
+		{
+			name:        "undefined",
+			envs:        EnvSlice{"GOOS=linux", "CGO_CFLAGS=-I${NOTSET}/include"},
+			expectedErr: `env "CGO_CFLAGS=-I${NOTSET}/include": variable NOTSET is not defined in the environment`,
+		},
+		{
+			name:        "nested braces",
+			envs:        EnvSlice{"CGO_CFLAGS=-I${MY${ARCH}}/include"},
+			expectedErr: `env "CGO_CFLAGS=-I${MY${ARCH}}/include": invalid variable reference "MY${ARCH"`,
+		},
+		{
+			name:        "empty reference",
+			envs:        EnvSlice{"CGO_CFLAGS=-I${}/include"},
+			expectedErr: `env "CGO_CFLAGS=-I${}/include": empty variable reference ${}`,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			got, err := tcase.envs.Expand(lookup)
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, got)
+		})
+	}
+}
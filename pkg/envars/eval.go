@@ -119,11 +119,12 @@ func trimDeclStmts(ast *syntax.File) {
 }
 
 // MergeEnvSlices merges two slices into single, sorted, deduplicated slice by applying `over` slice into `base`.
-// The `over` slice will be used if the key overlaps.
+// The `over` slice will be used if the key overlaps. If key is duplicated within the same slice, the latest occurrence is used.
+// Given slices are not modified.
 // See https://golang.org/pkg/os/exec/#Cmd `Env` field to read more about slice format.
 func MergeEnvSlices(base []string, over ...string) (merged []string) {
-	sort.Strings(base)
-	sort.Strings(over)
+	base = sortedByKey(base)
+	over = sortedByKey(over)
 
 	var b, o int
 	for b < len(base) || o < len(over) {
@@ -140,7 +141,7 @@ func MergeEnvSlices(base []string, over ...string) (merged []string) {
 			continue
 		}
 
-		switch strings.Compare(strings.Split(base[b], "=")[0], strings.Split(over[o], "=")[0]) {
+		switch strings.Compare(envKey(base[b]), envKey(over[o])) {
 		case 0:
 			// Same keys. Instead of picking over element, ignore base one. This ensure correct behaviour if base
 			// has duplicate elements.
@@ -163,9 +164,21 @@ func appendOrReplaceDup(appendable *[]string, item string) {
 	}
 
 	lastI := len(*appendable) - 1
-	if strings.Compare(strings.Split((*appendable)[lastI], "=")[0], strings.Split(item, "=")[0]) == 0 {
+	if envKey((*appendable)[lastI]) == envKey(item) {
 		(*appendable)[lastI] = item
 		return
 	}
 	*appendable = append(*appendable, item)
 }
+
+// sortedByKey returns copy of envs sorted by key only, so keys being prefixes of others (e.g. GO1 and GO111MODULE) are
+// ordered consistently with the merge. Order of duplicated keys is kept.
+func sortedByKey(envs []string) []string {
+	ret := append([]string(nil), envs...)
+	sort.SliceStable(ret, func(i, j int) bool { return envKey(ret[i]) < envKey(ret[j]) })
+	return ret
+}
+
+func envKey(env string) string {
+	return strings.SplitN(env, "=", 2)[0]
+}
@@ -193,5 +193,17 @@ func TestMergeEnvSlices(t *testing.T) {
 			"OPTION_J=postgres://localhost:5432/database?sslmode=disable22",
 		))
 	})
+	t.Run("key prefixed by other key", func(t *testing.T) {
+		base := []string{"GOFLAGS2=2", "GOFLAGS=-mod=readonly", "GOPROXY=https://proxy.golang.org"}
+		over := []string{"GOPROXY=https://internal", "GOFLAGS=-mod=mod", "GOFLAGS=-mod=vendor"}
+		testutil.Equals(t, []string{
+			"GOFLAGS=-mod=vendor",
+			"GOFLAGS2=2",
+			"GOPROXY=https://internal",
+		}, MergeEnvSlices(base, over...))
 
+		// Inputs are not modified.
+		testutil.Equals(t, []string{"GOFLAGS2=2", "GOFLAGS=-mod=readonly", "GOPROXY=https://proxy.golang.org"}, base)
+		testutil.Equals(t, []string{"GOPROXY=https://internal", "GOFLAGS=-mod=mod", "GOFLAGS=-mod=vendor"}, over)
+	})
 }
@@ -0,0 +1,106 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package logging
+
+import (
+	"log"
+	"strings"
+	"sync/atomic"
+
+	"github.com/efficientgo/core/errors"
+)
+
+// Level is a severity of the log message.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "unknown"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns level with the given name, one of debug, info, warn or error.
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	return 0, errors.Newf("unknown log level %q; expected one of %v", name, strings.Join(levelNames, ", "))
+}
+
+// Logger is a leveled logger bingo and runner log with. Debug messages are details of every step (e.g. executed
+// commands and their output), only useful when diagnosing failures.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// StdLogger adapts standard library logger to Logger. Messages below its level are dropped. Warnings and errors are
+// prefixed with "WARNING: " and "ERROR: ", so output is the same as before levels were introduced.
+type StdLogger struct {
+	l     *log.Logger
+	level int32
+}
+
+// NewStd returns Logger that writes messages of at least the given level to the standard library logger.
+func NewStd(l *log.Logger, level Level) *StdLogger {
+	return &StdLogger{l: l, level: int32(level)}
+}
+
+// SetLevel changes the minimum level of messages written, e.g. to debug when verbose mode is enabled. It's safe to
+// call concurrently with logging.
+func (s *StdLogger) SetLevel(level Level) {
+	atomic.StoreInt32(&s.level, int32(level))
+}
+
+// Level returns the minimum level of messages written.
+func (s *StdLogger) Level() Level {
+	return Level(atomic.LoadInt32(&s.level))
+}
+
+func (s *StdLogger) Debugf(format string, args ...interface{}) {
+	s.logf(LevelDebug, "", format, args...)
+}
+
+func (s *StdLogger) Infof(format string, args ...interface{}) {
+	s.logf(LevelInfo, "", format, args...)
+}
+
+func (s *StdLogger) Warnf(format string, args ...interface{}) {
+	s.logf(LevelWarn, "WARNING: ", format, args...)
+}
+
+func (s *StdLogger) Errorf(format string, args ...interface{}) {
+	s.logf(LevelError, "ERROR: ", format, args...)
+}
+
+func (s *StdLogger) logf(level Level, prefix string, format string, args ...interface{}) {
+	if level < s.Level() {
+		return
+	}
+	s.l.Printf(prefix+format, args...)
+}
+
+type discard struct{}
+
+func (discard) Debugf(string, ...interface{}) {}
+func (discard) Infof(string, ...interface{})  {}
+func (discard) Warnf(string, ...interface{})  {}
+func (discard) Errorf(string, ...interface{}) {}
+
+// Discard is a Logger that drops all messages.
+var Discard Logger = discard{}
@@ -0,0 +1,44 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package logging
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewStd(log.New(&buf, "", 0), LevelInfo)
+	l.Debugf("debug %v", 1)
+	l.Infof("info %v", 2)
+	l.Warnf("warn %v\n", 3)
+	l.Errorf("error %v", 4)
+	testutil.Equals(t, "info 2\nWARNING: warn 3\nERROR: error 4\n", buf.String())
+
+	buf.Reset()
+	l.SetLevel(LevelDebug)
+	l.Debugf("debug %v", 1)
+	l.SetLevel(LevelError)
+	l.Warnf("warn %v", 3)
+	testutil.Equals(t, "debug 1\n", buf.String())
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		got, err := ParseLevel(l.String())
+		testutil.Ok(t, err)
+		testutil.Equals(t, l, got)
+	}
+	got, err := ParseLevel("WARN")
+	testutil.Ok(t, err)
+	testutil.Equals(t, LevelWarn, got)
+
+	_, err = ParseLevel("trace")
+	testutil.NotOk(t, err)
+	testutil.Equals(t, `unknown log level "trace"; expected one of debug, info, warn, error`, err.Error())
+}
@@ -0,0 +1,133 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// LockTimeout is a maximum time OpenFile waits for the lock of the module file held by another process.
+var LockTimeout = 5 * time.Minute
+
+// ErrLocked is returned (wrapped) when lock of the file cannot be obtained before timeout.
+var ErrLocked = errors.New("file is locked by another process (e.g. concurrent bingo get)")
+
+const lockRetryInterval = 100 * time.Millisecond
+
+var (
+	heldLocksMtx sync.Mutex
+	// heldLocks are locks held by this process by lock file absolute path.
+	heldLocks = map[string]*heldLock{}
+)
+
+type heldLock struct {
+	f    *os.File
+	refs int
+}
+
+// FileLock is an advisory, exclusive lock of the file that prevents other processes from editing it concurrently.
+// The lock is taken on the separate <file>.lock file (see LockPath) and not on the file itself, since go command locks
+// module files it edits on its own. Lock files are never removed, since removing them would allow two processes to
+// hold the lock at once.
+//
+// Locks are reentrant within the process: locking a file already locked by this process succeeds immediately and the
+// file is unlocked once all its FileLocks are released.
+type FileLock struct {
+	path string
+
+	once sync.Once
+}
+
+// LockPath returns path of the lock file for the given file.
+func LockPath(path string) string {
+	return path + ".lock"
+}
+
+// IsLockFile returns true if the given file is a lock file of another file.
+func IsLockFile(path string) bool {
+	return filepath.Ext(path) == ".lock"
+}
+
+// Lock acquires lock of the given file. If the file is locked by another process, Lock retries until timeout and
+// returns error wrapping ErrLocked if the lock is still not released. Zero timeout means one attempt.
+// It's a caller responsibility to Unlock.
+func Lock(path string, timeout time.Duration) (_ *FileLock, err error) {
+	lockPath, err := filepath.Abs(LockPath(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "abs")
+	}
+
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, errors.Wrap(err, "open lock file")
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		l, err := lockWith(lockPath, f)
+		if err != nil || l != nil {
+			return l, err
+		}
+		if !time.Now().Before(deadline) {
+			err := errors.Wrapf(ErrLocked, "timed out after %v waiting for lock %v", timeout, lockPath)
+			errcapture.Do(&err, f.Close, "close")
+			return nil, err
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// lockWith returns lock of the lock file if it's already held by this process or if it can be locked with f, which is
+// the opened lock file. It returns nil lock if the file is locked by another process. On non-nil lock or error, f is
+// closed unless it holds the new lock.
+func lockWith(lockPath string, f *os.File) (*FileLock, error) {
+	heldLocksMtx.Lock()
+	defer heldLocksMtx.Unlock()
+
+	if h, ok := heldLocks[lockPath]; ok {
+		h.refs++
+		// Not used, so error on close does not matter.
+		_ = f.Close()
+		return &FileLock{path: lockPath}, nil
+	}
+
+	locked, err := tryLockFile(f)
+	if err != nil {
+		err = errors.Wrapf(err, "lock %v", lockPath)
+		errcapture.Do(&err, f.Close, "close")
+		return nil, err
+	}
+	if !locked {
+		return nil, nil
+	}
+	heldLocks[lockPath] = &heldLock{f: f, refs: 1}
+	return &FileLock{path: lockPath}, nil
+}
+
+// Unlock releases the lock. It's a no-op if already released or nil.
+func (l *FileLock) Unlock() (err error) {
+	if l == nil {
+		return nil
+	}
+	l.once.Do(func() {
+		heldLocksMtx.Lock()
+		defer heldLocksMtx.Unlock()
+
+		h := heldLocks[l.path]
+		if h.refs--; h.refs > 0 {
+			return
+		}
+		delete(heldLocks, l.path)
+
+		defer errcapture.Do(&err, h.f.Close, "close")
+		err = unlockFile(h.f)
+	})
+	return err
+}
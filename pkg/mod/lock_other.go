@@ -0,0 +1,14 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+//go:build !darwin && !dragonfly && !freebsd && !illumos && !linux && !netbsd && !openbsd && !windows
+
+package mod
+
+import "os"
+
+// File locking is not supported on this platform, so locks only coordinate edits within the process.
+
+func tryLockFile(*os.File) (bool, error) { return true, nil }
+
+func unlockFile(*os.File) error { return nil }
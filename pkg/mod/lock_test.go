@@ -0,0 +1,107 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+//go:build darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || windows
+
+package mod
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/efficientgo/core/errors"
+	"github.com/efficientgo/core/testutil"
+)
+
+// otherProcessLock opens the lock file of the given file separately, so locking it behaves like in other process.
+func otherProcessLock(t *testing.T, path string) *os.File {
+	f, err := os.OpenFile(LockPath(path), os.O_RDWR|os.O_CREATE, 0666)
+	testutil.Ok(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+	return f
+}
+
+func TestLock(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.mod")
+	other := otherProcessLock(t, testFile)
+
+	l, err := Lock(testFile, 0)
+	testutil.Ok(t, err)
+	// Reentrant within the process.
+	l2, err := Lock(testFile, 0)
+	testutil.Ok(t, err)
+
+	locked, err := tryLockFile(other)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !locked, "expected lock held")
+
+	testutil.Ok(t, l.Unlock())
+	// Double unlock does not release the lock of others.
+	testutil.Ok(t, l.Unlock())
+	locked, err = tryLockFile(other)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !locked, "expected lock still held")
+
+	testutil.Ok(t, l2.Unlock())
+	locked, err = tryLockFile(other)
+	testutil.Ok(t, err)
+	testutil.Assert(t, locked, "expected lock released")
+
+	start := time.Now()
+	_, err = Lock(testFile, 300*time.Millisecond)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, ErrLocked), "expected ErrLocked, got %v", err)
+	testutil.Assert(t, time.Since(start) >= 300*time.Millisecond, "expected waiting for timeout")
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = unlockFile(other)
+	}()
+	l, err = Lock(testFile, 10*time.Second)
+	testutil.Ok(t, err)
+	testutil.Ok(t, l.Unlock())
+}
+
+func TestFile_Lock(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.mod")
+	testutil.Ok(t, os.WriteFile(testFile, []byte("module _\n"), os.ModePerm))
+	other := otherProcessLock(t, testFile)
+
+	mf, err := OpenFile(testFile)
+	testutil.Ok(t, err)
+	locked, err := tryLockFile(other)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !locked, "expected lock held")
+
+	// Lock follows the file on rename.
+	newFile := filepath.Join(tmpDir, "new.mod")
+	newOther := otherProcessLock(t, newFile)
+	testutil.Ok(t, mf.Rename(newFile))
+	locked, err = tryLockFile(other)
+	testutil.Ok(t, err)
+	testutil.Assert(t, locked, "expected old lock released")
+	testutil.Ok(t, unlockFile(other))
+	locked, err = tryLockFile(newOther)
+	testutil.Ok(t, err)
+	testutil.Assert(t, !locked, "expected new lock held")
+
+	testutil.Ok(t, mf.Close())
+	locked, err = tryLockFile(newOther)
+	testutil.Ok(t, err)
+	testutil.Assert(t, locked, "expected lock released on close")
+
+	// File read only is not locked.
+	r, err := OpenFileForRead(newFile)
+	testutil.Ok(t, err)
+	testutil.Ok(t, r.Close())
+
+	// Other process holds the lock.
+	defer func(t time.Duration) { LockTimeout = t }(LockTimeout)
+	LockTimeout = 0
+	_, err = OpenFile(newFile)
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, ErrLocked), "expected ErrLocked, got %v", err)
+}
@@ -4,9 +4,12 @@
 package mod
 
 import (
+	"bytes"
 	"io"
 	"os"
+	"strings"
 
+	"github.com/bwplotka/bingo/pkg/atomicfile"
 	"github.com/efficientgo/core/errcapture"
 	"github.com/efficientgo/core/errors"
 	"golang.org/x/mod/modfile"
@@ -19,22 +22,30 @@ type File struct {
 
 	f *os.File
 	m *modfile.File
+
+	// lock is nil if the file is opened for read only.
+	lock *FileLock
 }
 
-// OpenFile opens mod file for edits in place.
+// OpenFile opens mod file for edits in place. It locks the file (see Lock) first, so other processes can't edit it
+// until Close. It waits up to LockTimeout if the file is locked already.
 // It's a caller responsibility to Close the file when not using anymore.
 func OpenFile(modFile string) (_ *File, err error) {
+	l, err := Lock(modFile, LockTimeout)
+	if err != nil {
+		return nil, err
+	}
 	f, err := os.OpenFile(modFile, os.O_RDWR, os.ModePerm)
 	if err != nil {
+		errcapture.Do(&err, l.Unlock, "unlock")
 		return nil, err
 	}
+	mf := &File{f: f, path: modFile, lock: l}
 	defer func() {
 		if err != nil {
-			errcapture.Do(&err, f.Close, "close")
+			errcapture.Do(&err, mf.Close, "close")
 		}
 	}()
-
-	mf := &File{f: f, path: modFile}
 	return mf, mf.Reload()
 }
 
@@ -62,6 +73,7 @@ type FileForRead interface {
 	Module() (path string, comment string)
 	Comments() (comments []string)
 	GoVersion() string
+	Toolchain() string
 	RequireDirectives() []RequireDirective
 	ReplaceDirectives() []ReplaceDirective
 	ExcludeDirectives() []ExcludeDirective
@@ -84,9 +96,63 @@ func (mf *File) Filepath() string {
 	return mf.path
 }
 
-// Close closes file.
+// Rename moves module file to the new path and keeps it open for edits. It fails if the new path already exists.
+func (mf *File) Rename(newPath string) error {
+	if _, err := os.Lstat(newPath); err == nil {
+		return errors.Newf("%v already exists", newPath)
+	} else if !os.IsNotExist(err) {
+		return errors.Wrapf(err, "stat %v", newPath)
+	}
+
+	var newLock *FileLock
+	if mf.lock != nil {
+		l, err := Lock(newPath, LockTimeout)
+		if err != nil {
+			return err
+		}
+		newLock = l
+	}
+
+	// Close first, so it works also on systems that do not allow renaming open files.
+	if err := mf.f.Close(); err != nil {
+		err = errors.Wrap(err, "close")
+		errcapture.Do(&err, newLock.Unlock, "unlock")
+		return err
+	}
+	if err := os.Rename(mf.path, newPath); err != nil {
+		err = errors.Wrap(err, "rename")
+		errcapture.Do(&err, newLock.Unlock, "unlock")
+		errcapture.Do(&err, func() error { return mf.reopen(mf.path) }, "reopen")
+		return err
+	}
+	if newLock != nil {
+		if err := mf.lock.Unlock(); err != nil {
+			errcapture.Do(&err, newLock.Unlock, "unlock")
+			return errors.Wrap(err, "unlock")
+		}
+		mf.lock = newLock
+	}
+	if err := mf.reopen(newPath); err != nil {
+		return err
+	}
+	return mf.Reload()
+}
+
+func (mf *File) reopen(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	mf.f, mf.path = f, path
+	return nil
+}
+
+// Close closes file and releases its lock.
 // TODO(bwplotka): Ensure other methods will return error on use after Close.
-func (mf *File) Close() error {
+func (mf *File) Close() (err error) {
+	if mf.lock != nil {
+		defer errcapture.Do(&err, mf.lock.Unlock, "unlock")
+	}
 	return mf.f.Close()
 }
 
@@ -105,7 +171,8 @@ func (mf *File) SetModule(path string, comment string) error {
 		return err
 	}
 
-	mf.m.Module.Syntax.Suffix = append(mf.m.Module.Syntax.Suffix, modfile.Comment{Suffix: true, Token: "// " + comment})
+	// Replace, so the module line has only the given comment (see Module).
+	mf.m.Module.Syntax.Suffix = []modfile.Comment{{Suffix: true, Token: "// " + comment}}
 
 	return mf.flush()
 }
@@ -124,6 +191,34 @@ func (mf *File) AddComment(comment string) error {
 
 	return mf.flush()
 }
+
+// DropComments removes all standalone comment lines starting with the given prefix (without '// ').
+func (mf *File) DropComments(prefix string) error {
+	stmts := mf.m.Syntax.Stmt[:0]
+	for _, e := range mf.m.Syntax.Stmt {
+		b, ok := e.(*modfile.CommentBlock)
+		if !ok {
+			stmts = append(stmts, e)
+			continue
+		}
+
+		before := b.Before[:0]
+		for _, c := range b.Before {
+			if strings.HasPrefix(c.Token[3:], prefix) {
+				continue
+			}
+			before = append(before, c)
+		}
+		b.Before = before
+		if len(b.Before) > 0 || len(b.Suffix) > 0 || len(b.After) > 0 {
+			stmts = append(stmts, e)
+		}
+	}
+	mf.m.Syntax.Stmt = stmts
+
+	return mf.flush()
+}
+
 func (mf *File) GoVersion() string {
 	if mf.m.Go == nil {
 		return ""
@@ -139,18 +234,46 @@ func (mf *File) SetGoVersion(version string) error {
 	return mf.flush()
 }
 
+// Toolchain returns toolchain name (e.g. "go1.22.0") from toolchain directive or empty string if there is none.
+func (mf *File) Toolchain() string {
+	if mf.m.Toolchain == nil {
+		return ""
+	}
+	return mf.m.Toolchain.Name
+}
+
+// SetToolchain sets toolchain directive to the given name (e.g. "go1.22.0"). Empty name removes the directive.
+func (mf *File) SetToolchain(name string) error {
+	if name == "" {
+		mf.m.DropToolchainStmt()
+		return mf.flush()
+	}
+	if err := mf.m.AddToolchainStmt(name); err != nil {
+		return err
+	}
+	return mf.flush()
+}
+
 // Flush saves all changes made to parsed syntax and reloads the parsed file.
 func (mf *File) flush() error {
 	mf.m.Cleanup()
 	newB := modfile.Format(mf.m.Syntax)
-	if err := mf.f.Truncate(0); err != nil {
-		return errors.Wrap(err, "truncate")
+	// Don't rewrite the same content, so no-op edits keep the file (including its modification time) untouched.
+	if oldB, err := os.ReadFile(mf.path); err == nil && bytes.Equal(oldB, newB) {
+		return mf.Reload()
 	}
-	if _, err := mf.f.Seek(0, 0); err != nil {
-		return errors.Wrap(err, "seek")
+	// Write atomically, so interrupted bingo never leaves truncated module file. Close first, so it works also on systems
+	// that do not allow replacing open files.
+	if err := mf.f.Close(); err != nil {
+		return errors.Wrap(err, "close")
 	}
-	if _, err := mf.f.Write(newB); err != nil {
-		return errors.Wrap(err, "write")
+	if err := atomicfile.WriteFile(mf.path, newB, os.ModePerm); err != nil {
+		err = errors.Wrap(err, "write")
+		errcapture.Do(&err, func() error { return mf.reopen(mf.path) }, "reopen")
+		return err
+	}
+	if err := mf.reopen(mf.path); err != nil {
+		return err
 	}
 	// Reload, so syntax gets rebuilt. It might change due to format.
 	return mf.Reload()
@@ -163,6 +286,9 @@ type RequireDirective struct {
 	// ExtraSuffixComment represents comment (without '// ') after potential indirect comment
 	// that can contain additional information.
 	ExtraSuffixComment string
+
+	// Line is a line number of the directive in the parsed file. Ignored on set.
+	Line int
 }
 
 func (mf *File) RequireDirectives() []RequireDirective {
@@ -171,6 +297,7 @@ func (mf *File) RequireDirectives() []RequireDirective {
 		ret[i] = RequireDirective{
 			Module:   r.Mod,
 			Indirect: r.Indirect,
+			Line:     r.Syntax.Start.Line,
 		}
 		if len(r.Syntax.Suffix) > 0 {
 			ret[i].ExtraSuffixComment = r.Syntax.Suffix[0].Token[3:]
@@ -302,6 +429,9 @@ func parseModFileOrReader(modFile string, r io.Reader) (*modfile.File, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "read")
 	}
+	// Files committed on Windows might have CRLF line endings. Normalize them, so trailing '\r' never ends up in parsed
+	// tokens (e.g. build flags in comments). Format writes LF only.
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
 
 	m, err := modfile.Parse(modFile, b, nil)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/efficientgo/core/testutil"
 	"golang.org/x/mod/module"
@@ -44,6 +45,7 @@ func TestFile(t *testing.T) {
 		testutil.Equals(t, "", comment)
 		testutil.Equals(t, []string(nil), mf.Comments())
 		testutil.Equals(t, "", mf.GoVersion())
+		testutil.Equals(t, "", mf.Toolchain())
 		testutil.Equals(t, 0, len(mf.RequireDirectives()))
 		testutil.Equals(t, 0, len(mf.ReplaceDirectives()))
 		testutil.Equals(t, 0, len(mf.ExcludeDirectives()))
@@ -194,4 +196,86 @@ retract [v0.9.0, v1.0.0]
 		testutil.Equals(t, "v0.9.0", retractDirectives[0].VersionInterval.Low)
 		testutil.Equals(t, "I don't know", retractDirectives[0].Rationale)
 	})
+	t.Run("toolchain", func(t *testing.T) {
+		t.Parallel()
+
+		testFile := filepath.Join(tmpDir, "test3.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte("module _\n\ngo 1.21\n\ntoolchain go1.21.4\n"), os.ModePerm))
+
+		mf, err := OpenFile(testFile)
+		testutil.Ok(t, err)
+		testutil.Equals(t, "go1.21.4", mf.Toolchain())
+
+		testutil.Ok(t, mf.SetToolchain("go1.22.0"))
+		expectContent(t, "module _\n\ngo 1.21\n\ntoolchain go1.22.0\n", testFile)
+		testutil.Equals(t, "go1.22.0", mf.Toolchain())
+
+		testutil.NotOk(t, mf.SetToolchain("1.22.0"))
+
+		testutil.Ok(t, mf.SetToolchain(""))
+		expectContent(t, "module _\n\ngo 1.21\n", testFile)
+		testutil.Equals(t, "", mf.Toolchain())
+	})
+	t.Run("rename", func(t *testing.T) {
+		t.Parallel()
+
+		testFile := filepath.Join(tmpDir, "test5.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte("module _\n\ngo 1.21\n"), os.ModePerm))
+		existingFile := filepath.Join(tmpDir, "test5-existing.mod")
+		testutil.Ok(t, os.WriteFile(existingFile, []byte("module _\n"), os.ModePerm))
+
+		mf, err := OpenFile(testFile)
+		testutil.Ok(t, err)
+		defer func() { testutil.Ok(t, mf.Close()) }()
+
+		testutil.NotOk(t, mf.Rename(existingFile))
+		testutil.Equals(t, testFile, mf.Filepath())
+
+		renamedFile := filepath.Join(tmpDir, "test5-renamed.mod")
+		testutil.Ok(t, mf.Rename(renamedFile))
+		testutil.Equals(t, renamedFile, mf.Filepath())
+		_, err = os.Stat(testFile)
+		testutil.Assert(t, os.IsNotExist(err))
+
+		// Still open for edits.
+		testutil.Ok(t, mf.SetGoVersion("1.22"))
+		expectContent(t, "module _\n\ngo 1.22\n", renamedFile)
+	})
+	t.Run("CRLF line endings", func(t *testing.T) {
+		t.Parallel()
+
+		testFile := filepath.Join(tmpDir, "test4.mod")
+		testutil.Ok(t, os.WriteFile(testFile, []byte("module _\r\n\r\ngo 1.17\r\n\r\n// Comment 1.\r\n\r\nrequire my/module v1.0.0 // cmd/yolo -tags=linux\r\n"), os.ModePerm))
+
+		mf, err := OpenFileForRead(testFile)
+		testutil.Ok(t, err)
+		testutil.Equals(t, []string{"Comment 1."}, mf.Comments())
+		testutil.Equals(t, "cmd/yolo -tags=linux", mf.RequireDirectives()[0].ExtraSuffixComment)
+		testutil.Ok(t, mf.Close())
+
+		m, err := OpenFile(testFile)
+		testutil.Ok(t, err)
+		testutil.Ok(t, m.SetGoVersion("1.18"))
+		expectContent(t, "module _\n\ngo 1.18\n\n// Comment 1.\n\nrequire my/module v1.0.0 // cmd/yolo -tags=linux\n", testFile)
+		testutil.Ok(t, m.Close())
+	})
+}
+
+func TestFile_UnchangedContentNotRewritten(t *testing.T) {
+	modFile := filepath.Join(t.TempDir(), "tool.mod")
+	testutil.Ok(t, os.WriteFile(modFile, []byte("module _ // comment\n\ngo 1.14\n"), os.ModePerm))
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	testutil.Ok(t, os.Chtimes(modFile, past, past))
+
+	mf, err := OpenFile(modFile)
+	testutil.Ok(t, err)
+	defer func() { testutil.Ok(t, mf.Close()) }()
+
+	testutil.Ok(t, mf.SetModule("_", "comment"))
+	fi, err := os.Stat(modFile)
+	testutil.Ok(t, err)
+	testutil.Equals(t, past, fi.ModTime())
+
+	testutil.Ok(t, mf.SetModule("_", "other comment"))
+	expectContent(t, "module _ // other comment\n\ngo 1.14\n", modFile)
 }
@@ -0,0 +1,14 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+//go:build !darwin && !dragonfly && !freebsd && !illumos && !linux && !netbsd && !openbsd
+
+package runner
+
+import "os/exec"
+
+// Process groups are not supported on this platform, so only the command itself is killed, not its children.
+
+func setProcessGroup(*exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error { return cmd.Process.Kill() }
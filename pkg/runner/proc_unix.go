@@ -0,0 +1,22 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+//go:build darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd
+
+package runner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes the command start in its own process group, so killProcessGroup terminates its children too
+// (e.g. git or compiler run by go).
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	// Negative pid signals the whole group, which has the same id as its leader.
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -6,6 +6,7 @@ package runner
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -14,11 +15,15 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
 	"github.com/bwplotka/bingo/pkg/version"
 	"github.com/efficientgo/core/errors"
+	"golang.org/x/mod/module"
 )
 
 // Runner allows to run certain commands against module aware Go CLI.
@@ -29,45 +34,237 @@ type Runner struct {
 	verbose   bool
 	goVersion *semver.Version
 
-	logger *log.Logger
+	// goEnv caches values of go environment variables, see GoEnv.
+	goEnvMtx sync.Mutex
+	goEnv    map[string]string
+
+	logger logging.Logger
+
+	// stdout and stderr, if set, receive go command output in real time when verbose.
+	stdout, stderr io.Writer
+
+	retryAttempts int
+	retryBase     time.Duration
+
+	workspace    bool
+	reproducible bool
+	gobin        string
+	offline      bool
+	// envOverrides replace variables of the process environment for all commands, see WithHTTPSProxy, WithGOPROXY and
+	// WithSSLCertFile.
+	envOverrides envars.EnvSlice
+}
+
+// Option configures Runner.
+type Option func(r *Runner)
+
+// WithOutput makes runner stream stdout and stderr of go commands to given writers, when verbose is enabled.
+// Output is still captured, so errors contain it too.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(r *Runner) {
+		r.stdout = stdout
+		r.stderr = stderr
+	}
+}
+
+// WithLogger makes runner log with the given leveled logger instead of the one given to NewRunner. Commands and their
+// output are logged on debug level, when verbose is enabled.
+func WithLogger(logger logging.Logger) Option {
+	return func(r *Runner) {
+		r.logger = logger
+	}
+}
+
+// WithRetry makes runner run go commands failing with ErrNetwork up to the given number of attempts in total,
+// waiting base, 2*base, 4*base... between them. Other failures are never retried. Attempts lower than 2 disable retries.
+// By default, go commands are attempted 3 times with 1s base.
+func WithRetry(attempts int, base time.Duration) Option {
+	return func(r *Runner) {
+		r.retryAttempts = attempts
+		r.retryBase = base
+	}
+}
+
+// WithWorkspaceMode makes runner leave GOWORK as set in the environment, so go commands resolve modules of go.work found
+// in the current or parent directories. By default, runner sets GOWORK=off, so each module file is resolved in isolation.
+// NOTE: go rejects -modfile in workspace mode, so commands run with module file fail if go.work is found.
+func WithWorkspaceMode(enabled bool) Option {
+	return func(r *Runner) {
+		r.workspace = enabled
+	}
+}
+
+// WithReproducible makes runner build binaries with flags that make them byte-identical across machines: -trimpath
+// (no local file system paths) and, since Go 1.18, -buildvcs=false (no VCS information of the current directory). Flags
+// are added in front of the build arguments, unless the same flag is given already, so a tool can opt out with its own
+// build flag, e.g. -trimpath=false.
+func WithReproducible(enabled bool) Option {
+	return func(r *Runner) {
+		r.reproducible = enabled
+	}
 }
 
-var versionRegexp = regexp.MustCompile(`^go version.* go((?:[0-9]+)(?:\.[0-9]+)?(?:\.[0-9]+)?)`)
+// WithGOBIN makes runner set GOBIN to the given directory for all commands, overriding GOBIN of the environment, so
+// binaries are installed there (see bingo.GoBin) without mutating the process environment. The directory has to be
+// absolute, as go requires. Output directory of the project (see bingo get --output-dir) still takes precedence.
+func WithGOBIN(dir string) Option {
+	return func(r *Runner) {
+		r.gobin = dir
+	}
+}
 
-// parseGoVersion ignores pre-release identifiers immediately following the
-// patch version since we don't expect goVersionOutput to be SemVer-compliant.
-func parseGoVersion(goVersionOutput string) (*semver.Version, error) {
-	goVersion := versionRegexp.FindStringSubmatch(goVersionOutput)
-	if goVersion == nil {
-		return nil, errors.Newf("unexpected go version output; expected 'go version go<semver> ...; found %v", strings.TrimRight(goVersionOutput, "\n"))
+// WithOffline makes runner set GOPROXY=off and GOFLAGS=-mod=mod for all commands, so go never accesses network and
+// builds only from modules already in the module cache (e.g. pre-populated GOMODCACHE in air-gapped environment).
+// Commands needing modules that are not cached fail with ErrNotInModCache.
+func WithOffline(enabled bool) Option {
+	return func(r *Runner) {
+		r.offline = enabled
 	}
-	return semver.NewVersion(goVersion[1])
+}
+
+// WithHTTPSProxy makes runner set HTTPS_PROXY to the given proxy URL for all commands, overriding HTTPS_PROXY of the
+// environment, so go (and git run by go) reach module proxies and VCS servers through it without mutating the process
+// environment or global git and go configuration. Empty URL disables proxy set in the environment.
+func WithHTTPSProxy(proxyURL string) Option {
+	return func(r *Runner) {
+		r.envOverrides.Set("HTTPS_PROXY=" + proxyURL)
+	}
+}
+
+// WithGOPROXY makes runner set GOPROXY to the given value (e.g. "https://proxy.corp.example.com,direct") for all
+// commands, overriding GOPROXY of the environment and go env. Build environment variables of the tool still take
+// precedence, and WithOffline disables the proxy anyway.
+func WithGOPROXY(goproxy string) Option {
+	return func(r *Runner) {
+		r.envOverrides.Set("GOPROXY=" + goproxy)
+	}
+}
+
+// WithSSLCertFile makes runner set SSL_CERT_FILE to the given file with PEM encoded CA certificates for all commands,
+// overriding SSL_CERT_FILE of the environment, so go trusts e.g. corporate proxy with custom CA. The path has to be
+// absolute, since commands run in the module directory. On platforms where go does not read SSL_CERT_FILE (e.g. macOS
+// and windows), it has no effect.
+func WithSSLCertFile(file string) Option {
+	return func(r *Runner) {
+		r.envOverrides.Set("SSL_CERT_FILE=" + file)
+	}
+}
+
+// reproducibleBuildFlags are build flags added by WithReproducible with the minimum Go version supporting them.
+var reproducibleBuildFlags = []struct {
+	flag  string
+	minGo *semver.Version
+}{
+	{flag: "-trimpath", minGo: version.Go114},
+	{flag: "-buildvcs=false", minGo: version.Go118},
+}
+
+// withReproducibleFlags returns build args with reproducibleBuildFlags supported by the given Go, that are not in args yet.
+func withReproducibleFlags(goVersion *semver.Version, args []string) []string {
+	given := map[string]struct{}{}
+	for _, a := range args {
+		given[buildFlagName(a)] = struct{}{}
+	}
+	ret := make([]string, 0, len(reproducibleBuildFlags)+len(args))
+	for _, f := range reproducibleBuildFlags {
+		if _, ok := given[buildFlagName(f.flag)]; ok {
+			continue
+		}
+		if goVersion != nil && !version.AtLeast(goVersion, f.minGo) {
+			continue
+		}
+		ret = append(ret, f.flag)
+	}
+	return append(ret, args...)
+}
+
+// buildFlagName returns name of the flag without dashes and value, e.g. "trimpath" for "--trimpath=false".
+func buildFlagName(arg string) string {
+	arg = strings.TrimLeft(arg, "-")
+	if i := strings.Index(arg, "="); i >= 0 {
+		return arg[:i]
+	}
+	return arg
+}
+
+var (
+	versionRegexp      = regexp.MustCompile(`^go version go((?:[0-9]+)(?:\.[0-9]+)?(?:\.[0-9]+)?)(\S*)`)
+	looseVersionRegexp = regexp.MustCompile(`\bgo((?:[0-9]+)(?:\.[0-9]+)?(?:\.[0-9]+)?)`)
+	preReleaseRegexp   = regexp.MustCompile(`^(?:rc|beta)[0-9]+$`)
+)
+
+// parseGoVersion returns Go version from `go version` output. It ignores pre-release identifiers immediately following
+// the patch version since we don't expect goVersionOutput to be SemVer-compliant. Development and vendor-patched
+// toolchains (e.g. "go version devel go1.23-abc ...") are parsed as the closest release version and reported as not
+// exact. Other lines (e.g. printed by go while downloading toolchain requested by GOTOOLCHAIN) are skipped.
+func parseGoVersion(goVersionOutput string) (_ *semver.Version, exact bool, _ error) {
+	for _, line := range strings.Split(goVersionOutput, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "go version ") {
+			continue
+		}
+
+		if m := versionRegexp.FindStringSubmatch(line); m != nil {
+			v, err := semver.NewVersion(m[1])
+			if err != nil {
+				return nil, false, err
+			}
+			return v, m[2] == "" || preReleaseRegexp.MatchString(m[2]), nil
+		}
+		if m := looseVersionRegexp.FindStringSubmatch(line); m != nil {
+			v, err := semver.NewVersion(m[1])
+			if err != nil {
+				return nil, false, err
+			}
+			return v, false, nil
+		}
+		if strings.HasPrefix(line, "go version devel") {
+			// Old development builds print only commit, they are likely newer than any release we know.
+			return version.Latest, false, nil
+		}
+	}
+	return nil, false, errors.Newf("unexpected go version output; expected 'go version go<semver> ...; found %v", strings.TrimRight(goVersionOutput, "\n"))
 }
 
 func isSupportedVersion(v *semver.Version) error {
-	if !v.LessThan(version.Go114) {
+	if version.AtLeast(v, version.Go114) {
 		return nil
 	}
 	return errors.Newf("found unsupported go version: %v; requires go 1.14.x or higher", v.String())
 }
 
-// NewRunner checks Go version compatibility then returns Runner.
-func NewRunner(ctx context.Context, logger *log.Logger, insecure bool, goCmd string) (*Runner, error) {
+// NewRunner checks Go version compatibility then returns Runner. It logs all messages to the given logger, unless
+// WithLogger is given, in which case logger can be nil.
+func NewRunner(ctx context.Context, logger *log.Logger, insecure bool, goCmd string, opts ...Option) (*Runner, error) {
 	output := &bytes.Buffer{}
 	r := &Runner{
-		goCmd:    goCmd,
-		insecure: insecure,
-		logger:   logger,
+		goCmd:         goCmd,
+		insecure:      insecure,
+		retryAttempts: 3,
+		retryBase:     time.Second,
+	}
+	if logger != nil {
+		// Verbose output is gated by Verbose, so the adapter does not filter anything.
+		r.logger = logging.NewStd(logger, logging.LevelDebug)
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	if r.logger == nil {
+		r.logger = logging.Discard
 	}
 
 	if err := r.execGo(ctx, output, nil, "", "", "version"); err != nil {
 		return nil, errors.Wrap(err, "exec go to detect the version")
 	}
 
-	goVersion, err := parseGoVersion(output.String())
+	goVersion, exact, err := parseGoVersion(output.String())
 	if err != nil {
 		return nil, errors.Wrap(err, "parse go version")
 	}
+	if !exact {
+		r.logger.Warnf("development or custom Go toolchain detected (%v); assuming it's compatible with Go %v\n", strings.TrimSpace(lastLine(output.String())), goVersion)
+	}
 
 	r.goVersion = goVersion
 	return r, isSupportedVersion(r.goVersion)
@@ -77,10 +274,80 @@ func (r *Runner) GoVersion() *semver.Version {
 	return r.goVersion
 }
 
+// Reproducible returns true if binaries are built with reproducible build flags. See WithReproducible.
+func (r *Runner) Reproducible() bool {
+	return r.reproducible
+}
+
+// GoEnv returns value of the given go environment variable, as `go env <key>` prints it in the environment of the
+// runner (without any module file or extra environment variables). Successful result is cached, so `go env` is run at
+// most once per key.
+func (r *Runner) GoEnv(ctx context.Context, key string) (string, error) {
+	r.goEnvMtx.Lock()
+	defer r.goEnvMtx.Unlock()
+
+	if v, ok := r.goEnv[key]; ok {
+		return v, nil
+	}
+	out := &bytes.Buffer{}
+	if err := r.execGo(ctx, out, nil, "", "", "env", key); err != nil {
+		return "", newGoError(err, out.String(), false)
+	}
+	if r.goEnv == nil {
+		r.goEnv = map[string]string{}
+	}
+	r.goEnv[key] = strings.Trim(out.String(), "\n")
+	return r.goEnv[key], nil
+}
+
+// GoModCache returns directory where go caches modules (GOMODCACHE). Go versions without GOMODCACHE (before 1.15) use
+// pkg/mod in the first GOPATH entry.
+func (r *Runner) GoModCache(ctx context.Context) (string, error) {
+	cache, err := r.GoEnv(ctx, "GOMODCACHE")
+	if err != nil {
+		return "", errors.Wrap(err, "go env GOMODCACHE")
+	}
+	if cache != "" {
+		return cache, nil
+	}
+	gopath, err := r.GoEnv(ctx, "GOPATH")
+	if err != nil {
+		return "", errors.Wrap(err, "go env GOPATH")
+	}
+	if l := filepath.SplitList(gopath); len(l) > 0 {
+		return filepath.Join(l[0], "pkg", "mod"), nil
+	}
+	return "", errors.New("neither GOMODCACHE nor GOPATH is set")
+}
+
 func (r *Runner) Verbose() {
 	r.verbose = true
 }
 
+// streams reports if go command output is streamed to configured writers.
+func (r *Runner) streams() bool {
+	return r.verbose && (r.stdout != nil || r.stderr != nil)
+}
+
+// syncWriter allows stdout and stderr to be captured into the same writer concurrently.
+type syncWriter struct {
+	mtx sync.Mutex
+	w   io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.w.Write(p)
+}
+
+func teeWriter(output io.Writer, w io.Writer) io.Writer {
+	if w == nil {
+		return output
+	}
+	return io.MultiWriter(output, w)
+}
+
 var cmdsSupportingModFileArg = map[string]struct{}{
 	"init":    {},
 	"get":     {},
@@ -89,33 +356,107 @@ var cmdsSupportingModFileArg = map[string]struct{}{
 	"build":   {},
 }
 
+// withModFileArg returns go command args with -modfile flag of the given module file put after the (sub)command supporting it.
+func withModFileArg(modFile string, args []string) []string {
+	if modFile == "" {
+		return args
+	}
+	for i, arg := range args {
+		if _, ok := cmdsSupportingModFileArg[arg]; ok {
+			ret := make([]string, 0, len(args)+1)
+			ret = append(ret, args[:i+1]...)
+			ret = append(ret, fmt.Sprintf("-modfile=%s", modFile))
+			return append(ret, args[i+1:]...)
+		}
+	}
+	return args
+}
+
 func (r *Runner) execGo(ctx context.Context, output io.Writer, e envars.EnvSlice, cd string, modFile string, args ...string) error {
-	if modFile != "" {
-		for i, arg := range args {
-			if _, ok := cmdsSupportingModFileArg[arg]; ok {
-				if i == len(args)-1 {
-					args = append(args, fmt.Sprintf("-modfile=%s", modFile))
-					break
-				}
-				args = append(args[:i+1], append([]string{fmt.Sprintf("-modfile=%s", modFile)}, args[i+1:]...)...)
-				break
-			}
+	args = withModFileArg(modFile, args)
+	for attempt := 1; ; attempt++ {
+		// Capture each attempt separately, so output of the failed ones does not leak into the result.
+		out := &bytes.Buffer{}
+		err := r.exec(ctx, out, e, cd, r.goCmd, args...)
+		if err == nil || attempt >= r.retryAttempts || classifyGoOutput(out.String(), false) != ErrNetwork {
+			_, _ = output.Write(out.Bytes())
+			return err
+		}
+
+		delay := r.retryBase << (attempt - 1)
+		r.logger.Infof("'go %s' failed with network error (attempt %d/%d), retrying in %v: %s\n", strings.Join(args, " "), attempt, r.retryAttempts, delay, lastLine(out.String()))
+		select {
+		case <-ctx.Done():
+			_, _ = output.Write(out.Bytes())
+			return err
+		case <-time.After(delay):
 		}
 	}
-	return r.exec(ctx, output, e, cd, r.goCmd, args...)
 }
 
-func (r *Runner) exec(ctx context.Context, output io.Writer, e envars.EnvSlice, cd string, command string, args ...string) error {
-	cmd := exec.CommandContext(ctx, command, args...)
-	cmd.Dir = filepath.Join(cmd.Dir, cd)
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	return s[strings.LastIndex(s, "\n")+1:]
+}
+
+type commandTimeoutKey struct{}
+
+// ContextWithCommandTimeout returns context that limits each command runner runs with it (e.g. go get for a single tool)
+// to the given timeout. On expiry or cancellation, the command is killed together with its child processes (on unix)
+// and the returned error matches (see errors.Is) context.DeadlineExceeded or context.Canceled. Zero timeout means no limit.
+func ContextWithCommandTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, commandTimeoutKey{}, timeout)
+}
+
+func withCommandTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout, _ := ctx.Value(commandTimeoutKey{}).(time.Duration); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// commandEnvs returns the given envs with ones runner enforces for every command on top of them.
+func (r *Runner) commandEnvs(e envars.EnvSlice) envars.EnvSlice {
+	// Overrides act as the process environment, so build envs of the tool take precedence.
+	e = envars.MergeEnvSlices(r.envOverrides, e...)
 	// TODO(bwplotka): Might be surprising, let's return err when this env variable is altered.
-	e = envars.MergeEnvSlices(os.Environ(), e...)
 	e.Set("GO111MODULE=on")
-	e.Set("GOWORK=off")
-	cmd.Env = e
+	if !r.workspace {
+		e.Set("GOWORK=off")
+	}
+	if r.gobin != "" {
+		e.Set("GOBIN=" + r.gobin)
+	}
+	if r.offline {
+		// Keep GOFLAGS of the tool or environment (e.g. -tags).
+		goflags, ok := e.Lookup("GOFLAGS")
+		if !ok {
+			goflags = os.Getenv("GOFLAGS")
+		}
+		e.Set("GOPROXY=off", "GOFLAGS="+strings.TrimSpace(goflags+" -mod=mod"))
+	}
+	return e
+}
+
+func (r *Runner) exec(ctx context.Context, output io.Writer, e envars.EnvSlice, cd string, command string, args ...string) error {
+	ctx, cancel := withCommandTimeout(ctx)
+	defer cancel()
+
+	cmd := exec.Command(command, args...)
+	setProcessGroup(cmd)
+	cmd.Dir = filepath.Join(cmd.Dir, cd)
+	cmd.Env = envars.MergeEnvSlices(os.Environ(), r.commandEnvs(e)...)
 	cmd.Stdout = output
 	cmd.Stderr = output
-	if err := cmd.Run(); err != nil {
+	if r.streams() {
+		sw := &syncWriter{w: output}
+		cmd.Stdout = teeWriter(sw, r.stdout)
+		cmd.Stderr = teeWriter(sw, r.stderr)
+	}
+	if err := run(ctx, cmd); err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "command '%s %s' killed", command, strings.Join(args, " "))
+		}
 		if _, ok := err.(*exec.ExitError); ok {
 			if r.verbose {
 				return errors.Newf("error while running command '%s %s'; err: %v", command, strings.Join(args, " "), err)
@@ -125,18 +466,247 @@ func (r *Runner) exec(ctx context.Context, output io.Writer, e envars.EnvSlice,
 		return errors.Newf("error while running command '%s %s'; err: %v", command, strings.Join(args, " "), err)
 	}
 	if r.verbose {
-		r.logger.Printf("exec '%s %s'\n", command, strings.Join(args, " "))
+		r.logger.Debugf("exec '%s %s'\n", command, strings.Join(args, " "))
 	}
 	return nil
 }
 
+// run runs the command and kills its process group when the context is done. Children have to be killed too, otherwise
+// they keep output pipes open and Wait blocks until they exit.
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+	return cmd.Wait()
+}
+
+var (
+	// ErrModuleNotFound means go could not find requested module, package or version (e.g. 404 from proxy or unknown revision).
+	ErrModuleNotFound = errors.New("module not found")
+	// ErrNetwork means go could not reach module proxy or VCS server (e.g. DNS, connection or TLS failure). It's usually transient.
+	ErrNetwork = errors.New("network error")
+	// ErrBuildFailed means go build failed to compile the package.
+	ErrBuildFailed = errors.New("build failed")
+	// ErrAuth means module proxy or VCS server rejected go, because credentials are missing or wrong (e.g. 401 from the
+	// server or git asking for username).
+	ErrAuth = errors.New("authentication failed")
+	// ErrForbidden means module proxy or VCS server denied access (403) without asking for credentials, e.g. because of
+	// the policy of the proxy or insufficient permissions of given credentials.
+	ErrForbidden = errors.New("access denied")
+	// ErrNotInModCache means go needed module that is not in the module cache, while downloading is disabled (GOPROXY=off,
+	// see WithOffline).
+	ErrNotInModCache = errors.New("module not in module cache")
+)
+
+var (
+	networkErrRegexp = regexp.MustCompile(`dial tcp|i/o timeout|connection refused|connection reset|no such host|server misbehaving|` +
+		`network is unreachable|TLS handshake timeout|Client\.Timeout exceeded|` +
+		`\b(500 Internal Server Error|502 Bad Gateway|503 Service Unavailable|504 Gateway Timeout)\b`)
+	// 403 alone is not a sign of missing credentials, e.g. proxies deny modules by policy with it too. See forbiddenErrRegexp.
+	authErrRegexp = regexp.MustCompile(`\b401 Unauthorized\b|terminal prompts disabled|could not read (Username|Password)|` +
+		`(?i:authentication failed|invalid username or password)`)
+	forbiddenErrRegexp      = regexp.MustCompile(`\b403 Forbidden\b`)
+	moduleNotFoundErrRegexp = regexp.MustCompile(`\b(404 Not Found|410 Gone)\b|unknown revision|no matching versions for query|` +
+		`cannot find module providing package|no required module provides package|does not contain package|` +
+		`not found: module|(?i:repository not found)|is not in (GOROOT|std)|malformed module path`)
+	notInModCacheErrRegexp = regexp.MustCompile(`disabled by GOPROXY=off`)
+	// Compiler errors are reported as <file>.go:<line>[:<column>]: <message>.
+	compileErrRegexp = regexp.MustCompile(`(?m)^\S+\.go:\d+(:\d+)?: `)
+)
+
+// GoError is returned when go command fails. It keeps full command output and matches (see errors.Is) ErrModuleNotFound,
+// ErrNetwork, ErrAuth, ErrForbidden, ErrBuildFailed or ErrNotInModCache if the failure was recognized from the output.
+type GoError struct {
+	// Output is the full (combined stdout and stderr) output of the go command.
+	Output string
+	// Kind is one of ErrModuleNotFound, ErrNetwork, ErrAuth, ErrForbidden, ErrBuildFailed, ErrNotInModCache or nil if unknown.
+	Kind error
+
+	err error
+}
+
+func newGoError(err error, output string, compiles bool) *GoError {
+	return &GoError{Output: output, Kind: classifyGoOutput(output, compiles), err: err}
+}
+
+// classifyGoOutput returns kind of go command failure based on its output. Only commands that compile (e.g. go build) can
+// fail with ErrBuildFailed. Network errors are checked first, since those typically cause other failures too.
+func classifyGoOutput(output string, compiles bool) error {
+	switch {
+	case networkErrRegexp.MatchString(output):
+		return ErrNetwork
+	case notInModCacheErrRegexp.MatchString(output):
+		return ErrNotInModCache
+	case authErrRegexp.MatchString(output):
+		return ErrAuth
+	case forbiddenErrRegexp.MatchString(output):
+		return ErrForbidden
+	case moduleNotFoundErrRegexp.MatchString(output):
+		return ErrModuleNotFound
+	case compiles && compileErrRegexp.MatchString(output):
+		return ErrBuildFailed
+	}
+	return nil
+}
+
+func (e *GoError) Error() string {
+	return e.Output + ": " + e.err.Error()
+}
+
+func (e *GoError) Unwrap() error {
+	return e.err
+}
+
+func (e *GoError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// BuildInfo is build information embedded in Go binary, as printed by `go version -m`.
+type BuildInfo struct {
+	// GoVersion is a version of Go the binary was built with, e.g. "go1.21.4".
+	GoVersion string
+	// Path is the main package path.
+	Path string
+	// Main is the module of the main package. Version is "(devel)" if built from local checkout.
+	Main module.Version
+	// MainReplace is set if the main module was replaced, e.g with local directory.
+	MainReplace *module.Version
+	Deps        []module.Version
+	// DepReplaces are replacements of Deps by dependency module path, e.g. from replace directives of the module file.
+	DepReplaces map[string]module.Version
+	// Settings are build settings, e.g. "-tags=yolo" or "CGO_ENABLED=0".
+	Settings []string
+}
+
+// BuildInfo runs `go version -m` against the given binary and returns its build information. It returns error if
+// the file is not Go binary or build information is missing (e.g. binary was built without Go modules).
+func (r *Runner) BuildInfo(ctx context.Context, binPath string) (BuildInfo, error) {
+	out := &bytes.Buffer{}
+	if err := r.execGo(ctx, out, nil, "", "", "version", "-m", binPath); err != nil {
+		return BuildInfo{}, errors.Wrap(err, strings.TrimSpace(out.String()))
+	}
+	return parseBuildInfo(out.String())
+}
+
+// Dependency is a module the binary was built with.
+type Dependency struct {
+	module.Version
+	// Replace is set if the module was replaced during build, so code of the replacement was built instead.
+	Replace *module.Version
+}
+
+// Dependencies runs `go version -m` against the given binary and returns all modules it was built with (except the
+// main one), in the order go prints them. It might be slow for many binaries, since go has to read each of them.
+func (r *Runner) Dependencies(ctx context.Context, binPath string) ([]Dependency, error) {
+	info, err := r.BuildInfo(ctx, binPath)
+	if err != nil {
+		return nil, err
+	}
+	return info.Dependencies(), nil
+}
+
+// Dependencies returns Deps with their replacements.
+func (i BuildInfo) Dependencies() []Dependency {
+	ret := make([]Dependency, 0, len(i.Deps))
+	for _, d := range i.Deps {
+		dep := Dependency{Version: d}
+		if r, ok := i.DepReplaces[d.Path]; ok {
+			dep.Replace = &r
+		}
+		ret = append(ret, dep)
+	}
+	return ret
+}
+
+func parseBuildInfo(output string) (BuildInfo, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	var info BuildInfo
+	if i := strings.LastIndex(lines[0], ": "); i >= 0 {
+		info.GoVersion = strings.TrimSpace(lines[0][i+2:])
+	}
+
+	lastWasMain, lastWasDep := false, false
+	for _, l := range lines[1:] {
+		fields := strings.Split(strings.TrimSpace(l), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		isMain, isDep := false, false
+		switch fields[0] {
+		case "path":
+			info.Path = fields[1]
+		case "mod", "dep":
+			m := module.Version{Path: fields[1]}
+			if len(fields) > 2 {
+				m.Version = fields[2]
+			}
+			if fields[0] == "dep" {
+				info.Deps, isDep = append(info.Deps, m), true
+				break
+			}
+			info.Main, isMain = m, true
+		case "=>":
+			// Replacement of the previous module.
+			r := module.Version{Path: fields[1]}
+			if len(fields) > 2 {
+				r.Version = fields[2]
+			}
+			switch {
+			case lastWasMain:
+				info.MainReplace = &r
+			case lastWasDep:
+				if info.DepReplaces == nil {
+					info.DepReplaces = map[string]module.Version{}
+				}
+				info.DepReplaces[info.Deps[len(info.Deps)-1].Path] = r
+			}
+		case "build":
+			info.Settings = append(info.Settings, fields[1])
+		}
+		lastWasMain, lastWasDep = isMain, isDep
+	}
+	if info.Main.Path == "" {
+		return info, errors.New("no module information found; binary was not built with Go modules")
+	}
+	return info, nil
+}
+
 type Runnable interface {
 	GoVersion() *semver.Version
 	List(args ...string) (string, error)
 	GetD(packages ...string) (string, error)
 	Build(pkg, out string, args ...string) error
+	BuildCommand(pkg, out string, args ...string) (Command, error)
 	GoEnv(args ...string) (string, error)
 	ModDownload(args ...string) error
+	ModVersions(modulePath string) ([]string, error)
+	ModQuery(modulePath, query string) (string, error)
+	ModQueryRef(modulePath, query string) (version string, ref string, err error)
+	ModList(args ...string) ([]Module, error)
+	Exec(command string, args ...string) (string, error)
+}
+
+// Command is a go command, as runner would run it. It allows to reproduce e.g. the build manually.
+type Command struct {
+	// Dir is a working directory of the command.
+	Dir string
+	// Env are environment variables set for the command on top of the environment.
+	Env envars.EnvSlice
+	// Args are the go command and its arguments.
+	Args []string
 }
 
 type runnable struct {
@@ -152,7 +722,7 @@ type runnable struct {
 func (r *Runner) ModInit(ctx context.Context, cd, modFile, moduleName string) error {
 	out := &bytes.Buffer{}
 	if err := r.execGo(ctx, out, nil, cd, modFile, append([]string{"mod", "init"}, moduleName)...); err != nil {
-		return errors.Wrap(err, out.String())
+		return newGoError(err, out.String(), false)
 	}
 	return nil
 }
@@ -169,6 +739,16 @@ func (r *Runner) With(ctx context.Context, modFile string, dir string, extraEnvV
 	return ru
 }
 
+// envs returns extra environment variables with $VAR and ${VAR} references expanded. Envs are stored un-expanded
+// (e.g. CGO_CFLAGS=-I${MYSDK}/include), so module files stay portable.
+func (r *runnable) envs() (envars.EnvSlice, error) {
+	envs, err := r.extraEnvVars.Expand(os.LookupEnv)
+	if err != nil {
+		return nil, errors.Wrap(err, "expand envs")
+	}
+	return envs, nil
+}
+
 func (r *runnable) GoVersion() *semver.Version {
 	return r.r.GoVersion()
 }
@@ -176,18 +756,128 @@ func (r *runnable) GoVersion() *semver.Version {
 // List runs `go list` against separate go modules files if any.
 func (r *runnable) List(args ...string) (string, error) {
 	a := []string{"list"}
+	envs, err := r.envs()
+	if err != nil {
+		return "", err
+	}
 	out := &bytes.Buffer{}
-	if err := r.r.execGo(r.ctx, out, r.extraEnvVars, r.dir, r.modFile, append(a, args...)...); err != nil {
-		return "", errors.Wrap(err, out.String())
+	if err := r.r.execGo(r.ctx, out, envs, r.dir, r.modFile, append(a, args...)...); err != nil {
+		return "", newGoError(err, out.String(), false)
 	}
 	return strings.Trim(out.String(), "\n"), nil
 }
 
+// ModVersions runs `go list -m -versions` and returns all known versions of the given module.
+func (r *runnable) ModVersions(modulePath string) ([]string, error) {
+	out, err := r.List("-m", "-versions", modulePath)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return nil, errors.Newf("unexpected empty output of go list -m -versions %v", modulePath)
+	}
+	return fields[1:], nil
+}
+
+// ModQuery runs `go list -m` for <modulePath>@<query> and returns resolved version, e.g. pseudo-version for commit SHA.
+func (r *runnable) ModQuery(modulePath, query string) (string, error) {
+	out, err := r.List("-m", "-f={{.Version}}", modulePath+"@"+query)
+	if err != nil {
+		return "", err
+	}
+	if out == "" {
+		return "", errors.Newf("unexpected empty output of go list -m %v@%v", modulePath, query)
+	}
+	return out, nil
+}
+
+// ModQueryRef is like ModQuery, but it also returns git reference the query was resolved from (e.g. "refs/tags/v1.0.0" or
+// "refs/heads/main"), as reported by the origin of the module version. Reference is empty if it's not known, e.g. for
+// go older than 1.19 or proxies that don't serve origin information.
+func (r *runnable) ModQueryRef(modulePath, query string) (string, string, error) {
+	out, err := r.List("-m", "-json", modulePath+"@"+query)
+	if err != nil {
+		return "", "", err
+	}
+	var m struct {
+		Version string
+		Origin  *struct {
+			Ref string
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &m); err != nil {
+		return "", "", errors.Wrapf(err, "parse go list -m -json %v@%v output", modulePath, query)
+	}
+	if m.Version == "" {
+		return "", "", errors.Newf("unexpected empty version in go list -m -json %v@%v output", modulePath, query)
+	}
+	if m.Origin == nil {
+		return m.Version, "", nil
+	}
+	return m.Version, m.Origin.Ref, nil
+}
+
+// Module is a module as printed by go list -m -json.
+type Module struct {
+	Path    string
+	Version string
+	// Main is true for the main module.
+	Main bool
+	// Dir is a directory with module sources. Empty if the module is not downloaded.
+	Dir string
+	// GoMod is a path to the go.mod file of the module, e.g. in the module cache download directory.
+	GoMod   string
+	Replace *Module
+	// Time is a time the module version was created, e.g. commit time. Nil if not known, e.g. for the main module.
+	Time *time.Time
+	// Origin describes where the module version comes from. Nil if not known, e.g. for go older than 1.19 or proxies that
+	// don't serve origin information.
+	Origin *ModuleOrigin
+}
+
+// ModuleOrigin is a VCS origin of the module version as printed by go list -m -json.
+type ModuleOrigin struct {
+	VCS string
+	URL string
+	// Hash is a full commit hash of the module version.
+	Hash string
+	// Ref is a reference the module version was resolved from, e.g. "refs/tags/v1.0.0".
+	Ref string
+}
+
+// ModList runs `go list -m -json` with given args (e.g. "all") and returns listed modules.
+func (r *runnable) ModList(args ...string) ([]Module, error) {
+	out, err := r.List(append([]string{"-m", "-json"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	// Output is a stream of JSON objects, but it might be preceded by go logs, e.g. about downloading.
+	i := strings.Index(out, "{")
+	if i < 0 {
+		return nil, nil
+	}
+	var ret []Module
+	d := json.NewDecoder(strings.NewReader(out[i:]))
+	for d.More() {
+		var m Module
+		if err := d.Decode(&m); err != nil {
+			return nil, errors.Wrapf(err, "parse go list -m -json %v output", strings.Join(args, " "))
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
 // GoEnv runs `go env` with given args.
 func (r *runnable) GoEnv(args ...string) (string, error) {
+	envs, err := r.envs()
+	if err != nil {
+		return "", err
+	}
 	out := &bytes.Buffer{}
-	if err := r.r.execGo(r.ctx, out, r.extraEnvVars, r.dir, "", append([]string{"env"}, args...)...); err != nil {
-		return "", errors.Wrap(err, out.String())
+	if err := r.r.execGo(r.ctx, out, envs, r.dir, "", append([]string{"env"}, args...)...); err != nil {
+		return "", newGoError(err, out.String(), false)
 	}
 	return strings.Trim(out.String(), "\n"), nil
 }
@@ -199,28 +889,80 @@ func (r *runnable) GetD(packages ...string) (string, error) {
 		args = append(args, "-insecure")
 	}
 
+	envs, err := r.envs()
+	if err != nil {
+		return "", err
+	}
 	out := &bytes.Buffer{}
-	if err := r.r.execGo(r.ctx, out, r.extraEnvVars, r.dir, r.modFile, append(args, packages...)...); err != nil {
-		return "", errors.Wrap(err, out.String())
+	if err := r.r.execGo(r.ctx, out, envs, r.dir, r.modFile, append(args, packages...)...); err != nil {
+		return "", newGoError(err, out.String(), false)
 	}
 	return strings.Trim(out.String(), "\n"), nil
 }
 
+// buildArgs returns arguments of go build of the package into out, including flags of WithReproducible, if enabled.
+func (r *runnable) buildArgs(pkg, out string, args []string) []string {
+	if r.r.reproducible {
+		args = withReproducibleFlags(r.r.goVersion, args)
+	}
+	ret := append([]string{"build", "-o=" + out}, args...)
+	return append(ret, pkg)
+}
+
+// BuildCommand returns go build command that Build with the same arguments runs, without running it.
+func (r *runnable) BuildCommand(pkg, out string, args ...string) (Command, error) {
+	envs, err := r.envs()
+	if err != nil {
+		return Command{}, err
+	}
+	return Command{
+		Dir:  r.dir,
+		Env:  r.r.commandEnvs(envs),
+		Args: append([]string{r.r.goCmd}, withModFileArg(r.modFile, r.buildArgs(pkg, out, args))...),
+	}, nil
+}
+
 // Build runs 'go build' against separate go modules file with given packages.
+// Build flags of WithReproducible are added, if enabled.
 func (r *runnable) Build(pkg, out string, args ...string) error {
-	args = append([]string{"build", "-o=" + out}, args...)
+	envs, err := r.envs()
+	if err != nil {
+		return err
+	}
 	output := &bytes.Buffer{}
-	if err := r.r.execGo(r.ctx, output, r.extraEnvVars, r.dir, r.modFile, append(args, pkg)...); err != nil {
-		return errors.Wrap(err, output.String())
+	if err := r.r.execGo(r.ctx, output, envs, r.dir, r.modFile, r.buildArgs(pkg, out, args)...); err != nil {
+		return newGoError(err, output.String(), true)
 	}
 
 	trimmed := strings.TrimSpace(output.String())
-	if r.r.verbose && trimmed != "" {
-		r.r.logger.Println(trimmed)
+	if r.r.verbose && !r.r.streams() && trimmed != "" {
+		r.r.logger.Debugf("%s", trimmed)
 	}
 	return nil
 }
 
+// Exec runs the given (non-go) command in the directory and with the environment of the runnable, e.g. to post-process built
+// binary. It returns trimmed, combined output of the command.
+func (r *runnable) Exec(command string, args ...string) (string, error) {
+	envs, err := r.envs()
+	if err != nil {
+		return "", err
+	}
+	output := &bytes.Buffer{}
+	if err := r.r.exec(r.ctx, output, envs, r.dir, command, args...); err != nil {
+		if trimmed := strings.TrimSpace(output.String()); trimmed != "" {
+			return "", errors.Wrapf(err, "%s", trimmed)
+		}
+		return "", err
+	}
+
+	trimmed := strings.TrimSpace(output.String())
+	if r.r.verbose && !r.r.streams() && trimmed != "" {
+		r.r.logger.Debugf("%s", trimmed)
+	}
+	return trimmed, nil
+}
+
 // ModDownload runs 'go mod download' against separate go modules file.
 func (r *runnable) ModDownload(args ...string) error {
 	a := []string{"mod", "download"}
@@ -229,14 +971,18 @@ func (r *runnable) ModDownload(args ...string) error {
 	}
 	a = append(a, fmt.Sprintf("-modfile=%s", r.modFile))
 
+	envs, err := r.envs()
+	if err != nil {
+		return err
+	}
 	out := &bytes.Buffer{}
-	if err := r.r.execGo(r.ctx, out, r.extraEnvVars, r.dir, r.modFile, append(a, args...)...); err != nil {
-		return errors.Wrap(err, out.String())
+	if err := r.r.execGo(r.ctx, out, envs, r.dir, r.modFile, append(a, args...)...); err != nil {
+		return newGoError(err, out.String(), false)
 	}
 
 	trimmed := strings.TrimSpace(out.String())
-	if r.r.verbose && trimmed != "" {
-		r.r.logger.Println(trimmed)
+	if r.r.verbose && !r.r.streams() && trimmed != "" {
+		r.r.logger.Debugf("%s", trimmed)
 	}
 	return nil
 }
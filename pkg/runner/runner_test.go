@@ -4,11 +4,25 @@
 package runner
 
 import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Masterminds/semver"
+	"github.com/bwplotka/bingo/internal/fakecmd"
+	"github.com/bwplotka/bingo/pkg/envars"
+	"github.com/bwplotka/bingo/pkg/logging"
 	"github.com/efficientgo/core/errors"
 	"github.com/efficientgo/core/merrors"
 	"github.com/efficientgo/core/testutil"
+	"golang.org/x/mod/module"
 )
 
 func TestParseAndIsSupportedVersion(t *testing.T) {
@@ -36,7 +50,7 @@ func TestParseAndIsSupportedVersion(t *testing.T) {
 	} {
 		t.Run(tcase.output, func(t *testing.T) {
 			errs := merrors.New()
-			v, err := parseGoVersion(tcase.output)
+			v, _, err := parseGoVersion(tcase.output)
 			if err != nil {
 				errs.Add(err)
 			}
@@ -55,3 +69,640 @@ func TestParseAndIsSupportedVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestParseGoVersion(t *testing.T) {
+	for _, tcase := range []struct {
+		output  string
+		version string
+		exact   bool
+	}{
+		{output: "go version go1.21.4 linux/amd64", version: "1.21.4", exact: true},
+		{output: "go version go1.22rc1 darwin/arm64", version: "1.22.0", exact: true},
+		{output: "go version go1.20.5 X:boringcrypto linux/amd64", version: "1.20.5", exact: true},
+		// Toolchain downloaded because of GOTOOLCHAIN.
+		{output: "go: downloading go1.22.0 (linux/amd64)\ngo version go1.22.0 linux/amd64\n", version: "1.22.0", exact: true},
+		{output: "go version devel go1.23-abc linux/amd64", version: "1.23.0"},
+		{output: "go version devel go1.21-02d8ebda83 Mon Feb 6 22:13:07 2023 +0000 linux/amd64", version: "1.21.0"},
+		{output: "go version devel +b7a85e0003 Fri Jul 17 16:47:59 2020 +0000 linux/amd64", version: "1.23.0"},
+		// Vendor-patched toolchains.
+		{output: "go version go1.21.6-bigcorp.3 linux/amd64", version: "1.21.6"},
+		{output: "go version go1.20.12 X:strictfipsruntime linux/amd64", version: "1.20.12", exact: true},
+		{output: "go version go1.19.13-microsoft linux/amd64", version: "1.19.13"},
+	} {
+		t.Run(tcase.output, func(t *testing.T) {
+			v, exact, err := parseGoVersion(tcase.output)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.version, v.String())
+			testutil.Equals(t, tcase.exact, exact)
+		})
+	}
+}
+
+func TestRunner_WithOutput(t *testing.T) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	r := &Runner{logger: logging.Discard}
+	WithOutput(stdout, stderr)(r)
+
+	t.Run("not verbose", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		testutil.Ok(t, r.exec(context.Background(), out, nil, "", "sh", "-c", "echo out; echo err >&2"))
+		testutil.Equals(t, "out\nerr\n", out.String())
+		testutil.Equals(t, "", stdout.String())
+		testutil.Equals(t, "", stderr.String())
+	})
+	t.Run("verbose", func(t *testing.T) {
+		r.Verbose()
+
+		out := &bytes.Buffer{}
+		testutil.NotOk(t, r.exec(context.Background(), out, nil, "", "sh", "-c", "echo out; echo err >&2; exit 1"))
+		// Streams are copied concurrently, so captured order is not guaranteed.
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		sort.Strings(lines)
+		testutil.Equals(t, []string{"err", "out"}, lines)
+		testutil.Equals(t, "out\n", stdout.String())
+		testutil.Equals(t, "err\n", stderr.String())
+	})
+}
+
+func TestRunner_WithLogger(t *testing.T) {
+	// Fake development toolchain, so runner warns on creation.
+	goCmd := fakecmd.Write(t, t.TempDir(), "go", "echo \"go version devel go1.23-abc123 linux/amd64\"\n")
+
+	for _, tcase := range []struct {
+		level    logging.Level
+		expected string
+	}{
+		{level: logging.LevelWarn, expected: "WARNING: development or custom Go toolchain detected (go version devel go1.23-abc123 linux/amd64); assuming it's compatible with Go 1.23.0\n"},
+		{level: logging.LevelError, expected: ""},
+	} {
+		t.Run(tcase.level.String(), func(t *testing.T) {
+			logs := &bytes.Buffer{}
+			r, err := NewRunner(context.Background(), nil, false, goCmd, WithLogger(logging.NewStd(log.New(logs, "", 0), tcase.level)))
+			testutil.Ok(t, err)
+			r.Verbose()
+			_, err = r.With(context.Background(), "", "", nil).Exec(goCmd, "version")
+			testutil.Ok(t, err)
+			// Executed commands are logged on debug level only.
+			testutil.Equals(t, tcase.expected, logs.String())
+		})
+	}
+}
+
+func TestRunner_Retry(t *testing.T) {
+	dir := t.TempDir()
+	// Fake go that fails with given output until attempts file has enough lines.
+	goCmd := fakecmd.Write(t, dir, "go", `echo attempt >> "$ATTEMPTS_FILE"
+if [ "$(wc -l < "$ATTEMPTS_FILE")" -lt "$SUCCESS_AFTER" ]; then
+  echo "$FAIL_OUTPUT" >&2
+  exit 1
+fi
+echo ok
+`)
+
+	for _, tcase := range []struct {
+		name         string
+		failOutput   string
+		successAfter int
+
+		expectedAttempts int
+		expectedErr      bool
+	}{
+		{name: "network error, succeeds on retry", failOutput: "go: reading https://proxy.golang.org/x/@v/list: 502 Bad Gateway", successAfter: 3, expectedAttempts: 3},
+		{name: "network error, attempts exhausted", failOutput: "dial tcp: lookup proxy.golang.org: no such host", successAfter: 10, expectedAttempts: 3, expectedErr: true},
+		{name: "not found error is not retried", failOutput: "go: reading https://proxy.golang.org/x/@v/v1.0.0.info: 404 Not Found", successAfter: 3, expectedAttempts: 1, expectedErr: true},
+		{name: "unknown error is not retried", failOutput: "go: yolo", successAfter: 3, expectedAttempts: 1, expectedErr: true},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			attemptsFile := filepath.Join(t.TempDir(), "attempts")
+			logs := &bytes.Buffer{}
+			r := &Runner{goCmd: goCmd, logger: logging.NewStd(log.New(logs, "", 0), logging.LevelDebug)}
+			WithRetry(3, time.Millisecond)(r)
+
+			out := &bytes.Buffer{}
+			err := r.execGo(context.Background(), out, []string{
+				"ATTEMPTS_FILE=" + attemptsFile,
+				"SUCCESS_AFTER=" + strconv.Itoa(tcase.successAfter),
+				"FAIL_OUTPUT=" + tcase.failOutput,
+			}, "", "", "get", "x")
+
+			b, rerr := os.ReadFile(attemptsFile)
+			testutil.Ok(t, rerr)
+			testutil.Equals(t, tcase.expectedAttempts, strings.Count(string(b), "attempt"))
+			testutil.Equals(t, tcase.expectedAttempts-1, strings.Count(logs.String(), "retrying"))
+			if tcase.expectedErr {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.failOutput+"\n", out.String())
+				return
+			}
+			testutil.Ok(t, err)
+			// Output of failed attempts is dropped.
+			testutil.Equals(t, "ok\n", out.String())
+		})
+	}
+}
+
+func TestRunner_CommandTimeout(t *testing.T) {
+	// Fake slow go with child process holding its output open.
+	goCmd := fakecmd.Write(t, t.TempDir(), "go", "echo started\nsleep 30 &\nsleep 30\n")
+
+	r := &Runner{goCmd: goCmd, logger: logging.Discard}
+	start := time.Now()
+	_, err := r.With(ContextWithCommandTimeout(context.Background(), 200*time.Millisecond), "", "", nil).List("-m", "x")
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, context.DeadlineExceeded), "expected deadline exceeded, got %v", err)
+	// Wait returns only when children holding the output are killed too.
+	testutil.Assert(t, time.Since(start) < 10*time.Second, "expected command with children killed on timeout, took %v", time.Since(start))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = r.With(ctx, "", "", nil).List("-m", "x")
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, context.Canceled), "expected canceled, got %v", err)
+
+	// No timeout by default.
+	fakecmd.Write(t, filepath.Dir(goCmd), "go", "sleep 0.3\necho ok\n")
+	out, err := r.With(ContextWithCommandTimeout(context.Background(), 0), "", "", nil).List("-m", "x")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "ok", out)
+}
+
+func TestRunnable_Envs(t *testing.T) {
+	// Fake go that prints environment it was run with.
+	goCmd := fakecmd.Write(t, t.TempDir(), "go", "env\n")
+
+	t.Setenv("GOPROXY", "https://proxy.golang.org,direct")
+	t.Setenv("GOFLAGS", "-mod=readonly")
+	t.Setenv("BINGO_TEST_INHERITED", "yes")
+	t.Setenv("BINGO_TEST_PRIVATE_HOST", "internal.example.com")
+
+	r := &Runner{goCmd: goCmd, logger: logging.Discard}
+	out, err := r.With(context.Background(), "", "", []string{
+		"GOPROXY=https://${BINGO_TEST_PRIVATE_HOST}",
+		"GONOSUMDB=${BINGO_TEST_PRIVATE_HOST}",
+		"GOFLAGS=-mod=mod",
+	}).GoEnv()
+	testutil.Ok(t, err)
+
+	envs := map[string][]string{}
+	for _, l := range strings.Split(out, "\n") {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) == 2 {
+			envs[kv[0]] = append(envs[kv[0]], kv[1])
+		}
+	}
+	testutil.Equals(t, []string{"https://internal.example.com"}, envs["GOPROXY"])
+	testutil.Equals(t, []string{"internal.example.com"}, envs["GONOSUMDB"])
+	testutil.Equals(t, []string{"-mod=mod"}, envs["GOFLAGS"])
+	testutil.Equals(t, []string{"yes"}, envs["BINGO_TEST_INHERITED"])
+	testutil.Equals(t, []string{"on"}, envs["GO111MODULE"])
+	testutil.Equals(t, []string{"off"}, envs["GOWORK"])
+
+	_, err = r.With(context.Background(), "", "", []string{"GOPROXY=https://${BINGO_TEST_NOT_DEFINED}"}).GoEnv()
+	testutil.NotOk(t, err)
+}
+
+func TestRunner_WithWorkspaceMode(t *testing.T) {
+	// Fake go that prints GOWORK it was run with.
+	goCmd := fakecmd.Write(t, t.TempDir(), "go", "echo \"GOWORK=$GOWORK\"\n")
+	t.Setenv("GOWORK", "/repo/go.work")
+
+	for _, tcase := range []struct {
+		opts     []Option
+		expected string
+	}{
+		{expected: "GOWORK=off"},
+		{opts: []Option{WithWorkspaceMode(false)}, expected: "GOWORK=off"},
+		{opts: []Option{WithWorkspaceMode(true)}, expected: "GOWORK=/repo/go.work"},
+	} {
+		t.Run(tcase.expected, func(t *testing.T) {
+			r := &Runner{goCmd: goCmd, logger: logging.Discard}
+			for _, o := range tcase.opts {
+				o(r)
+			}
+			out, err := r.With(context.Background(), "", "", nil).GoEnv()
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, out)
+		})
+	}
+}
+
+func TestRunner_WithReproducible(t *testing.T) {
+	// Fake go that records build arguments.
+	dir := t.TempDir()
+	goCmd := fakecmd.Write(t, dir, "go", "echo \"$@\" > \"$CALLS_FILE\"\n")
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+
+	for _, tcase := range []struct {
+		name      string
+		opts      []Option
+		goVersion string
+		flags     []string
+		expected  string
+	}{
+		{name: "disabled", goVersion: "1.21", flags: []string{"-tags=netgo"}, expected: "build -o=out -tags=netgo pkg"},
+		{name: "enabled", opts: []Option{WithReproducible(true)}, goVersion: "1.21", flags: []string{"-tags=netgo"}, expected: "build -o=out -trimpath -buildvcs=false -tags=netgo pkg"},
+		{name: "tool opts out", opts: []Option{WithReproducible(true)}, goVersion: "1.21", flags: []string{"-trimpath=false", "--buildvcs"}, expected: "build -o=out -trimpath=false --buildvcs pkg"},
+		{name: "no buildvcs before Go 1.18", opts: []Option{WithReproducible(true)}, goVersion: "1.17", expected: "build -o=out -trimpath pkg"},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			r := &Runner{goCmd: goCmd, logger: logging.Discard, goVersion: semver.MustParse(tcase.goVersion)}
+			for _, o := range tcase.opts {
+				o(r)
+			}
+			testutil.Ok(t, r.With(context.Background(), "", "", nil).Build("pkg", "out", tcase.flags...))
+			b, err := os.ReadFile(callsFile)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, strings.TrimSpace(string(b)))
+		})
+	}
+}
+
+func TestRunner_WithGOBIN(t *testing.T) {
+	// Trivial tool module.
+	modDir := t.TempDir()
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "go.mod"), []byte("module example.com/tool\n\ngo 1.14\n"), 0600))
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0600))
+
+	envGoBin := t.TempDir()
+	t.Setenv("GOBIN", envGoBin)
+	gobin := t.TempDir()
+
+	r, err := NewRunner(context.Background(), log.New(&bytes.Buffer{}, "", 0), false, "go", WithGOBIN(gobin))
+	testutil.Ok(t, err)
+	ru := r.With(context.Background(), "", modDir, nil)
+
+	out, err := ru.GoEnv("GOBIN")
+	testutil.Ok(t, err)
+	testutil.Equals(t, gobin, out)
+
+	_, err = ru.Exec("go", "install", ".")
+	testutil.Ok(t, err)
+	_, err = os.Stat(filepath.Join(gobin, "tool"))
+	testutil.Ok(t, err)
+	_, err = os.Stat(filepath.Join(envGoBin, "tool"))
+	testutil.Assert(t, os.IsNotExist(err), "expected nothing installed in GOBIN of the environment, got %v", err)
+}
+
+func TestRunner_WithOffline(t *testing.T) {
+	// Empty module cache, so nothing can be resolved without network.
+	t.Setenv("GOMODCACHE", t.TempDir())
+	t.Setenv("GOFLAGS", "-tags=yolo")
+	modDir := t.TempDir()
+	// Go requires go.mod in the directory to use -modfile, like in .bingo.
+	testutil.Ok(t, os.WriteFile(filepath.Join(modDir, "go.mod"), []byte("module _\n"), 0600))
+
+	r, err := NewRunner(context.Background(), log.New(&bytes.Buffer{}, "", 0), false, "go", WithOffline(true))
+	testutil.Ok(t, err)
+	testutil.Ok(t, r.ModInit(context.Background(), modDir, filepath.Join(modDir, "tool.mod"), "_"))
+	ru := r.With(context.Background(), filepath.Join(modDir, "tool.mod"), modDir, nil)
+
+	out, err := ru.GoEnv("GOPROXY", "GOFLAGS")
+	testutil.Ok(t, err)
+	testutil.Equals(t, "off\n-tags=yolo -mod=mod", out)
+
+	_, err = ru.GetD("github.com/fatih/faillint@v1.5.0")
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, ErrNotInModCache), "expected not in module cache error, got %v", err)
+	testutil.Assert(t, !errors.Is(err, ErrNetwork) && !errors.Is(err, ErrModuleNotFound))
+}
+
+func TestRunner_WithNetworkEnvs(t *testing.T) {
+	// Fake go that prints network related environment variables it was run with.
+	dir := t.TempDir()
+	goCmd := fakecmd.Write(t, dir, "go", "echo \"$HTTPS_PROXY $GOPROXY $SSL_CERT_FILE\"\n")
+	t.Setenv("HTTPS_PROXY", "http://ambient:3128")
+	t.Setenv("GOPROXY", "https://proxy.golang.org,direct")
+	t.Setenv("SSL_CERT_FILE", "")
+
+	newRunnable := func(envs envars.EnvSlice, opts ...Option) Runnable {
+		r := &Runner{goCmd: goCmd, logger: logging.Discard, goVersion: semver.MustParse("1.21")}
+		for _, o := range opts {
+			o(r)
+		}
+		return r.With(context.Background(), "", dir, envs)
+	}
+	opts := []Option{
+		WithHTTPSProxy("http://proxy.corp:3128"),
+		WithGOPROXY("https://goproxy.corp,direct"),
+		WithSSLCertFile("/etc/corp/ca.pem"),
+	}
+
+	out, err := newRunnable(nil).List()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "http://ambient:3128 https://proxy.golang.org,direct ", out)
+
+	out, err = newRunnable(nil, opts...).List()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "http://proxy.corp:3128 https://goproxy.corp,direct /etc/corp/ca.pem", out)
+
+	// Build envs of the tool take precedence, offline mode disables proxy anyway.
+	out, err = newRunnable(envars.EnvSlice{"GOPROXY=https://tool.proxy"}, opts...).List()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "http://proxy.corp:3128 https://tool.proxy /etc/corp/ca.pem", out)
+
+	out, err = newRunnable(nil, append(opts, WithOffline(true))...).List()
+	testutil.Ok(t, err)
+	testutil.Equals(t, "http://proxy.corp:3128 off /etc/corp/ca.pem", out)
+}
+
+func TestRunnable_BuildCommand(t *testing.T) {
+	// Fake go that records build arguments, so the command can be compared with what Build runs.
+	dir := t.TempDir()
+	goCmd := fakecmd.Write(t, dir, "go", "echo \"$@\" > \"$CALLS_FILE\"\n")
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+	t.Setenv("BINGO_TEST_TAGS", "netgo")
+
+	r := &Runner{goCmd: goCmd, logger: logging.Discard, goVersion: semver.MustParse("1.21"), reproducible: true}
+	ru := r.With(context.Background(), "tool.mod", dir, envars.EnvSlice{"CGO_ENABLED=0", "GOFLAGS=-tags=${BINGO_TEST_TAGS}"})
+
+	cmd, err := ru.BuildCommand("pkg", "out", "-ldflags=-s -w")
+	testutil.Ok(t, err)
+	testutil.Equals(t, Command{
+		Dir:  dir,
+		Env:  envars.EnvSlice{"CGO_ENABLED=0", "GO111MODULE=on", "GOFLAGS=-tags=netgo", "GOWORK=off"},
+		Args: []string{goCmd, "build", "-modfile=tool.mod", "-o=out", "-trimpath", "-buildvcs=false", "-ldflags=-s -w", "pkg"},
+	}, cmd)
+
+	testutil.Ok(t, ru.Build("pkg", "out", "-ldflags=-s -w"))
+	b, err := os.ReadFile(callsFile)
+	testutil.Ok(t, err)
+	testutil.Equals(t, strings.Join(cmd.Args[1:], " "), strings.TrimSpace(string(b)))
+}
+
+func TestRunnable_ModQueryRef(t *testing.T) {
+	// Fake go that prints go list -m -json output for queries resolved from a tag, branch and without origin.
+	dir := t.TempDir()
+	goCmd := fakecmd.Write(t, dir, "go", `for last; do :; done
+case "$last" in
+  *@release-2024-01) echo '{"Path":"github.com/x/tool","Version":"v0.0.0-20240105120000-def123456789","Origin":{"VCS":"git","Ref":"refs/tags/release-2024-01"}}' ;;
+  *@main) echo '{"Path":"github.com/x/tool","Version":"v0.0.0-20200519204825-abc123456789","Origin":{"VCS":"git","Ref":"refs/heads/main"}}' ;;
+  *@v1.0.0) echo '{"Path":"github.com/x/tool","Version":"v1.0.0"}' ;;
+  *) echo "go: $last: unknown revision" >&2; exit 1 ;;
+esac
+`)
+
+	r := &Runner{goCmd: goCmd, logger: logging.Discard, goVersion: semver.MustParse("1.21")}
+	ru := r.With(context.Background(), "tool.mod", dir, nil)
+	for _, tcase := range []struct {
+		query, expectedVersion, expectedRef string
+	}{
+		{query: "release-2024-01", expectedVersion: "v0.0.0-20240105120000-def123456789", expectedRef: "refs/tags/release-2024-01"},
+		{query: "main", expectedVersion: "v0.0.0-20200519204825-abc123456789", expectedRef: "refs/heads/main"},
+		{query: "v1.0.0", expectedVersion: "v1.0.0"},
+	} {
+		v, ref, err := ru.ModQueryRef("github.com/x/tool", tcase.query)
+		testutil.Ok(t, err)
+		testutil.Equals(t, tcase.expectedVersion, v)
+		testutil.Equals(t, tcase.expectedRef, ref)
+	}
+
+	_, _, err := ru.ModQueryRef("github.com/x/tool", "release-1999")
+	testutil.NotOk(t, err)
+	testutil.Assert(t, errors.Is(err, ErrModuleNotFound))
+}
+
+func TestRunnable_ModList(t *testing.T) {
+	// Fake go that prints download log followed by go list -m -json stream.
+	dir := t.TempDir()
+	goCmd := fakecmd.Write(t, dir, "go", `echo "go: downloading github.com/x/dep v0.1.0"
+cat <<EOF
+{
+	"Path": "_",
+	"Main": true
+}
+{
+	"Path": "github.com/x/dep",
+	"Version": "v0.1.0",
+	"Dir": "/cache/github.com/x/dep@v0.1.0",
+	"GoMod": "/cache/download/github.com/x/dep/@v/v0.1.0.mod"
+}
+{
+	"Path": "github.com/x/other",
+	"Version": "v0.2.0",
+	"Replace": {
+		"Path": "../other"
+	}
+}
+EOF
+`)
+
+	r := &Runner{goCmd: goCmd, logger: logging.Discard, goVersion: semver.MustParse("1.21")}
+	mods, err := r.With(context.Background(), "tool.mod", dir, nil).ModList("all")
+	testutil.Ok(t, err)
+	testutil.Equals(t, []Module{
+		{Path: "_", Main: true},
+		{Path: "github.com/x/dep", Version: "v0.1.0", Dir: "/cache/github.com/x/dep@v0.1.0", GoMod: "/cache/download/github.com/x/dep/@v/v0.1.0.mod"},
+		{Path: "github.com/x/other", Version: "v0.2.0", Replace: &Module{Path: "../other"}},
+	}, mods)
+}
+
+func TestRunner_GoEnv(t *testing.T) {
+	// Fake go that records each call and prints GOMODCACHE_VALUE for GOMODCACHE and /gopath1:/gopath2 for GOPATH.
+	dir := t.TempDir()
+	goCmd := fakecmd.Write(t, dir, "go", `echo "$@" >> "$CALLS_FILE"
+case "$2" in
+  GOMODCACHE) echo "$GOMODCACHE_VALUE" ;;
+  GOPATH) echo "/gopath1:/gopath2" ;;
+  *) echo "unknown variable $2" >&2; exit 1 ;;
+esac
+`)
+	callsFile := filepath.Join(dir, "calls")
+	t.Setenv("CALLS_FILE", callsFile)
+	calls := func() []string {
+		b, err := os.ReadFile(callsFile)
+		testutil.Ok(t, err)
+		return strings.Split(strings.TrimSpace(string(b)), "\n")
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		t.Setenv("GOMODCACHE_VALUE", "/cache")
+		testutil.Ok(t, os.WriteFile(callsFile, nil, 0600))
+
+		r := &Runner{goCmd: goCmd, logger: logging.Discard}
+		for i := 0; i < 3; i++ {
+			cache, err := r.GoModCache(context.Background())
+			testutil.Ok(t, err)
+			testutil.Equals(t, "/cache", cache)
+		}
+		testutil.Equals(t, []string{"env GOMODCACHE"}, calls())
+
+		_, err := r.GoEnv(context.Background(), "GOYOLO")
+		testutil.NotOk(t, err)
+		_, err = r.GoEnv(context.Background(), "GOYOLO")
+		testutil.NotOk(t, err)
+		// Errors are not cached.
+		testutil.Equals(t, []string{"env GOMODCACHE", "env GOYOLO", "env GOYOLO"}, calls())
+	})
+	t.Run("fallback to GOPATH", func(t *testing.T) {
+		t.Setenv("GOMODCACHE_VALUE", "")
+		testutil.Ok(t, os.WriteFile(callsFile, nil, 0600))
+
+		r := &Runner{goCmd: goCmd, logger: logging.Discard}
+		cache, err := r.GoModCache(context.Background())
+		testutil.Ok(t, err)
+		testutil.Equals(t, filepath.Join("/gopath1", "pkg", "mod"), cache)
+		testutil.Equals(t, []string{"env GOMODCACHE", "env GOPATH"}, calls())
+	})
+}
+
+func TestParseBuildInfo(t *testing.T) {
+	t.Run("released module", func(t *testing.T) {
+		info, err := parseBuildInfo(`/gobin/faillint: go1.21.4
+	path	github.com/fatih/faillint
+	mod	github.com/fatih/faillint	v1.5.0	h1:fUolG+EsD6zdRW4rapzrM0tSf7VdpxWG3GLCPafUOcE=
+	dep	golang.org/x/tools	v0.0.0-20200207224406-61798d64f025	h1:i84/3szN87uN9jFX/jRqUbszQto2oAsFlqPf6lbR8H4=
+	=>	golang.org/x/tools	v0.1.0	h1:1B2E0r0Xb2e9n2j1aGVz7n6yYkFkEP+S6Ih5kmlDvmg=
+	dep	golang.org/x/mod	v0.12.0	h1:rmsUpXtvNzj340zd98LZ4KntptpfRHwpFOHG188oHXc=
+	build	-tags=yolo
+	build	CGO_ENABLED=0
+`)
+		testutil.Ok(t, err)
+		testutil.Equals(t, BuildInfo{
+			GoVersion: "go1.21.4",
+			Path:      "github.com/fatih/faillint",
+			Main:      module.Version{Path: "github.com/fatih/faillint", Version: "v1.5.0"},
+			Deps: []module.Version{
+				{Path: "golang.org/x/tools", Version: "v0.0.0-20200207224406-61798d64f025"},
+				{Path: "golang.org/x/mod", Version: "v0.12.0"},
+			},
+			DepReplaces: map[string]module.Version{"golang.org/x/tools": {Path: "golang.org/x/tools", Version: "v0.1.0"}},
+			Settings:    []string{"-tags=yolo", "CGO_ENABLED=0"},
+		}, info)
+		testutil.Equals(t, []Dependency{
+			{
+				Version: module.Version{Path: "golang.org/x/tools", Version: "v0.0.0-20200207224406-61798d64f025"},
+				Replace: &module.Version{Path: "golang.org/x/tools", Version: "v0.1.0"},
+			},
+			{Version: module.Version{Path: "golang.org/x/mod", Version: "v0.12.0"}},
+		}, info.Dependencies())
+	})
+	t.Run("replaced main module", func(t *testing.T) {
+		info, err := parseBuildInfo(`/gobin/foo: go1.21.4
+	path	github.com/x/tool/cmd/foo
+	mod	github.com/x/tool	v0.0.0-00010101000000-000000000000
+	=>	../tool	(devel)	
+
+	build	-compiler=gc
+`)
+		testutil.Ok(t, err)
+		testutil.Equals(t, BuildInfo{
+			GoVersion:   "go1.21.4",
+			Path:        "github.com/x/tool/cmd/foo",
+			Main:        module.Version{Path: "github.com/x/tool", Version: "v0.0.0-00010101000000-000000000000"},
+			MainReplace: &module.Version{Path: "../tool", Version: "(devel)"},
+			Settings:    []string{"-compiler=gc"},
+		}, info)
+	})
+	t.Run("no module info", func(t *testing.T) {
+		_, err := parseBuildInfo(`/gobin/old: go1.10
+`)
+		testutil.NotOk(t, err)
+	})
+}
+
+func TestGoError(t *testing.T) {
+	for _, tcase := range []struct {
+		name     string
+		output   string
+		compiles bool
+
+		expectedKind error
+	}{
+		{
+			name:         "proxy 404",
+			output:       "go: github.com/fatih/faillint@v9.9.9: reading https://proxy.golang.org/github.com/fatih/faillint/@v/v9.9.9.info: 404 Not Found\n",
+			expectedKind: ErrModuleNotFound,
+		},
+		{
+			name:         "unknown revision",
+			output:       "go: github.com/fatih/faillint@abcdef1: invalid version: unknown revision abcdef1\n",
+			expectedKind: ErrModuleNotFound,
+		},
+		{
+			name:         "missing package",
+			output:       "go: module github.com/fatih/faillint@v1.5.0 found, but does not contain package github.com/fatih/faillint/yolo\n",
+			expectedKind: ErrModuleNotFound,
+		},
+		{
+			name:         "private repository",
+			output:       "go: github.com/x/private@v1.0.0: git ls-remote -q origin: exit status 128:\n\tremote: Repository not found.\n",
+			expectedKind: ErrModuleNotFound,
+		},
+		{
+			name:         "private proxy without credentials",
+			output:       "go: internal.example.com/tool@v1.0.0: reading https://goproxy.internal.example.com/internal.example.com/tool/@v/v1.0.0.info: 401 Unauthorized\n",
+			expectedKind: ErrAuth,
+		},
+		{
+			name: "git without credentials",
+			output: "go: github.com/x/private@v1.0.0: git ls-remote -q origin: exit status 128:\n" +
+				"\tfatal: could not read Username for 'https://github.com': terminal prompts disabled\n",
+			expectedKind: ErrAuth,
+		},
+		{
+			name: "git forbidden without credentials",
+			output: "go: github.com/x/private@v1.0.0: git ls-remote -q origin: exit status 128:\n" +
+				"\tremote: 403 Forbidden\n\tfatal: could not read Username for 'https://github.com': terminal prompts disabled\n",
+			expectedKind: ErrAuth,
+		},
+		{
+			name:         "proxy policy",
+			output:       "go: github.com/x/tool@v1.0.0: reading https://goproxy.internal.example.com/github.com/x/tool/@v/v1.0.0.info: 403 Forbidden\n",
+			expectedKind: ErrForbidden,
+		},
+		{
+			name:         "DNS",
+			output:       "go: github.com/fatih/faillint@v1.5.0: Get \"https://proxy.golang.org/github.com/fatih/faillint/@v/v1.5.0.info\": dial tcp: lookup proxy.golang.org: no such host\n",
+			expectedKind: ErrNetwork,
+		},
+		{
+			name:         "proxy unavailable",
+			output:       "go: github.com/fatih/faillint@v1.5.0: reading https://proxy.golang.org/github.com/fatih/faillint/@v/v1.5.0.zip: 503 Service Unavailable\n",
+			compiles:     true,
+			expectedKind: ErrNetwork,
+		},
+		{
+			name:         "compile error",
+			output:       "# github.com/x/tool\n../../go/pkg/mod/github.com/x/tool@v1.0.0/main.go:12:2: undefined: yolo\n",
+			compiles:     true,
+			expectedKind: ErrBuildFailed,
+		},
+		{
+			name:         "offline",
+			output:       "go: github.com/fatih/faillint@v1.5.0: module lookup disabled by GOPROXY=off\n",
+			expectedKind: ErrNotInModCache,
+		},
+		{
+			name:         "offline build",
+			output:       "main.go:3:8: cannot find module providing package github.com/fatih/faillint: module lookup disabled by GOPROXY=off\n",
+			compiles:     true,
+			expectedKind: ErrNotInModCache,
+		},
+		{
+			name:   "compile error output for non compiling command",
+			output: "main.go:12:2: undefined: yolo\n",
+		},
+		{
+			name:     "unknown",
+			output:   "go: something went wrong\n",
+			compiles: true,
+		},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			err := newGoError(errors.New("exit 1"), tcase.output, tcase.compiles)
+			testutil.Equals(t, tcase.output+": exit 1", err.Error())
+			testutil.Equals(t, tcase.expectedKind, err.Kind)
+
+			wrapped := errors.Wrap(err, "get")
+			for _, kind := range []error{ErrModuleNotFound, ErrNetwork, ErrAuth, ErrForbidden, ErrBuildFailed, ErrNotInModCache} {
+				testutil.Equals(t, kind == tcase.expectedKind, errors.Is(wrapped, kind))
+			}
+
+			var goErr *GoError
+			testutil.Assert(t, errors.As(wrapped, &goErr))
+			testutil.Equals(t, tcase.output, goErr.Output)
+		})
+	}
+}
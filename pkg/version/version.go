@@ -3,7 +3,13 @@
 
 package version
 
-import "github.com/Masterminds/semver"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/efficientgo/core/errors"
+)
 
 // Version returns 'bingo' version.
 const Version = "v0.9"
@@ -11,5 +17,41 @@ const Version = "v0.9"
 var (
 	Go114 = semver.MustParse("1.14")
 	Go116 = semver.MustParse("1.16")
+	Go118 = semver.MustParse("1.18")
+	Go119 = semver.MustParse("1.19")
+	Go120 = semver.MustParse("1.20")
 	Go121 = semver.MustParse("1.21")
+	Go122 = semver.MustParse("1.22")
+	Go123 = semver.MustParse("1.23")
+
+	// Latest is the latest Go version bingo knows about.
+	Latest = Go123
 )
+
+// Parse parses Go version in any form Go uses, e.g. in go directive or toolchain name: "1.21", "1.21.4", "go1.21.4" or
+// "1.21rc2". Pre-release suffix is kept as semver pre-release, so "1.21rc2" is lower than "1.21".
+func Parse(v string) (*semver.Version, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(v), "go")
+	if i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }); i > 0 && s[i] != '-' {
+		s = s[:i] + "-" + s[i:]
+	}
+	sv, err := semver.NewVersion(s)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse Go version %q", v)
+	}
+	return sv, nil
+}
+
+// AtLeast returns true if v is the same or newer than min.
+func AtLeast(v, min *semver.Version) bool {
+	return !v.LessThan(min)
+}
+
+// ModInitGoVersion returns go directive version `go mod init` writes when run with the given Go version.
+// Starting from Go 1.21 it's complete version (e.g. "1.21.4"), before it's <major>.<minor> (e.g. "1.20").
+func ModInitGoVersion(v *semver.Version) string {
+	if !AtLeast(v, Go121) {
+		return fmt.Sprintf("%v.%v", v.Major(), v.Minor())
+	}
+	return v.String()
+}
@@ -0,0 +1,70 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package version
+
+import (
+	"testing"
+
+	"github.com/efficientgo/core/testutil"
+)
+
+func TestParse(t *testing.T) {
+	for _, tcase := range []struct {
+		input string
+
+		expected    string
+		expectedErr string
+	}{
+		{input: "1.21", expected: "1.21.0"},
+		{input: "1.21.4", expected: "1.21.4"},
+		{input: "go1.21.4", expected: "1.21.4"},
+		{input: "1.21rc2", expected: "1.21.0-rc2"},
+		{input: "go1.22beta1", expected: "1.22.0-beta1"},
+		{input: "1", expected: "1.0.0"},
+		{input: "yolo", expectedErr: `parse Go version "yolo": Invalid Semantic Version`},
+	} {
+		t.Run(tcase.input, func(t *testing.T) {
+			v, err := Parse(tcase.input)
+			if tcase.expectedErr != "" {
+				testutil.NotOk(t, err)
+				testutil.Equals(t, tcase.expectedErr, err.Error())
+				return
+			}
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, v.String())
+		})
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	for _, tcase := range []struct {
+		v        string
+		expected bool
+	}{
+		{v: "1.20.14", expected: false},
+		{v: "1.21rc2", expected: false},
+		{v: "1.21", expected: true},
+		{v: "1.21.4", expected: true},
+		{v: "1.23", expected: true},
+	} {
+		t.Run(tcase.v, func(t *testing.T) {
+			v, err := Parse(tcase.v)
+			testutil.Ok(t, err)
+			testutil.Equals(t, tcase.expected, AtLeast(v, Go121))
+		})
+	}
+}
+
+func TestModInitGoVersion(t *testing.T) {
+	for input, expected := range map[string]string{
+		"1.17":   "1.17",
+		"1.20.5": "1.20",
+		"1.21":   "1.21.0",
+		"1.22.3": "1.22.3",
+	} {
+		v, err := Parse(input)
+		testutil.Ok(t, err)
+		testutil.Equals(t, expected, ModInitGoVersion(v))
+	}
+}
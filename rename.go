@@ -0,0 +1,130 @@
+// Copyright (c) Bartłomiej Płotka @bwplotka
+// Licensed under the Apache License 2.0.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bwplotka/bingo/pkg/bingo"
+	"github.com/bwplotka/bingo/pkg/logging"
+	"github.com/efficientgo/core/errcapture"
+	"github.com/efficientgo/core/errors"
+)
+
+// renameTool renames pinned tool, so its module files and binary follow the new name. The tool is reinstalled under the new
+// binary name, then old binaries and links pointing to them (in GOBIN and link directory) are removed.
+func renameTool(ctx context.Context, logger logging.Logger, c getConfig, gobin, name, newName string) (err error) {
+	if name == newName {
+		return errors.Newf("tool is already named %v", newName)
+	}
+	existing, err := existingModFiles(c.modDir, name)
+	if err != nil {
+		return errors.Wrapf(err, "existing mod files for %v", name)
+	}
+	if len(existing) == 0 {
+		return errors.Newf("nothing to rename, tool %v is not pinned", name)
+	}
+	newExisting, err := existingModFiles(c.modDir, newName)
+	if err != nil {
+		return errors.Wrapf(err, "existing mod files for %v", newName)
+	}
+	if len(newExisting) > 0 {
+		return errors.Newf("found existing module files %v under name you want to rename to. Remove tool %s first or use different name", newExisting, newName)
+	}
+
+	// Open all first, so nothing is renamed if any of module files is broken.
+	mfs := make([]*bingo.ModFile, 0, len(existing))
+	closeAll := func() {
+		for _, mf := range mfs {
+			errcapture.Do(&err, mf.Close, "close")
+		}
+		mfs = nil
+	}
+	defer closeAll()
+	for _, e := range existing {
+		mf, err := bingo.OpenModFile(e)
+		if err != nil {
+			return errors.Wrapf(err, "found unparsable mod file %v. Fix it manually first", e)
+		}
+		mfs = append(mfs, mf)
+
+		if err := mf.Validate(); err != nil {
+			return errors.Wrapf(err, "found malformed mod file %v. Fix it manually first", e)
+		}
+		if mf.DirectPackage() == nil {
+			return errors.Newf("found empty mod file %v; use full path to install tool again", e)
+		}
+	}
+
+	var (
+		oldLinkNames = map[string]struct{}{}
+		oldBinaries  []string
+	)
+	for _, mf := range mfs {
+		binNames, err := bingo.BinaryNames(name, mf.DirectPackages())
+		if err != nil {
+			return errors.Wrap(err, mf.Filepath())
+		}
+		// Only the main binary is named after the tool, additional packages keep their names.
+		if binNames[0] != newName {
+			pkg := *mf.DirectPackage()
+			pkg.Name = binNames[0]
+			oldLinkNames[binNames[0]+pkg.PlatformSuffix()+pkg.ExeSuffix()] = struct{}{}
+			oldBinaries = append(oldBinaries, bingo.BinaryPath(pkg, gobin))
+		}
+		if err := mf.Rename(newName); err != nil {
+			return errors.Wrapf(err, "rename %v", mf.Filepath())
+		}
+	}
+	closeAll()
+	if err != nil {
+		return err
+	}
+	// Meta files describe old binaries, new ones are written on install.
+	if err := removeAllGlob(filepath.Join(c.modDir, name+".*")); err != nil {
+		return err
+	}
+
+	if err := get(ctx, logger, c, newName); err != nil {
+		return errors.Wrapf(err, "reinstall %v", newName)
+	}
+
+	removed := map[string]struct{}{}
+	for _, b := range oldBinaries {
+		if err := os.Remove(b); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return errors.Wrapf(err, "remove old binary %v", b)
+		}
+		removed[filepath.Base(b)] = struct{}{}
+		_, _ = fmt.Fprintf(os.Stdout, "removed %v\n", b)
+	}
+
+	linkDirs := []string{gobin}
+	if c.linkDir != "" {
+		linkDirs = append(linkDirs, c.linkDir)
+	}
+	for _, dir := range linkDirs {
+		for linkName := range oldLinkNames {
+			l := filepath.Join(dir, linkName)
+			target, err := os.Readlink(l)
+			if err != nil {
+				// Not a link (e.g. copied binary) or does not exist.
+				continue
+			}
+			if _, ok := removed[filepath.Base(target)]; !ok {
+				continue
+			}
+			if err := os.Remove(l); err != nil {
+				return errors.Wrapf(err, "remove old link %v", l)
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "removed %v\n", l)
+		}
+	}
+	return nil
+}